@@ -0,0 +1,103 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package certstore
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// KeySecurity returns DACL of private key of certificate c, in SDDL.
+func (c *Certificate) KeySecurity() (string, error) {
+	k, err := acquireKey(c.ctx, c.Leaf.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	defer k.close()
+	prop := syscall.StringToUTF16Ptr(winapi.NCRYPT_SECURITY_DESCR_PROPERTY)
+	var n uint32
+	err = winapi.NCryptGetProperty(k.key, prop, nil, 0, &n, winapi.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, n)
+	err = winapi.NCryptGetProperty(k.key, prop, &b[0], n, &n, winapi.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return "", err
+	}
+	var p *uint16
+	err = winapi.ConvertSecurityDescriptorToStringSecurityDescriptor(&b[0],
+		winapi.SDDL_REVISION_1, winapi.DACL_SECURITY_INFORMATION, &p, nil)
+	if err != nil {
+		return "", err
+	}
+	defer syscall.LocalFree(syscall.Handle(unsafe.Pointer(p)))
+	return winapi.UTF16PtrToString(p, 1<<20), nil
+}
+
+// sddlAliases maps SIDs of well known accounts
+// to abbreviations SDDL uses for them.
+var sddlAliases = map[string]string{
+	"S-1-1-0":      "WD",
+	"S-1-5-11":     "AU",
+	"S-1-5-18":     "SY",
+	"S-1-5-19":     "LS",
+	"S-1-5-20":     "NS",
+	"S-1-5-32-544": "BA",
+	"S-1-5-32-545": "BU",
+}
+
+// allowed reports whether DACL sddl has allow ACE for any of sids.
+func allowed(sddl string, sids ...string) bool {
+	for _, ace := range strings.Split(sddl, "(")[1:] {
+		f := strings.Split(strings.TrimSuffix(ace, ")"), ";")
+		if len(f) < 6 || f[0] != "A" {
+			continue
+		}
+		for _, sid := range sids {
+			if strings.EqualFold(f[5], sid) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckKeyAccess checks DACL of private key of certificate c for
+// allow entry of account, like `NT SERVICE\myservice`, LocalSystem or
+// `NT AUTHORITY\NETWORK SERVICE`, or of Everyone or Authenticated
+// Users. Its error explains how to grant access. Access through
+// other groups is not checked, so CheckKeyAccess is guidance, that
+// is best run by installer, not a guarantee.
+func (c *Certificate) CheckKeyAccess(account string) error {
+	sddl, err := c.KeySecurity()
+	if err != nil {
+		return err
+	}
+	sid, _, _, err := syscall.LookupSID("", account)
+	if err != nil {
+		return err
+	}
+	s, err := sid.String()
+	if err != nil {
+		return err
+	}
+	sids := []string{s, "WD", "AU"}
+	if a, ok := sddlAliases[s]; ok {
+		sids = append(sids, a)
+	}
+	if allowed(sddl, sids...) {
+		return nil
+	}
+	return fmt.Errorf("certstore: %s has no access to private key of certificate %q; "+
+		"grant it Read permission with \"Manage Private Keys...\" in certlm.msc (key DACL is %s)",
+		account, c.Leaf.Subject.CommonName, sddl)
+}
@@ -0,0 +1,181 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Package certstore loads TLS certificates and their private keys
+// from Windows certificate stores, usually the LocalMachine "My"
+// store, so services need no key files on disk. Private keys never
+// leave the store, they sign through CNG. CheckKeyAccess tells,
+// whether service account can use the private key at all, which is
+// the usual reason TLS services fail to start.
+//
+package certstore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// ErrNotFound is returned, wrapped, when store
+// has no certificate matching search.
+var ErrNotFound error = winapi.CRYPT_E_NOT_FOUND
+
+const encoding = winapi.X509_ASN_ENCODING | winapi.PKCS_7_ASN_ENCODING
+
+// Store is an open certificate store.
+type Store struct {
+	h syscall.Handle
+}
+
+// OpenMachineStore opens LocalMachine certificate
+// store name, like "My", for reading.
+func OpenMachineStore(name string) (*Store, error) {
+	return openStore(name, winapi.CERT_SYSTEM_STORE_LOCAL_MACHINE)
+}
+
+// OpenUserStore opens certificate store name of the account
+// process runs as, for reading. Service account has its own.
+func OpenUserStore(name string) (*Store, error) {
+	return openStore(name, winapi.CERT_SYSTEM_STORE_CURRENT_USER)
+}
+
+func openStore(name string, location uint32) (*Store, error) {
+	p, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	h, err := syscall.CertOpenStore(winapi.CERT_STORE_PROV_SYSTEM_W, 0, 0,
+		location|winapi.CERT_STORE_OPEN_EXISTING_FLAG|winapi.CERT_STORE_READONLY_FLAG,
+		uintptr(unsafe.Pointer(p)))
+	if err != nil {
+		return nil, err
+	}
+	return &Store{h: h}, nil
+}
+
+// Close closes store s. Certificates found in s stay valid.
+func (s *Store) Close() error {
+	return syscall.CertCloseStore(s.h, 0)
+}
+
+// FindByThumbprint returns certificate with SHA-1 thumbprint
+// thumbprint, in hex, as shown by certlm.msc. Spaces are ignored.
+func (s *Store) FindByThumbprint(thumbprint string) (*Certificate, error) {
+	b, err := hex.DecodeString(strings.Replace(thumbprint, " ", "", -1))
+	if err != nil {
+		return nil, errors.New("certstore: invalid thumbprint " + thumbprint)
+	}
+	blob := winapi.DATA_BLOB{Size: uint32(len(b)), Data: &b[0]}
+	ctx, err := winapi.CertFindCertificateInStore(s.h, encoding, 0,
+		winapi.CERT_FIND_SHA1_HASH, uintptr(unsafe.Pointer(&blob)), nil)
+	if err != nil {
+		return nil, err
+	}
+	return newCertificate(ctx)
+}
+
+// FindBySubject returns certificate with subject containing subject,
+// like host name. If many match, valid certificate, that expires last,
+// is returned, so renewed certificate is picked up once installed.
+func (s *Store) FindBySubject(subject string) (*Certificate, error) {
+	p, err := syscall.UTF16PtrFromString(subject)
+	if err != nil {
+		return nil, err
+	}
+	var best *Certificate
+	now := time.Now()
+	var ctx *syscall.CertContext
+	for {
+		// ctx passed in is freed by CertFindCertificateInStore
+		ctx, err = winapi.CertFindCertificateInStore(s.h, encoding, 0,
+			winapi.CERT_FIND_SUBJECT_STR_W, uintptr(unsafe.Pointer(p)), ctx)
+		if err != nil {
+			break
+		}
+		c, cerr := newCertificate(winapi.CertDuplicateCertificateContext(ctx))
+		if cerr != nil {
+			continue
+		}
+		if now.Before(c.Leaf.NotBefore) || now.After(c.Leaf.NotAfter) ||
+			(best != nil && !c.Leaf.NotAfter.After(best.Leaf.NotAfter)) {
+			c.Close()
+			continue
+		}
+		if best != nil {
+			best.Close()
+		}
+		best = c
+	}
+	if best == nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, fmt.Errorf("certstore: no valid certificate for %s: %w", subject, err)
+		}
+		return nil, err
+	}
+	return best, nil
+}
+
+// Certificate is a certificate found in certificate store.
+type Certificate struct {
+	Leaf *x509.Certificate
+	ctx  *syscall.CertContext
+	key  *signer // created by TLSCertificate
+}
+
+func newCertificate(ctx *syscall.CertContext) (*Certificate, error) {
+	// copy, ctx memory is freed by Close
+	b := append([]byte(nil), unsafe.Slice(ctx.EncodedCert, ctx.Length)...)
+	leaf, err := x509.ParseCertificate(b)
+	if err != nil {
+		syscall.CertFreeCertificateContext(ctx)
+		return nil, err
+	}
+	return &Certificate{Leaf: leaf, ctx: ctx}, nil
+}
+
+// Close releases certificate c and its private key.
+// tls.Certificate returned by c cannot sign afterwards.
+func (c *Certificate) Close() error {
+	if c.key != nil {
+		c.key.close()
+		c.key = nil
+	}
+	return syscall.CertFreeCertificateContext(c.ctx)
+}
+
+// TLSCertificate returns c for use in tls.Config. Private key stays
+// in the store, and signs as long as c is not closed. Intermediate
+// certificates are included, if chain to trusted root is found.
+func (c *Certificate) TLSCertificate() (tls.Certificate, error) {
+	if c.key == nil {
+		k, err := acquireKey(c.ctx, c.Leaf.PublicKey)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		c.key = k
+	}
+	t := tls.Certificate{
+		Certificate: [][]byte{c.Leaf.Raw},
+		PrivateKey:  c.key,
+		Leaf:        c.Leaf,
+	}
+	chains, err := c.Leaf.Verify(x509.VerifyOptions{KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+	if err == nil && len(chains) > 0 && len(chains[0]) > 2 {
+		// chain ends with root, that clients have already
+		for _, ic := range chains[0][1 : len(chains[0])-1] {
+			t.Certificate = append(t.Certificate, ic.Raw)
+		}
+	}
+	return t, nil
+}
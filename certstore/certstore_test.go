@@ -0,0 +1,46 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package certstore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAllowed(t *testing.T) {
+	const sddl = "D:PAI(A;;FA;;;SY)(A;;FA;;;BA)(D;;FR;;;S-1-5-80-1)(A;;FR;;;S-1-5-80-2)"
+	tests := []struct {
+		sids []string
+		want bool
+	}{
+		{[]string{"SY"}, true},
+		{[]string{"S-1-5-80-2"}, true},
+		{[]string{"S-1-5-80-1"}, false}, // deny entry
+		{[]string{"NS", "WD"}, false},
+	}
+	for _, test := range tests {
+		if got := allowed(sddl, test.sids...); got != test.want {
+			t.Errorf("allowed(%q) = %v, want %v", test.sids, got, test.want)
+		}
+	}
+}
+
+func TestFindByThumbprint(t *testing.T) {
+	s, err := OpenMachineStore("My")
+	if err != nil {
+		t.Fatalf("OpenMachineStore failed: %v", err)
+	}
+	defer s.Close()
+	_, err = s.FindByThumbprint("00 11 22 33 44 55 66 77 88 99 aa bb cc dd ee ff 00 11 22 33")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("FindByThumbprint of missing certificate returned %v, want %v", err, ErrNotFound)
+	}
+	_, err = s.FindByThumbprint("xyz")
+	if err == nil {
+		t.Fatal("FindByThumbprint accepted invalid thumbprint")
+	}
+}
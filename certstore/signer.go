@@ -0,0 +1,137 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package certstore
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// signer is crypto.Signer signing with CNG private key.
+type signer struct {
+	pub crypto.PublicKey
+
+	mu   sync.Mutex
+	key  uintptr // NCRYPT_KEY_HANDLE, 0 once closed
+	free bool    // key must be freed
+}
+
+// acquireKey returns signer for private key of certificate ctx.
+func acquireKey(ctx *syscall.CertContext, pub crypto.PublicKey) (*signer, error) {
+	var key uintptr
+	var spec, free uint32
+	err := winapi.CryptAcquireCertificatePrivateKey(ctx,
+		winapi.CRYPT_ACQUIRE_ONLY_NCRYPT_KEY_FLAG|winapi.CRYPT_ACQUIRE_SILENT_FLAG,
+		0, &key, &spec, &free)
+	if err != nil {
+		return nil, err
+	}
+	switch pub.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		if free != 0 {
+			winapi.NCryptFreeObject(key)
+		}
+		return nil, errors.New("certstore: unsupported public key type")
+	}
+	return &signer{pub: pub, key: key, free: free != 0}, nil
+}
+
+func (s *signer) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key != 0 && s.free {
+		winapi.NCryptFreeObject(s.key)
+	}
+	s.key = 0
+}
+
+func (s *signer) Public() crypto.PublicKey {
+	return s.pub
+}
+
+// hashAlgs maps hashes to CNG algorithm names.
+var hashAlgs = map[crypto.Hash]string{
+	crypto.SHA1:   "SHA1",
+	crypto.SHA256: "SHA256",
+	crypto.SHA384: "SHA384",
+	crypto.SHA512: "SHA512",
+}
+
+func (s *signer) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key == 0 {
+		return nil, errors.New("certstore: certificate is closed")
+	}
+	switch pub := s.pub.(type) {
+	case *rsa.PublicKey:
+		return s.signRSA(digest, opts)
+	case *ecdsa.PublicKey:
+		sig, err := s.sign(digest, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		// CNG returns r and s, TLS wants them ASN.1 encoded
+		n := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*n {
+			return nil, errors.New("certstore: unexpected ECDSA signature size")
+		}
+		return asn1.Marshal(struct{ R, S *big.Int }{
+			new(big.Int).SetBytes(sig[:n]),
+			new(big.Int).SetBytes(sig[n:]),
+		})
+	}
+	return nil, errors.New("certstore: unsupported public key type")
+}
+
+func (s *signer) signRSA(digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	h := opts.HashFunc()
+	var alg *uint16
+	if h != crypto.MD5SHA1 {
+		name, ok := hashAlgs[h]
+		if !ok {
+			return nil, errors.New("certstore: unsupported hash " + h.String())
+		}
+		alg = syscall.StringToUTF16Ptr(name)
+	}
+	if pss, ok := opts.(*rsa.PSSOptions); ok {
+		salt := pss.SaltLength
+		if salt == rsa.PSSSaltLengthAuto || salt == rsa.PSSSaltLengthEqualsHash {
+			salt = h.Size()
+		}
+		info := winapi.BCRYPT_PSS_PADDING_INFO{AlgId: alg, Salt: uint32(salt)}
+		return s.sign(digest, unsafe.Pointer(&info), winapi.BCRYPT_PAD_PSS)
+	}
+	info := winapi.BCRYPT_PKCS1_PADDING_INFO{AlgId: alg}
+	return s.sign(digest, unsafe.Pointer(&info), winapi.BCRYPT_PAD_PKCS1)
+}
+
+// sign signs digest with padding, and returns signature.
+func (s *signer) sign(digest []byte, padding unsafe.Pointer, flags uint32) ([]byte, error) {
+	var n uint32
+	err := winapi.NCryptSignHash(s.key, padding, &digest[0], uint32(len(digest)), nil, 0, &n, flags|winapi.NCRYPT_SILENT_FLAG)
+	if err != nil {
+		return nil, err
+	}
+	sig := make([]byte, n)
+	err = winapi.NCryptSignHash(s.key, padding, &digest[0], uint32(len(digest)), &sig[0], n, &n, flags|winapi.NCRYPT_SILENT_FLAG)
+	if err != nil {
+		return nil, err
+	}
+	return sig[:n], nil
+}
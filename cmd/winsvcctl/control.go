@@ -0,0 +1,154 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/multiplay/winsvc/mgr"
+	"github.com/multiplay/winsvc/svc"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+var reasonFlags = map[string]uint32{
+	"planned":   winapi.SERVICE_STOP_REASON_FLAG_PLANNED,
+	"unplanned": winapi.SERVICE_STOP_REASON_FLAG_UNPLANNED,
+	"custom":    winapi.SERVICE_STOP_REASON_FLAG_CUSTOM,
+}
+
+var reasonMajors = map[string]uint32{
+	"other":           winapi.SERVICE_STOP_REASON_MAJOR_OTHER,
+	"hardware":        winapi.SERVICE_STOP_REASON_MAJOR_HARDWARE,
+	"operatingsystem": winapi.SERVICE_STOP_REASON_MAJOR_OPERATINGSYSTEM,
+	"software":        winapi.SERVICE_STOP_REASON_MAJOR_SOFTWARE,
+	"application":     winapi.SERVICE_STOP_REASON_MAJOR_APPLICATION,
+	"none":            winapi.SERVICE_STOP_REASON_MAJOR_NONE,
+}
+
+var reasonMinors = map[string]uint32{
+	"other":          winapi.SERVICE_STOP_REASON_MINOR_OTHER,
+	"maintenance":    winapi.SERVICE_STOP_REASON_MINOR_MAINTENANCE,
+	"installation":   winapi.SERVICE_STOP_REASON_MINOR_INSTALLATION,
+	"upgrade":        winapi.SERVICE_STOP_REASON_MINOR_UPGRADE,
+	"reconfig":       winapi.SERVICE_STOP_REASON_MINOR_RECONFIG,
+	"hung":           winapi.SERVICE_STOP_REASON_MINOR_HUNG,
+	"unstable":       winapi.SERVICE_STOP_REASON_MINOR_UNSTABLE,
+	"environment":    winapi.SERVICE_STOP_REASON_MINOR_ENVIRONMENT,
+	"securityfix":    winapi.SERVICE_STOP_REASON_MINOR_SECURITYFIX,
+	"security":       winapi.SERVICE_STOP_REASON_MINOR_SECURITY,
+	"connectivity":   winapi.SERVICE_STOP_REASON_MINOR_NETWORK_CONNECTIVITY,
+	"softwareupdate": winapi.SERVICE_STOP_REASON_MINOR_SOFTWARE_UPDATE,
+	"none":           winapi.SERVICE_STOP_REASON_MINOR_NONE,
+}
+
+// parseReason converts stop reason like "planned,application,maintenance"
+// into combination of SERVICE_STOP_REASON_* flag, major and minor reason.
+func parseReason(s string) (uint32, error) {
+	f := strings.Split(s, ",")
+	if len(f) != 3 {
+		return 0, fmt.Errorf("stop reason %q is not flag,major,minor", s)
+	}
+	flag, ok := reasonFlags[f[0]]
+	if !ok {
+		return 0, fmt.Errorf("unknown stop reason flag %q", f[0])
+	}
+	major, ok := reasonMajors[f[1]]
+	if !ok {
+		return 0, fmt.Errorf("unknown major stop reason %q", f[1])
+	}
+	minor, ok := reasonMinors[f[2]]
+	if !ok {
+		return 0, fmt.Errorf("unknown minor stop reason %q", f[2])
+	}
+	return flag | major | minor, nil
+}
+
+func cmdStart(args []string) error {
+	fs := newFlagSet("start")
+	timeout := fs.Duration("timeout", 30*time.Second, "how long to wait for service to start, or 0 not to wait")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		return errUsage
+	}
+	m, s, err := openService(fs.Arg(0), mgr.ServiceStart|mgr.ServiceQueryStatus)
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+	err = s.Start(fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+	if *timeout == 0 {
+		return nil
+	}
+	st, err := s.WaitState(svc.Running, *timeout)
+	if err != nil {
+		return err
+	}
+	printStatus(s.Name, st)
+	return nil
+}
+
+func cmdStop(args []string) error {
+	fs := newFlagSet("stop")
+	reason := fs.String("reason", "", "stop reason, like planned,application,maintenance")
+	comment := fs.String("comment", "", "stop reason comment")
+	timeout := fs.Duration("timeout", 30*time.Second, "how long to wait for service to stop, or 0 not to wait")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+	m, s, err := openService(fs.Arg(0), mgr.ServiceStop|mgr.ServiceQueryStatus)
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+	if *reason != "" {
+		r, err := parseReason(*reason)
+		if err != nil {
+			return err
+		}
+		_, err = s.ControlWithReason(svc.Stop, r, *comment)
+		if err != nil {
+			return err
+		}
+	} else {
+		_, err = s.Control(svc.Stop)
+		if err != nil {
+			return err
+		}
+	}
+	if *timeout == 0 {
+		return nil
+	}
+	st, err := s.WaitState(svc.Stopped, *timeout)
+	if err != nil {
+		return err
+	}
+	printStatus(s.Name, st)
+	return nil
+}
+
+func cmdReload(args []string) error {
+	fs := newFlagSet("reload")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+	m, s, err := openService(fs.Arg(0), mgr.ServiceUserDefinedControl)
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+	return s.Reload()
+}
@@ -0,0 +1,101 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/multiplay/winsvc/eventlog"
+)
+
+var eventTypes = map[uint16]string{
+	eventlog.Error:   "ERROR",
+	eventlog.Warning: "WARNING",
+	eventlog.Info:    "INFO",
+}
+
+func formatRecord(r *eventlog.Record) string {
+	t, ok := eventTypes[r.Type]
+	if !ok {
+		t = fmt.Sprintf("TYPE%d", r.Type)
+	}
+	return fmt.Sprintf("%s %-7s %s[%d]: %s", r.Time.Format("2006-01-02 15:04:05"),
+		t, r.Source, r.EventID, strings.Join(r.Strings, " "))
+}
+
+func cmdEventlog(args []string) error {
+	if len(args) < 1 || args[0] != "tail" {
+		return errUsage
+	}
+	fs := newFlagSet("eventlog")
+	log := fs.String("log", "Application", "event log to read")
+	n := fs.Int("n", 10, "number of last records to print")
+	follow := fs.Bool("f", false, "keep printing new records")
+	fs.Parse(args[1:])
+	if fs.NArg() > 1 {
+		return errUsage
+	}
+	source := fs.Arg(0)
+	r, err := eventlog.OpenReader("", *log)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	oldest, count, err := r.Range()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		if !*follow {
+			return nil
+		}
+	} else if source == "" && *n > 0 && uint32(*n) < count {
+		r.Seek(oldest + count - uint32(*n))
+	}
+	// with source filter, last n records of
+	// source are only known after reading all
+	var last []*eventlog.Record
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if source != "" && !strings.EqualFold(rec.Source, source) {
+			continue
+		}
+		last = append(last, rec)
+		if len(last) > *n {
+			last = last[1:]
+		}
+	}
+	for _, rec := range last {
+		fmt.Println(formatRecord(rec))
+	}
+	if !*follow {
+		return nil
+	}
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			time.Sleep(time.Second)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if source != "" && !strings.EqualFold(rec.Source, source) {
+			continue
+		}
+		fmt.Println(formatRecord(rec))
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/multiplay/winsvc/eventlog"
+	"github.com/multiplay/winsvc/firewall"
+	"github.com/multiplay/winsvc/install"
+	"github.com/multiplay/winsvc/mgr"
+)
+
+// spec is service definition, as read from JSON file.
+type spec struct {
+	Name          string
+	DisplayName   string
+	Description   string
+	Exe           string
+	StartType     string // manual (default), auto, delayed or disabled
+	Account       string
+	Password      string
+	Dependencies  []string
+	Recovery      string // like "restart/5s,restart/1m,none"
+	RecoveryReset string // like "24h"
+	EventLog      bool
+	Firewall      []firewall.Rule
+}
+
+// definition converts s into install.Definition.
+func (s *spec) definition() (*install.Definition, error) {
+	if s.Name == "" {
+		return nil, fmt.Errorf("service name is empty")
+	}
+	if s.Exe == "" {
+		return nil, fmt.Errorf("service %s executable is empty", s.Name)
+	}
+	st, delayed := mgr.StartManual, false
+	if s.StartType != "" {
+		var err error
+		st, delayed, err = mgr.ParseStartType(s.StartType)
+		if err != nil {
+			return nil, err
+		}
+	}
+	d := &install.Definition{
+		Name:    s.Name,
+		ExePath: s.Exe,
+		Config: mgr.Config{
+			StartType:        st,
+			DisplayName:      s.DisplayName,
+			Description:      s.Description,
+			ServiceStartName: s.Account,
+			Password:         s.Password,
+			Dependencies:     s.Dependencies,
+			DelayedAutoStart: delayed,
+		},
+		Firewall: s.Firewall,
+	}
+	if s.Recovery != "" {
+		var err error
+		d.Recovery, err = parseActions(s.Recovery)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.RecoveryReset != "" {
+		var err error
+		d.RecoveryReset, err = time.ParseDuration(s.RecoveryReset)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.EventLog {
+		d.EventLog = eventlog.Error | eventlog.Warning | eventlog.Info
+	}
+	return d, nil
+}
+
+func cmdInstall(args []string) error {
+	fs := newFlagSet("install")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var s spec
+	err = json.NewDecoder(f).Decode(&s)
+	if err != nil {
+		return fmt.Errorf("%s: %v", fs.Arg(0), err)
+	}
+	d, err := s.definition()
+	if err != nil {
+		return fmt.Errorf("%s: %v", fs.Arg(0), err)
+	}
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	err = install.Install(m, d)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("service %s installed\n", d.Name)
+	return nil
+}
+
+func cmdUninstall(args []string) error {
+	fs := newFlagSet("uninstall")
+	timeout := fs.Duration("timeout", 30*time.Second, "how long to wait for service to stop")
+	elog := fs.Bool("eventlog", true, "remove event source named after service")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	r, err := install.Uninstall(m, fs.Arg(0), install.Options{Timeout: *timeout, EventLog: *elog})
+	if r != nil {
+		for _, s := range r.Removed {
+			fmt.Println("removed", s)
+		}
+	}
+	return err
+}
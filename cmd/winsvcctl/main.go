@@ -0,0 +1,171 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Winsvcctl manages Windows services, like sc.exe does.
+//
+// Usage:
+//
+//	winsvcctl [-elevate] COMMAND [ARGS]
+//
+// Commands are:
+//
+//	winsvcctl install FILE.json
+//	winsvcctl uninstall [-timeout T] [-eventlog=false] NAME
+//	winsvcctl start [-timeout T] NAME [ARG...]
+//	winsvcctl stop [-reason R] [-comment C] [-timeout T] NAME
+//	winsvcctl reload NAME
+//	winsvcctl status [-watch] [-interval T] NAME
+//	winsvcctl recovery show NAME
+//	winsvcctl recovery set [-reset T] [-command C] [-noncrash] NAME ACTIONS
+//	winsvcctl triggers list NAME
+//	winsvcctl eventlog tail [-log L] [-n N] [-f] [SOURCE]
+//
+// Install reads service definition from JSON file, like:
+//
+//	{
+//		"Name": "myservice",
+//		"DisplayName": "My Service",
+//		"Exe": "C:\\Program Files\\My\\myservice.exe",
+//		"StartType": "delayed",
+//		"Recovery": "restart/5s,restart/1m,none",
+//		"RecoveryReset": "24h",
+//		"EventLog": true,
+//		"Firewall": [{"Protocol": "tcp", "Ports": [8080]}]
+//	}
+//
+// ACTIONS is a comma separated list of restart, reboot, run or none,
+// each optionally followed by /delay, like restart/5s.
+//
+// Reload asks service, that uses svc.WithReload, to reload
+// its configuration without restart.
+//
+// Stop reason R is a comma separated flag, major and minor reason,
+// like planned,application,maintenance.
+//
+// Commands, that change services, need administrative rights. With
+// -elevate, winsvcctl run without them relaunches itself elevated,
+// once User Account Control allows it, and exits with its exit code.
+//
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/multiplay/winsvc/elevate"
+	"github.com/multiplay/winsvc/mgr"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+	admin bool // needs administrative rights
+}
+
+var commands map[string]command
+
+func init() {
+	// set in init, as commands refer to commands for usage
+	commands = map[string]command{
+		"install":   {"FILE.json", cmdInstall, true},
+		"uninstall": {"[-timeout T] [-eventlog=false] NAME", cmdUninstall, true},
+		"start":     {"[-timeout T] NAME [ARG...]", cmdStart, true},
+		"stop":      {"[-reason R] [-comment C] [-timeout T] NAME", cmdStop, true},
+		"reload":    {"NAME", cmdReload, true},
+		"status":    {"[-watch] [-interval T] NAME", cmdStatus, false},
+		"recovery":  {"show NAME | set [-reset T] [-command C] [-noncrash] NAME ACTIONS", cmdRecovery, true},
+		"triggers":  {"list NAME", cmdTriggers, false},
+		"eventlog":  {"tail [-log L] [-n N] [-f] [SOURCE]", cmdEventlog, false},
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: winsvcctl [-elevate] COMMAND [ARGS]\n\ncommands:\n")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "\t%s %s\n", name, commands[name].usage)
+	}
+	os.Exit(2)
+}
+
+// errUsage is returned by commands called with wrong arguments.
+var errUsage = fmt.Errorf("wrong arguments")
+
+// newFlagSet returns flag set for command name.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: winsvcctl %s %s\n", name, commands[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// openService connects to service control manager,
+// and opens service name with access rights.
+func openService(name string, access mgr.ServiceAccess) (*mgr.Mgr, *mgr.Service, error) {
+	m, err := mgr.ConnectAccess("", mgr.ManagerConnect)
+	if err != nil {
+		return nil, nil, err
+	}
+	s, err := m.OpenServiceAccess(name, access)
+	if err != nil {
+		m.Disconnect()
+		return nil, nil, err
+	}
+	return m, s, nil
+}
+
+// relaunch runs winsvcctl with args elevated,
+// if it is not, and exits with its exit code.
+func relaunch(args []string) {
+	elevated, err := elevate.IsElevated()
+	if err != nil || elevated {
+		return
+	}
+	ec, err := elevate.Relaunch(args...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "winsvcctl %s: elevation failed: %v\n", args[0], err)
+		os.Exit(1)
+	}
+	os.Exit(int(ec))
+}
+
+func main() {
+	args := os.Args[1:]
+	elevateArg := len(args) > 0 && args[0] == "-elevate"
+	if elevateArg {
+		args = args[1:]
+	}
+	if len(args) < 1 {
+		usage()
+	}
+	c, ok := commands[args[0]]
+	if !ok {
+		usage()
+	}
+	if elevateArg && c.admin {
+		relaunch(args)
+	}
+	err := c.run(args[1:])
+	if err == errUsage {
+		fmt.Fprintf(os.Stderr, "usage: winsvcctl %s %s\n", args[0], c.usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		if c.admin && elevate.Denied(err) {
+			err = fmt.Errorf("%v (run as administrator, or with -elevate)", err)
+		}
+		fmt.Fprintf(os.Stderr, "winsvcctl %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+}
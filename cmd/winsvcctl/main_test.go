@@ -0,0 +1,90 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/multiplay/winsvc/mgr"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+func TestParseActions(t *testing.T) {
+	s := "restart/5s,run/1m0s,reboot,none"
+	got, err := parseActions(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+		{Type: mgr.RunCommand, Delay: time.Minute},
+		{Type: mgr.ComputerReboot},
+		{Type: mgr.NoAction},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseActions(%q) = %v, want %v", s, got, want)
+	}
+	if f := formatActions(got); f != s {
+		t.Errorf("formatActions returns %q, want %q", f, s)
+	}
+	for _, s := range []string{"", "restart/5", "stop/5s"} {
+		_, err := parseActions(s)
+		if err == nil {
+			t.Errorf("parseActions(%q) should fail", s)
+		}
+	}
+}
+
+func TestParseReason(t *testing.T) {
+	r, err := parseReason("planned,application,maintenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := uint32(winapi.SERVICE_STOP_REASON_FLAG_PLANNED |
+		winapi.SERVICE_STOP_REASON_MAJOR_APPLICATION |
+		winapi.SERVICE_STOP_REASON_MINOR_MAINTENANCE)
+	if r != want {
+		t.Errorf("parseReason returns %#x, want %#x", r, want)
+	}
+	for _, s := range []string{"planned", "planned,application", "later,application,maintenance"} {
+		_, err := parseReason(s)
+		if err == nil {
+			t.Errorf("parseReason(%q) should fail", s)
+		}
+	}
+}
+
+func TestSpecDefinition(t *testing.T) {
+	s := spec{
+		Name:          "myservice",
+		Exe:           `C:\myservice.exe`,
+		StartType:     "delayed",
+		Recovery:      "restart/5s",
+		RecoveryReset: "24h",
+		EventLog:      true,
+	}
+	d, err := s.definition()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Config.StartType != mgr.StartAutomatic || !d.Config.DelayedAutoStart {
+		t.Errorf("delayed start type is not converted: %+v", d.Config)
+	}
+	if len(d.Recovery) != 1 || d.RecoveryReset != 24*time.Hour {
+		t.Errorf("recovery is not converted: %v %v", d.Recovery, d.RecoveryReset)
+	}
+	if d.EventLog == 0 {
+		t.Error("event log is not enabled")
+	}
+	s.StartType = "sometimes"
+	_, err = s.definition()
+	if err == nil {
+		t.Error("unknown start type should fail")
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/multiplay/winsvc/mgr"
+)
+
+var actionTypes = map[string]int{
+	"none":    mgr.NoAction,
+	"reboot":  mgr.ComputerReboot,
+	"restart": mgr.ServiceRestart,
+	"run":     mgr.RunCommand,
+}
+
+// parseActions converts list of recovery actions,
+// like "restart/5s,restart/1m,none", into []mgr.RecoveryAction.
+func parseActions(s string) ([]mgr.RecoveryAction, error) {
+	var actions []mgr.RecoveryAction
+	for _, f := range strings.Split(s, ",") {
+		var a mgr.RecoveryAction
+		name, delay := f, ""
+		if i := strings.Index(f, "/"); i >= 0 {
+			name, delay = f[:i], f[i+1:]
+		}
+		t, ok := actionTypes[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown recovery action %q", name)
+		}
+		a.Type = t
+		if delay != "" {
+			d, err := time.ParseDuration(delay)
+			if err != nil {
+				return nil, fmt.Errorf("recovery action %q: %v", f, err)
+			}
+			a.Delay = d
+		}
+		actions = append(actions, a)
+	}
+	return actions, nil
+}
+
+// formatActions is the reverse of parseActions.
+func formatActions(actions []mgr.RecoveryAction) string {
+	var fs []string
+	for _, a := range actions {
+		name := fmt.Sprintf("action%d", a.Type)
+		for n, t := range actionTypes {
+			if t == a.Type {
+				name = n
+			}
+		}
+		if a.Delay != 0 {
+			name += "/" + a.Delay.String()
+		}
+		fs = append(fs, name)
+	}
+	return strings.Join(fs, ",")
+}
+
+func cmdRecovery(args []string) error {
+	if len(args) < 1 {
+		return errUsage
+	}
+	switch args[0] {
+	case "show":
+		return recoveryShow(args[1:])
+	case "set":
+		return recoverySet(args[1:])
+	}
+	return errUsage
+}
+
+func recoveryShow(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	m, s, err := openService(args[0], mgr.ServiceQueryConfig)
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+	actions, err := s.RecoveryActions()
+	if err != nil {
+		return err
+	}
+	reset, err := s.ResetPeriod()
+	if err != nil {
+		return err
+	}
+	cmd, err := s.RecoveryCommand()
+	if err != nil {
+		return err
+	}
+	noncrash, err := s.RecoveryActionsOnNonCrashFailures()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("actions:  %s\n", formatActions(actions))
+	fmt.Printf("reset:    %v\n", time.Duration(reset)*time.Second)
+	fmt.Printf("command:  %s\n", cmd)
+	fmt.Printf("noncrash: %v\n", noncrash)
+	return nil
+}
+
+func recoverySet(args []string) error {
+	fs := newFlagSet("recovery")
+	reset := fs.Duration("reset", 24*time.Hour, "time after which failure count is reset")
+	cmd := fs.String("command", "", "command line run by run action")
+	noncrash := fs.Bool("noncrash", false, "also recover when service stops with nonzero exit code")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return errUsage
+	}
+	actions, err := parseActions(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	m, s, err := openService(fs.Arg(0), mgr.ServiceQueryConfig|mgr.ServiceChangeConfig|mgr.ServiceStart)
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+	err = s.SetRecoveryActions(actions, uint32(*reset/time.Second))
+	if err != nil {
+		return err
+	}
+	if *cmd != "" {
+		err = s.SetRecoveryCommand(*cmd)
+		if err != nil {
+			return err
+		}
+	}
+	return s.SetRecoveryActionsOnNonCrashFailures(*noncrash)
+}
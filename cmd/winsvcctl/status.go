@@ -0,0 +1,99 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/multiplay/winsvc/mgr"
+	"github.com/multiplay/winsvc/svc"
+)
+
+var stateNames = map[svc.State]string{
+	svc.Stopped:         "stopped",
+	svc.StartPending:    "start pending",
+	svc.StopPending:     "stop pending",
+	svc.Running:         "running",
+	svc.ContinuePending: "continue pending",
+	svc.PausePending:    "pause pending",
+	svc.Paused:          "paused",
+}
+
+// acceptNames returns names of commands accepted in a.
+func acceptNames(a svc.Accepted) string {
+	var names []string
+	if a&svc.AcceptStop != 0 {
+		names = append(names, "stop")
+	}
+	if a&svc.AcceptShutdown != 0 {
+		names = append(names, "shutdown")
+	}
+	if a&svc.AcceptPreShutdown != 0 {
+		names = append(names, "preshutdown")
+	}
+	if a&svc.AcceptPauseAndContinue != 0 {
+		names = append(names, "pause,continue")
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+// formatStatus returns one line description of status st. It is
+// not svc.Status String, as scripts parse winsvcctl output.
+func formatStatus(st svc.Status) string {
+	state, ok := stateNames[st.State]
+	if !ok {
+		state = fmt.Sprintf("state %d", st.State)
+	}
+	s := fmt.Sprintf("%s pid=%d accepts=%s", state, st.ProcessId, acceptNames(st.Accepts))
+	if st.CheckPoint != 0 || st.WaitHint != 0 {
+		s += fmt.Sprintf(" checkpoint=%d waithint=%dms", st.CheckPoint, st.WaitHint)
+	}
+	return s
+}
+
+func printStatus(name string, st svc.Status) {
+	fmt.Printf("%s: %s\n", name, formatStatus(st))
+}
+
+func cmdStatus(args []string) error {
+	fs := newFlagSet("status")
+	watch := fs.Bool("watch", false, "keep printing status changes")
+	interval := fs.Duration("interval", time.Second, "how often to poll status when watching")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+	m, s, err := openService(fs.Arg(0), mgr.ServiceQueryStatus)
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+	last := ""
+	for {
+		st, err := s.Query()
+		if err != nil {
+			return err
+		}
+		if f := formatStatus(st); f != last {
+			if *watch {
+				fmt.Printf("%s ", time.Now().Format("15:04:05"))
+			}
+			printStatus(s.Name, st)
+			last = f
+		}
+		if !*watch {
+			return nil
+		}
+		time.Sleep(*interval)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/multiplay/winsvc/mgr"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+var triggerTypes = map[uint32]string{
+	winapi.SERVICE_TRIGGER_TYPE_DEVICE_INTERFACE_ARRIVAL:   "device interface arrival",
+	winapi.SERVICE_TRIGGER_TYPE_IP_ADDRESS_AVAILABILITY:    "ip address availability",
+	winapi.SERVICE_TRIGGER_TYPE_DOMAIN_JOIN:                "domain join",
+	winapi.SERVICE_TRIGGER_TYPE_FIREWALL_PORT_EVENT:        "firewall port event",
+	winapi.SERVICE_TRIGGER_TYPE_GROUP_POLICY:               "group policy",
+	winapi.SERVICE_TRIGGER_TYPE_NETWORK_ENDPOINT:           "network endpoint",
+	winapi.SERVICE_TRIGGER_TYPE_CUSTOM_SYSTEM_STATE_CHANGE: "custom system state change",
+	winapi.SERVICE_TRIGGER_TYPE_CUSTOM:                     "custom",
+	winapi.SERVICE_TRIGGER_TYPE_AGGREGATE:                  "aggregate",
+}
+
+// formatGUID returns g in registry format, like
+// {4D36E96A-E325-11CE-BFC1-08002BE10318}.
+func formatGUID(g winapi.GUID) string {
+	return fmt.Sprintf("{%08X-%04X-%04X-%02X%02X-%02X%02X%02X%02X%02X%02X}",
+		g.Data1, g.Data2, g.Data3, g.Data4[0], g.Data4[1],
+		g.Data4[2], g.Data4[3], g.Data4[4], g.Data4[5], g.Data4[6], g.Data4[7])
+}
+
+func cmdTriggers(args []string) error {
+	if len(args) != 2 || args[0] != "list" {
+		return errUsage
+	}
+	m, s, err := openService(args[1], mgr.ServiceQueryConfig)
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+	triggers, err := s.Triggers()
+	if err != nil {
+		return err
+	}
+	for _, t := range triggers {
+		name, ok := triggerTypes[t.Type]
+		if !ok {
+			name = fmt.Sprintf("type %d", t.Type)
+		}
+		action := "start"
+		if t.Action == mgr.TriggerStop {
+			action = "stop"
+		}
+		fmt.Printf("%s %s %s\n", action, name, formatGUID(t.Subtype))
+		for _, d := range t.Data {
+			if d.Type == winapi.SERVICE_TRIGGER_DATA_TYPE_STRING {
+				for _, s := range utf16Strings(d.Data) {
+					fmt.Printf("\t%q\n", s)
+				}
+			} else {
+				fmt.Printf("\t% x\n", d.Data)
+			}
+		}
+	}
+	return nil
+}
+
+// utf16Strings decodes strings of string trigger data item b.
+func utf16Strings(b []byte) []string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = uint16(b[2*i]) | uint16(b[2*i+1])<<8
+	}
+	return winapi.UTF16ToStrings(u)
+}
@@ -0,0 +1,169 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package debug
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/multiplay/winsvc/svc"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+var simpleControls = map[string]svc.Cmd{
+	"stop":        svc.Stop,
+	"pause":       svc.Pause,
+	"continue":    svc.Continue,
+	"interrogate": svc.Interrogate,
+	"shutdown":    svc.Shutdown,
+	"preshutdown": svc.PreShutdown,
+	"paramchange": svc.ParamChange,
+	"hwprofile":   svc.HardwareProfileChange,
+}
+
+var sessionEvents = map[string]svc.EventType{
+	"console-connect":    winapi.WTS_CONSOLE_CONNECT,
+	"console-disconnect": winapi.WTS_CONSOLE_DISCONNECT,
+	"remote-connect":     winapi.WTS_REMOTE_CONNECT,
+	"remote-disconnect":  winapi.WTS_REMOTE_DISCONNECT,
+	"logon":              winapi.WTS_SESSION_LOGON,
+	"logoff":             winapi.WTS_SESSION_LOGOFF,
+	"lock":               winapi.WTS_SESSION_LOCK,
+	"unlock":             winapi.WTS_SESSION_UNLOCK,
+	"remote-control":     winapi.WTS_SESSION_REMOTE_CONTROL,
+	"create":             winapi.WTS_SESSION_CREATE,
+	"terminate":          winapi.WTS_SESSION_TERMINATE,
+}
+
+var powerEvents = map[string]svc.EventType{
+	"suspend":     winapi.PBT_APMSUSPEND,
+	"resume":      winapi.PBT_APMRESUMESUSPEND,
+	"resume-auto": winapi.PBT_APMRESUMEAUTOMATIC,
+	"status":      winapi.PBT_APMPOWERSTATUSCHANGE,
+	"battery-low": winapi.PBT_APMBATTERYLOW,
+}
+
+var deviceEvents = map[string]svc.EventType{
+	"arrival":         winapi.DBT_DEVICEARRIVAL,
+	"query-remove":    winapi.DBT_DEVICEQUERYREMOVE,
+	"remove-pending":  winapi.DBT_DEVICEREMOVEPENDING,
+	"remove-complete": winapi.DBT_DEVICEREMOVECOMPLETE,
+}
+
+// event looks up event name in events, and fails with list of valid names.
+func event(kind string, events map[string]svc.EventType, f []string) (svc.EventType, error) {
+	if len(f) > 1 {
+		if t, ok := events[f[1]]; ok {
+			return t, nil
+		}
+	}
+	var names []string
+	for n := range events {
+		names = append(names, n)
+	}
+	return 0, fmt.Errorf("%s event must be one of %s", kind, strings.Join(names, ", "))
+}
+
+// ParseControl returns control request described by line, so tests
+// and Controls can simulate requests that are hard to cause for real.
+// Line is one of:
+//
+//	stop, pause, continue, interrogate, shutdown, preshutdown, paramchange, hwprofile
+//	session EVENT ID  (EVENT is logon, logoff, lock, unlock, remote-connect and so on)
+//	power EVENT       (EVENT is suspend, resume, resume-auto, status or battery-low)
+//	device EVENT      (EVENT is arrival, query-remove, remove-pending or remove-complete)
+//	control CODE [EVENTTYPE]
+//
+// Event data is set, like the service control manager does it.
+func ParseControl(line string) (svc.ChangeRequest, error) {
+	f := strings.Fields(line)
+	if len(f) == 0 {
+		return svc.ChangeRequest{}, errors.New("empty control request")
+	}
+	if c, ok := simpleControls[f[0]]; ok && len(f) == 1 {
+		return svc.ChangeRequest{Cmd: c}, nil
+	}
+	r := svc.ChangeRequest{}
+	var err error
+	switch f[0] {
+	case "session":
+		r.Cmd = svc.SessionChange
+		r.EventType, err = event("session", sessionEvents, f)
+		if err != nil {
+			return r, err
+		}
+		if len(f) != 3 {
+			return r, errors.New("session id is missing")
+		}
+		id, err := strconv.ParseUint(f[2], 10, 32)
+		if err != nil {
+			return r, fmt.Errorf("invalid session id %q", f[2])
+		}
+		// winapi.WTS_SESSION_NOTIFICATION
+		r.EventData = make([]byte, 8)
+		binary.LittleEndian.PutUint32(r.EventData, 8)
+		binary.LittleEndian.PutUint32(r.EventData[4:], uint32(id))
+	case "power":
+		r.Cmd = svc.PowerEvent
+		r.EventType, err = event("power", powerEvents, f)
+	case "device":
+		r.Cmd = svc.DeviceEvent
+		r.EventType, err = event("device", deviceEvents, f)
+		// winapi.DEV_BROADCAST_HDR of DBT_DEVTYP_OEM device
+		r.EventData = make([]byte, 12)
+		binary.LittleEndian.PutUint32(r.EventData, 12)
+	case "control":
+		if len(f) < 2 || len(f) > 3 {
+			return r, errors.New("usage: control CODE [EVENTTYPE]")
+		}
+		c, err := strconv.ParseUint(f[1], 0, 32)
+		if err != nil {
+			return r, fmt.Errorf("invalid control code %q", f[1])
+		}
+		r.Cmd = svc.Cmd(c)
+		if len(f) == 3 {
+			t, err := strconv.ParseUint(f[2], 0, 32)
+			if err != nil {
+				return r, fmt.Errorf("invalid event type %q", f[2])
+			}
+			r.EventType = svc.EventType(t)
+		}
+	default:
+		return r, fmt.Errorf("unknown control request %q", line)
+	}
+	return r, err
+}
+
+// Controls reads control requests, one per line in ParseControl
+// format, from r, and sends them to channel returned, that is closed
+// at the end of r. Invalid lines are reported to standard error.
+// Use Controls(os.Stdin) with RunWith to type requests on console.
+func Controls(r io.Reader) <-chan svc.ChangeRequest {
+	c := make(chan svc.ChangeRequest)
+	go func() {
+		defer close(c)
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			if strings.TrimSpace(s.Text()) == "" {
+				continue
+			}
+			req, err := ParseControl(s.Text())
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			}
+			c <- req
+		}
+	}()
+	return c
+}
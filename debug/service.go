@@ -9,6 +9,7 @@
 package debug
 
 import (
+	"errors"
 	"os"
 	"os/signal"
 	"syscall"
@@ -20,6 +21,14 @@ import (
 // The process is running on console, unlike real service. Use Ctrl+C to
 // send "Stop" command to your service.
 func Run(name string, handler svc.Handler) error {
+	return RunWith(name, handler, nil)
+}
+
+// RunWith is like Run, but also passes requests received from controls
+// to handler, so it can be tested with requests, like SessionChange or
+// PowerEvent, that console process never receives. CurrentStatus of
+// requests is set to status reported by handler last.
+func RunWith(name string, handler svc.Handler, controls <-chan svc.ChangeRequest) error {
 	cmds := make(chan svc.ChangeRequest)
 	changes := make(chan svc.Status)
 
@@ -28,10 +37,27 @@ func Run(name string, handler svc.Handler) error {
 
 	go func() {
 		status := svc.Status{State: svc.Stopped}
+		var pending []svc.ChangeRequest
 		for {
+			// send pending requests, while still receiving status changes
+			var out chan svc.ChangeRequest
+			var next svc.ChangeRequest
+			if len(pending) > 0 {
+				out = cmds
+				next = pending[0]
+				next.CurrentStatus = status
+			}
 			select {
 			case <-sig:
-				cmds <- svc.ChangeRequest{svc.Stop, status}
+				pending = append(pending, svc.ChangeRequest{Cmd: svc.Stop})
+			case c, ok := <-controls:
+				if !ok {
+					controls = nil
+					continue
+				}
+				pending = append(pending, c)
+			case out <- next:
+				pending = pending[1:]
 			case status = <-changes:
 			}
 		}
@@ -43,3 +69,14 @@ func Run(name string, handler svc.Handler) error {
 	}
 	return nil
 }
+
+// RunOrConsole runs service named name, like svc.Run, when process
+// is started by the service control manager, and on console, like
+// Run, otherwise.
+func RunOrConsole(name string, handler svc.Handler) error {
+	err := svc.Run(name, handler)
+	if errors.Is(err, svc.ErrNotService) {
+		return Run(name, handler)
+	}
+	return err
+}
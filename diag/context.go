@@ -0,0 +1,176 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package diag
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/registry"
+	"github.com/multiplay/winsvc/svc"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// Context describes where the current process runs. Services run
+// in session 0, on a window station that cannot display windows,
+// so GUI and tray icon code that works on console does nothing
+// there. Context helps to find out why.
+type Context struct {
+	SessionId     uint32
+	Account       string // like DOMAIN\user or NT AUTHORITY\SYSTEM
+	WindowStation string // like WinSta0 or Service-0x0-3e7$
+	Desktop       string // like Default
+	Service       bool   // process runs as a service, not interactively
+
+	// Visible is true, if window station can display
+	// windows and receive user input.
+	Visible bool
+
+	// InteractiveServices is true, if the system allows services
+	// to interact with desktop. Even then, their windows are only
+	// shown in session 0, not on desktop of logged on user.
+	InteractiveServices bool
+}
+
+// Diagnose returns execution context of the current process.
+func Diagnose() (*Context, error) {
+	var c Context
+	err := winapi.ProcessIdToSessionId(uint32(os.Getpid()), &c.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	c.Account, err = account()
+	if err != nil {
+		return nil, err
+	}
+	// minimal Windows editions, like Nano Server, have no desktops
+	if winapi.CapDesktop.Available() {
+		err = c.desktop()
+		if err != nil {
+			return nil, err
+		}
+	}
+	interactive, err := svc.IsAnInteractiveSession()
+	if err != nil {
+		return nil, err
+	}
+	c.Service = !interactive
+	c.InteractiveServices, err = interactiveServices()
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// desktop fills in window station and desktop of c.
+func (c *Context) desktop() error {
+	ws, err := winapi.GetProcessWindowStation()
+	if err != nil {
+		return err
+	}
+	c.WindowStation, err = objectName(ws)
+	if err != nil {
+		return err
+	}
+	var flags winapi.USEROBJECTFLAGS
+	var n uint32
+	err = winapi.GetUserObjectInformation(ws, winapi.UOI_FLAGS,
+		(*byte)(unsafe.Pointer(&flags)), uint32(unsafe.Sizeof(flags)), &n)
+	if err != nil {
+		return err
+	}
+	c.Visible = flags.Flags&winapi.WSF_VISIBLE != 0
+	d, err := winapi.GetThreadDesktop(winapi.GetCurrentThreadId())
+	if err != nil {
+		return err
+	}
+	c.Desktop, err = objectName(d)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// String returns c as multi line report, including
+// explanation of why windows might not be shown.
+func (c *Context) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "session:              %d\n", c.SessionId)
+	fmt.Fprintf(&b, "account:              %s\n", c.Account)
+	fmt.Fprintf(&b, "window station:       %s (visible: %v)\n", c.WindowStation, c.Visible)
+	fmt.Fprintf(&b, "desktop:              %s\n", c.Desktop)
+	fmt.Fprintf(&b, "service:              %v\n", c.Service)
+	fmt.Fprintf(&b, "interactive services: %v\n", c.InteractiveServices)
+	switch {
+	case c.WindowStation == "":
+		b.WriteString("note: this edition of Windows has no window stations, windows cannot be created\n")
+	case c.SessionId == 0:
+		b.WriteString("note: session 0 is isolated from logged on users, windows and tray icons created here are not seen by them\n")
+	case !c.Visible:
+		b.WriteString("note: window station is not visible, windows created here are not displayed\n")
+	}
+	return b.String()
+}
+
+// account returns name of the user the current process runs as.
+func account() (string, error) {
+	t, err := syscall.OpenCurrentProcessToken()
+	if err != nil {
+		return "", err
+	}
+	defer t.Close()
+	u, err := t.GetTokenUser()
+	if err != nil {
+		return "", err
+	}
+	user, domain, _, err := u.User.Sid.LookupAccount("")
+	if err != nil {
+		return "", err
+	}
+	return domain + `\` + user, nil
+}
+
+// objectName returns name of window station or desktop h.
+func objectName(h syscall.Handle) (string, error) {
+	b := make([]uint16, 64)
+	for {
+		var n uint32
+		err := winapi.GetUserObjectInformation(h, winapi.UOI_NAME,
+			(*byte)(unsafe.Pointer(&b[0])), uint32(2*len(b)), &n)
+		if err == nil {
+			return syscall.UTF16ToString(b), nil
+		}
+		if !errors.Is(err, syscall.ERROR_INSUFFICIENT_BUFFER) || n <= uint32(2*len(b)) {
+			return "", err
+		}
+		b = make([]uint16, (n+1)/2)
+	}
+}
+
+// interactiveServices reports whether NoInteractiveServices
+// policy allows services to interact with desktop.
+func interactiveServices() (bool, error) {
+	k, err := registry.OpenKeyAccess(syscall.HKEY_LOCAL_MACHINE,
+		`SYSTEM\CurrentControlSet\Control\Windows`, syscall.KEY_READ)
+	if err != nil {
+		return false, err
+	}
+	defer k.Close()
+	v, err := k.GetUInt32("NoInteractiveServices")
+	if errors.Is(err, syscall.ERROR_FILE_NOT_FOUND) {
+		// missing value means services are not allowed since Vista
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return v == 0, nil
+}
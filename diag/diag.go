@@ -0,0 +1,211 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Package diag provides an optional diagnostics mode for services.
+// A designated custom control starts a loopback pprof listener and
+// writes goroutine and heap dumps, so a production hang can be
+// investigated without redeploying. Sending the control again stops
+// the listener. Diagnose reports execution context of the process,
+// explaining why windows are not shown when it runs as a service.
+// Environment reports whether the process runs in a container.
+//
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	rpprof "runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/multiplay/winsvc/debug"
+	"github.com/multiplay/winsvc/svc"
+)
+
+// DefaultControl is the custom control used when Config.Control is 0.
+const DefaultControl = svc.Cmd(201)
+
+// Config describes diagnostics mode.
+type Config struct {
+	Control svc.Cmd   // custom control (128 to 255) toggling diagnostics
+	Addr    string    // loopback address of pprof listener, "127.0.0.1:0" if empty
+	Dir     string    // directory dumps are written to, no dumps if empty
+	Log     debug.Log // receives listener address and dump file names, if not nil
+}
+
+// Handler returns handler that runs h, but intercepts c.Control
+// to toggle diagnostics mode. Diagnostics are switched off when
+// h returns. c.Control must be user defined control, 128 to 255.
+func Handler(h svc.Handler, c Config) (svc.Handler, error) {
+	if c.Control == 0 {
+		c.Control = DefaultControl
+	}
+	if c.Control < 128 || c.Control > 255 {
+		return nil, fmt.Errorf("diagnostics control %d is not in user defined range 128 to 255", c.Control)
+	}
+	if c.Addr == "" {
+		c.Addr = "127.0.0.1:0"
+	}
+	return &handler{h: h, c: c}, nil
+}
+
+type handler struct {
+	h  svc.Handler
+	c  Config
+	mu sync.Mutex
+	l  net.Listener
+}
+
+func (d *handler) Execute(args []string, r <-chan svc.ChangeRequest, s chan<- svc.Status) (bool, uint32) {
+	in := make(chan svc.ChangeRequest)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case c := <-r:
+				if c.Cmd == d.c.Control {
+					d.toggle()
+					continue
+				}
+				select {
+				case in <- c:
+				case <-done:
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	defer d.stop()
+	defer close(done)
+	return d.h.Execute(args, in, s)
+}
+
+func (d *handler) toggle() {
+	d.mu.Lock()
+	on := d.l != nil
+	d.mu.Unlock()
+	if on {
+		d.stop()
+		d.info("diagnostics stopped")
+		return
+	}
+	err := d.start()
+	if err != nil {
+		d.error(fmt.Sprintf("failed to start diagnostics: %v", err))
+		return
+	}
+	if d.c.Dir == "" {
+		return
+	}
+	names, err := Dump(d.c.Dir)
+	if err != nil {
+		d.error(fmt.Sprintf("failed to write diagnostics dump: %v", err))
+	}
+	for _, n := range names {
+		d.info("diagnostics dump written to " + n)
+	}
+}
+
+func (d *handler) start() error {
+	host, _, err := net.SplitHostPort(d.c.Addr)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return errors.New("diagnostics address " + d.c.Addr + " is not a loopback address")
+	}
+	l, err := net.Listen("tcp", d.c.Addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	go http.Serve(l, mux)
+
+	d.mu.Lock()
+	d.l = l
+	d.mu.Unlock()
+	d.info("diagnostics listening on http://" + l.Addr().String() + "/debug/pprof/")
+	return nil
+}
+
+func (d *handler) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.l != nil {
+		d.l.Close()
+		d.l = nil
+	}
+}
+
+func (d *handler) info(msg string) {
+	if d.c.Log != nil {
+		d.c.Log.Info(1, msg)
+	}
+}
+
+func (d *handler) error(msg string) {
+	if d.c.Log != nil {
+		d.c.Log.Error(1, msg)
+	}
+}
+
+// Dump writes goroutine and heap profiles of the current process
+// into directory dir, and returns names of the files created.
+func Dump(dir string) ([]string, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	ts := time.Now().Format("20060102-150405")
+	dumps := []struct {
+		profile string
+		debug   int
+	}{
+		{"goroutine", 2},
+		{"heap", 0},
+	}
+	var names []string
+	for _, p := range dumps {
+		// time stamp orders dumps, random suffix keeps
+		// dumps taken within the same second apart
+		pattern := p.profile + "-" + ts + "-*.pprof"
+		if p.debug != 0 {
+			pattern = p.profile + "-" + ts + "-*.txt"
+		}
+		name, err := writeProfile(dir, pattern, p.profile, p.debug)
+		if err != nil {
+			return names, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// writeProfile writes profile into new file in directory dir,
+// named after pattern, as ioutil.TempFile does, and returns its name.
+func writeProfile(dir, pattern, profile string, debug int) (string, error) {
+	f, err := ioutil.TempFile(dir, pattern)
+	if err != nil {
+		return "", err
+	}
+	err = rpprof.Lookup(profile).WriteTo(f, debug)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	return f.Name(), err
+}
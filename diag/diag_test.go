@@ -0,0 +1,88 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package diag_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/multiplay/winsvc/diag"
+	"github.com/multiplay/winsvc/svc"
+)
+
+func TestDump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diag")
+	if err != nil {
+		t.Fatalf("failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	names, err := diag.Dump(dir)
+	if err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	if len(names) != 2 {
+		t.Fatalf("Dump created %d files, want 2", len(names))
+	}
+	for _, n := range names {
+		fi, err := os.Stat(n)
+		if err != nil {
+			t.Fatalf("Stat(%s) failed: %v", n, err)
+		}
+		if fi.Size() == 0 {
+			t.Fatalf("%s is empty", n)
+		}
+	}
+	// taken within the same second, as dumps often are
+	again, err := diag.Dump(dir)
+	if err != nil {
+		t.Fatalf("second Dump failed: %v", err)
+	}
+	for i := range again {
+		if again[i] == names[i] {
+			t.Fatalf("second Dump overwrote %s", names[i])
+		}
+	}
+}
+
+func TestHandlerControl(t *testing.T) {
+	for _, c := range []svc.Cmd{0, 128, 255} {
+		_, err := diag.Handler(nil, diag.Config{Control: c})
+		if err != nil {
+			t.Errorf("Handler rejects control %d: %v", c, err)
+		}
+	}
+	for _, c := range []svc.Cmd{svc.Stop, 127, 256} {
+		_, err := diag.Handler(nil, diag.Config{Control: c})
+		if err == nil {
+			t.Errorf("Handler accepts control %d", c)
+		}
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	c, err := diag.Diagnose()
+	if err != nil {
+		t.Fatalf("Diagnose failed: %v", err)
+	}
+	if c.Account == "" || c.WindowStation == "" || c.Desktop == "" {
+		t.Fatalf("Diagnose returned incomplete context:\n%v", c)
+	}
+	t.Logf("execution context:\n%v", c)
+}
+
+func TestEnvironment(t *testing.T) {
+	e, err := diag.Environment()
+	if err != nil {
+		t.Fatalf("Environment failed: %v", err)
+	}
+	if e.InContainer() == e.InstallEventSource() {
+		t.Errorf("in container %v, but install event source %v", e.InContainer(), e.InstallEventSource())
+	}
+	t.Logf("environment:\n%v", e)
+}
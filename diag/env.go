@@ -0,0 +1,154 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package diag
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/multiplay/winsvc/registry"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// Container describes the kind of container process runs in.
+type Container int
+
+const (
+	NoContainer          Container = iota // process runs on the host
+	ServerContainer                       // Windows Server or Hyper-V isolated container
+	HostProcessContainer                  // HostProcess container, sharing host network and file system
+)
+
+func (c Container) String() string {
+	switch c {
+	case NoContainer:
+		return "none"
+	case ServerContainer:
+		return "container"
+	case HostProcessContainer:
+		return "host process container"
+	}
+	return fmt.Sprintf("Container(%d)", int(c))
+}
+
+// ReadyFileEnv names environment variable, that holds path of the
+// file Env.Ready creates, so container readiness probe can check it.
+const ReadyFileEnv = "WINSVC_READY_FILE"
+
+// Env describes environment the same binary may be deployed to,
+// either on the host or in a container.
+type Env struct {
+	Container Container
+
+	// ContainerType is ContainerType registry value,
+	// set by Windows in containers, 0 on the host.
+	ContainerType uint32
+
+	// SandboxMountPoint is the root of container files,
+	// when process runs in HostProcess container.
+	SandboxMountPoint string
+
+	Kubernetes bool   // process is started by Kubernetes
+	ReadyFile  string // value of ReadyFileEnv, if any
+
+	// Unavailable names winapi capabilities missing on this
+	// edition of Windows, like desktop on Nano Server.
+	Unavailable []string
+}
+
+// Environment returns environment of the current process.
+func Environment() (*Env, error) {
+	e := Env{
+		SandboxMountPoint: os.Getenv("CONTAINER_SANDBOX_MOUNT_POINT"),
+		Kubernetes:        os.Getenv("KUBERNETES_SERVICE_HOST") != "",
+		ReadyFile:         os.Getenv(ReadyFileEnv),
+	}
+	var err error
+	e.ContainerType, err = containerType()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range winapi.Capabilities {
+		if !c.Available() {
+			e.Unavailable = append(e.Unavailable, c.Name)
+		}
+	}
+	switch {
+	case e.SandboxMountPoint != "":
+		e.Container = HostProcessContainer
+	case e.ContainerType != 0:
+		e.Container = ServerContainer
+	}
+	return &e, nil
+}
+
+// InContainer reports whether process runs in a container of any kind.
+func (e *Env) InContainer() bool {
+	return e.Container != NoContainer
+}
+
+// InstallEventSource reports whether event source should be
+// registered by default. Containers are usually short lived and
+// their event log is not collected, so it is not.
+func (e *Env) InstallEventSource() bool {
+	return !e.InContainer()
+}
+
+// Ready signals that service is ready in a way that can be seen
+// from outside of container, where status reported to the service
+// control manager is not. It writes "ready" line to standard output,
+// which container log collectors pick up, and creates e.ReadyFile,
+// if set, for readiness probes. Ready does nothing on the host.
+func (e *Env) Ready() error {
+	if !e.InContainer() {
+		return nil
+	}
+	fmt.Fprintln(os.Stdout, "ready")
+	if e.ReadyFile == "" {
+		return nil
+	}
+	return ioutil.WriteFile(e.ReadyFile, []byte("ready\n"), 0644)
+}
+
+// String returns e as multi line report.
+func (e *Env) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "container:      %v\n", e.Container)
+	fmt.Fprintf(&b, "container type: %d\n", e.ContainerType)
+	if e.SandboxMountPoint != "" {
+		fmt.Fprintf(&b, "sandbox mount:  %s\n", e.SandboxMountPoint)
+	}
+	fmt.Fprintf(&b, "kubernetes:     %v\n", e.Kubernetes)
+	if e.ReadyFile != "" {
+		fmt.Fprintf(&b, "ready file:     %s\n", e.ReadyFile)
+	}
+	if len(e.Unavailable) > 0 {
+		fmt.Fprintf(&b, "unavailable:    %s\n", strings.Join(e.Unavailable, ", "))
+	}
+	return b.String()
+}
+
+// containerType returns ContainerType registry value,
+// or 0, if process does not run in a container.
+func containerType() (uint32, error) {
+	k, err := registry.OpenKeyAccess(syscall.HKEY_LOCAL_MACHINE,
+		`SYSTEM\CurrentControlSet\Control`, syscall.KEY_READ)
+	if err != nil {
+		return 0, err
+	}
+	defer k.Close()
+	v, err := k.GetUInt32("ContainerType")
+	if errors.Is(err, syscall.ERROR_FILE_NOT_FOUND) {
+		return 0, nil
+	}
+	return v, err
+}
@@ -0,0 +1,100 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Package elevate tells whether the current process runs elevated,
+// that is with administrative rights, and relaunches it elevated.
+// Installing, removing, starting and stopping services fails with
+// ERROR_ACCESS_DENIED, when run without them, even by administrators,
+// while User Account Control is on.
+//
+package elevate
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// ErrCancelled is returned by Relaunch, when user
+// declines User Account Control prompt.
+var ErrCancelled error = winapi.ERROR_CANCELLED
+
+// IsElevated reports whether the current process runs elevated.
+func IsElevated() (bool, error) {
+	t, err := syscall.OpenCurrentProcessToken()
+	if err != nil {
+		return false, err
+	}
+	defer t.Close()
+	var e winapi.TOKEN_ELEVATION
+	var n uint32
+	err = syscall.GetTokenInformation(t, winapi.TokenElevation, (*byte)(unsafe.Pointer(&e)), uint32(unsafe.Sizeof(e)), &n)
+	if err != nil {
+		return false, err
+	}
+	return e.TokenIsElevated != 0, nil
+}
+
+// Relaunch runs the current program elevated, with arguments
+// args, waits for it to exit, and returns its exit code. User
+// Account Control asks user to allow it first, or to enter
+// administrator credentials. The elevated process runs in its
+// own console window, that is closed, once it exits.
+func Relaunch(args ...string) (uint32, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+	a := make([]string, len(args))
+	for i := range args {
+		a[i] = syscall.EscapeArg(args[i])
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return 0, err
+	}
+	info := winapi.SHELLEXECUTEINFO{
+		Mask:       winapi.SEE_MASK_NOCLOSEPROCESS | winapi.SEE_MASK_NOASYNC,
+		Verb:       syscall.StringToUTF16Ptr("runas"),
+		File:       syscall.StringToUTF16Ptr(exe),
+		Parameters: syscall.StringToUTF16Ptr(strings.Join(a, " ")),
+		Directory:  syscall.StringToUTF16Ptr(dir),
+		Show:       winapi.SW_SHOWNORMAL,
+	}
+	info.Size = uint32(unsafe.Sizeof(info))
+	err = winapi.ShellExecuteEx(&info)
+	if err != nil {
+		return 0, err
+	}
+	if info.Process == 0 {
+		return 0, errors.New("elevate: no process started")
+	}
+	defer syscall.CloseHandle(info.Process)
+	_, err = syscall.WaitForSingleObject(info.Process, syscall.INFINITE)
+	if err != nil {
+		return 0, err
+	}
+	var ec uint32
+	err = syscall.GetExitCodeProcess(info.Process, &ec)
+	if err != nil {
+		return 0, err
+	}
+	return ec, nil
+}
+
+// Denied reports whether err is ERROR_ACCESS_DENIED returned
+// to process, that is not elevated, so Relaunch could help.
+func Denied(err error) bool {
+	if !errors.Is(err, syscall.ERROR_ACCESS_DENIED) {
+		return false
+	}
+	elevated, e := IsElevated()
+	return e == nil && !elevated
+}
@@ -0,0 +1,100 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package eventlog
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+)
+
+// ActivityID identifies operation, like request handled by service,
+// so events logged during it can be found together.
+type ActivityID [16]byte
+
+// NewActivityID returns new random ActivityID.
+func NewActivityID() ActivityID {
+	var id ActivityID
+	rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40 // version 4
+	id[8] = id[8]&0x3f | 0x80 // variant
+	return id
+}
+
+// String returns id in GUID format used by Windows, like
+// {5d3e9a59-e9d5-4b00-a6bd-ff34ff516548}.
+func (id ActivityID) String() string {
+	return fmt.Sprintf("{%x-%x-%x-%x-%x}", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+}
+
+type activityKey struct{}
+
+type activity struct {
+	id, related ActivityID
+}
+
+// StartActivity returns copy of ctx carrying new activity id. Activity
+// carried by ctx, if any, becomes related activity of the new one.
+func StartActivity(ctx context.Context) (context.Context, ActivityID) {
+	id := NewActivityID()
+	return WithActivity(ctx, id), id
+}
+
+// WithActivity returns copy of ctx carrying activity id, for example,
+// received with request from another service. Activity carried by
+// ctx, if any, becomes related activity.
+func WithActivity(ctx context.Context, id ActivityID) context.Context {
+	a := activity{id: id}
+	if parent, ok := ctx.Value(activityKey{}).(activity); ok {
+		a.related = parent.id
+	}
+	return context.WithValue(ctx, activityKey{}, a)
+}
+
+// ActivityFromContext returns activity id carried by ctx, and id
+// of related activity, that is zero, if there is none.
+func ActivityFromContext(ctx context.Context) (id, related ActivityID, ok bool) {
+	a, ok := ctx.Value(activityKey{}).(activity)
+	return a.id, a.related, ok
+}
+
+// ReportContext is like Report, but attaches activity carried by ctx
+// to event e. Event log written with ReportEvent has no place for
+// activity ids, so they are appended to insertion strings, as
+// "ActivityID={...}" and "RelatedActivityID={...}". Messages do not
+// show them, unless they refer to them, but they are part of event
+// data, that Event Viewer filters and log collectors can query.
+func (l *Log) ReportContext(ctx context.Context, e Event) error {
+	id, related, ok := ActivityFromContext(ctx)
+	if ok {
+		ss := append([]string(nil), e.Strings...)
+		ss = append(ss, "ActivityID="+id.String())
+		if related != (ActivityID{}) {
+			ss = append(ss, "RelatedActivityID="+related.String())
+		}
+		e.Strings = ss
+	}
+	return l.Report(e)
+}
+
+// InfoContext is like Info, but attaches activity carried by ctx,
+// like ReportContext.
+func (l *Log) InfoContext(ctx context.Context, eid uint32, msg string) error {
+	return l.ReportContext(ctx, Event{Type: Info, ID: eid, Strings: []string{msg}})
+}
+
+// WarningContext is like Warning, but attaches activity carried by
+// ctx, like ReportContext.
+func (l *Log) WarningContext(ctx context.Context, eid uint32, msg string) error {
+	return l.ReportContext(ctx, Event{Type: Warning, ID: eid, Strings: []string{msg}})
+}
+
+// ErrorContext is like Error, but attaches activity carried by ctx,
+// like ReportContext.
+func (l *Log) ErrorContext(ctx context.Context, eid uint32, msg string) error {
+	return l.ReportContext(ctx, Event{Type: Error, ID: eid, Strings: []string{msg}})
+}
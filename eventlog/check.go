@@ -0,0 +1,111 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package eventlog
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/multiplay/winsvc/registry"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// CheckError lists problems Check found with event source.
+type CheckError struct {
+	Source   string
+	Problems []string
+}
+
+func (e *CheckError) Error() string {
+	return "event source " + e.Source + " cannot be used: " + strings.Join(e.Problems, "; ")
+}
+
+// Check verifies that events written with event source are logged
+// and readable: source is registered, its message files exist, and
+// the current account can write events. Windows accepts events of
+// misconfigured source silently, so Check is worth calling during
+// service start or install. Check returns *CheckError listing what
+// is wrong, and what to do about it.
+func Check(source string) error {
+	e := &CheckError{Source: source}
+	k, err := registry.OpenKeyAccess(syscall.HKEY_LOCAL_MACHINE, addKeyName+`\`+source, syscall.KEY_READ)
+	switch {
+	case errors.Is(err, syscall.ERROR_FILE_NOT_FOUND):
+		e.Problems = append(e.Problems, `registry key HKLM\`+addKeyName+`\`+source+` does not exist, install event source with eventlog.Install or eventlog.InstallAsEventCreate`)
+	case err != nil:
+		e.Problems = append(e.Problems, `cannot read registry key HKLM\`+addKeyName+`\`+source+": "+err.Error())
+	default:
+		e.Problems = append(e.Problems, messageFileProblems(k)...)
+		k.Close()
+	}
+	h, err := winapi.RegisterEventSource(nil, syscall.StringToUTF16Ptr(source))
+	if err != nil {
+		if errors.Is(err, syscall.ERROR_ACCESS_DENIED) {
+			e.Problems = append(e.Problems, "current account is not allowed to write to Application log, check CustomSD value of the log")
+		} else {
+			e.Problems = append(e.Problems, "cannot open event source: "+err.Error())
+		}
+	} else {
+		winapi.DeregisterEventSource(h)
+	}
+	if len(e.Problems) > 0 {
+		return e
+	}
+	return nil
+}
+
+// messageFileProblems returns problems with EventMessageFile value of key k.
+func messageFileProblems(k *registry.Key) []string {
+	v, err := k.GetString("EventMessageFile")
+	if err != nil {
+		return []string{"EventMessageFile value cannot be read (" + err.Error() + "), events are shown without message"}
+	}
+	var ps []string
+	for _, f := range strings.Split(v, ";") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		path := expandEnv(f)
+		if _, err := os.Stat(path); err != nil {
+			ps = append(ps, "message file "+path+" does not exist, events are shown without message")
+		}
+	}
+	if len(ps) == 0 && strings.TrimSpace(v) == "" {
+		ps = append(ps, "EventMessageFile value is empty, events are shown without message")
+	}
+	return ps
+}
+
+// expandEnv replaces %NAME% in s by value of environment variable
+// NAME, as Windows does for REG_EXPAND_SZ values. Unknown names are
+// left as they are.
+func expandEnv(s string) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '%')
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(s[i+1:], '%')
+		if j < 0 {
+			break
+		}
+		name := s[i+1 : i+1+j]
+		if v, ok := os.LookupEnv(name); ok && name != "" {
+			b.WriteString(s[:i])
+			b.WriteString(v)
+		} else {
+			b.WriteString(s[:i+2+j])
+		}
+		s = s[i+2+j:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
@@ -0,0 +1,59 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package eventlog
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// ErrSharedLog is returned by Clear for logs shared by the whole
+// system, which are not owned by any one service.
+var ErrSharedLog = errors.New("eventlog: refusing to clear log shared by the system")
+
+// sharedLogs lists logs Clear refuses to clear.
+var sharedLogs = []string{"Application", "System", "Security", "Setup"}
+
+// Backup saves all records of log r to file path, which must not
+// exist. The backup can be opened with Event Viewer.
+func (r *Reader) Backup(path string) error {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	return winapi.BackupEventLog(r.Handle, p)
+}
+
+// Clear removes all records from log r. If backupPath is not "",
+// records are saved there first, and the log is only cleared, if
+// that succeeds. Clear only clears logs owned by services, and
+// returns ErrSharedLog for Application, System and other logs
+// shared by the system. Use Range to check what will be cleared.
+func (r *Reader) Clear(backupPath string) error {
+	for _, n := range sharedLogs {
+		if strings.EqualFold(r.name, n) {
+			return ErrSharedLog
+		}
+	}
+	var p *uint16
+	if backupPath != "" {
+		var err error
+		p, err = syscall.UTF16PtrFromString(backupPath)
+		if err != nil {
+			return err
+		}
+	}
+	err := winapi.ClearEventLog(r.Handle, p)
+	if err != nil {
+		return err
+	}
+	r.Seek(0)
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package eventlog
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// EventIDs derives stable event ids from message templates, so
+// events can be filtered by id without message catalog. Same
+// template always gets the same id, on every computer and in
+// every version of the program. Different templates can get the
+// same id, use Override to separate them.
+type EventIDs struct {
+	// Min and Max limit ids derived. They default to 1 and 1000,
+	// which EventCreate.exe message file supports.
+	Min, Max uint32
+
+	// Override maps templates to ids chosen by user.
+	Override map[string]uint32
+}
+
+// ID returns event id for message template.
+func (e *EventIDs) ID(template string) uint32 {
+	if id, ok := e.Override[template]; ok {
+		return id
+	}
+	min, max := e.Min, e.Max
+	if min == 0 {
+		min = 1
+	}
+	if max == 0 {
+		max = 1000
+	}
+	if max <= min {
+		return min
+	}
+	h := fnv.New32a()
+	h.Write([]byte(template))
+	return min + h.Sum32()%(max-min+1)
+}
+
+// id returns event id for template, as set by l.IDs.
+func (l *Log) id(template string) uint32 {
+	if l.IDs == nil {
+		return (&EventIDs{}).ID(template)
+	}
+	return l.IDs.ID(template)
+}
+
+// Infof writes an information event, formatted according to format,
+// with event id derived from format by l.IDs.
+func (l *Log) Infof(format string, a ...interface{}) error {
+	return l.report(Info, l.id(format), fmt.Sprintf(format, a...))
+}
+
+// Warningf writes a warning event, formatted according to format,
+// with event id derived from format by l.IDs.
+func (l *Log) Warningf(format string, a ...interface{}) error {
+	return l.report(Warning, l.id(format), fmt.Sprintf(format, a...))
+}
+
+// Errorf writes an error event, formatted according to format,
+// with event id derived from format by l.IDs.
+func (l *Log) Errorf(format string, a ...interface{}) error {
+	return l.report(Error, l.id(format), fmt.Sprintf(format, a...))
+}
@@ -17,6 +17,10 @@ import (
 // Log provides access to system log.
 type Log struct {
 	Handle syscall.Handle
+
+	// IDs derives event ids used by Infof, Warningf and Errorf.
+	// Default EventIDs is used, if IDs is nil.
+	IDs *EventIDs
 }
 
 // Open retrieves a handle to the specified event log.
@@ -46,8 +50,45 @@ func (l *Log) Close() error {
 }
 
 func (l *Log) report(etype uint16, eid uint32, msg string) error {
-	ss := []*uint16{syscall.StringToUTF16Ptr(msg)}
-	return winapi.ReportEvent(l.Handle, etype, 0, eid, 0, 1, 0, &ss[0], nil)
+	return l.Report(Event{Type: etype, ID: eid, Strings: []string{msg}})
+}
+
+// Event is an event written by Report.
+type Event struct {
+	Type     uint16 // one of Error, Warning or Info
+	ID       uint32
+	Category uint16
+	Strings  []string // insertion strings, %1, %2 and so on in message
+	Data     []byte   // binary data, shown with event details
+}
+
+// maxStrings is the maximum number of insertion strings of event.
+const maxStrings = 256
+
+// Report writes event e to the end of event log l. Unlike Info, Warning
+// and Error, it passes every insertion string separately, so message
+// file can place them, and can attach binary data to event.
+func (l *Log) Report(e Event) error {
+	if len(e.Strings) > maxStrings {
+		return errors.New("event has more than 256 insertion strings")
+	}
+	var ss []*uint16
+	for _, s := range e.Strings {
+		p, err := syscall.UTF16PtrFromString(s)
+		if err != nil {
+			return err
+		}
+		ss = append(ss, p)
+	}
+	var sp **uint16
+	if len(ss) > 0 {
+		sp = &ss[0]
+	}
+	var data *byte
+	if len(e.Data) > 0 {
+		data = &e.Data[0]
+	}
+	return winapi.ReportEvent(l.Handle, e.Type, e.Category, e.ID, 0, uint16(len(ss)), uint32(len(e.Data)), sp, data)
 }
 
 // Info writes an information event msg with event id eid to the end of event log l.
@@ -7,7 +7,13 @@
 package eventlog_test
 
 import (
+	"context"
+	"errors"
 	"github.com/multiplay/winsvc/eventlog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -40,3 +46,179 @@ func TestLog(t *testing.T) {
 		t.Fatalf("Remove failed: %s", err)
 	}
 }
+
+func TestReader(t *testing.T) {
+	const name = "mylog"
+	const supports = eventlog.Error | eventlog.Warning | eventlog.Info
+	err := eventlog.InstallAsEventCreate(name, supports)
+	if err != nil {
+		t.Fatalf("Install failed: %s", err)
+	}
+	defer eventlog.Remove(name)
+	l, err := eventlog.Open(name)
+	if err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	defer l.Close()
+
+	r, err := eventlog.OpenReader("", "Application")
+	if err != nil {
+		t.Fatalf("OpenReader failed: %s", err)
+	}
+	defer r.Close()
+	oldest, count, err := r.Range()
+	if err != nil {
+		t.Fatalf("Range failed: %s", err)
+	}
+	err = l.Warning(7, "reader test")
+	if err != nil {
+		t.Fatalf("Warning failed: %s", err)
+	}
+	r.Seek(oldest + count)
+	for {
+		rec, err := r.Next()
+		if err != nil {
+			t.Fatalf("event not found: %v", err)
+		}
+		if rec.Source == name && rec.EventID == 7 {
+			if rec.Type != eventlog.Warning || len(rec.Strings) != 1 || rec.Strings[0] != "reader test" {
+				t.Fatalf("unexpected record %+v", rec)
+			}
+			return
+		}
+	}
+}
+
+func TestEventIDs(t *testing.T) {
+	var ids eventlog.EventIDs
+	const tmpl = "connection to %s failed: %v"
+	id := ids.ID(tmpl)
+	if id < 1 || id > 1000 {
+		t.Errorf("id %d is not between 1 and 1000", id)
+	}
+	if ids.ID(tmpl) != id {
+		t.Error("id of the same template changed")
+	}
+	if ids.ID("another template") == id {
+		t.Error("different templates got the same id")
+	}
+	ids = eventlog.EventIDs{Min: 2000, Max: 2009, Override: map[string]uint32{"started": 1}}
+	if id := ids.ID(tmpl); id < 2000 || id > 2009 {
+		t.Errorf("id %d is not between 2000 and 2009", id)
+	}
+	if id := ids.ID("started"); id != 1 {
+		t.Errorf("id of overridden template is %d, want 1", id)
+	}
+}
+
+func TestReport(t *testing.T) {
+	const name = "mylog"
+	const supports = eventlog.Error | eventlog.Warning | eventlog.Info
+	err := eventlog.InstallAsEventCreate(name, supports)
+	if err != nil {
+		t.Fatalf("Install failed: %s", err)
+	}
+	defer eventlog.Remove(name)
+	l, err := eventlog.Open(name)
+	if err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	defer l.Close()
+
+	r, err := eventlog.OpenReader("", "Application")
+	if err != nil {
+		t.Fatalf("OpenReader failed: %s", err)
+	}
+	defer r.Close()
+	oldest, count, err := r.Range()
+	if err != nil {
+		t.Fatalf("Range failed: %s", err)
+	}
+	e := eventlog.Event{
+		Type:    eventlog.Info,
+		ID:      8,
+		Strings: []string{"first", "second"},
+		Data:    []byte{1, 2, 3},
+	}
+	err = l.Report(e)
+	if err != nil {
+		t.Fatalf("Report failed: %s", err)
+	}
+	r.Seek(oldest + count)
+	for {
+		rec, err := r.Next()
+		if err != nil {
+			t.Fatalf("event not found: %v", err)
+		}
+		if rec.Source == name && rec.EventID == 8 {
+			if !reflect.DeepEqual(rec.Strings, e.Strings) || !reflect.DeepEqual(rec.Data, e.Data) {
+				t.Fatalf("unexpected record %+v", rec)
+			}
+			return
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	const name = "mylog"
+	err := eventlog.Check(name)
+	var ce *eventlog.CheckError
+	if !errors.As(err, &ce) || !strings.Contains(ce.Error(), "does not exist") {
+		t.Fatalf("Check of missing source returned %v", err)
+	}
+	err = eventlog.Install(name, `C:\no\such\file.dll`, false, eventlog.Info)
+	if err != nil {
+		t.Fatalf("Install failed: %s", err)
+	}
+	err = eventlog.Check(name)
+	eventlog.Remove(name)
+	if err == nil || !strings.Contains(err.Error(), `C:\no\such\file.dll does not exist`) {
+		t.Errorf("Check of source with missing message file returned %v", err)
+	}
+	err = eventlog.InstallAsEventCreate(name, eventlog.Info)
+	if err != nil {
+		t.Fatalf("Install failed: %s", err)
+	}
+	defer eventlog.Remove(name)
+	err = eventlog.Check(name)
+	if err != nil {
+		t.Errorf("Check failed: %v", err)
+	}
+}
+
+func TestActivity(t *testing.T) {
+	ctx := context.Background()
+	if _, _, ok := eventlog.ActivityFromContext(ctx); ok {
+		t.Fatal("background context carries activity")
+	}
+	ctx, parent := eventlog.StartActivity(ctx)
+	ctx, child := eventlog.StartActivity(ctx)
+	id, related, ok := eventlog.ActivityFromContext(ctx)
+	if !ok || id != child || related != parent || child == parent {
+		t.Errorf("activity is %v related to %v, want %v related to %v", id, related, child, parent)
+	}
+	s := child.String()
+	if len(s) != 38 || s[0] != '{' || s[15] != '4' {
+		t.Errorf("activity id %s is not version 4 GUID", s)
+	}
+}
+
+func TestBackupAndClear(t *testing.T) {
+	r, err := eventlog.OpenReader("", "Application")
+	if err != nil {
+		t.Fatalf("OpenReader failed: %s", err)
+	}
+	defer r.Close()
+	path := filepath.Join(t.TempDir(), "app.evt")
+	err = r.Backup(path)
+	if err != nil {
+		t.Fatalf("Backup failed: %s", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("backup file is missing: %v", err)
+	}
+	err = r.Clear("")
+	if !errors.Is(err, eventlog.ErrSharedLog) {
+		t.Errorf("Clear of Application log returned %v, want %v", err, eventlog.ErrSharedLog)
+	}
+}
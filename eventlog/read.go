@@ -0,0 +1,173 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package eventlog
+
+import (
+	"errors"
+	"io"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// Record is an event log record.
+type Record struct {
+	Number   uint32
+	Time     time.Time // time the event was generated
+	EventID  uint32    // as passed to Info, Warning or Error
+	Type     uint16    // one of Error, Warning or Info, or audit event type
+	Source   string
+	Strings  []string // insertion strings, message of events written by Log
+	Category uint16
+	Data     []byte // binary data
+}
+
+// Reader reads records from event log, like "Application"
+// or "System", oldest first.
+type Reader struct {
+	Handle syscall.Handle
+	name   string
+	buf    []byte
+	next   []byte // records read, but not returned yet
+	seek   uint32 // record number to seek to, if not 0
+}
+
+// OpenReader opens event log name on computer host
+// (local computer, if host is "") for reading.
+func OpenReader(host, name string) (*Reader, error) {
+	var s *uint16
+	if host != "" {
+		s = syscall.StringToUTF16Ptr(host)
+	}
+	h, err := winapi.OpenEventLog(s, syscall.StringToUTF16Ptr(name))
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{Handle: h, name: name, buf: make([]byte, 64<<10)}, nil
+}
+
+// Close closes reader r.
+func (r *Reader) Close() error {
+	return winapi.CloseEventLog(r.Handle)
+}
+
+// Range returns number of the oldest record in the log
+// and number of records in it.
+func (r *Reader) Range() (oldest, count uint32, err error) {
+	err = winapi.GetOldestEventLogRecord(r.Handle, &oldest)
+	if err != nil {
+		return 0, 0, err
+	}
+	err = winapi.GetNumberOfEventLogRecords(r.Handle, &count)
+	if err != nil {
+		return 0, 0, err
+	}
+	return oldest, count, nil
+}
+
+// Seek makes Next continue from record number n.
+func (r *Reader) Seek(n uint32) {
+	r.seek = n
+	r.next = nil
+}
+
+// Next returns next record. It returns io.EOF, if there
+// are no more records. New records written after that
+// are returned by later calls to Next.
+func (r *Reader) Next() (*Record, error) {
+	if len(r.next) == 0 {
+		err := r.read()
+		if err != nil {
+			return nil, err
+		}
+	}
+	rec, n, err := parseRecord(r.next)
+	if err != nil {
+		r.next = nil
+		return nil, err
+	}
+	r.next = r.next[n:]
+	return rec, nil
+}
+
+func (r *Reader) read() error {
+	for {
+		flags := uint32(winapi.EVENTLOG_SEQUENTIAL_READ | winapi.EVENTLOG_FORWARDS_READ)
+		if r.seek != 0 {
+			flags = winapi.EVENTLOG_SEEK_READ | winapi.EVENTLOG_FORWARDS_READ
+		}
+		var n, needed uint32
+		err := winapi.ReadEventLog(r.Handle, flags, r.seek, &r.buf[0], uint32(len(r.buf)), &n, &needed)
+		switch {
+		case err == nil:
+			r.seek = 0
+			r.next = r.buf[:n]
+			return nil
+		case errors.Is(err, syscall.ERROR_INSUFFICIENT_BUFFER) && needed > uint32(len(r.buf)):
+			r.buf = make([]byte, needed)
+		case errors.Is(err, syscall.ERROR_HANDLE_EOF):
+			return io.EOF
+		default:
+			return err
+		}
+	}
+}
+
+// parseRecord decodes EVENTLOGRECORD at the start of b,
+// and returns it and its length.
+func parseRecord(b []byte) (*Record, int, error) {
+	hdr := int(unsafe.Sizeof(winapi.EVENTLOGRECORD{}))
+	if len(b) < hdr {
+		return nil, 0, errors.New("eventlog: short record")
+	}
+	p := (*winapi.EVENTLOGRECORD)(unsafe.Pointer(&b[0]))
+	n := int(p.Length)
+	if n < hdr || n > len(b) || int(p.StringOffset) > n || uint64(p.DataOffset)+uint64(p.DataLength) > uint64(n) {
+		return nil, 0, errors.New("eventlog: malformed record")
+	}
+	u := toUTF16(b[:n])
+	r := &Record{
+		Number:   p.RecordNumber,
+		Time:     time.Unix(int64(p.TimeGenerated), 0),
+		EventID:  p.EventID & 0xffff,
+		Type:     p.EventType,
+		Category: p.EventCategory,
+	}
+	if p.DataLength > 0 {
+		r.Data = append([]byte(nil), b[p.DataOffset:p.DataOffset+p.DataLength]...)
+	}
+	r.Source, _ = utf16String(u[hdr/2:])
+	strs := u[p.StringOffset/2:]
+	for i := 0; i < int(p.NumStrings) && len(strs) > 0; i++ {
+		s, rest := utf16String(strs)
+		r.Strings = append(r.Strings, s)
+		strs = rest
+	}
+	return r, n, nil
+}
+
+// toUTF16 returns b as []uint16. Odd byte at the end is dropped.
+func toUTF16(b []byte) []uint16 {
+	if len(b) < 2 {
+		return nil
+	}
+	return unsafe.Slice((*uint16)(unsafe.Pointer(&b[0])), len(b)/2)
+}
+
+// utf16String decodes string at the start of u, up to
+// the first 0 or the end of u, and returns rest of u
+// that follows the 0.
+func utf16String(u []uint16) (string, []uint16) {
+	for i, c := range u {
+		if c == 0 {
+			return syscall.UTF16ToString(u[:i]), u[i+1:]
+		}
+	}
+	return syscall.UTF16ToString(u), nil
+}
@@ -7,14 +7,14 @@
 package main
 
 import (
-	"syscall"
+	"github.com/multiplay/winsvc/winapi"
 )
 
 // BUG(brainman): MessageBeep Windows api is broken on Windows 7,
 // so this example does not beep when runs as service on Windows 7.
 
 var (
-	beepFunc = syscall.MustLoadDLL("user32.dll").MustFindProc("MessageBeep")
+	beepFunc = winapi.NewSystemDLL("user32.dll").NewProc("MessageBeep")
 )
 
 func beep() {
@@ -0,0 +1,50 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Echo is example service that runs TCP echo server. On Stop
+// it stops accepting connections, and gives open connections
+// a few seconds to finish, before closing them.
+//
+// Run "echo install" as administrator to install it, and
+// "echo debug" to run it on console.
+//
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/multiplay/winsvc/debug"
+	"github.com/multiplay/winsvc/eventlog"
+	"github.com/multiplay/winsvc/examples/internal/cli"
+	"github.com/multiplay/winsvc/firewall"
+	"github.com/multiplay/winsvc/install"
+	"github.com/multiplay/winsvc/mgr"
+	"github.com/multiplay/winsvc/svc"
+)
+
+const port = 7007
+
+func main() {
+	cli.Main(&cli.Service{
+		Definition: install.Definition{
+			Name: "exampleecho",
+			Config: mgr.Config{
+				DisplayName: "Example Echo Server",
+				Description: "TCP echo server on port 7007.",
+				StartType:   mgr.StartAutomatic,
+			},
+			Recovery: []mgr.RecoveryAction{
+				{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+			},
+			EventLog: eventlog.Error | eventlog.Warning | eventlog.Info,
+			Firewall: []firewall.Rule{{Protocol: "tcp", Ports: []int{port}}},
+		},
+		New: func(elog debug.Log) svc.Handler {
+			return &server{addr: fmt.Sprintf(":%d", port), elog: elog}
+		},
+	})
+}
@@ -0,0 +1,122 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/multiplay/winsvc/debug"
+	"github.com/multiplay/winsvc/svc"
+)
+
+// drainTimeout is how long open connections
+// are allowed to finish after Stop.
+const drainTimeout = 5 * time.Second
+
+type server struct {
+	addr string
+	elog debug.Log
+
+	mu       sync.Mutex
+	conns    map[net.Conn]bool
+	stopping bool // listener is closed on purpose
+	wg       sync.WaitGroup
+}
+
+func (s *server) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (ssec bool, errno uint32) {
+	const cmdsAccepted = svc.AcceptStop | svc.AcceptShutdown
+	changes <- svc.Status{State: svc.StartPending}
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		s.elog.Error(1, fmt.Sprintf("listen failed: %v", err))
+		return true, 1
+	}
+	s.conns = make(map[net.Conn]bool)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.serve(ln)
+	}()
+	changes <- svc.Status{State: svc.Running, Accepts: cmdsAccepted}
+loop:
+	for {
+		select {
+		case err := <-done:
+			s.elog.Error(1, fmt.Sprintf("accept failed: %v", err))
+			ln.Close()
+			s.shutdown(changes)
+			return true, 2
+		case c := <-r:
+			switch c.Cmd {
+			case svc.Interrogate:
+				changes <- c.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				break loop
+			default:
+				s.elog.Warning(1, fmt.Sprintf("unexpected control request #%d", c.Cmd))
+			}
+		}
+	}
+	s.mu.Lock()
+	s.stopping = true
+	s.mu.Unlock()
+	ln.Close()
+	<-done
+	s.shutdown(changes)
+	return false, 0
+}
+
+// serve accepts connections on ln, until ln is closed.
+func (s *server) serve(ln net.Listener) error {
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.stopping {
+				return nil
+			}
+			return err
+		}
+		s.mu.Lock()
+		s.conns[c] = true
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.handle(c)
+	}
+}
+
+func (s *server) handle(c net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.Close()
+	}()
+	io.Copy(c, c)
+}
+
+// shutdown waits for open connections to finish, and closes
+// them, if they do not finish within drainTimeout.
+func (s *server) shutdown(changes chan<- svc.Status) {
+	s.mu.Lock()
+	n := len(s.conns)
+	for c := range s.conns {
+		// let clients finish, but do not wait for them forever
+		c.SetReadDeadline(time.Now().Add(drainTimeout))
+	}
+	s.mu.Unlock()
+	changes <- svc.Status{State: svc.StopPending, CheckPoint: 1, WaitHint: uint32(2 * drainTimeout / time.Millisecond)}
+	if n > 0 {
+		s.elog.Info(1, fmt.Sprintf("waiting for %d connections to finish", n))
+	}
+	s.wg.Wait()
+}
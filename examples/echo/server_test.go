@@ -0,0 +1,78 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package main
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/multiplay/winsvc/debug"
+	"github.com/multiplay/winsvc/svc"
+)
+
+func TestGracefulStop(t *testing.T) {
+	s := &server{addr: "127.0.0.1:0", elog: debug.New("exampleecho")}
+	// find free port
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.addr = ln.Addr().String()
+	ln.Close()
+
+	r := make(chan svc.ChangeRequest)
+	changes := make(chan svc.Status, 10)
+	done := make(chan uint32)
+	go func() {
+		_, ec := s.Execute(nil, r, changes)
+		done <- ec
+	}()
+	for st := range changes {
+		if st.State == svc.Running {
+			break
+		}
+	}
+	c, err := net.Dial("tcp", s.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	_, err = c.Write([]byte("hello\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(c).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "hello\n" {
+		t.Fatalf("echo returned %q, want %q", line, "hello\n")
+	}
+
+	r <- svc.ChangeRequest{Cmd: svc.Stop}
+	// open connection keeps service from stopping
+	select {
+	case <-done:
+		t.Fatal("service stopped before connection was closed")
+	case <-time.After(100 * time.Millisecond):
+	}
+	c.Close()
+	select {
+	case ec := <-done:
+		if ec != 0 {
+			t.Errorf("exit code is %d, want 0", ec)
+		}
+	case <-time.After(drainTimeout):
+		t.Fatal("service did not stop after connection was closed")
+	}
+	_, err = net.Dial("tcp", s.addr)
+	if err == nil {
+		t.Error("service still accepts connections after stop")
+	}
+}
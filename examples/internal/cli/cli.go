@@ -0,0 +1,227 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Package cli implements command line shared by example services.
+// Every example program hosts one or more services, and accepts
+// these commands:
+//
+//	install           install all services and their event sources
+//	uninstall         stop and remove all services
+//	start             start all services, in dependency order
+//	stop              stop all services
+//	debug [NAME]      run service NAME on console, Ctrl+C stops it
+//	run NAME          run service NAME, used by service control manager
+//
+// Commands install, uninstall, start and stop need administrative
+// rights. Preceded by -elevate, like "-elevate install", they relaunch
+// the program elevated, if it is not, once User Account Control allows.
+//
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/multiplay/winsvc/debug"
+	"github.com/multiplay/winsvc/elevate"
+	"github.com/multiplay/winsvc/eventlog"
+	"github.com/multiplay/winsvc/firewall"
+	"github.com/multiplay/winsvc/install"
+	"github.com/multiplay/winsvc/mgr"
+	"github.com/multiplay/winsvc/svc"
+)
+
+// Service is a service hosted by example program.
+type Service struct {
+	// Definition describes how service is installed.
+	// Its ExePath is set by Main.
+	Definition install.Definition
+
+	// New returns handler of the service, logging to elog.
+	New func(elog debug.Log) svc.Handler
+}
+
+const timeout = 30 * time.Second
+
+// Main runs command given on command line for services ss.
+func Main(ss ...*Service) {
+	err := run(ss, os.Args[1:])
+	if err == errUsage {
+		usage(ss)
+	}
+	if elevate.Denied(err) {
+		err = fmt.Errorf("%v (run as administrator, or with -elevate)", err)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", filepath.Base(os.Args[0]), err)
+		os.Exit(1)
+	}
+}
+
+var errUsage = fmt.Errorf("wrong arguments")
+
+func usage(ss []*Service) {
+	var names []string
+	for _, s := range ss {
+		names = append(names, s.Definition.Name)
+	}
+	fmt.Fprintf(os.Stderr, "usage: %s [-elevate] install | uninstall | start | stop | debug [NAME] | run NAME\n"+
+		"services: %s\n", filepath.Base(os.Args[0]), strings.Join(names, ", "))
+	os.Exit(2)
+}
+
+func run(ss []*Service, args []string) error {
+	elevateArg := len(args) > 0 && args[0] == "-elevate"
+	if elevateArg {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "run", "debug":
+		if len(args) > 2 || (len(args) == 1 && (args[0] == "run" || len(ss) != 1)) {
+			return errUsage
+		}
+		s := ss[0]
+		if len(args) == 2 {
+			s = find(ss, args[1])
+			if s == nil {
+				return fmt.Errorf("unknown service %s", args[1])
+			}
+		}
+		return runService(s, args[0] == "debug")
+	}
+	if len(args) != 1 {
+		return errUsage
+	}
+	switch args[0] {
+	case "install", "uninstall", "start", "stop":
+	default:
+		return errUsage
+	}
+	if elevateArg {
+		elevated, err := elevate.IsElevated()
+		if err == nil && !elevated {
+			ec, err := elevate.Relaunch(args...)
+			if err != nil {
+				return err
+			}
+			if ec != 0 {
+				return fmt.Errorf("elevated %s failed with exit code %d", args[0], ec)
+			}
+			return nil
+		}
+	}
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	ds, err := definitions(ss)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "install":
+		return install.InstallAll(m, ds)
+	case "uninstall":
+		return install.UninstallAll(m, ds, timeout)
+	case "start":
+		return install.StartAll(m, ds, timeout)
+	case "stop":
+		return stopAll(m, ds)
+	}
+	return errUsage
+}
+
+func find(ss []*Service, name string) *Service {
+	for _, s := range ss {
+		if strings.EqualFold(s.Definition.Name, name) {
+			return s
+		}
+	}
+	return nil
+}
+
+// definitions returns definitions of services ss, with
+// command line running each of them by this program.
+func definitions(ss []*Service) ([]*install.Definition, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	var ds []*install.Definition
+	for _, s := range ss {
+		d := s.Definition
+		d.ExePath = fmt.Sprintf(`"%s" run %s`, exe, d.Name)
+		d.Firewall = append([]firewall.Rule(nil), d.Firewall...)
+		for i := range d.Firewall {
+			if d.Firewall[i].Program == "" {
+				d.Firewall[i].Program = exe
+			}
+		}
+		ds = append(ds, &d)
+	}
+	return ds, nil
+}
+
+// stopAll stops services ds in reverse dependency order.
+func stopAll(m *mgr.Mgr, ds []*install.Definition) error {
+	ds, err := install.Order(ds)
+	if err != nil {
+		return err
+	}
+	for i := len(ds) - 1; i >= 0; i-- {
+		s, err := m.OpenServiceAccess(ds[i].Name, mgr.ServiceStop|mgr.ServiceQueryStatus)
+		if err != nil {
+			return err
+		}
+		_, err = s.Control(svc.Stop)
+		if err == nil {
+			_, err = s.WaitState(svc.Stopped, timeout)
+		} else if errors.Is(err, mgr.ErrServiceNotActive) {
+			err = nil
+		}
+		s.Close()
+		if err != nil {
+			return fmt.Errorf("stopping %s: %v", ds[i].Name, err)
+		}
+	}
+	return nil
+}
+
+func runService(s *Service, isDebug bool) error {
+	name := s.Definition.Name
+	var elog debug.Log
+	if isDebug {
+		elog = debug.New(name)
+	} else {
+		l, err := eventlog.Open(name)
+		if err != nil {
+			return err
+		}
+		elog = l
+	}
+	defer elog.Close()
+
+	elog.Info(1, fmt.Sprintf("starting %s service", name))
+	run := svc.Run
+	if isDebug {
+		run = debug.Run
+	}
+	err := run(name, s.New(elog))
+	if err != nil {
+		elog.Error(1, fmt.Sprintf("%s service failed: %v", name, err))
+		return err
+	}
+	elog.Info(1, fmt.Sprintf("%s service stopped", name))
+	return nil
+}
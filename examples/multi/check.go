@@ -0,0 +1,63 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/multiplay/winsvc/debug"
+	"github.com/multiplay/winsvc/svc"
+)
+
+type check struct {
+	addr     string
+	interval time.Duration
+	elog     debug.Log
+}
+
+// probe reads current time from daytime server at c.addr.
+func (c *check) probe() (string, error) {
+	conn, err := net.DialTimeout("tcp", c.addr, 5*time.Second)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return bufio.NewReader(conn).ReadString('\n')
+}
+
+func (c *check) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (ssec bool, errno uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+	tick := time.NewTicker(c.interval)
+	defer tick.Stop()
+	healthy := true
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case <-tick.C:
+			_, err := c.probe()
+			switch {
+			case err != nil && healthy:
+				c.elog.Warning(2, fmt.Sprintf("%s does not answer: %v", c.addr, err))
+			case err == nil && !healthy:
+				c.elog.Info(2, fmt.Sprintf("%s answers again", c.addr))
+			}
+			healthy = err == nil
+		case cr := <-r:
+			switch cr.Cmd {
+			case svc.Interrogate:
+				changes <- cr.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				return false, 0
+			}
+		}
+	}
+}
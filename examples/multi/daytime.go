@@ -0,0 +1,52 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/multiplay/winsvc/debug"
+	"github.com/multiplay/winsvc/svc"
+)
+
+type daytime struct {
+	addr string
+	elog debug.Log
+}
+
+func (d *daytime) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (ssec bool, errno uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+	ln, err := net.Listen("tcp", d.addr)
+	if err != nil {
+		d.elog.Error(1, fmt.Sprintf("listen failed: %v", err))
+		return true, 1
+	}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Write([]byte(time.Now().Format(time.RFC1123) + "\r\n"))
+			c.Close()
+		}
+	}()
+	svc.Ready(changes)
+	for c := range r {
+		switch c.Cmd {
+		case svc.Interrogate:
+			changes <- c.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			svc.Stopping(changes, time.Second)
+			ln.Close()
+			return false, 0
+		}
+	}
+	return false, 0
+}
@@ -0,0 +1,64 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Multi is example program that hosts two services in one binary.
+// Service exampledaytime answers TCP connections on port 7013 with
+// current time (RFC 867). Service examplecheck depends on it, and
+// connects to it periodically, logging a warning when it does not
+// answer. Each service runs in its own process, selected by the
+// service name given on its command line.
+//
+// Run "multi install" as administrator to install both services,
+// and "multi debug exampledaytime" to run one of them on console.
+//
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/multiplay/winsvc/debug"
+	"github.com/multiplay/winsvc/eventlog"
+	"github.com/multiplay/winsvc/examples/internal/cli"
+	"github.com/multiplay/winsvc/install"
+	"github.com/multiplay/winsvc/mgr"
+	"github.com/multiplay/winsvc/svc"
+)
+
+const addr = "127.0.0.1:7013"
+
+func main() {
+	const events = eventlog.Error | eventlog.Warning | eventlog.Info
+	cli.Main(
+		&cli.Service{
+			Definition: install.Definition{
+				Name: "exampledaytime",
+				Config: mgr.Config{
+					DisplayName: "Example Daytime Server",
+					Description: fmt.Sprintf("Daytime server on %s.", addr),
+				},
+				EventLog: events,
+			},
+			New: func(elog debug.Log) svc.Handler {
+				return &daytime{addr: addr, elog: elog}
+			},
+		},
+		&cli.Service{
+			Definition: install.Definition{
+				Name: "examplecheck",
+				Config: mgr.Config{
+					DisplayName:  "Example Daytime Check",
+					Description:  "Checks that exampledaytime service answers.",
+					Dependencies: []string{"exampledaytime"},
+				},
+				EventLog: events,
+			},
+			New: func(elog debug.Log) svc.Handler {
+				return &check{addr: addr, interval: 10 * time.Second, elog: elog}
+			},
+		},
+	)
+}
@@ -0,0 +1,50 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Watcher is example service that watches a directory, and
+// writes an event log entry for every file created, changed or
+// removed in it. The directory is the first service start
+// argument, or %ProgramData%\examplewatcher by default.
+// Pause stops watching, and Continue reports changes made
+// while paused.
+//
+// Run "watcher install" as administrator to install it, and
+// "watcher debug" to run it on console.
+//
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/multiplay/winsvc/debug"
+	"github.com/multiplay/winsvc/eventlog"
+	"github.com/multiplay/winsvc/examples/internal/cli"
+	"github.com/multiplay/winsvc/install"
+	"github.com/multiplay/winsvc/mgr"
+	"github.com/multiplay/winsvc/svc"
+)
+
+func main() {
+	cli.Main(&cli.Service{
+		Definition: install.Definition{
+			Name: "examplewatcher",
+			Config: mgr.Config{
+				DisplayName: "Example Directory Watcher",
+				Description: "Logs changes to files in watched directory.",
+			},
+			EventLog: eventlog.Error | eventlog.Warning | eventlog.Info,
+		},
+		New: func(elog debug.Log) svc.Handler {
+			return &watcher{
+				dir:      filepath.Join(os.Getenv("ProgramData"), "examplewatcher"),
+				interval: time.Second,
+				elog:     elog,
+			}
+		},
+	})
+}
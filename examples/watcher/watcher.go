@@ -0,0 +1,121 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/multiplay/winsvc/debug"
+	"github.com/multiplay/winsvc/svc"
+)
+
+// snapshot maps names of files in directory to their modification times.
+type snapshot map[string]time.Time
+
+func scan(dir string) (snapshot, error) {
+	es, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := make(snapshot)
+	for _, e := range es {
+		fi, err := e.Info()
+		if err != nil {
+			// removed since ReadDir
+			continue
+		}
+		s[e.Name()] = fi.ModTime()
+	}
+	return s, nil
+}
+
+// diff returns descriptions of changes from old to s, sorted by name.
+func (s snapshot) diff(old snapshot) []string {
+	var changes []string
+	for name, t := range s {
+		ot, ok := old[name]
+		switch {
+		case !ok:
+			changes = append(changes, name+" created")
+		case !t.Equal(ot):
+			changes = append(changes, name+" changed")
+		}
+	}
+	for name := range old {
+		if _, ok := s[name]; !ok {
+			changes = append(changes, name+" removed")
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+type watcher struct {
+	dir      string
+	interval time.Duration
+	elog     debug.Log
+}
+
+func (w *watcher) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (ssec bool, errno uint32) {
+	const cmdsAccepted = svc.AcceptStop | svc.AcceptShutdown | svc.AcceptPauseAndContinue
+	changes <- svc.Status{State: svc.StartPending}
+	dir := w.dir
+	if len(args) > 1 {
+		dir = args[1]
+	}
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		w.elog.Error(1, err.Error())
+		return true, 1
+	}
+	last, err := scan(dir)
+	if err != nil {
+		w.elog.Error(1, err.Error())
+		return true, 2
+	}
+	w.elog.Info(1, "watching "+dir)
+	tick := time.NewTicker(w.interval)
+	defer tick.Stop()
+	paused := false
+	changes <- svc.Status{State: svc.Running, Accepts: cmdsAccepted}
+	for {
+		select {
+		case <-tick.C:
+			if paused {
+				continue
+			}
+			cur, err := scan(dir)
+			if err != nil {
+				w.elog.Warning(2, err.Error())
+				continue
+			}
+			for _, c := range cur.diff(last) {
+				w.elog.Info(3, c)
+			}
+			last = cur
+		case c := <-r:
+			switch c.Cmd {
+			case svc.Interrogate:
+				changes <- c.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				return false, 0
+			case svc.Pause:
+				paused = true
+				changes <- svc.Status{State: svc.Paused, Accepts: cmdsAccepted}
+			case svc.Continue:
+				paused = false
+				changes <- svc.Status{State: svc.Running, Accepts: cmdsAccepted}
+			default:
+				w.elog.Warning(1, fmt.Sprintf("unexpected control request #%d", c.Cmd))
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Package firewall manages Windows Firewall inbound rules for services.
+// It drives "netsh advfirewall", which is present on every Windows
+// version with the advanced firewall.
+//
+// A rule with Service set applies to processes of that service only,
+// rather than the whole machine. Windows identifies such processes by
+// their per-service SID, so the service SID type must be set with
+// mgr.Service.SetSIDType, otherwise the rule never matches.
+//
+package firewall
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Rule is an inbound rule allowing connections to local ports.
+type Rule struct {
+	Name        string
+	Description string
+	Protocol    string // "tcp" or "udp"
+	Ports       []int  // local ports, all ports if empty
+	Program     string // executable the rule applies to, any if empty
+	Service     string // service the rule applies to, any if empty
+}
+
+// ForService returns rule named name that opens ports of protocol
+// proto for service svc only.
+func ForService(name, svc, proto string, ports ...int) Rule {
+	return Rule{Name: name, Service: svc, Protocol: proto, Ports: ports}
+}
+
+// args returns netsh arguments describing rule r.
+func (r *Rule) args() ([]string, error) {
+	if r.Name == "" {
+		return nil, errors.New("firewall: rule name is empty")
+	}
+	proto := strings.ToLower(r.Protocol)
+	if proto != "tcp" && proto != "udp" {
+		return nil, errors.New("firewall: invalid protocol " + r.Protocol)
+	}
+	a := []string{"name=" + r.Name, "dir=in", "action=allow", "enable=yes", "protocol=" + proto}
+	if len(r.Ports) > 0 {
+		ps := make([]string, len(r.Ports))
+		for i, p := range r.Ports {
+			if p <= 0 || p > 65535 {
+				return nil, fmt.Errorf("firewall: invalid port %d", p)
+			}
+			ps[i] = strconv.Itoa(p)
+		}
+		a = append(a, "localport="+strings.Join(ps, ","))
+	}
+	if r.Program != "" {
+		a = append(a, "program="+r.Program)
+	}
+	if r.Service != "" {
+		a = append(a, "service="+r.Service)
+	}
+	if r.Description != "" {
+		a = append(a, "description="+r.Description)
+	}
+	return a, nil
+}
+
+func netsh(args ...string) error {
+	a := append([]string{"advfirewall", "firewall"}, args...)
+	o, err := exec.Command("netsh", a...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("netsh %s failed: %v\n%s", strings.Join(args, " "), err, o)
+	}
+	return nil
+}
+
+// Add creates firewall rule r.
+func Add(r Rule) error {
+	a, err := r.args()
+	if err != nil {
+		return err
+	}
+	return netsh(append([]string{"add", "rule"}, a...)...)
+}
+
+// Delete removes all firewall rules named name.
+func Delete(name string) error {
+	return netsh("delete", "rule", "name="+name)
+}
+
+// noRuleMatches is what netsh prints, when no rule matches.
+const noRuleMatches = "No rules match the specified criteria."
+
+// Exists reports whether firewall rule name exists.
+func Exists(name string) (bool, error) {
+	o, err := exec.Command("netsh", "advfirewall", "firewall", "show", "rule", "name="+name).CombinedOutput()
+	if err == nil {
+		return true, nil
+	}
+	if _, ok := err.(*exec.ExitError); ok && bytes.Contains(o, []byte(noRuleMatches)) {
+		return false, nil
+	}
+	return false, fmt.Errorf("netsh show rule name=%s failed: %v\n%s", name, err, o)
+}
+
+// Update replaces all settings of existing firewall rule r.Name with r.
+// Settings r leaves empty are reset to allow any value.
+func Update(r Rule) error {
+	a, err := r.args()
+	if err != nil {
+		return err
+	}
+	// netsh keeps settings not mentioned, so reset them explicitly
+	if len(r.Ports) == 0 {
+		a = append(a, "localport=any")
+	}
+	if r.Program == "" {
+		a = append(a, "program=any")
+	}
+	if r.Service == "" {
+		a = append(a, "service=any")
+	}
+	return netsh(append([]string{"set", "rule", "name=" + r.Name, "new"}, a[1:]...)...)
+}
+
+// Ensure creates firewall rule r, or updates it, if it exists already.
+func Ensure(r Rule) error {
+	ok, err := Exists(r.Name)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return Update(r)
+	}
+	return Add(r)
+}
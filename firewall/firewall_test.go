@@ -0,0 +1,36 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package firewall
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRuleArgs(t *testing.T) {
+	r := ForService("game", "gamesvc", "UDP", 7777, 7778)
+	a, err := r.args()
+	if err != nil {
+		t.Fatalf("args failed: %v", err)
+	}
+	want := []string{"name=game", "dir=in", "action=allow", "enable=yes", "protocol=udp", "localport=7777,7778", "service=gamesvc"}
+	if !reflect.DeepEqual(a, want) {
+		t.Fatalf("args returned %q, want %q", a, want)
+	}
+
+	bad := []Rule{
+		{Protocol: "tcp"},
+		{Name: "x", Protocol: "icmp"},
+		{Name: "x", Protocol: "tcp", Ports: []int{70000}},
+	}
+	for _, r := range bad {
+		_, err := r.args()
+		if err == nil {
+			t.Errorf("args(%+v) succeeded, but should have failed", r)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Package impersonate runs code with the identity of a service client.
+//
+// Windows impersonation applies to a single OS thread. All functions
+// here lock the calling goroutine to its thread while the impersonation
+// is in effect, and revert it before returning, even if f panics.
+// If impersonation cannot be reverted, they panic and leave the
+// goroutine locked, so its thread is never returned to the runtime.
+// Goroutines started by f do not run as the client.
+//
+package impersonate
+
+import (
+	"runtime"
+	"syscall"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// do impersonates with begin, calls f and reverts impersonation with end.
+func do(begin, end func() error, f func() error) error {
+	runtime.LockOSThread()
+	err := begin()
+	if err != nil {
+		runtime.UnlockOSThread()
+		return err
+	}
+	defer func() {
+		err2 := end()
+		if err2 != nil {
+			// Thread still runs as the client, so code after do must
+			// not run on it. Keep it locked, so it is terminated when
+			// this goroutine exits, instead of being reused.
+			panic("impersonate: failed to revert impersonation: " + err2.Error())
+		}
+		runtime.UnlockOSThread()
+	}()
+	return f()
+}
+
+// PipeClient calls f while impersonating the client
+// connected to the server end of named pipe h.
+func PipeClient(h syscall.Handle, f func() error) error {
+	return do(func() error {
+		return winapi.ImpersonateNamedPipeClient(h)
+	}, winapi.RevertToSelf, f)
+}
+
+// Token calls f while impersonating the user of token t.
+func Token(t syscall.Token, f func() error) error {
+	return do(func() error {
+		return winapi.ImpersonateLoggedOnUser(t)
+	}, winapi.RevertToSelf, f)
+}
+
+// RPCClient calls f while impersonating the client of RPC binding
+// handle binding. Use 0 for binding inside a server routine to
+// impersonate the client of the current call.
+func RPCClient(binding uintptr, f func() error) error {
+	return do(func() error {
+		return winapi.RpcImpersonateClient(binding)
+	}, func() error {
+		return winapi.RpcRevertToSelfEx(binding)
+	}, f)
+}
@@ -0,0 +1,166 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package install
+
+import (
+	"errors"
+	"path/filepath"
+	"syscall"
+
+	"github.com/multiplay/winsvc/registry"
+)
+
+// werKeyName is Windows Error Reporting settings key. Its LocalDumps
+// subkey lists programs, whose crash dumps are kept locally.
+const (
+	werKeyName        = `SOFTWARE\Microsoft\Windows\Windows Error Reporting`
+	localDumpsKeyName = werKeyName + `\LocalDumps`
+
+	// installedByValueName names service, that created LocalDumps
+	// key of its executable. Uninstall deletes only keys it names.
+	installedByValueName = "InstalledBy"
+)
+
+// DumpType is kind of crash dump Windows Error Reporting writes.
+type DumpType uint32
+
+const (
+	DumpMini DumpType = 1 // stacks and modules only
+	DumpFull DumpType = 2 // whole process memory
+)
+
+// CrashDumps configures Windows Error Reporting to keep dumps of
+// crashes of service executable, like native crashes in cgo code,
+// which Go cannot recover or log. Settings are stored in LocalDumps
+// registry key named after executable file, so they apply to every
+// process running it, and to every instance of the service.
+type CrashDumps struct {
+	// Folder, if not empty, is where dumps are written. Environment
+	// variables, like %ProgramData%, are expanded. Windows Error
+	// Reporting uses %LOCALAPPDATA%\CrashDumps of service account
+	// by default.
+	Folder string
+
+	// Type is kind of dump to write, DumpMini if 0.
+	Type DumpType
+
+	// Count, if not 0, is number of dumps kept, oldest are
+	// deleted. Windows Error Reporting keeps 10 by default.
+	Count uint32
+}
+
+// exeName returns file name of service executable, like "game.exe".
+func (d *Definition) exeName() string {
+	return filepath.Base(d.ExePath)
+}
+
+// set stores c in LocalDumps key of program exe, and records
+// service, if it creates the key. It reports whether the key
+// had existed before. Settings c leaves empty are deleted.
+func (c *CrashDumps) set(exe, service string) (existed bool, err error) {
+	k, err := registry.OpenKey(syscall.HKEY_LOCAL_MACHINE, werKeyName)
+	if err != nil {
+		return false, err
+	}
+	defer k.Close()
+	// LocalDumps key is not there by default, and is created too
+	p, existed, err := k.CreateSubKey(`LocalDumps\` + exe)
+	if err != nil {
+		return false, err
+	}
+	defer p.Close()
+	if !existed {
+		err = p.SetString(installedByValueName, service)
+		if err != nil {
+			return existed, err
+		}
+	}
+	t := c.Type
+	if t == 0 {
+		t = DumpMini
+	}
+	err = p.SetUInt32("DumpType", uint32(t))
+	if err != nil {
+		return existed, err
+	}
+	if c.Folder != "" {
+		err = p.SetStringExpand("DumpFolder", c.Folder)
+	} else {
+		err = deleteValue(p, "DumpFolder")
+	}
+	if err != nil {
+		return existed, err
+	}
+	if c.Count != 0 {
+		err = p.SetUInt32("DumpCount", c.Count)
+	} else {
+		err = deleteValue(p, "DumpCount")
+	}
+	return existed, err
+}
+
+// deleteValue deletes value name of key k, if it is there.
+func deleteValue(k *registry.Key, name string) error {
+	err := k.DeleteValue(name)
+	if errors.Is(err, syscall.ERROR_FILE_NOT_FOUND) {
+		return nil
+	}
+	return err
+}
+
+// deleteCrashDumps deletes LocalDumps key of program exe.
+func deleteCrashDumps(exe string) error {
+	k, err := registry.OpenKey(syscall.HKEY_LOCAL_MACHINE, localDumpsKeyName)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	return k.DeleteSubKey(exe)
+}
+
+// deleteInstalledCrashDumps deletes LocalDumps key of program exe,
+// if Install created it. It reports whether the key is deleted.
+func deleteInstalledCrashDumps(exe string) (bool, error) {
+	k, err := registry.OpenKeyAccess(syscall.HKEY_LOCAL_MACHINE, localDumpsKeyName+`\`+exe, syscall.KEY_READ)
+	if errors.Is(err, syscall.ERROR_FILE_NOT_FOUND) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	_, err = k.GetString(installedByValueName)
+	k.Close()
+	if errors.Is(err, syscall.ERROR_FILE_NOT_FOUND) {
+		// configured by administrator, not Install
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, deleteCrashDumps(exe)
+}
+
+// crashDumpsStep returns step, that configures crash dumps of d
+// executable, and, when undone, deletes the configuration, unless
+// it had existed before.
+func (d *Definition) crashDumpsStep() Hook {
+	exe := d.exeName()
+	existed := true
+	return Hook{
+		Do: func(string) error {
+			var err error
+			existed, err = d.CrashDumps.set(exe, d.Name)
+			return err
+		},
+		Undo: func(string) error {
+			if existed {
+				return nil
+			}
+			return deleteCrashDumps(exe)
+		},
+	}
+}
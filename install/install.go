@@ -0,0 +1,348 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Package install installs and removes Windows services in one call.
+// Besides creating the service, it sets its recovery actions, registers
+// its event log source and opens firewall ports it needs. User supplied
+// hooks run before and after the service control manager does its part.
+// A failed step rolls back the steps completed before it.
+//
+package install
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/multiplay/winsvc/diag"
+	"github.com/multiplay/winsvc/eventlog"
+	"github.com/multiplay/winsvc/firewall"
+	"github.com/multiplay/winsvc/mgr"
+	"github.com/multiplay/winsvc/registry"
+	"github.com/multiplay/winsvc/secret"
+	"github.com/multiplay/winsvc/svc"
+)
+
+// Hook is a step run during install or uninstall of service name.
+// Undo, if not nil, reverses Do. It is called when a later step fails.
+type Hook struct {
+	Do   func(name string) error
+	Undo func(name string) error
+}
+
+// Func returns Hook that calls f, and cannot be undone.
+func Func(f func(name string) error) Hook {
+	return Hook{Do: f}
+}
+
+// Command returns Hook that runs program prog with arguments args.
+// Every "{name}" in args is replaced by service name.
+func Command(prog string, args ...string) Hook {
+	return Hook{Do: func(name string) error {
+		a := make([]string, len(args))
+		for i := range args {
+			a[i] = strings.Replace(args[i], "{name}", name, -1)
+		}
+		o, err := exec.Command(prog, a...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%s failed: %v\n%s", prog, err, o)
+		}
+		return nil
+	}}
+}
+
+// Hooks lists steps to run around CreateService and DeleteService.
+type Hooks struct {
+	PreInstall    []Hook
+	PostInstall   []Hook
+	PreUninstall  []Hook
+	PostUninstall []Hook
+}
+
+// Definition describes service to be installed.
+type Definition struct {
+	Name    string
+	ExePath string
+	Config  mgr.Config // set Config.DelayedAutoStart for delayed start
+
+	// Recovery lists actions performed when service fails,
+	// and RecoveryReset is the time after which failure count
+	// is reset. Nothing is configured, if Recovery is empty.
+	Recovery      []mgr.RecoveryAction
+	RecoveryReset time.Duration
+
+	// RecoveryOnNonCrashFailures makes Recovery actions run also
+	// when service stops with non zero exit code, not only when it
+	// crashes. Package recycle relies on it to restart service.
+	RecoveryOnNonCrashFailures bool
+
+	// RecoveryCommand, if not empty, is program and its arguments
+	// run by mgr.RunCommand recovery action. See
+	// mgr.Service.SetRecoveryCommandTemplate for placeholders.
+	RecoveryCommand []string
+
+	// RebootMessage, if not empty, is broadcast to users before
+	// mgr.ComputerReboot recovery action reboots the computer.
+	RebootMessage string
+
+	// EventLog, if not 0, registers event source Name with
+	// EventCreate.exe as message file, supporting these event
+	// types, like eventlog.Error|eventlog.Warning|eventlog.Info.
+	// Event source is not registered in a container, unless
+	// EventLogInContainer is set.
+	EventLog            uint32
+	EventLogInContainer bool
+
+	// Firewall lists inbound rules to create. Empty rule Name,
+	// Program and Service default to values derived from the service,
+	// and service SID type is set, so rules apply to this service only.
+	Firewall []firewall.Rule
+
+	// LogonRight grants SeServiceLogonRight to Config.ServiceStartName,
+	// when service runs under user account, like domain account.
+	// Built in accounts have the right already.
+	LogonRight bool
+
+	// Secrets are stored as LSA private data, by key, for the
+	// service to read with secret.RetrievePrivateData at run time.
+	// Keys should start with "L$", so secrets stay on the computer.
+	Secrets map[string][]byte
+
+	// Instance, if not empty, names instance of service, when ExePath
+	// is installed more than once, under different Names, like one
+	// service per game title. It is passed to service as svc.InstanceArg
+	// argument, see svc.Args.Instance. Event source, firewall rules
+	// and Parameters are named after Name, so instances do not share them.
+	Instance string
+
+	// Parameters are stored as strings in Parameters registry key
+	// of service, for the service to read at run time, see
+	// registry.OpenServiceParameters. Every instance has its own.
+	Parameters map[string]string
+
+	// CrashDumps, if not nil, makes Windows Error Reporting
+	// keep dumps of ExePath crashes.
+	CrashDumps *CrashDumps
+
+	Hooks Hooks
+}
+
+// firewallRules returns d.Firewall with defaults filled in.
+func (d *Definition) firewallRules() []firewall.Rule {
+	rs := make([]firewall.Rule, len(d.Firewall))
+	for i, r := range d.Firewall {
+		if r.Program == "" {
+			r.Program = d.ExePath
+		}
+		if r.Service == "" {
+			r.Service = d.Name
+		}
+		if r.Name == "" {
+			r.Name = d.Name + " " + strings.ToUpper(r.Protocol)
+			for _, p := range r.Ports {
+				r.Name += " " + strconv.Itoa(p)
+			}
+		}
+		rs[i] = r
+	}
+	return rs
+}
+
+// commandLine returns command line service is started with. ExePath
+// is quoted, if it has spaces, so Windows does not try to run its
+// prefix, like C:\Program.exe of C:\Program Files\game\game.exe.
+func (d *Definition) commandLine() string {
+	if d.Instance == "" {
+		return syscall.EscapeArg(d.ExePath)
+	}
+	return syscall.EscapeArg(d.ExePath) + " " + syscall.EscapeArg(svc.InstanceArg+"="+d.Instance)
+}
+
+// setParameters stores d.Parameters in Parameters
+// registry key of service name.
+func (d *Definition) setParameters(name string) error {
+	k, err := registry.OpenKey(syscall.HKEY_LOCAL_MACHINE, servicesKeyName+`\`+name)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	p, _, err := k.CreateSubKey("Parameters")
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	for n, v := range d.Parameters {
+		err = p.SetString(n, v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// installEventSource reports whether event source is registered by
+// default, that is outside of containers. Tests replace it.
+var installEventSource = func() bool {
+	e, err := diag.Environment()
+	return err != nil || e.InstallEventSource()
+}
+
+// secretKeys returns keys of d.Secrets in order.
+func (d *Definition) secretKeys() []string {
+	keys := make([]string, 0, len(d.Secrets))
+	for k := range d.Secrets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// builtinAccount reports whether service account name is one of
+// built in accounts, LocalSystem by default, or a virtual account.
+func builtinAccount(name string) bool {
+	n := strings.ToLower(name)
+	return n == "" || n == "localsystem" || n == `.\localsystem` ||
+		strings.HasPrefix(n, `nt authority\`) || strings.HasPrefix(n, `nt service\`)
+}
+
+// setupSteps returns steps, other than creating service itself,
+// that Install performs for d.
+func (d *Definition) setupSteps() []Hook {
+	var hs []Hook
+	if d.EventLog != 0 && (d.EventLogInContainer || installEventSource()) {
+		hs = append(hs, Hook{
+			Do: func(name string) error {
+				return eventlog.InstallAsEventCreate(name, d.EventLog)
+			},
+			Undo: eventlog.Remove,
+		})
+	}
+	if d.LogonRight && !builtinAccount(d.Config.ServiceStartName) {
+		hs = append(hs, Func(func(string) error {
+			return secret.GrantRights(d.Config.ServiceStartName, secret.SeServiceLogonRight)
+		}))
+	}
+	if d.CrashDumps != nil {
+		hs = append(hs, d.crashDumpsStep())
+	}
+	if len(d.Parameters) > 0 {
+		hs = append(hs, Hook{Do: d.setParameters, Undo: deleteParameters})
+	}
+	for _, key := range d.secretKeys() {
+		key := key
+		hs = append(hs, Hook{
+			Do: func(string) error {
+				return secret.StorePrivateData(key, d.Secrets[key])
+			},
+			Undo: func(string) error {
+				return secret.StorePrivateData(key, nil)
+			},
+		})
+	}
+	for _, r := range d.firewallRules() {
+		r := r
+		hs = append(hs, Hook{
+			Do: func(string) error {
+				return firewall.Add(r)
+			},
+			Undo: func(string) error {
+				return firewall.Delete(r.Name)
+			},
+		})
+	}
+	return hs
+}
+
+// steps runs hooks and remembers which of them succeeded.
+type steps struct {
+	name string
+	done []Hook
+}
+
+func (s *steps) run(hs []Hook) error {
+	for _, h := range hs {
+		err := h.Do(s.name)
+		if err != nil {
+			return err
+		}
+		s.done = append(s.done, h)
+	}
+	return nil
+}
+
+// undo reverses all successful steps in reverse order.
+func (s *steps) undo() {
+	for i := len(s.done) - 1; i >= 0; i-- {
+		if u := s.done[i].Undo; u != nil {
+			u(s.name)
+		}
+	}
+	s.done = nil
+}
+
+// Install creates service described by d, and configures everything
+// else d asks for. If any step fails, the service is deleted and
+// completed steps are undone.
+func Install(m *mgr.Mgr, d *Definition) error {
+	pre := &steps{name: d.Name}
+	err := pre.run(d.Hooks.PreInstall)
+	if err != nil {
+		pre.undo()
+		return fmt.Errorf("pre-install of %s failed: %v", d.Name, err)
+	}
+	s, err := m.CreateService(d.Name, d.commandLine(), d.Config)
+	if err != nil {
+		pre.undo()
+		return err
+	}
+	defer s.Close()
+	// steps are undone in reverse order: those that
+	// followed CreateService, then the service itself,
+	// then pre-install hooks
+	st := &steps{name: d.Name}
+	rollback := func() {
+		st.undo()
+		s.Delete()
+		pre.undo()
+	}
+	if len(d.Firewall) > 0 {
+		// rules are scoped to the service by its SID
+		err = s.SetSIDType(mgr.SIDTypeUnrestricted)
+		if err != nil {
+			rollback()
+			return err
+		}
+	}
+	if len(d.Recovery) > 0 {
+		err = s.SetRecoveryActions(d.Recovery, uint32(d.RecoveryReset/time.Second))
+		if err != nil {
+			rollback()
+			return err
+		}
+	}
+	if d.RecoveryOnNonCrashFailures {
+		err = s.SetRecoveryActionsOnNonCrashFailures(true)
+		if err != nil {
+			rollback()
+			return err
+		}
+	}
+	err = st.run(d.setupSteps())
+	if err != nil {
+		rollback()
+		return err
+	}
+	err = st.run(d.Hooks.PostInstall)
+	if err != nil {
+		rollback()
+		return fmt.Errorf("post-install of %s failed: %v", d.Name, err)
+	}
+	return nil
+}
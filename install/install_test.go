@@ -0,0 +1,206 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package install
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/multiplay/winsvc/firewall"
+)
+
+func TestStepsUndo(t *testing.T) {
+	var log []string
+	hook := func(id string, fail bool) Hook {
+		return Hook{
+			Do: func(name string) error {
+				log = append(log, "do "+id+" "+name)
+				if fail {
+					return errors.New(id + " failed")
+				}
+				return nil
+			},
+			Undo: func(name string) error {
+				log = append(log, "undo "+id+" "+name)
+				return nil
+			},
+		}
+	}
+	st := &steps{name: "svc"}
+	err := st.run([]Hook{hook("a", false), hook("b", false), hook("c", true), hook("d", false)})
+	if err == nil {
+		t.Fatal("run succeeded, but should have failed")
+	}
+	st.undo()
+	want := []string{"do a svc", "do b svc", "do c svc", "undo b svc", "undo a svc"}
+	if !reflect.DeepEqual(log, want) {
+		t.Fatalf("hooks called %q, want %q", log, want)
+	}
+}
+
+func TestOrder(t *testing.T) {
+	def := func(name string, deps ...string) *Definition {
+		d := &Definition{Name: name}
+		d.Config.Dependencies = deps
+		return d
+	}
+	ds, err := Order([]*Definition{
+		def("web", "db", "cache", "Tcpip"),
+		def("cache", "db"),
+		def("db"),
+	})
+	if err != nil {
+		t.Fatalf("Order failed: %v", err)
+	}
+	var names []string
+	for _, d := range ds {
+		names = append(names, d.Name)
+	}
+	want := []string{"db", "cache", "web"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("Order returned %q, want %q", names, want)
+	}
+
+	_, err = Order([]*Definition{def("a", "b"), def("b", "a")})
+	if err == nil {
+		t.Fatal("Order succeeded on dependency cycle")
+	}
+}
+
+func TestFirewallRuleDefaults(t *testing.T) {
+	d := &Definition{
+		Name:    "gamesvc",
+		ExePath: `C:\games\gamesvc.exe`,
+		Firewall: []firewall.Rule{
+			{Protocol: "udp", Ports: []int{7777, 7778}},
+			{Name: "query", Protocol: "tcp", Ports: []int{27015}, Program: `C:\games\query.exe`},
+		},
+	}
+	want := []firewall.Rule{
+		{Name: "gamesvc UDP 7777 7778", Protocol: "udp", Ports: []int{7777, 7778}, Program: `C:\games\gamesvc.exe`, Service: "gamesvc"},
+		{Name: "query", Protocol: "tcp", Ports: []int{27015}, Program: `C:\games\query.exe`, Service: "gamesvc"},
+	}
+	if rs := d.firewallRules(); !reflect.DeepEqual(rs, want) {
+		t.Fatalf("firewallRules returned %+v, want %+v", rs, want)
+	}
+}
+
+func TestEventLogInContainer(t *testing.T) {
+	defer func(f func() bool) { installEventSource = f }(installEventSource)
+	installEventSource = func() bool { return false }
+
+	d := &Definition{Name: "gamesvc", EventLog: 7}
+	if n := len(d.setupSteps()); n != 0 {
+		t.Errorf("%d setup steps in container, want event source to be skipped", n)
+	}
+	d.EventLogInContainer = true
+	if n := len(d.setupSteps()); n != 1 {
+		t.Errorf("%d setup steps in container, with EventLogInContainer set, want 1", n)
+	}
+}
+
+func TestSecretsAndLogonRight(t *testing.T) {
+	d := &Definition{
+		Name:       "gamesvc",
+		LogonRight: true,
+		Secrets:    map[string][]byte{"L$gamesvc-db": []byte("pw"), "L$gamesvc-api": []byte("token")},
+	}
+	// LocalSystem has the right already
+	if n := len(d.setupSteps()); n != 2 {
+		t.Errorf("%d setup steps for LocalSystem, want 2", n)
+	}
+	d.Config.ServiceStartName = `CORP\gamesvc`
+	if n := len(d.setupSteps()); n != 3 {
+		t.Errorf("%d setup steps for domain account, want 3", n)
+	}
+	want := []string{"L$gamesvc-api", "L$gamesvc-db"}
+	if got := d.UninstallOptions().Secrets; !reflect.DeepEqual(got, want) {
+		t.Errorf("uninstall deletes secrets %q, want %q", got, want)
+	}
+}
+
+func TestInstance(t *testing.T) {
+	d := &Definition{Name: "game@eu1", ExePath: `C:\Program Files\game\game.exe`}
+	if cl, want := d.commandLine(), `"C:\Program Files\game\game.exe"`; cl != want {
+		t.Errorf("command line is %s, want %s", cl, want)
+	}
+	if n := len(d.setupSteps()); n != 0 {
+		t.Errorf("%d setup steps without parameters, want 0", n)
+	}
+	d.Instance = "eu1"
+	d.Parameters = map[string]string{"Title": "eu1", "Port": "7777"}
+	want := `"C:\Program Files\game\game.exe" -instance=eu1`
+	if cl := d.commandLine(); cl != want {
+		t.Errorf("command line is %s, want %s", cl, want)
+	}
+	if n := len(d.setupSteps()); n != 1 {
+		t.Errorf("%d setup steps with parameters, want 1", n)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tmpl := Template{Name: "game", DisplayName: "Game server"}
+	if s := tmpl.Service("eu1"); s != "game@eu1" {
+		t.Errorf("service of instance eu1 is %q, want game@eu1", s)
+	}
+	if s := tmpl.Display("eu1"); s != "Game server (eu1)" {
+		t.Errorf("display name of instance eu1 is %q, want %q", s, "Game server (eu1)")
+	}
+	for name, want := range map[string]string{"game@eu1": "eu1", "Game@EU1": "EU1", "game": "", "game@": "", "gamer@eu1": ""} {
+		i, ok := tmpl.Instance(name)
+		if i != want || ok != (want != "") {
+			t.Errorf("Instance(%q) returned %q, %v, want %q", name, i, ok, want)
+		}
+	}
+
+	d := Definition{
+		ExePath:  `C:\games\game.exe`,
+		Firewall: []firewall.Rule{{Protocol: "udp", Ports: []int{7777}}, {Name: "query {instance}", Protocol: "tcp"}},
+		Secrets:  map[string][]byte{"L$game-{instance}-db": []byte("pw")},
+	}
+	eu := tmpl.Define("eu1", d)
+	if eu.Name != "game@eu1" || eu.Instance != "eu1" || eu.Config.DisplayName != "Game server (eu1)" {
+		t.Errorf("defined instance %s (%q), display name %q", eu.Name, eu.Instance, eu.Config.DisplayName)
+	}
+	var rules []string
+	for _, r := range eu.firewallRules() {
+		rules = append(rules, r.Name)
+	}
+	if want := []string{"game@eu1 UDP 7777", "query eu1"}; !reflect.DeepEqual(rules, want) {
+		t.Errorf("firewall rules are %q, want %q", rules, want)
+	}
+	if want := []string{"L$game-eu1-db"}; !reflect.DeepEqual(eu.secretKeys(), want) {
+		t.Errorf("secret keys are %q, want %q", eu.secretKeys(), want)
+	}
+	if d.Firewall[1].Name != "query {instance}" || d.Secrets["L$game-{instance}-db"] == nil {
+		t.Errorf("Define changed template definition")
+	}
+}
+
+func TestCrashDumps(t *testing.T) {
+	d := &Definition{Name: "gamesvc", ExePath: `C:\games\gamesvc.exe`}
+	if o := d.UninstallOptions(); o.CrashDumps != "" {
+		t.Errorf("uninstall deletes crash dump settings of %q, when there are none", o.CrashDumps)
+	}
+	d.CrashDumps = &CrashDumps{Folder: `%ProgramData%\gamesvc\dumps`, Type: DumpFull, Count: 5}
+	if n := len(d.setupSteps()); n != 1 {
+		t.Errorf("%d setup steps with crash dumps, want 1", n)
+	}
+	if o := d.UninstallOptions(); o.CrashDumps != "gamesvc.exe" {
+		t.Errorf("uninstall deletes crash dump settings of %q, want gamesvc.exe", o.CrashDumps)
+	}
+	if o := d.UninstallOptions(); o.Template != nil {
+		t.Errorf("uninstall of service, that is not instance, has template %+v", o.Template)
+	}
+	// instances share settings of their executable, they
+	// are deleted with the last instance of template
+	d.Name, d.Instance = "gamesvc@eu1", "eu1"
+	if o := d.UninstallOptions(); o.CrashDumps != "gamesvc.exe" || o.Template == nil || o.Template.Name != "gamesvc" {
+		t.Errorf("uninstall of instance deletes crash dump settings of %q with template %+v, want gamesvc.exe and gamesvc", o.CrashDumps, o.Template)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package install
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/multiplay/winsvc/firewall"
+	"github.com/multiplay/winsvc/mgr"
+	"github.com/multiplay/winsvc/svc"
+)
+
+// InstanceSeparator separates template name and instance
+// in service name, like "game@eu1". svc.Args.Instance
+// recognizes it.
+const InstanceSeparator = svc.InstanceSeparator
+
+// Template names instances of service installed from the same
+// executable more than once, like one per game title. Service of
+// instance is named Name@instance, and everything named after
+// service, like event source, firewall rules and Parameters registry
+// key, is named after instance service too.
+type Template struct {
+	Name        string // like "game"
+	DisplayName string // like "Game server", "" for no display name
+}
+
+// Service returns name of service of instance, like "game@eu1".
+func (t Template) Service(instance string) string {
+	return t.Name + InstanceSeparator + instance
+}
+
+// Display returns display name of service of
+// instance, like "Game server (eu1)".
+func (t Template) Display(instance string) string {
+	if t.DisplayName == "" {
+		return ""
+	}
+	return t.DisplayName + " (" + instance + ")"
+}
+
+// Instance returns instance service name belongs to. ok is false,
+// if name is not service of t instance. Names are compared ignoring
+// case, as the service control manager does.
+func (t Template) Instance(name string) (instance string, ok bool) {
+	prefix := t.Name + InstanceSeparator
+	if len(name) <= len(prefix) || !strings.EqualFold(name[:len(prefix)], prefix) {
+		return "", false
+	}
+	return name[len(prefix):], true
+}
+
+// Define returns copy of d for instance: with Name, Instance and
+// display name set, and every "{instance}" in firewall rule names,
+// secret keys, Parameters values and description replaced by
+// instance, so instances do not share them.
+func (t Template) Define(instance string, d Definition) *Definition {
+	expand := func(s string) string {
+		return strings.Replace(s, "{instance}", instance, -1)
+	}
+	d.Name = t.Service(instance)
+	d.Instance = instance
+	if d.Config.DisplayName == "" {
+		d.Config.DisplayName = t.Display(instance)
+	}
+	d.Config.Description = expand(d.Config.Description)
+	d.Firewall = append([]firewall.Rule(nil), d.Firewall...)
+	for i := range d.Firewall {
+		d.Firewall[i].Name = expand(d.Firewall[i].Name)
+	}
+	if d.Secrets != nil {
+		secrets := make(map[string][]byte, len(d.Secrets))
+		for k, v := range d.Secrets {
+			secrets[expand(k)] = v
+		}
+		d.Secrets = secrets
+	}
+	if d.Parameters != nil {
+		params := make(map[string]string, len(d.Parameters))
+		for k, v := range d.Parameters {
+			params[k] = expand(v)
+		}
+		d.Parameters = params
+	}
+	return &d
+}
+
+// Instances returns instances of t installed in m, in order.
+func (t Template) Instances(m *mgr.Mgr) ([]string, error) {
+	names, err := m.ListServices()
+	if err != nil {
+		return nil, err
+	}
+	var is []string
+	for _, name := range names {
+		if i, ok := t.Instance(name); ok {
+			is = append(is, i)
+		}
+	}
+	sort.Strings(is)
+	return is, nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package install
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/multiplay/winsvc/mgr"
+	"github.com/multiplay/winsvc/svc"
+)
+
+// Order sorts definitions ds so that every service comes after
+// services it depends on. Dependencies are taken from Config.Dependencies,
+// names not present in ds are assumed to be installed already.
+// Order fails if dependencies form a cycle.
+func Order(ds []*Definition) ([]*Definition, error) {
+	byName := make(map[string]*Definition)
+	for _, d := range ds {
+		byName[strings.ToLower(d.Name)] = d
+	}
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[*Definition]int)
+	r := make([]*Definition, 0, len(ds))
+	var visit func(d *Definition) error
+	visit = func(d *Definition) error {
+		switch state[d] {
+		case visiting:
+			return errors.New("install: dependency cycle at service " + d.Name)
+		case visited:
+			return nil
+		}
+		state[d] = visiting
+		for _, dep := range d.Config.Dependencies {
+			if dd, ok := byName[strings.ToLower(dep)]; ok {
+				err := visit(dd)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		state[d] = visited
+		r = append(r, d)
+		return nil
+	}
+	for _, d := range ds {
+		err := visit(d)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// InstallAll installs services ds in dependency order. If any of
+// them fails, services installed so far are removed again.
+func InstallAll(m *mgr.Mgr, ds []*Definition) error {
+	ds, err := Order(ds)
+	if err != nil {
+		return err
+	}
+	for i, d := range ds {
+		err := Install(m, d)
+		if err != nil {
+			for j := i - 1; j >= 0; j-- {
+				// undone, not uninstalled by user,
+				// so uninstall hooks are not run
+				o := ds[j].UninstallOptions()
+				o.Hooks = Hooks{}
+				Uninstall(m, ds[j].Name, o)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// StartAll starts services ds in dependency order, waiting up
+// to timeout for each of them to reach Running state before
+// starting the next one. Services already running are left as is.
+func StartAll(m *mgr.Mgr, ds []*Definition, timeout time.Duration) error {
+	ds, err := Order(ds)
+	if err != nil {
+		return err
+	}
+	for _, d := range ds {
+		err := startAndWait(m, d.Name, timeout)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UninstallAll stops and removes services ds in reverse dependency
+// order, so no service is stopped while its dependents still run.
+func UninstallAll(m *mgr.Mgr, ds []*Definition, timeout time.Duration) error {
+	ds, err := Order(ds)
+	if err != nil {
+		return err
+	}
+	for i := len(ds) - 1; i >= 0; i-- {
+		o := ds[i].UninstallOptions()
+		o.Timeout = timeout
+		_, err := Uninstall(m, ds[i].Name, o)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startAndWait(m *mgr.Mgr, name string, timeout time.Duration) error {
+	s, err := m.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	err = s.Start(nil)
+	if err != nil && !errors.Is(err, mgr.ErrServiceAlreadyRunning) {
+		return err
+	}
+	_, err = s.WaitState(svc.Running, timeout)
+	return err
+}
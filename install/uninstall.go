@@ -0,0 +1,230 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package install
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/multiplay/winsvc/eventlog"
+	"github.com/multiplay/winsvc/firewall"
+	"github.com/multiplay/winsvc/mgr"
+	"github.com/multiplay/winsvc/registry"
+	"github.com/multiplay/winsvc/secret"
+)
+
+const servicesKeyName = `SYSTEM\CurrentControlSet\Services`
+
+// Options describes what Uninstall removes besides the service itself.
+type Options struct {
+	// Timeout is how long to wait for the service to stop.
+	// It defaults to 30 seconds.
+	Timeout time.Duration
+
+	// EventLog removes event source named after the service.
+	EventLog bool
+
+	// Firewall lists names of firewall rules to delete.
+	Firewall []string
+
+	// Secrets lists keys of LSA private data to delete.
+	Secrets []string
+
+	// CrashDumps, if not empty, is file name of executable, like
+	// "game.exe", whose crash dump configuration is deleted, if
+	// Install created it.
+	CrashDumps string
+
+	// Template, if not nil, is template of instance uninstalled.
+	// Configuration its instances share, like crash dumps, is
+	// deleted only once no other instance is left.
+	Template *Template
+
+	// DataDir, if not empty, is a directory removed with all its contents.
+	DataDir string
+
+	Hooks Hooks
+}
+
+// UninstallOptions returns Options that remove everything
+// Install configures for d. Service data directory is kept.
+func (d *Definition) UninstallOptions() Options {
+	o := Options{
+		EventLog: d.EventLog != 0,
+		Secrets:  d.secretKeys(),
+		Hooks:    d.Hooks,
+	}
+	for _, r := range d.firewallRules() {
+		o.Firewall = append(o.Firewall, r.Name)
+	}
+	if d.CrashDumps != nil {
+		o.CrashDumps = d.exeName()
+	}
+	if d.Instance != "" {
+		o.Template = &Template{Name: strings.TrimSuffix(d.Name, InstanceSeparator+d.Instance)}
+	}
+	return o
+}
+
+// otherInstances reports whether instances of o.Template,
+// other than service name, are installed in m.
+func (o *Options) otherInstances(m *mgr.Mgr, name string) (bool, error) {
+	if o.Template == nil {
+		return false, nil
+	}
+	is, err := o.Template.Instances(m)
+	if err != nil {
+		return false, err
+	}
+	for _, i := range is {
+		// name may still be listed, until its last handle is closed
+		if !strings.EqualFold(o.Template.Service(i), name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Report lists everything Uninstall has removed.
+type Report struct {
+	Removed []string
+}
+
+func (r *Report) add(format string, a ...interface{}) {
+	r.Removed = append(r.Removed, fmt.Sprintf(format, a...))
+}
+
+// Uninstall stops service name and deletes it. It then deletes
+// service Parameters registry key, everything else listed in o, and
+// runs post-uninstall hooks. Once the service is deleted, Uninstall
+// carries on after errors, so as little as possible is left behind,
+// and returns the first error.
+// If a pre-uninstall hook fails, the service is left installed.
+func Uninstall(m *mgr.Mgr, name string, o Options) (*Report, error) {
+	if o.Timeout == 0 {
+		o.Timeout = 30 * time.Second
+	}
+	r := &Report{}
+	st := &steps{name: name}
+	err := st.run(o.Hooks.PreUninstall)
+	if err != nil {
+		st.undo()
+		return r, fmt.Errorf("pre-uninstall of %s failed: %v", name, err)
+	}
+	err = stopAndWait(m, name, o.Timeout)
+	if err != nil {
+		st.undo()
+		return r, err
+	}
+	s, err := m.OpenService(name)
+	if err != nil {
+		st.undo()
+		return r, err
+	}
+	err = s.Delete()
+	s.Close()
+	if err != nil {
+		st.undo()
+		return r, err
+	}
+	r.add("service %s", name)
+
+	keep := func(err2 error) {
+		if err == nil {
+			err = err2
+		}
+	}
+	err2 := deleteParameters(name)
+	switch {
+	case err2 == nil:
+		r.add(`registry key HKLM\%s\%s\Parameters`, servicesKeyName, name)
+	case errors.Is(err2, syscall.ERROR_FILE_NOT_FOUND):
+	default:
+		keep(err2)
+	}
+	if o.EventLog {
+		err2 := eventlog.Remove(name)
+		switch {
+		case err2 == nil:
+			r.add("event log source %s", name)
+		case errors.Is(err2, syscall.ERROR_FILE_NOT_FOUND):
+		default:
+			keep(err2)
+		}
+	}
+	for _, rule := range o.Firewall {
+		err2 := firewall.Delete(rule)
+		if err2 != nil {
+			keep(err2)
+			continue
+		}
+		r.add("firewall rule %s", rule)
+	}
+	for _, key := range o.Secrets {
+		err2 := secret.StorePrivateData(key, nil)
+		switch {
+		case err2 == nil:
+			r.add("LSA secret %s", key)
+		case errors.Is(err2, secret.ErrPrivateDataNotFound):
+		default:
+			keep(err2)
+		}
+	}
+	if o.CrashDumps != "" {
+		shared, err2 := o.otherInstances(m, name)
+		if err2 == nil && !shared {
+			var deleted bool
+			deleted, err2 = deleteInstalledCrashDumps(o.CrashDumps)
+			if deleted && err2 == nil {
+				r.add(`registry key HKLM\%s\%s`, localDumpsKeyName, o.CrashDumps)
+			}
+		}
+		if err2 != nil {
+			keep(err2)
+		}
+	}
+	if o.DataDir != "" {
+		if _, err2 := os.Stat(o.DataDir); err2 == nil {
+			err2 = os.RemoveAll(o.DataDir)
+			if err2 != nil {
+				keep(err2)
+			} else {
+				r.add("directory %s", o.DataDir)
+			}
+		}
+	}
+	// post-uninstall hooks run, even if cleanup failed,
+	// as the service is gone already
+	err2 = st.run(o.Hooks.PostUninstall)
+	if err2 != nil {
+		keep(fmt.Errorf("post-uninstall of %s failed: %v", name, err2))
+	}
+	return r, err
+}
+
+// deleteParameters deletes Parameters registry key of service name.
+func deleteParameters(name string) error {
+	k, err := registry.OpenKey(syscall.HKEY_LOCAL_MACHINE, servicesKeyName+`\`+name)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	return k.DeleteSubKeyTree("Parameters")
+}
+
+func stopAndWait(m *mgr.Mgr, name string, timeout time.Duration) error {
+	s, err := m.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return s.StopAndWait(timeout)
+}
@@ -0,0 +1,66 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// ManagerAccess is a set of access rights to the service control manager.
+type ManagerAccess uint32
+
+const (
+	ManagerConnect          = ManagerAccess(winapi.SC_MANAGER_CONNECT)
+	ManagerCreateService    = ManagerAccess(winapi.SC_MANAGER_CREATE_SERVICE)
+	ManagerEnumerateService = ManagerAccess(winapi.SC_MANAGER_ENUMERATE_SERVICE)
+	ManagerLock             = ManagerAccess(winapi.SC_MANAGER_LOCK)
+	ManagerQueryLockStatus  = ManagerAccess(winapi.SC_MANAGER_QUERY_LOCK_STATUS)
+	ManagerModifyBootConfig = ManagerAccess(winapi.SC_MANAGER_MODIFY_BOOT_CONFIG)
+	ManagerAllAccess        = ManagerAccess(winapi.SC_MANAGER_ALL_ACCESS)
+
+	// ManagerReadOnly is enough to list services and query
+	// their status and config. It is granted to all
+	// authenticated users.
+	ManagerReadOnly = ManagerConnect | ManagerEnumerateService | ManagerQueryLockStatus
+)
+
+// Has reports whether a includes all rights in b.
+func (a ManagerAccess) Has(b ManagerAccess) bool {
+	return a&b == b
+}
+
+// ServiceAccess is a set of access rights to a service.
+type ServiceAccess uint32
+
+const (
+	ServiceQueryConfig         = ServiceAccess(winapi.SERVICE_QUERY_CONFIG)
+	ServiceChangeConfig        = ServiceAccess(winapi.SERVICE_CHANGE_CONFIG)
+	ServiceQueryStatus         = ServiceAccess(winapi.SERVICE_QUERY_STATUS)
+	ServiceEnumerateDependents = ServiceAccess(winapi.SERVICE_ENUMERATE_DEPENDENTS)
+	ServiceStart               = ServiceAccess(winapi.SERVICE_START)
+	ServiceStop                = ServiceAccess(winapi.SERVICE_STOP)
+	ServicePauseContinue       = ServiceAccess(winapi.SERVICE_PAUSE_CONTINUE)
+	ServiceInterrogate         = ServiceAccess(winapi.SERVICE_INTERROGATE)
+	ServiceUserDefinedControl  = ServiceAccess(winapi.SERVICE_USER_DEFINED_CONTROL)
+	ServiceDelete              = ServiceAccess(winapi.DELETE)
+	ServiceReadControl         = ServiceAccess(winapi.READ_CONTROL) // read security descriptor
+	ServiceWriteDAC            = ServiceAccess(winapi.WRITE_DAC)    // change security descriptor
+	ServiceWriteOwner          = ServiceAccess(winapi.WRITE_OWNER)
+	ServiceAllAccess           = ServiceAccess(winapi.SERVICE_ALL_ACCESS)
+
+	// ServiceReadOnly is enough to query service status and config.
+	ServiceReadOnly = ServiceQueryConfig | ServiceQueryStatus | ServiceEnumerateDependents | ServiceInterrogate | ServiceReadControl
+
+	// ServiceControl is enough to start, stop, pause and
+	// continue service, and to wait for it to change state.
+	ServiceControl = ServiceQueryStatus | ServiceStart | ServiceStop | ServicePauseContinue | ServiceInterrogate | ServiceUserDefinedControl
+)
+
+// Has reports whether a includes all rights in b.
+func (a ServiceAccess) Has(b ServiceAccess) bool {
+	return a&b == b
+}
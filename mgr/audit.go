@@ -0,0 +1,243 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/svc"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// Operation is call changing a service, reported to audit hook.
+type Operation struct {
+	Op       string            // call made, like "StartService"
+	Service  string            // service name, "" if not known
+	Params   map[string]string // parameters of the call, never passwords
+	Start    time.Time
+	Duration time.Duration
+	Err      error // nil, if call succeeded
+}
+
+// String returns o on one line, like
+// "StartService fake args=-v took 1.2ms: ok".
+func (o *Operation) String() string {
+	var b strings.Builder
+	b.WriteString(o.Op)
+	if o.Service != "" {
+		b.WriteString(" " + o.Service)
+	}
+	keys := make([]string, 0, len(o.Params))
+	for k := range o.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%s", k, o.Params[k])
+	}
+	result := "ok"
+	if o.Err != nil {
+		result = o.Err.Error()
+	}
+	fmt.Fprintf(&b, " took %v: %s", o.Duration, result)
+	return b.String()
+}
+
+// AuditSCM returns SCM, that calls scm, and reports calls changing
+// services, like CreateService, ChangeServiceConfig, StartService,
+// ControlService and DeleteService, to audit after they return,
+// whether they succeed or not. Queries are not reported. audit is
+// called synchronously, by goroutine that made the call, so it should
+// not block.
+func AuditSCM(scm SCM, audit func(*Operation)) SCM {
+	return auditSCM{audit, &handleNames{}, scm}
+}
+
+// WithAudit returns copy of m, that reports calls to audit, like
+// AuditSCM. Services opened or created with the copy are audited too.
+// The copy shares handle with m, so only one of them should be
+// disconnected.
+func (m *Mgr) WithAudit(audit func(*Operation)) *Mgr {
+	return &Mgr{Handle: m.Handle, scm: AuditSCM(m.sys(), audit)}
+}
+
+// WithAudit returns copy of s, that reports calls to audit, like
+// AuditSCM. The copy shares handle with s, so only one of them
+// should be closed.
+func (s *Service) WithAudit(audit func(*Operation)) *Service {
+	a := AuditSCM(s.sys(), audit).(auditSCM)
+	a.names.set(s.Handle, s.Name)
+	return &Service{Name: s.Name, Handle: s.Handle, scm: a}
+}
+
+type auditSCM struct {
+	audit func(*Operation)
+	names *handleNames
+	SCM
+}
+
+func (a auditSCM) unwrap() SCM { return a.SCM }
+
+// report runs f as operation op of service name with params.
+func (a auditSCM) report(op, name string, params map[string]string, f func() error) error {
+	o := &Operation{Op: op, Service: name, Params: params, Start: time.Now()}
+	o.Err = f()
+	o.Duration = time.Since(o.Start)
+	a.audit(o)
+	return o.Err
+}
+
+// call is like report, but for service handle h.
+func (a auditSCM) call(op string, h syscall.Handle, params map[string]string, f func() error) error {
+	name, _ := a.names.get(h)
+	return a.report(op, name, params, f)
+}
+
+func (a auditSCM) setEnvironment(s *Service, env []string) error {
+	params := map[string]string{"environment": strings.Join(env, ",")}
+	return a.call("SetEnvironment", s.Handle, params, func() error {
+		return s.setEnvironment(a.SCM, env)
+	})
+}
+
+// configParams returns parameters of ChangeServiceConfig or
+// CreateService, that are set, leaving password out.
+func configParams(serviceType, startType, errorControl uint32, binaryPathName, loadOrderGroup, dependencies, serviceStartName, password, displayName *uint16) map[string]string {
+	p := make(map[string]string)
+	if serviceType != winapi.SERVICE_NO_CHANGE {
+		p["service_type"] = ServiceType(serviceType).String()
+	}
+	if startType != winapi.SERVICE_NO_CHANGE {
+		p["start_type"] = StartType(startType).String()
+	}
+	if errorControl != winapi.SERVICE_NO_CHANGE {
+		p["error_control"] = ErrorControl(errorControl).String()
+	}
+	for k, v := range map[string]*uint16{
+		"path":             binaryPathName,
+		"load_order_group": loadOrderGroup,
+		"start_name":       serviceStartName,
+		"display_name":     displayName,
+	} {
+		if v != nil {
+			p[k] = toString(v)
+		}
+	}
+	if dependencies != nil {
+		p["dependencies"] = strings.Join(winapi.UTF16PtrToStrings(dependencies), ",")
+	}
+	if password != nil {
+		p["password"] = "(set)"
+	}
+	return p
+}
+
+// startArgs returns StartService arguments as command line.
+func startArgs(numArgs uint32, argVectors **uint16) string {
+	if argVectors == nil {
+		return ""
+	}
+	var args []string
+	for _, s := range unsafe.Slice(argVectors, numArgs) {
+		args = append(args, syscall.EscapeArg(toString(s)))
+	}
+	return strings.Join(args, " ")
+}
+
+func (a auditSCM) CloseServiceHandle(handle syscall.Handle) error {
+	a.names.remove(handle)
+	return a.SCM.CloseServiceHandle(handle)
+}
+
+func (a auditSCM) CreateService(mgr syscall.Handle, serviceName *uint16, displayName *uint16, access uint32, srvType uint32, startType uint32, errCtl uint32, pathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16) (h syscall.Handle, err error) {
+	name := toString(serviceName)
+	params := configParams(srvType, startType, errCtl, pathName, loadOrderGroup, dependencies, serviceStartName, password, displayName)
+	err = a.report("CreateService", name, params, func() error {
+		h, err = a.SCM.CreateService(mgr, serviceName, displayName, access, srvType, startType, errCtl, pathName, loadOrderGroup, tagId, dependencies, serviceStartName, password)
+		return err
+	})
+	if err == nil {
+		a.names.set(h, name)
+	}
+	return h, err
+}
+
+func (a auditSCM) OpenService(mgr syscall.Handle, serviceName *uint16, access uint32) (syscall.Handle, error) {
+	h, err := a.SCM.OpenService(mgr, serviceName, access)
+	if err == nil {
+		a.names.set(h, toString(serviceName))
+	}
+	return h, err
+}
+
+func (a auditSCM) DeleteService(service syscall.Handle) error {
+	return a.call("DeleteService", service, nil, func() error {
+		return a.SCM.DeleteService(service)
+	})
+}
+
+func (a auditSCM) StartService(service syscall.Handle, numArgs uint32, argVectors **uint16) error {
+	params := map[string]string{"args": startArgs(numArgs, argVectors)}
+	return a.call("StartService", service, params, func() error {
+		return a.SCM.StartService(service, numArgs, argVectors)
+	})
+}
+
+func (a auditSCM) ControlService(service syscall.Handle, control uint32, status *winapi.SERVICE_STATUS) error {
+	params := map[string]string{"control": svc.Cmd(control).String()}
+	return a.call("ControlService", service, params, func() error {
+		return a.SCM.ControlService(service, control, status)
+	})
+}
+
+func (a auditSCM) ControlServiceEx(service syscall.Handle, control uint32, infoLevel uint32, params *byte) error {
+	p := map[string]string{"control": svc.Cmd(control).String()}
+	if infoLevel == winapi.SERVICE_CONTROL_STATUS_REASON_INFO {
+		r := (*winapi.SERVICE_CONTROL_STATUS_REASON_PARAMS)(unsafe.Pointer(params))
+		p["reason"] = "0x" + strconv.FormatUint(uint64(r.Reason), 16)
+		if r.Comment != nil {
+			p["comment"] = toString(r.Comment)
+		}
+	}
+	return a.call("ControlService", service, p, func() error {
+		return a.SCM.ControlServiceEx(service, control, infoLevel, params)
+	})
+}
+
+func (a auditSCM) ChangeServiceConfig(service syscall.Handle, serviceType uint32, startType uint32, errorControl uint32, binaryPathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16, displayName *uint16) error {
+	params := configParams(serviceType, startType, errorControl, binaryPathName, loadOrderGroup, dependencies, serviceStartName, password, displayName)
+	return a.call("ChangeServiceConfig", service, params, func() error {
+		return a.SCM.ChangeServiceConfig(service, serviceType, startType, errorControl, binaryPathName, loadOrderGroup, tagId, dependencies, serviceStartName, password, displayName)
+	})
+}
+
+func (a auditSCM) ChangeServiceConfig2(service syscall.Handle, infoLevel uint32, info *byte) error {
+	params := make(map[string]string)
+	settings := config2Settings(infoLevel, info)
+	if settings == nil {
+		params["info_level"] = strconv.FormatUint(uint64(infoLevel), 10)
+	}
+	for _, c := range settings {
+		params[c.param] = c.value
+	}
+	return a.call("ChangeServiceConfig2", service, params, func() error {
+		return a.SCM.ChangeServiceConfig2(service, infoLevel, info)
+	})
+}
+
+func (a auditSCM) SetServiceObjectSecurity(service syscall.Handle, securityInformation uint32, sd *byte) error {
+	params := map[string]string{"security_information": "0x" + strconv.FormatUint(uint64(securityInformation), 16)}
+	return a.call("SetServiceObjectSecurity", service, params, func() error {
+		return a.SCM.SetServiceObjectSecurity(service, securityInformation, sd)
+	})
+}
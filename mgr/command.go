@@ -0,0 +1,90 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"syscall"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+var placeholderRE = regexp.MustCompile(`\{[^{}]*\}`)
+
+// expandCommand returns command line running program path with
+// arguments args, where placeholders are replaced: "{name}" by name
+// of service, and "{failures}" by "%1%", that the service control
+// manager replaces by failure count, when it runs the command.
+func expandCommand(name, path string, args []string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", errors.New("recovery command " + path + " is not absolute path")
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if fi.IsDir() {
+		return "", errors.New("recovery command " + path + " is a directory")
+	}
+	cmd := syscall.EscapeArg(path)
+	for _, a := range args {
+		var bad string
+		a = placeholderRE.ReplaceAllStringFunc(a, func(p string) string {
+			switch p {
+			case "{name}":
+				return name
+			case "{failures}":
+				return "%1%"
+			case "{exitcode}":
+				bad = "{exitcode} is not passed to recovery command by the service control manager, command can get it with Service.ExitCode"
+			default:
+				bad = "unknown placeholder " + p + " in recovery command argument"
+			}
+			return p
+		})
+		if bad != "" {
+			return "", errors.New(bad)
+		}
+		cmd += " " + syscall.EscapeArg(a)
+	}
+	return cmd, nil
+}
+
+// SetRecoveryCommandTemplate sets command run by RunCommand recovery
+// action to program path, that must exist, with arguments args. Arguments
+// can contain placeholders "{name}", replaced by service name, and
+// "{failures}", replaced by number of times service failed, so
+// notification scripts know what happened.
+func (s *Service) SetRecoveryCommandTemplate(path string, args ...string) error {
+	cmd, err := expandCommand(s.Name, path, args)
+	if err != nil {
+		return err
+	}
+	return s.SetRecoveryCommand(cmd)
+}
+
+// ExitCode returns exit code service s reported last time it stopped.
+// When service has failed, the service control manager sets it to
+// ERROR_PROCESS_ABORTED. Service specific exit code is returned, if
+// service reported one.
+func (s *Service) ExitCode() (uint32, error) {
+	var t winapi.SERVICE_STATUS_PROCESS
+	var needed uint32
+	err := s.sys().QueryServiceStatusEx(s.Handle, winapi.SC_STATUS_PROCESS_INFO,
+		(*byte)(unsafe.Pointer(&t)), uint32(unsafe.Sizeof(t)), &needed)
+	if err != nil {
+		return 0, err
+	}
+	if t.Win32ExitCode == uint32(winapi.ERROR_SERVICE_SPECIFIC_ERROR) {
+		return t.ServiceSpecificExitCode, nil
+	}
+	return t.Win32ExitCode, nil
+}
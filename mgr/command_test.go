@@ -0,0 +1,37 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExpandCommand(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := expandCommand("my svc", exe, []string{"-service={name}", "-failures", "{failures}"})
+	if err != nil {
+		t.Fatalf("expandCommand failed: %v", err)
+	}
+	want := exe + ` "-service=my svc" -failures %1%`
+	if cmd != want && cmd != `"`+exe+`"`+want[len(exe):] {
+		t.Errorf("command is %s, want %s", cmd, want)
+	}
+	for _, args := range [][]string{{"{exitcode}"}, {"{nosuch}"}} {
+		_, err = expandCommand("svc", exe, args)
+		if err == nil {
+			t.Errorf("placeholder %s is accepted", args[0])
+		}
+	}
+	_, err = expandCommand("svc", "notify.exe", nil)
+	if err == nil {
+		t.Error("relative command path is accepted")
+	}
+}
@@ -7,115 +7,105 @@
 package mgr
 
 import (
+	"errors"
 	"github.com/multiplay/winsvc/winapi"
 	"syscall"
-	"unicode/utf16"
 	"unsafe"
 )
 
-const (
-	// Service start types
-	StartManual    = winapi.SERVICE_DEMAND_START // the service must be started manually
-	StartAutomatic = winapi.SERVICE_AUTO_START   // the service will start by itself whenever the computer reboots
-	StartDisabled  = winapi.SERVICE_DISABLED     // the service cannot be started
-
-	// The severity of the error, and action taken,
-	// if this service fails to start.
-	ErrorCritical = winapi.SERVICE_ERROR_CRITICAL
-	ErrorIgnore   = winapi.SERVICE_ERROR_IGNORE
-	ErrorNormal   = winapi.SERVICE_ERROR_NORMAL
-	ErrorSevere   = winapi.SERVICE_ERROR_SEVERE
-)
-
 // TODO: Password is not returned by winapi.QueryServiceConfig, maybe I should do something about it
 
 type Config struct {
-	ServiceType      uint32
-	StartType        uint32
-	ErrorControl     uint32
+	ServiceType      ServiceType
+	StartType        StartType
+	ErrorControl     ErrorControl
 	BinaryPathName   string
 	LoadOrderGroup   string
 	TagId            uint32
 	Dependencies     []string
 	ServiceStartName string // name of the account under which the service should run
 	DisplayName      string
-	Password         string
+	Password         string `json:"-"` // not returned by Config, and left out of JSON
 	Description      string
+	DelayedAutoStart bool // the service is started after other auto-start services are started plus a short delay
 }
 
 func toString(p *uint16) string {
 	if p == nil {
 		return ""
 	}
-	return syscall.UTF16ToString((*[4096]uint16)(unsafe.Pointer(p))[:])
-}
-
-func toStringSlice(ps *uint16) []string {
-	if ps == nil {
-		return nil
-	}
-	r := make([]string, 0)
-	for from, i, p := 0, 0, (*[1 << 24]uint16)(unsafe.Pointer(ps)); true; i++ {
-		if p[i] == 0 {
-			// empty string marks the end
-			if i <= from {
-				break
-			}
-			r = append(r, string(utf16.Decode(p[from:i])))
-			from = i + 1
-		}
-	}
-	return r
+	return winapi.UTF16PtrToString(p, 1<<20)
 }
 
 func (s *Service) Config() (Config, error) {
 	b := make([]byte, 1024)
 	p := (*winapi.QUERY_SERVICE_CONFIG)(unsafe.Pointer(&b[0]))
 	var l uint32
-	err := winapi.QueryServiceConfig(s.Handle, p, uint32(len(b)), &l)
+	err := s.sys().QueryServiceConfig(s.Handle, p, uint32(len(b)), &l)
 	if err != nil {
-		if err.(syscall.Errno) != syscall.ERROR_INSUFFICIENT_BUFFER {
+		if !errors.Is(err, syscall.ERROR_INSUFFICIENT_BUFFER) {
 			return Config{}, err
 		}
 		b = make([]byte, l)
 		p = (*winapi.QUERY_SERVICE_CONFIG)(unsafe.Pointer(&b[0]))
-		err = winapi.QueryServiceConfig(s.Handle, p, l, &l)
+		err = s.sys().QueryServiceConfig(s.Handle, p, l, &l)
 		if err != nil {
 			return Config{}, err
 		}
 	}
-	b = make([]byte, 1024)
-	err = winapi.QueryServiceConfig2(s.Handle,
-		winapi.SERVICE_CONFIG_DESCRIPTION, &b[0], uint32(len(b)), &l)
+	b, err = s.queryServiceConfig2(winapi.SERVICE_CONFIG_DESCRIPTION)
 	if err != nil {
-		if err.(syscall.Errno) != syscall.ERROR_INSUFFICIENT_BUFFER {
-			return Config{}, err
-		}
-		b = make([]byte, l)
-		err = winapi.QueryServiceConfig2(s.Handle,
-			winapi.SERVICE_CONFIG_DESCRIPTION, &b[0], uint32(len(b)), &l)
-		if err != nil {
-			return Config{}, err
-		}
+		return Config{}, err
 	}
 	p2 := (*winapi.SERVICE_DESCRIPTION)(unsafe.Pointer(&b[0]))
+
+	b, err = s.queryServiceConfig2(winapi.SERVICE_CONFIG_DELAYED_AUTO_START_INFO)
+	if err != nil {
+		return Config{}, err
+	}
+	p3 := (*winapi.SERVICE_DELAYED_AUTO_START_INFO)(unsafe.Pointer(&b[0]))
+	delayedStart := false
+	if p3.IsDelayedAutoStartUp != 0 {
+		delayedStart = true
+	}
+
 	return Config{
-		ServiceType:      p.ServiceType,
-		StartType:        p.StartType,
-		ErrorControl:     p.ErrorControl,
+		ServiceType:      ServiceType(p.ServiceType),
+		StartType:        StartType(p.StartType),
+		ErrorControl:     ErrorControl(p.ErrorControl),
 		BinaryPathName:   toString(p.BinaryPathName),
 		LoadOrderGroup:   toString(p.LoadOrderGroup),
 		TagId:            p.TagId,
-		Dependencies:     toStringSlice(p.Dependencies),
+		Dependencies:     winapi.UTF16PtrToStrings(p.Dependencies),
 		ServiceStartName: toString(p.ServiceStartName),
 		DisplayName:      toString(p.DisplayName),
 		Description:      toString(p2.Description),
+		DelayedAutoStart: delayedStart,
 	}, nil
 }
 
-func updateDescription(handle syscall.Handle, desc string) error {
+// queryServiceConfig2 retrieves infoLevel
+// optional configuration parameters of service s.
+func (s *Service) queryServiceConfig2(infoLevel uint32) ([]byte, error) {
+	n := uint32(1024)
+	for {
+		b := make([]byte, n)
+		err := s.sys().QueryServiceConfig2(s.Handle, infoLevel, &b[0], n, &n)
+		if err == nil {
+			return b, nil
+		}
+		if !errors.Is(err, syscall.ERROR_INSUFFICIENT_BUFFER) {
+			return nil, err
+		}
+		if n <= uint32(len(b)) {
+			return nil, err
+		}
+	}
+}
+
+func (s *Service) updateDescription(desc string) error {
 	d := winapi.SERVICE_DESCRIPTION{toPtr(desc)}
-	err := winapi.ChangeServiceConfig2(handle,
+	err := s.sys().ChangeServiceConfig2(s.Handle,
 		winapi.SERVICE_CONFIG_DESCRIPTION, (*byte)(unsafe.Pointer(&d)))
 	if err != nil {
 		return err
@@ -123,15 +113,32 @@ func updateDescription(handle syscall.Handle, desc string) error {
 	return nil
 }
 
+func (s *Service) updateStartUp(isDelayed bool) error {
+	var d winapi.SERVICE_DELAYED_AUTO_START_INFO
+	if isDelayed {
+		d.IsDelayedAutoStartUp = 1
+	}
+	return s.sys().ChangeServiceConfig2(s.Handle,
+		winapi.SERVICE_CONFIG_DELAYED_AUTO_START_INFO, (*byte)(unsafe.Pointer(&d)))
+}
+
 func (s *Service) UpdateConfig(c Config) error {
-	err := winapi.ChangeServiceConfig(s.Handle, c.ServiceType, c.StartType,
-		c.ErrorControl, toPtr(c.BinaryPathName), toPtr(c.LoadOrderGroup),
-		nil, toStringBlock(c.Dependencies), toPtr(c.ServiceStartName),
+	deps, err := winapi.UTF16PtrFromStrings(c.Dependencies)
+	if err != nil {
+		return err
+	}
+	err = s.sys().ChangeServiceConfig(s.Handle, uint32(c.ServiceType), uint32(c.StartType),
+		uint32(c.ErrorControl), toPtr(c.BinaryPathName), toPtr(c.LoadOrderGroup),
+		nil, deps, toPtr(c.ServiceStartName),
 		toPtr(c.Password), toPtr(c.DisplayName))
 	if err != nil {
 		return err
 	}
-	err = updateDescription(s.Handle, c.Description)
+	err = s.updateDescription(c.Description)
+	if err != nil {
+		return err
+	}
+	err = s.updateStartUp(c.DelayedAutoStart)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,365 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"context"
+	"syscall"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// ConnectContext is like ConnectAccess, but gives up, once ctx is
+// done. The returned Mgr, and services opened with it, use ctx for
+// every call, as if returned by Mgr.WithContext.
+func ConnectContext(ctx context.Context, host string, access ManagerAccess) (*Mgr, error) {
+	return ConnectWith(contextSCM{ctx, SystemSCM}, host, access)
+}
+
+// WithContext returns copy of m, that gives up calls to the service
+// control manager, once ctx is done, and returns ctx.Err() then.
+// Services opened or created with the copy use ctx too. Windows
+// cannot cancel calls, so call given up keeps running in background,
+// with its own copy of buffers, and handle or subscription it
+// returns, if any, is released. The copy shares handle
+// with m, so only one of them should be disconnected.
+func (m *Mgr) WithContext(ctx context.Context) *Mgr {
+	return &Mgr{Handle: m.Handle, scm: withContext(ctx, m.sys())}
+}
+
+// WithContext returns copy of s, that uses ctx, like Mgr.WithContext.
+// The copy shares handle with s, so only one of them should be closed.
+func (s *Service) WithContext(ctx context.Context) *Service {
+	return &Service{Name: s.Name, Handle: s.Handle, scm: withContext(ctx, s.sys())}
+}
+
+func withContext(ctx context.Context, scm SCM) SCM {
+	if c, ok := scm.(contextSCM); ok {
+		scm = c.SCM
+	}
+	if r, ok := scm.(retrySCM); ok {
+		// so it stops retrying, once call is given up
+		r.ctx = ctx
+		scm = r
+	}
+	return contextSCM{ctx, scm}
+}
+
+// contextSCM calls SCM, and gives up, once ctx is done.
+type contextSCM struct {
+	ctx context.Context
+	SCM
+}
+
+func (c contextSCM) unwrap() SCM { return c.SCM }
+
+// wait runs f, and reports whether f returned before ctx was done,
+// with error of f, or ctx.Err(). If f is given up, undo, if not nil,
+// is called once f succeeds, to release what f acquired.
+func (c contextSCM) wait(f func() error, undo func()) (bool, error) {
+	err := c.ctx.Err()
+	if err != nil {
+		return false, err
+	}
+	errc := make(chan error)
+	abandoned := make(chan struct{})
+	go func() {
+		err := f()
+		select {
+		case errc <- err:
+		case <-abandoned:
+			if err == nil && undo != nil {
+				undo()
+			}
+		}
+	}()
+	select {
+	case err = <-errc:
+		return true, err
+	case <-c.ctx.Done():
+		close(abandoned)
+		return false, c.ctx.Err()
+	}
+}
+
+// call runs f, and returns its error, or ctx.Err(),
+// if ctx is done before f returns.
+func (c contextSCM) call(f func() error) error {
+	_, err := c.wait(f, nil)
+	return err
+}
+
+// open is like call, but for f returning handle. If ctx is
+// done first, handle is closed, once f returns it.
+func (c contextSCM) open(f func() (syscall.Handle, error)) (syscall.Handle, error) {
+	var h syscall.Handle
+	done, err := c.wait(func() (err error) {
+		h, err = f()
+		return err
+	}, func() {
+		c.SCM.CloseServiceHandle(h)
+	})
+	if !done {
+		return 0, err
+	}
+	return h, err
+}
+
+// outBuffer is private copy of n bytes long caller buffer, that
+// call fills in instead, so call given up, that keeps running in
+// background, does not write into caller memory. It is copied
+// to caller buffer once call returns in time.
+type outBuffer []byte
+
+func newOutBuffer(n uint32) outBuffer {
+	if n == 0 {
+		return nil
+	}
+	// aligned, as structures Windows stores in it are
+	a := make([]uint64, (n+7)/8)
+	return unsafe.Slice((*byte)(unsafe.Pointer(&a[0])), n)
+}
+
+func (b outBuffer) ptr() *byte {
+	if len(b) == 0 {
+		return nil
+	}
+	return &b[0]
+}
+
+// copyTo copies b to caller buffer p. Self-relative structures,
+// like QUERY_SERVICE_CONFIG, point into buffer they are stored in,
+// so pointers into b are moved to p.
+func (b outBuffer) copyTo(p *byte) {
+	if p == nil || len(b) == 0 {
+		return
+	}
+	base, end := uintptr(unsafe.Pointer(&b[0])), uintptr(unsafe.Pointer(&b[0]))+uintptr(len(b))
+	to := uintptr(unsafe.Pointer(p))
+	const ptrSize = unsafe.Sizeof(uintptr(0))
+	for i := uintptr(0); i+ptrSize <= uintptr(len(b)); i += ptrSize {
+		v := (*uintptr)(unsafe.Pointer(&b[i]))
+		if *v >= base && *v < end {
+			*v = to + (*v - base)
+		}
+	}
+	copy(unsafe.Slice(p, len(b)), b)
+}
+
+// copyUint32 stores v in p, if p is not nil.
+func copyUint32(p *uint32, v uint32) {
+	if p != nil {
+		*p = v
+	}
+}
+
+// valueUint32 returns value p points to, or 0, if p is nil.
+func valueUint32(p *uint32) uint32 {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
+func (c contextSCM) OpenSCManager(machineName *uint16, databaseName *uint16, access uint32) (syscall.Handle, error) {
+	return c.open(func() (syscall.Handle, error) {
+		return c.SCM.OpenSCManager(machineName, databaseName, access)
+	})
+}
+
+// CloseServiceHandle ignores ctx, so handles are not leaked.
+func (c contextSCM) CloseServiceHandle(handle syscall.Handle) error {
+	return c.SCM.CloseServiceHandle(handle)
+}
+
+func (c contextSCM) CreateService(mgr syscall.Handle, serviceName *uint16, displayName *uint16, access uint32, srvType uint32, startType uint32, errCtl uint32, pathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16) (syscall.Handle, error) {
+	return c.open(func() (syscall.Handle, error) {
+		return c.SCM.CreateService(mgr, serviceName, displayName, access, srvType, startType, errCtl, pathName, loadOrderGroup, tagId, dependencies, serviceStartName, password)
+	})
+}
+
+func (c contextSCM) OpenService(mgr syscall.Handle, serviceName *uint16, access uint32) (syscall.Handle, error) {
+	return c.open(func() (syscall.Handle, error) {
+		return c.SCM.OpenService(mgr, serviceName, access)
+	})
+}
+
+func (c contextSCM) DeleteService(service syscall.Handle) error {
+	return c.call(func() error {
+		return c.SCM.DeleteService(service)
+	})
+}
+
+func (c contextSCM) StartService(service syscall.Handle, numArgs uint32, argVectors **uint16) error {
+	return c.call(func() error {
+		return c.SCM.StartService(service, numArgs, argVectors)
+	})
+}
+
+func (c contextSCM) ControlService(service syscall.Handle, control uint32, status *winapi.SERVICE_STATUS) error {
+	var t winapi.SERVICE_STATUS
+	done, err := c.wait(func() error {
+		return c.SCM.ControlService(service, control, &t)
+	}, nil)
+	if done && status != nil {
+		*status = t
+	}
+	return err
+}
+
+func (c contextSCM) ControlServiceEx(service syscall.Handle, control uint32, infoLevel uint32, params *byte) error {
+	return c.call(func() error {
+		return c.SCM.ControlServiceEx(service, control, infoLevel, params)
+	})
+}
+
+func (c contextSCM) QueryServiceStatusEx(service syscall.Handle, infoLevel uint32, buff *byte, buffSize uint32, bytesNeeded *uint32) error {
+	b := newOutBuffer(buffSize)
+	var n uint32
+	done, err := c.wait(func() error {
+		return c.SCM.QueryServiceStatusEx(service, infoLevel, b.ptr(), buffSize, &n)
+	}, nil)
+	if done {
+		b.copyTo(buff)
+		copyUint32(bytesNeeded, n)
+	}
+	return err
+}
+
+func (c contextSCM) QueryServiceConfig(service syscall.Handle, serviceConfig *winapi.QUERY_SERVICE_CONFIG, bufSize uint32, bytesNeeded *uint32) error {
+	b := newOutBuffer(bufSize)
+	var n uint32
+	done, err := c.wait(func() error {
+		return c.SCM.QueryServiceConfig(service, (*winapi.QUERY_SERVICE_CONFIG)(unsafe.Pointer(b.ptr())), bufSize, &n)
+	}, nil)
+	if done {
+		b.copyTo((*byte)(unsafe.Pointer(serviceConfig)))
+		copyUint32(bytesNeeded, n)
+	}
+	return err
+}
+
+func (c contextSCM) ChangeServiceConfig(service syscall.Handle, serviceType uint32, startType uint32, errorControl uint32, binaryPathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16, displayName *uint16) error {
+	return c.call(func() error {
+		return c.SCM.ChangeServiceConfig(service, serviceType, startType, errorControl, binaryPathName, loadOrderGroup, tagId, dependencies, serviceStartName, password, displayName)
+	})
+}
+
+func (c contextSCM) QueryServiceConfig2(service syscall.Handle, infoLevel uint32, buff *byte, buffSize uint32, bytesNeeded *uint32) error {
+	b := newOutBuffer(buffSize)
+	var n uint32
+	done, err := c.wait(func() error {
+		return c.SCM.QueryServiceConfig2(service, infoLevel, b.ptr(), buffSize, &n)
+	}, nil)
+	if done {
+		b.copyTo(buff)
+		copyUint32(bytesNeeded, n)
+	}
+	return err
+}
+
+func (c contextSCM) ChangeServiceConfig2(service syscall.Handle, infoLevel uint32, info *byte) error {
+	return c.call(func() error {
+		return c.SCM.ChangeServiceConfig2(service, infoLevel, info)
+	})
+}
+
+func (c contextSCM) EnumDependentServices(service syscall.Handle, serviceState uint32, services *winapi.ENUM_SERVICE_STATUS, bufSize uint32, bytesNeeded *uint32, servicesReturned *uint32) error {
+	b := newOutBuffer(bufSize)
+	var n, count uint32
+	done, err := c.wait(func() error {
+		return c.SCM.EnumDependentServices(service, serviceState, (*winapi.ENUM_SERVICE_STATUS)(unsafe.Pointer(b.ptr())), bufSize, &n, &count)
+	}, nil)
+	if done {
+		b.copyTo((*byte)(unsafe.Pointer(services)))
+		copyUint32(bytesNeeded, n)
+		copyUint32(servicesReturned, count)
+	}
+	return err
+}
+
+func (c contextSCM) EnumServicesStatusEx(mgr syscall.Handle, infoLevel uint32, serviceType uint32, serviceState uint32, services *byte, bufSize uint32, bytesNeeded *uint32, servicesReturned *uint32, resumeHandle *uint32, groupName *uint16) error {
+	b := newOutBuffer(bufSize)
+	var n, count uint32
+	resume := valueUint32(resumeHandle)
+	var r *uint32
+	if resumeHandle != nil {
+		r = &resume
+	}
+	done, err := c.wait(func() error {
+		return c.SCM.EnumServicesStatusEx(mgr, infoLevel, serviceType, serviceState, b.ptr(), bufSize, &n, &count, r, groupName)
+	}, nil)
+	if done {
+		b.copyTo(services)
+		copyUint32(bytesNeeded, n)
+		copyUint32(servicesReturned, count)
+		copyUint32(resumeHandle, resume)
+	}
+	return err
+}
+
+func (c contextSCM) QueryServiceObjectSecurity(service syscall.Handle, securityInformation uint32, sd *byte, bufSize uint32, bytesNeeded *uint32) error {
+	b := newOutBuffer(bufSize)
+	var n uint32
+	done, err := c.wait(func() error {
+		return c.SCM.QueryServiceObjectSecurity(service, securityInformation, b.ptr(), bufSize, &n)
+	}, nil)
+	if done {
+		b.copyTo(sd)
+		copyUint32(bytesNeeded, n)
+	}
+	return err
+}
+
+func (c contextSCM) SetServiceObjectSecurity(service syscall.Handle, securityInformation uint32, sd *byte) error {
+	return c.call(func() error {
+		return c.SCM.SetServiceObjectSecurity(service, securityInformation, sd)
+	})
+}
+
+func (c contextSCM) GetServiceDisplayName(mgr syscall.Handle, serviceName *uint16, displayName *uint16, bufSize *uint32) error {
+	return c.lookupName(c.SCM.GetServiceDisplayName, mgr, serviceName, displayName, bufSize)
+}
+
+func (c contextSCM) GetServiceKeyName(mgr syscall.Handle, displayName *uint16, serviceName *uint16, bufSize *uint32) error {
+	return c.lookupName(c.SCM.GetServiceKeyName, mgr, displayName, serviceName, bufSize)
+}
+
+// lookupName calls GetServiceDisplayName or GetServiceKeyName f
+// with private buffer for name, of *bufSize characters.
+func (c contextSCM) lookupName(f func(syscall.Handle, *uint16, *uint16, *uint32) error, mgr syscall.Handle, from *uint16, name *uint16, bufSize *uint32) error {
+	size := valueUint32(bufSize)
+	b := newOutBuffer(2 * size)
+	done, err := c.wait(func() error {
+		return f(mgr, from, (*uint16)(unsafe.Pointer(b.ptr())), &size)
+	}, nil)
+	if done {
+		b.copyTo((*byte)(unsafe.Pointer(name)))
+		copyUint32(bufSize, size)
+	}
+	return err
+}
+
+func (c contextSCM) SubscribeServiceChangeNotifications(service syscall.Handle, eventType uint32, callback uintptr, context uintptr, subscription *uintptr) error {
+	var sub uintptr
+	done, err := c.wait(func() error {
+		return c.SCM.SubscribeServiceChangeNotifications(service, eventType, callback, context, &sub)
+	}, func() {
+		c.SCM.UnsubscribeServiceChangeNotifications(sub)
+	})
+	if done && subscription != nil {
+		*subscription = sub
+	}
+	return err
+}
+
+// UnsubscribeServiceChangeNotifications ignores ctx,
+// so subscriptions are not leaked.
+func (c contextSCM) UnsubscribeServiceChangeNotifications(subscription uintptr) {
+	c.SCM.UnsubscribeServiceChangeNotifications(subscription)
+}
@@ -0,0 +1,70 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// slowSCM fills in status of service, once release is closed.
+type slowSCM struct {
+	SCM
+	release  chan struct{}
+	returned chan struct{}
+}
+
+func (s *slowSCM) QueryServiceStatusEx(service syscall.Handle, infoLevel uint32, buff *byte, buffSize uint32, bytesNeeded *uint32) error {
+	defer close(s.returned)
+	<-s.release
+	t := (*winapi.SERVICE_STATUS_PROCESS)(unsafe.Pointer(buff))
+	t.CurrentState = winapi.SERVICE_RUNNING
+	*bytesNeeded = buffSize
+	return nil
+}
+
+func TestContextAbandonedBuffers(t *testing.T) {
+	f := &slowSCM{release: make(chan struct{}), returned: make(chan struct{})}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var status winapi.SERVICE_STATUS_PROCESS
+	var n uint32
+	err := withContext(ctx, f).QueryServiceStatusEx(0, winapi.SC_STATUS_PROCESS_INFO,
+		(*byte)(unsafe.Pointer(&status)), uint32(unsafe.Sizeof(status)), &n)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("QueryServiceStatusEx returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	close(f.release)
+	<-f.returned
+	if status.CurrentState != 0 || n != 0 {
+		t.Errorf("call given up wrote state %d and size %d into caller memory", status.CurrentState, n)
+	}
+}
+
+func TestOutBufferCopy(t *testing.T) {
+	// self-relative structure, with name stored after it
+	b := newOutBuffer(uint32(unsafe.Sizeof(winapi.SERVICE_DESCRIPTION{})) + 4)
+	d := (*winapi.SERVICE_DESCRIPTION)(unsafe.Pointer(b.ptr()))
+	name := (*[2]uint16)(unsafe.Pointer(&b[unsafe.Sizeof(*d)]))
+	name[0] = 'a'
+	d.Description = &name[0]
+
+	caller := make([]byte, len(b))
+	b.copyTo(&caller[0])
+	got := (*winapi.SERVICE_DESCRIPTION)(unsafe.Pointer(&caller[0]))
+	if p := unsafe.Pointer(got.Description); p != unsafe.Pointer(&caller[unsafe.Sizeof(*d)]) {
+		t.Fatalf("description points to %p, want it moved into caller buffer", p)
+	}
+	if s := winapi.UTF16PtrToString(got.Description, 2); s != "a" {
+		t.Errorf("description is %q, want %q", s, "a")
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+func TestStructLayout(t *testing.T) {
+	// offsets of every field and size of the structure, as
+	// defined by winsvc.h for 32 and 64 bit platforms
+	type layout struct {
+		v       interface{}
+		offsets [2][]uintptr
+		sizes   [2]uintptr
+	}
+	layouts := []layout{
+		{winapi.SC_ACTION{}, [2][]uintptr{{0, 4}, {0, 4}}, [2]uintptr{8, 8}},
+		{winapi.SERVICE_FAILURE_ACTIONS{}, [2][]uintptr{{0, 4, 8, 12, 16}, {0, 8, 16, 24, 32}}, [2]uintptr{20, 40}},
+		{winapi.SERVICE_TRIGGER_SPECIFIC_DATA_ITEM{}, [2][]uintptr{{0, 4, 8}, {0, 4, 8}}, [2]uintptr{12, 16}},
+		{winapi.SERVICE_TRIGGER{}, [2][]uintptr{{0, 4, 8, 12, 16}, {0, 4, 8, 16, 24}}, [2]uintptr{20, 32}},
+		{winapi.SERVICE_TRIGGER_INFO{}, [2][]uintptr{{0, 4, 8}, {0, 8, 16}}, [2]uintptr{12, 24}},
+		{winapi.GUID{}, [2][]uintptr{{0, 4, 6, 8}, {0, 4, 6, 8}}, [2]uintptr{16, 16}},
+	}
+	arch := 0
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		arch = 1
+	}
+	for _, l := range layouts {
+		typ := reflect.TypeOf(l.v)
+		if typ.Size() != l.sizes[arch] {
+			t.Errorf("%s size is %d, want %d", typ.Name(), typ.Size(), l.sizes[arch])
+		}
+		for i, want := range l.offsets[arch] {
+			f := typ.Field(i)
+			if f.Offset != want {
+				t.Errorf("%s.%s offset is %d, want %d", typ.Name(), f.Name, f.Offset, want)
+			}
+		}
+	}
+}
+
+func TestSCActionsConversion(t *testing.T) {
+	ras := []RecoveryAction{
+		{Type: ServiceRestart, Delay: time.Minute},
+		{Type: RunCommand, Delay: 1500 * time.Millisecond},
+		{Type: NoAction},
+	}
+	actions := toSCActions(ras)
+	b := (*[24]byte)(unsafe.Pointer(&actions[0]))[:]
+	want := []byte{
+		1, 0, 0, 0, 0x60, 0xea, 0, 0,
+		3, 0, 0, 0, 0xdc, 0x05, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	if !reflect.DeepEqual(b, want) {
+		t.Errorf("SC_ACTION array is % x, want % x", b, want)
+	}
+	got := fromSCActions(actions)
+	if !reflect.DeepEqual(got, ras) {
+		t.Errorf("converted back to %v, want %v", got, ras)
+	}
+}
+
+func TestTriggerConversion(t *testing.T) {
+	triggers := []Trigger{
+		{
+			Type:    winapi.SERVICE_TRIGGER_TYPE_IP_ADDRESS_AVAILABILITY,
+			Action:  TriggerStart,
+			Subtype: winapi.GUID{Data1: 0x4f27f2de, Data2: 0x14e2, Data3: 0x430b, Data4: [8]byte{0xa5, 0x49, 0x7c, 0xd4, 0x8c, 0xbc, 0x82, 0x45}},
+		},
+		{
+			Type:    winapi.SERVICE_TRIGGER_TYPE_FIREWALL_PORT_EVENT,
+			Action:  TriggerStop,
+			Subtype: winapi.GUID{Data1: 0xa144ed38, Data2: 0x8e12, Data3: 0x4de4, Data4: [8]byte{0x9d, 0x96, 0xe6, 0x47, 0x40, 0xb1, 0xa5, 0x24}},
+			Data: []TriggerData{
+				{Type: winapi.SERVICE_TRIGGER_DATA_TYPE_STRING, Data: []byte{'1', 0, '0', 0, 0, 0}},
+			},
+		},
+	}
+	info := toTriggerInfo(triggers)
+	if info.TriggersCount != 2 {
+		t.Fatalf("TriggersCount is %d, want 2", info.TriggersCount)
+	}
+	// first 8 bytes of SERVICE_TRIGGER are type and action
+	b := (*[8]byte)(unsafe.Pointer(info.Triggers))[:]
+	want := []byte{2, 0, 0, 0, 1, 0, 0, 0}
+	if !reflect.DeepEqual(b, want) {
+		t.Errorf("SERVICE_TRIGGER starts with % x, want % x", b, want)
+	}
+	got := fromTriggerInfo(info)
+	if !reflect.DeepEqual(got, triggers) {
+		t.Errorf("converted back to %v, want %v", got, triggers)
+	}
+	if got := fromTriggerInfo(toTriggerInfo(nil)); got != nil {
+		t.Errorf("empty triggers converted to %v", got)
+	}
+}
+
+func TestSecurityInformation(t *testing.T) {
+	tests := []struct {
+		sddl string
+		info uint32
+	}{
+		{"O:SYG:SY", winapi.OWNER_SECURITY_INFORMATION | winapi.GROUP_SECURITY_INFORMATION},
+		{"D:(A;;GA;;;SY)", winapi.DACL_SECURITY_INFORMATION},
+		// "G:" and "O:" inside ACE condition do not count
+		{`D:(XA;;FX;;;S-1-1-0;(@User.Title=="G:O:"))`, winapi.DACL_SECURITY_INFORMATION},
+		{"O:BAD:(A;;GA;;;BA)", winapi.OWNER_SECURITY_INFORMATION | winapi.DACL_SECURITY_INFORMATION},
+	}
+	for _, test := range tests {
+		var sd *byte
+		err := winapi.ConvertStringSecurityDescriptorToSecurityDescriptor(syscall.StringToUTF16Ptr(test.sddl),
+			winapi.SDDL_REVISION_1, &sd, nil)
+		if err != nil {
+			t.Fatalf("failed to convert %q: %v", test.sddl, err)
+		}
+		info, err := securityInformation(sd)
+		syscall.LocalFree(syscall.Handle(unsafe.Pointer(sd)))
+		if err != nil {
+			t.Fatalf("securityInformation(%q) failed: %v", test.sddl, err)
+		}
+		if info != test.info {
+			t.Errorf("securityInformation(%q) = %#x, want %#x", test.sddl, info, test.info)
+		}
+	}
+}
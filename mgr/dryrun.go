@@ -0,0 +1,414 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/svc"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// Change is change of service, that dry run recorded instead
+// of making it. Old is value read from the service control
+// manager, and is empty for services created in dry run.
+type Change struct {
+	Service string // service name
+	Op      string // call, that would make the change, like "ChangeServiceConfig"
+	Field   string // setting changed, like "StartType", or "" for whole service
+	Old     string
+	New     string
+}
+
+func (c Change) String() string {
+	s := c.Service + ": " + c.Op
+	if c.Field != "" {
+		s += " " + c.Field
+	}
+	switch {
+	case c.Old != "" || c.Field != "":
+		s += fmt.Sprintf(" %q -> %q", c.Old, c.New)
+	case c.New != "":
+		s += " " + c.New
+	}
+	return s
+}
+
+// Plan lists changes recorded by dry run, in order they were made.
+// Plan is safe for concurrent use.
+type Plan struct {
+	mu      sync.Mutex
+	changes []Change
+}
+
+func (p *Plan) add(c Change) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.changes = append(p.changes, c)
+}
+
+// Changes returns changes recorded so far.
+func (p *Plan) Changes() []Change {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Change(nil), p.changes...)
+}
+
+// String returns p with one change per line.
+func (p *Plan) String() string {
+	var b strings.Builder
+	for _, c := range p.Changes() {
+		b.WriteString(c.String() + "\n")
+	}
+	return b.String()
+}
+
+// DryRunSCM returns SCM, that records calls of scm changing services
+// in plan p, instead of making them. Queries are passed to scm, so
+// recorded changes include current values, and settings that would not
+// change are left out. Services created in dry run get handles, that
+// scm does not know, and cannot be queried. Passwords are never
+// recorded.
+func DryRunSCM(scm SCM, p *Plan) SCM {
+	return &dryRunSCM{plan: p, names: &handleNames{}, SCM: scm}
+}
+
+// WithDryRun returns copy of m, that records changes in plan p
+// instead of making them, like DryRunSCM. Services opened or created
+// with the copy do dry run too. The copy shares handle with m, so
+// only one of them should be disconnected.
+func (m *Mgr) WithDryRun(p *Plan) *Mgr {
+	return &Mgr{Handle: m.Handle, scm: DryRunSCM(m.sys(), p)}
+}
+
+// WithDryRun returns copy of s, that records changes in plan p
+// instead of making them, like DryRunSCM. The copy shares handle
+// with s, so only one of them should be closed.
+func (s *Service) WithDryRun(p *Plan) *Service {
+	d := DryRunSCM(s.sys(), p).(*dryRunSCM)
+	d.names.set(s.Handle, s.Name)
+	return &Service{Name: s.Name, Handle: s.Handle, scm: d}
+}
+
+type dryRunSCM struct {
+	plan  *Plan
+	names *handleNames
+
+	mu      sync.Mutex
+	created int // number of services created in dry run
+
+	SCM
+}
+
+// createdHandle returns handle of n-th service created in dry run.
+func createdHandle(n int) syscall.Handle {
+	return ^syscall.Handle(0)>>1 - syscall.Handle(n)
+}
+
+// isDryRun reports whether scm only records changes.
+func isDryRun(scm SCM) bool {
+	return dryRun(scm) != nil
+}
+
+// dryRun returns dry run SCM, that scm is, or calls through
+// other SCMs of this package, or nil, if there is none.
+func dryRun(scm SCM) *dryRunSCM {
+	for {
+		switch s := scm.(type) {
+		case *dryRunSCM:
+			return s
+		case interface{ unwrap() SCM }:
+			scm = s.unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// current returns service h, that queries underlying SCM, and
+// reports whether it exists outside of the dry run.
+func (d *dryRunSCM) current(h syscall.Handle) (*Service, bool) {
+	name, _ := d.names.get(h)
+	d.mu.Lock()
+	created := h >= createdHandle(d.created) && h < createdHandle(0)
+	d.mu.Unlock()
+	return &Service{Name: name, Handle: h, scm: d.SCM}, !created
+}
+
+// change records change of field of service h from
+// old to new, unless they are the same.
+func (d *dryRunSCM) change(h syscall.Handle, op, field, old, new string) {
+	if field != "" && old == new {
+		return
+	}
+	name, _ := d.names.get(h)
+	d.plan.add(Change{Service: name, Op: op, Field: field, Old: old, New: new})
+}
+
+func (d *dryRunSCM) CloseServiceHandle(handle syscall.Handle) error {
+	d.names.remove(handle)
+	if _, exists := d.current(handle); !exists {
+		return nil
+	}
+	return d.SCM.CloseServiceHandle(handle)
+}
+
+func (d *dryRunSCM) OpenService(mgr syscall.Handle, serviceName *uint16, access uint32) (syscall.Handle, error) {
+	h, err := d.SCM.OpenService(mgr, serviceName, access)
+	if err == nil {
+		d.names.set(h, toString(serviceName))
+	}
+	return h, err
+}
+
+func (d *dryRunSCM) CreateService(mgr syscall.Handle, serviceName *uint16, displayName *uint16, access uint32, srvType uint32, startType uint32, errCtl uint32, pathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16) (syscall.Handle, error) {
+	d.mu.Lock()
+	d.created++
+	h := createdHandle(d.created)
+	d.mu.Unlock()
+	d.names.set(h, toString(serviceName))
+	d.change(h, "CreateService", "", "", toString(pathName))
+	d.changeConfig(h, nil, srvType, startType, errCtl, pathName, loadOrderGroup, dependencies, serviceStartName, password, displayName)
+	return h, nil
+}
+
+func (d *dryRunSCM) DeleteService(service syscall.Handle) error {
+	d.change(service, "DeleteService", "", "", "")
+	return nil
+}
+
+func (d *dryRunSCM) StartService(service syscall.Handle, numArgs uint32, argVectors **uint16) error {
+	d.change(service, "StartService", "", "", startArgs(numArgs, argVectors))
+	return nil
+}
+
+func (d *dryRunSCM) ControlService(service syscall.Handle, control uint32, status *winapi.SERVICE_STATUS) error {
+	d.change(service, "ControlService", "", "", svc.Cmd(control).String())
+	return nil
+}
+
+func (d *dryRunSCM) ControlServiceEx(service syscall.Handle, control uint32, infoLevel uint32, params *byte) error {
+	d.change(service, "ControlService", "", "", svc.Cmd(control).String())
+	return nil
+}
+
+func (d *dryRunSCM) ChangeServiceConfig(service syscall.Handle, serviceType uint32, startType uint32, errorControl uint32, binaryPathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16, displayName *uint16) error {
+	var old *Config
+	if s, exists := d.current(service); exists {
+		c, err := s.Config()
+		if err != nil {
+			return err
+		}
+		old = &c
+	}
+	d.changeConfig(service, old, serviceType, startType, errorControl, binaryPathName, loadOrderGroup, dependencies, serviceStartName, password, displayName)
+	return nil
+}
+
+// changeConfig records changes of ChangeServiceConfig or
+// CreateService parameters from old configuration, which is
+// nil for services created in dry run.
+func (d *dryRunSCM) changeConfig(h syscall.Handle, old *Config, serviceType, startType, errorControl uint32, binaryPathName, loadOrderGroup, dependencies, serviceStartName, password, displayName *uint16) {
+	const op = "ChangeServiceConfig"
+	exists := old != nil
+	if !exists {
+		old = &Config{}
+	}
+	// was returns name of old value v, and "" for new services,
+	// where 0 does not mean boot start or kernel driver
+	was := func(v fmt.Stringer) string {
+		if !exists {
+			return ""
+		}
+		return v.String()
+	}
+	if serviceType != winapi.SERVICE_NO_CHANGE {
+		d.change(h, op, "ServiceType", was(old.ServiceType), ServiceType(serviceType).String())
+	}
+	if startType != winapi.SERVICE_NO_CHANGE {
+		d.change(h, op, "StartType", was(old.StartType), StartType(startType).String())
+	}
+	if errorControl != winapi.SERVICE_NO_CHANGE {
+		d.change(h, op, "ErrorControl", was(old.ErrorControl), ErrorControl(errorControl).String())
+	}
+	if binaryPathName != nil {
+		d.change(h, op, "BinaryPathName", old.BinaryPathName, toString(binaryPathName))
+	}
+	if loadOrderGroup != nil {
+		d.change(h, op, "LoadOrderGroup", old.LoadOrderGroup, toString(loadOrderGroup))
+	}
+	if dependencies != nil {
+		d.change(h, op, "Dependencies", strings.Join(old.Dependencies, ","), strings.Join(winapi.UTF16PtrToStrings(dependencies), ","))
+	}
+	if serviceStartName != nil {
+		d.change(h, op, "ServiceStartName", old.ServiceStartName, toString(serviceStartName))
+	}
+	if password != nil {
+		// only the fact password is set is recorded
+		d.change(h, op, "Password", "", "(set)")
+	}
+	if displayName != nil {
+		d.change(h, op, "DisplayName", old.DisplayName, toString(displayName))
+	}
+}
+
+func (d *dryRunSCM) setEnvironment(s *Service, env []string) error {
+	var old []string
+	if _, exists := d.current(s.Handle); exists {
+		var err error
+		old, err = s.Environment()
+		if err != nil {
+			return err
+		}
+	}
+	d.change(s.Handle, "SetEnvironment", "Environment", strings.Join(old, ","), strings.Join(env, ","))
+	return nil
+}
+
+func (d *dryRunSCM) ChangeServiceConfig2(service syscall.Handle, infoLevel uint32, info *byte) error {
+	const op = "ChangeServiceConfig2"
+	settings := config2Settings(infoLevel, info)
+	if settings == nil {
+		d.change(service, op, fmt.Sprintf("InfoLevel(%d)", infoLevel), "", "(changed)")
+		return nil
+	}
+	var old map[string]string
+	if s, exists := d.current(service); exists {
+		var err error
+		old, err = s.config2Fields(infoLevel)
+		if err != nil {
+			return err
+		}
+	}
+	for _, c := range settings {
+		d.change(service, op, c.field, old[c.field], c.value)
+	}
+	return nil
+}
+
+// config2Setting is setting, that ChangeServiceConfig2 changes.
+type config2Setting struct {
+	field string // name of setting, like "Description", see Change
+	param string // name of parameter, like "description", see Operation
+	value string
+}
+
+// config2Settings returns settings, that ChangeServiceConfig2 with
+// info of infoLevel changes, or nil, if infoLevel is not known.
+func config2Settings(infoLevel uint32, info *byte) []config2Setting {
+	switch infoLevel {
+	case winapi.SERVICE_CONFIG_DESCRIPTION:
+		p := (*winapi.SERVICE_DESCRIPTION)(unsafe.Pointer(info))
+		return []config2Setting{{"Description", "description", toString(p.Description)}}
+	case winapi.SERVICE_CONFIG_DELAYED_AUTO_START_INFO:
+		p := (*winapi.SERVICE_DELAYED_AUTO_START_INFO)(unsafe.Pointer(info))
+		return []config2Setting{{"DelayedAutoStart", "delayed_auto_start", strconv.FormatBool(p.IsDelayedAutoStartUp != 0)}}
+	case winapi.SERVICE_CONFIG_FAILURE_ACTIONS_FLAG:
+		p := (*winapi.SERVICE_FAILURE_ACTIONS_FLAG)(unsafe.Pointer(info))
+		return []config2Setting{{"RecoveryActionsOnNonCrashFailures", "recovery_actions_on_non_crash_failures", strconv.FormatBool(p.FailureActionsOnNonCrashFailures != 0)}}
+	case winapi.SERVICE_CONFIG_FAILURE_ACTIONS:
+		// fields left nil are not changed
+		p := (*winapi.SERVICE_FAILURE_ACTIONS)(unsafe.Pointer(info))
+		ss := []config2Setting{}
+		if p.Actions != nil {
+			ss = append(ss,
+				config2Setting{"RecoveryActions", "recovery_actions", formatActions(p)},
+				config2Setting{"ResetPeriod", "reset_period", strconv.FormatUint(uint64(p.ResetPeriod), 10)})
+		}
+		if p.RebootMsg != nil {
+			ss = append(ss, config2Setting{"RebootMessage", "reboot_message", toString(p.RebootMsg)})
+		}
+		if p.Command != nil {
+			ss = append(ss, config2Setting{"RecoveryCommand", "recovery_command", toString(p.Command)})
+		}
+		return ss
+	}
+	return nil
+}
+
+// config2Fields returns current settings of s, that ChangeServiceConfig2
+// with infoLevel changes, by field name, as config2Settings names them.
+func (s *Service) config2Fields(infoLevel uint32) (map[string]string, error) {
+	switch infoLevel {
+	case winapi.SERVICE_CONFIG_DESCRIPTION, winapi.SERVICE_CONFIG_DELAYED_AUTO_START_INFO:
+		c, err := s.Config()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{
+			"Description":      c.Description,
+			"DelayedAutoStart": strconv.FormatBool(c.DelayedAutoStart),
+		}, nil
+	case winapi.SERVICE_CONFIG_FAILURE_ACTIONS_FLAG:
+		f, err := s.RecoveryActionsOnNonCrashFailures()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"RecoveryActionsOnNonCrashFailures": strconv.FormatBool(f)}, nil
+	case winapi.SERVICE_CONFIG_FAILURE_ACTIONS:
+		p, err := s.failureActions()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{
+			"RecoveryActions": formatActions(p),
+			"ResetPeriod":     strconv.FormatUint(uint64(p.ResetPeriod), 10),
+			"RebootMessage":   toString(p.RebootMsg),
+			"RecoveryCommand": toString(p.Command),
+		}, nil
+	}
+	return nil, nil
+}
+
+var actionNames = map[int]string{
+	NoAction:       "none",
+	ComputerReboot: "reboot",
+	ServiceRestart: "restart",
+	RunCommand:     "run",
+}
+
+// formatActions returns recovery actions of p, like "restart/5s,none/0s".
+func formatActions(p *winapi.SERVICE_FAILURE_ACTIONS) string {
+	if p.Actions == nil {
+		return ""
+	}
+	var as []string
+	for _, a := range fromSCActions(unsafe.Slice(p.Actions, p.ActionsCount)) {
+		name, ok := actionNames[a.Type]
+		if !ok {
+			name = strconv.Itoa(a.Type)
+		}
+		as = append(as, name+"/"+a.Delay.Round(time.Millisecond).String())
+	}
+	return strings.Join(as, ",")
+}
+
+func (d *dryRunSCM) SetServiceObjectSecurity(service syscall.Handle, securityInformation uint32, sd *byte) error {
+	var old string
+	if s, exists := d.current(service); exists {
+		var err error
+		old, err = s.Security()
+		if err != nil {
+			return err
+		}
+	}
+	var p *uint16
+	err := winapi.ConvertSecurityDescriptorToStringSecurityDescriptor(sd,
+		winapi.SDDL_REVISION_1, securityInformation, &p, nil)
+	if err != nil {
+		return err
+	}
+	defer syscall.LocalFree(syscall.Handle(unsafe.Pointer(p)))
+	d.change(service, "SetServiceObjectSecurity", "Security", old, toString(p))
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// EnsureService makes sure service name is installed to run exepath
+// with settings c. It creates the service, like CreateService, if there
+// is none, or else changes settings, that differ from c, like Apply.
+// Used with WithDryRun, it records in the plan what it would do.
+func (m *Mgr) EnsureService(name, exepath string, c Config) (*Service, error) {
+	s, err := m.OpenService(name)
+	if errors.Is(err, ErrServiceDoesNotExist) {
+		return m.CreateService(name, exepath, c)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.BinaryPathName = exepath
+	err = s.Apply(c)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// withDefaults returns c with zero ServiceType, StartType
+// and ErrorControl set to defaults of CreateService.
+func withDefaults(c Config) Config {
+	if c.StartType == 0 {
+		c.StartType = StartManual
+	}
+	if c.ErrorControl == 0 {
+		c.ErrorControl = ErrorNormal
+	}
+	if c.ServiceType == 0 {
+		c.ServiceType = TypeOwnProcess
+	}
+	return c
+}
+
+// Apply changes settings of service s, that differ from c, and leaves
+// others alone, so applying c again changes nothing. Unlike UpdateConfig,
+// zero ServiceType, StartType and ErrorControl mean defaults of
+// CreateService, and empty BinaryPathName, ServiceStartName and
+// DisplayName are not changed. TagId is ignored. Password cannot be
+// compared, so it is set, if not empty.
+func (s *Service) Apply(c Config) error {
+	old, err := s.Config()
+	if err != nil {
+		return err
+	}
+	c = withDefaults(c)
+	typ, start, errCtl := uint32(winapi.SERVICE_NO_CHANGE), uint32(winapi.SERVICE_NO_CHANGE), uint32(winapi.SERVICE_NO_CHANGE)
+	if c.ServiceType != old.ServiceType {
+		typ = uint32(c.ServiceType)
+	}
+	if c.StartType != old.StartType {
+		start = uint32(c.StartType)
+	}
+	if c.ErrorControl != old.ErrorControl {
+		errCtl = uint32(c.ErrorControl)
+	}
+	// changed returns v, if it is not the same as old, or nil
+	changed := func(v, old string) *uint16 {
+		if v == old {
+			return nil
+		}
+		return syscall.StringToUTF16Ptr(v)
+	}
+	// set is like changed, but returns nil for empty v too
+	set := func(v, old string) *uint16 {
+		if v == "" {
+			return nil
+		}
+		return changed(v, old)
+	}
+	path, group := set(c.BinaryPathName, old.BinaryPathName), changed(c.LoadOrderGroup, old.LoadOrderGroup)
+	startName, display := set(c.ServiceStartName, old.ServiceStartName), set(c.DisplayName, old.DisplayName)
+	var deps *uint16
+	if strings.Join(c.Dependencies, "\x00") != strings.Join(old.Dependencies, "\x00") {
+		deps, err = winapi.UTF16PtrFromStrings(c.Dependencies)
+		if err != nil {
+			return err
+		}
+		if deps == nil {
+			// empty list removes all dependencies
+			deps = &[]uint16{0, 0}[0]
+		}
+	}
+	password := toPtr(c.Password)
+	if typ != winapi.SERVICE_NO_CHANGE || start != winapi.SERVICE_NO_CHANGE || errCtl != winapi.SERVICE_NO_CHANGE ||
+		path != nil || group != nil || deps != nil || startName != nil || password != nil || display != nil {
+		err = s.sys().ChangeServiceConfig(s.Handle, typ, start, errCtl, path, group,
+			nil, deps, startName, password, display)
+		if err != nil {
+			return err
+		}
+	}
+	if c.Description != old.Description {
+		// unlike in updateDescription, empty description removes it
+		d := winapi.SERVICE_DESCRIPTION{syscall.StringToUTF16Ptr(c.Description)}
+		err = s.sys().ChangeServiceConfig2(s.Handle,
+			winapi.SERVICE_CONFIG_DESCRIPTION, (*byte)(unsafe.Pointer(&d)))
+		if err != nil {
+			return err
+		}
+	}
+	if c.DelayedAutoStart != old.DelayedAutoStart {
+		err = s.updateStartUp(c.DelayedAutoStart)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+
+	"github.com/multiplay/winsvc/registry"
+)
+
+const servicesKeyName = `SYSTEM\CurrentControlSet\Services`
+
+// SetEnvironment sets environment variables of service s. The
+// variables, in "key=value" form, are added to the environment
+// the service process is started with, in place of setting them
+// machine wide. Empty env removes all service specific variables.
+// It only works for services installed on local computer, and managed
+// through SystemSCM, possibly wrapped by SCMs of this package. Dry run
+// and audit apply to it, as to changes made through SCM.
+func (s *Service) SetEnvironment(env []string) error {
+	for _, e := range env {
+		if strings.Index(e, "=") <= 0 {
+			return errors.New("invalid environment variable " + e)
+		}
+	}
+	return s.setEnvironment(s.sys(), env)
+}
+
+// environmentSetter is implemented by SCMs, that set environment
+// of services. Environment is kept in the registry, not set through
+// SCM, so only the system SCM writes it, while dry run and audit
+// record the change.
+type environmentSetter interface {
+	setEnvironment(s *Service, env []string) error
+}
+
+// setEnvironment sets environment variables of service s, through
+// scm or SCM it wraps. SCMs, that do not set environment, and do not
+// wrap other SCM of this package, fail, so fakes never write the
+// registry.
+func (s *Service) setEnvironment(scm SCM, env []string) error {
+	for {
+		switch m := scm.(type) {
+		case environmentSetter:
+			return m.setEnvironment(s, env)
+		case interface{ unwrap() SCM }:
+			scm = m.unwrap()
+		default:
+			return errors.New("environment of service " + s.Name + " can only be set through system SCM")
+		}
+	}
+}
+
+func (systemSCM) setEnvironment(s *Service, env []string) error {
+	k, err := registry.OpenKey(syscall.HKEY_LOCAL_MACHINE, servicesKeyName+`\`+s.Name)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	if len(env) == 0 {
+		err = k.DeleteValue("Environment")
+		if errors.Is(err, syscall.ERROR_FILE_NOT_FOUND) {
+			return nil
+		}
+		return err
+	}
+	return k.SetStrings("Environment", env)
+}
+
+// Environment returns environment variables set by SetEnvironment.
+func (s *Service) Environment() ([]string, error) {
+	k, err := registry.OpenKeyAccess(syscall.HKEY_LOCAL_MACHINE, servicesKeyName+`\`+s.Name, syscall.KEY_READ)
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+	env, err := k.GetStrings("Environment")
+	if errors.Is(err, syscall.ERROR_FILE_NOT_FOUND) {
+		return nil, nil
+	}
+	return env, err
+}
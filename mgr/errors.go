@@ -0,0 +1,38 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"syscall"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// Errors commonly returned by service control manager. Errors
+// returned by Mgr and Service methods wrap them, so use errors.Is
+// to test for them, like:
+//
+//	s, err := m.OpenService(name)
+//	if errors.Is(err, mgr.ErrServiceDoesNotExist) {
+//		...
+//	}
+var (
+	ErrAccessDenied           error = syscall.ERROR_ACCESS_DENIED
+	ErrServiceDoesNotExist    error = winapi.ERROR_SERVICE_DOES_NOT_EXIST
+	ErrServiceExists          error = winapi.ERROR_SERVICE_EXISTS
+	ErrDuplicateServiceName   error = winapi.ERROR_DUPLICATE_SERVICE_NAME
+	ErrServiceMarkedForDelete error = winapi.ERROR_SERVICE_MARKED_FOR_DELETE
+	ErrServiceAlreadyRunning  error = winapi.ERROR_SERVICE_ALREADY_RUNNING
+	ErrServiceNotActive       error = winapi.ERROR_SERVICE_NOT_ACTIVE
+	ErrServiceDisabled        error = winapi.ERROR_SERVICE_DISABLED
+	ErrCannotAcceptControl    error = winapi.ERROR_SERVICE_CANNOT_ACCEPT_CTRL
+	ErrInvalidControl         error = winapi.ERROR_INVALID_SERVICE_CONTROL
+	ErrRequestTimeout         error = winapi.ERROR_SERVICE_REQUEST_TIMEOUT
+	ErrDatabaseLocked         error = winapi.ERROR_SERVICE_DATABASE_LOCKED
+	ErrDependencyFailed       error = winapi.ERROR_SERVICE_DEPENDENCY_FAIL
+	ErrLogonFailed            error = winapi.ERROR_SERVICE_LOGON_FAILED
+)
@@ -12,14 +12,17 @@
 package mgr
 
 import (
+	"errors"
+	"fmt"
 	"github.com/multiplay/winsvc/winapi"
 	"syscall"
-	"unicode/utf16"
+	"unsafe"
 )
 
 // Mgr is used to manage Windows service.
 type Mgr struct {
 	Handle syscall.Handle
+	scm    SCM
 }
 
 // Connect establishes a connection to the service control manager.
@@ -30,20 +33,35 @@ func Connect() (*Mgr, error) {
 // ConnectRemote establishes a connection to the
 // service control manager on computer named host.
 func ConnectRemote(host string) (*Mgr, error) {
+	return ConnectAccess(host, ManagerAllAccess)
+}
+
+// ConnectAccess establishes a connection to the service control
+// manager on computer named host (local computer, if host is "")
+// requesting access rights only. Use ManagerReadOnly to query
+// services without administrative privileges.
+func ConnectAccess(host string, access ManagerAccess) (*Mgr, error) {
+	return ConnectWith(SystemSCM, host, access)
+}
+
+// ConnectWith is like ConnectAccess, but uses scm to talk to
+// the service control manager. All services opened or created
+// with returned Mgr use scm too.
+func ConnectWith(scm SCM, host string, access ManagerAccess) (*Mgr, error) {
 	var s *uint16
 	if host != "" {
 		s = syscall.StringToUTF16Ptr(host)
 	}
-	h, err := winapi.OpenSCManager(s, nil, winapi.SC_MANAGER_ALL_ACCESS)
+	h, err := scm.OpenSCManager(s, nil, uint32(access))
 	if err != nil {
 		return nil, err
 	}
-	return &Mgr{Handle: h}, nil
+	return &Mgr{Handle: h, scm: scm}, nil
 }
 
 // Disconnect closes connection m to servise control manager.
 func (m *Mgr) Disconnect() error {
-	return winapi.CloseServiceHandle(m.Handle)
+	return m.sys().CloseServiceHandle(m.Handle)
 }
 
 func toPtr(s string) *uint16 {
@@ -53,58 +71,163 @@ func toPtr(s string) *uint16 {
 	return syscall.StringToUTF16Ptr(s)
 }
 
-// toStringBlock terminates strings in ss with 0, and then
-// concatenates them together. It also adds extra 0 at the end.
-func toStringBlock(ss []string) *uint16 {
-	if len(ss) == 0 {
-		return nil
-	}
-	t := ""
-	for _, s := range ss {
-		if s != "" {
-			t += s + "\x00"
-		}
-	}
-	if t == "" {
-		return nil
-	}
-	t += "\x00"
-	return &utf16.Encode([]rune(t))[0]
-}
-
 // CreateService installs new service name on the system.
 // The service will be executed by running exepath binary,
 // while service settings are specified in config c.
 func (m *Mgr) CreateService(name, exepath string, c Config) (*Service, error) {
-	if c.StartType == 0 {
-		c.StartType = StartManual
+	c = withDefaults(c)
+	c.BinaryPathName = exepath // execpath is important, do not rely on BinaryPathName field to be set
+	deps, err := winapi.UTF16PtrFromStrings(c.Dependencies)
+	if err != nil {
+		return nil, err
 	}
-	if c.ErrorControl == 0 {
-		c.ErrorControl = ErrorNormal
+	var tag *uint32
+	if c.LoadOrderGroup != "" && c.ServiceType&winapi.SERVICE_DRIVER != 0 {
+		// tags are only assigned to drivers in a load order group
+		tag = &c.TagId
 	}
-	c.BinaryPathName = exepath // execpath is important, do not rely on BinaryPathName field to be set
-	h, err := winapi.CreateService(m.Handle, toPtr(name), toPtr(c.DisplayName),
-		winapi.SERVICE_ALL_ACCESS, winapi.SERVICE_WIN32_OWN_PROCESS,
-		c.StartType, c.ErrorControl, toPtr(exepath), toPtr(c.LoadOrderGroup),
-		nil, toStringBlock(c.Dependencies), toPtr(c.ServiceStartName), toPtr(c.Password))
+	h, err := m.sys().CreateService(m.Handle, toPtr(name), toPtr(c.DisplayName),
+		winapi.SERVICE_ALL_ACCESS, uint32(c.ServiceType),
+		uint32(c.StartType), uint32(c.ErrorControl), toPtr(exepath), toPtr(c.LoadOrderGroup),
+		tag, deps, toPtr(c.ServiceStartName), toPtr(c.Password))
 	if err != nil {
 		return nil, err
 	}
+	s := &Service{Name: name, Handle: h, scm: m.scm}
 	if c.Description != "" {
-		err = updateDescription(h, c.Description)
+		err = s.updateDescription(c.Description)
 		if err != nil {
-			return nil, err
+			return nil, s.abandon(err)
 		}
 	}
-	return &Service{Name: name, Handle: h}, nil
+	if c.DelayedAutoStart {
+		err = s.updateStartUp(c.DelayedAutoStart)
+		if err != nil {
+			return nil, s.abandon(err)
+		}
+	}
+	return s, nil
+}
+
+// abandon deletes and closes service s, that could not be fully
+// created because of err. It returns err, noting that s is left
+// installed, if it cannot be deleted.
+func (s *Service) abandon(err error) error {
+	err2 := s.Delete()
+	s.Close()
+	if err2 != nil {
+		return fmt.Errorf("%w; service %s is left installed, as it cannot be deleted: %v", err, s.Name, err2)
+	}
+	return err
 }
 
 // OpenService retrievs access to service name, so it can
 // be interrogated and controlled.
 func (m *Mgr) OpenService(name string) (*Service, error) {
-	h, err := winapi.OpenService(m.Handle, syscall.StringToUTF16Ptr(name), winapi.SERVICE_ALL_ACCESS)
+	return m.OpenServiceAccess(name, ServiceAllAccess)
+}
+
+// OpenServiceAccess is like OpenService, but requests
+// access rights only. Methods that require other rights
+// will fail with ERROR_ACCESS_DENIED.
+func (m *Mgr) OpenServiceAccess(name string, access ServiceAccess) (*Service, error) {
+	h, err := m.sys().OpenService(m.Handle, syscall.StringToUTF16Ptr(name), uint32(access))
 	if err != nil {
 		return nil, err
 	}
-	return &Service{Name: name, Handle: h}, nil
+	return &Service{Name: name, Handle: h, scm: m.scm}, nil
+}
+
+// DisplayName returns display name of service name.
+func (m *Mgr) DisplayName(name string) (string, error) {
+	return lookupName(m.sys().GetServiceDisplayName, m.Handle, name)
+}
+
+// KeyName returns name of service with display name displayName.
+func (m *Mgr) KeyName(displayName string) (string, error) {
+	return lookupName(m.sys().GetServiceKeyName, m.Handle, displayName)
+}
+
+// lookupName calls GetServiceDisplayName or GetServiceKeyName f,
+// growing its buffer as required, and returns name found.
+func lookupName(f func(syscall.Handle, *uint16, *uint16, *uint32) error, h syscall.Handle, name string) (string, error) {
+	p, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return "", err
+	}
+	b := make([]uint16, 256)
+	for {
+		n := uint32(len(b))
+		err = f(h, p, &b[0], &n)
+		if err == nil {
+			return syscall.UTF16ToString(b), nil
+		}
+		if !errors.Is(err, syscall.ERROR_INSUFFICIENT_BUFFER) || n < uint32(len(b)) {
+			return "", err
+		}
+		// n excludes terminating 0
+		b = make([]uint16, n+1)
+	}
+}
+
+// ListServices enumerates services in the specified
+// service control manager database m. If the caller does
+// not have the SERVICE_QUERY_STATUS access right to a service,
+// the service is silently omitted from the list returned.
+func (m *Mgr) ListServices() ([]string, error) {
+	var names []string
+	err := m.enum(winapi.SERVICE_WIN32, func(s *winapi.ENUM_SERVICE_STATUS_PROCESS) {
+		names = append(names, toString(s.ServiceName))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// enum calls f for every service of type typ in m. Entries
+// passed to f are only valid until f returns.
+func (m *Mgr) enum(typ uint32, f func(*winapi.ENUM_SERVICE_STATUS_PROCESS)) error {
+	var resume uint32
+	b := make([]byte, 16*1024)
+	for {
+		var needed, count uint32
+		err := m.sys().EnumServicesStatusEx(m.Handle, winapi.SC_ENUM_PROCESS_INFO,
+			typ, winapi.SERVICE_STATE_ALL,
+			&b[0], uint32(len(b)), &needed, &count, &resume, nil)
+		more := errors.Is(err, syscall.ERROR_MORE_DATA)
+		if err != nil && !more {
+			return err
+		}
+		services, err := enumServices(b, count)
+		if err != nil {
+			return err
+		}
+		// names point into b, f must copy them before b is reused
+		for i := range services {
+			f(&services[i])
+		}
+		if !more {
+			return nil
+		}
+		if count == 0 {
+			if needed <= uint32(len(b)) {
+				return errors.New("EnumServicesStatusEx made no progress")
+			}
+			b = make([]byte, needed)
+		}
+	}
+}
+
+// enumServices returns first count entries of
+// ENUM_SERVICE_STATUS_PROCESS array stored in b.
+func enumServices(b []byte, count uint32) ([]winapi.ENUM_SERVICE_STATUS_PROCESS, error) {
+	if count == 0 {
+		return nil, nil
+	}
+	size := unsafe.Sizeof(winapi.ENUM_SERVICE_STATUS_PROCESS{})
+	if uintptr(count) > uintptr(len(b))/size {
+		return nil, errors.New("EnumServicesStatusEx returned more services than fit its buffer")
+	}
+	return unsafe.Slice((*winapi.ENUM_SERVICE_STATUS_PROCESS)(unsafe.Pointer(&b[0])), count), nil
 }
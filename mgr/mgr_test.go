@@ -7,12 +7,17 @@
 package mgr_test
 
 import (
+	"context"
+	"errors"
 	"github.com/multiplay/winsvc/mgr"
+	"github.com/multiplay/winsvc/registry"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestOpenLanManServer(t *testing.T) {
@@ -32,6 +37,96 @@ func TestOpenLanManServer(t *testing.T) {
 	defer s.Close()
 }
 
+func TestServiceDoesNotExist(t *testing.T) {
+	m, err := mgr.Connect()
+	if err != nil {
+		t.Fatalf("SCM connection failed: %s", err)
+	}
+	defer m.Disconnect()
+	_, err = m.OpenService("winsvc-no-such-service")
+	if !errors.Is(err, mgr.ErrServiceDoesNotExist) {
+		t.Fatalf("OpenService returned %v, want %v", err, mgr.ErrServiceDoesNotExist)
+	}
+}
+
+func TestReadOnlyAccess(t *testing.T) {
+	m, err := mgr.ConnectAccess("", mgr.ManagerReadOnly)
+	if err != nil {
+		t.Fatalf("SCM connection failed: %s", err)
+	}
+	defer m.Disconnect()
+	s, err := m.OpenServiceAccess("LanmanServer", mgr.ServiceReadOnly)
+	if err != nil {
+		t.Fatalf("OpenServiceAccess(lanmanserver) failed: %s", err)
+	}
+	defer s.Close()
+	_, err = s.Query()
+	if err != nil {
+		t.Fatalf("Query failed: %s", err)
+	}
+	_, err = s.Config()
+	if err != nil {
+		t.Fatalf("Config failed: %s", err)
+	}
+}
+
+func TestListServices(t *testing.T) {
+	m, err := mgr.Connect()
+	if err != nil {
+		t.Fatalf("SCM connection failed: %s", err)
+	}
+	defer m.Disconnect()
+	names, err := m.ListServices()
+	if err != nil {
+		t.Fatalf("ListServices failed: %s", err)
+	}
+	for _, name := range names {
+		if strings.EqualFold(name, "LanmanServer") {
+			return
+		}
+	}
+	t.Errorf("LanmanServer is not listed in %d services", len(names))
+}
+
+func TestServiceNames(t *testing.T) {
+	m, err := mgr.Connect()
+	if err != nil {
+		t.Fatalf("SCM connection failed: %s", err)
+	}
+	defer m.Disconnect()
+	dn, err := m.DisplayName("LanmanServer")
+	if err != nil {
+		t.Fatalf("DisplayName(lanmanserver) failed: %s", err)
+	}
+	name, err := m.KeyName(dn)
+	if err != nil {
+		t.Fatalf("KeyName(%q) failed: %s", dn, err)
+	}
+	if !strings.EqualFold(name, "LanmanServer") {
+		t.Errorf("KeyName(%q) returned %q, want LanmanServer", dn, name)
+	}
+}
+
+func TestServiceSecurity(t *testing.T) {
+	m, err := mgr.Connect()
+	if err != nil {
+		t.Fatalf("SCM connection failed: %s", err)
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService("LanmanServer")
+	if err != nil {
+		t.Fatalf("OpenService(lanmanserver) failed: %s", err)
+	}
+	defer s.Close()
+	sddl, err := s.Security()
+	if err != nil {
+		t.Fatalf("Security failed: %s", err)
+	}
+	if !strings.HasPrefix(sddl, "O:") || !strings.Contains(sddl, "D:") {
+		t.Errorf("unexpected security descriptor %q", sddl)
+	}
+}
+
 func install(t *testing.T, m *mgr.Mgr, name, exepath string, c mgr.Config) {
 	s, err := m.OpenService(name)
 	if err == nil {
@@ -128,3 +223,77 @@ func TestMyService(t *testing.T) {
 
 	remove(t, s)
 }
+
+func TestServiceSID(t *testing.T) {
+	const want = "S-1-5-80-956008885-3418522649-1831038044-1853292631-2271478464"
+	if sid := mgr.ServiceSID("TrustedInstaller"); sid != want {
+		t.Fatalf("ServiceSID(TrustedInstaller) = %s, want %s", sid, want)
+	}
+}
+
+func TestConfigAddArgs(t *testing.T) {
+	c := mgr.Config{BinaryPathName: `"C:\Program Files\svc.exe" run`}
+	c.AddArgs("-feature", "new cache")
+	want := `"C:\Program Files\svc.exe" run -feature "new cache"`
+	if c.BinaryPathName != want {
+		t.Errorf("command line is %s, want %s", c.BinaryPathName, want)
+	}
+}
+
+func TestResolveString(t *testing.T) {
+	s, err := mgr.ResolveString("plain")
+	if err != nil || s != "plain" {
+		t.Fatalf(`ResolveString("plain") = %q, %v`, s, err)
+	}
+	k, err := registry.OpenKeyAccess(syscall.HKEY_LOCAL_MACHINE,
+		`SYSTEM\CurrentControlSet\Services\LanmanServer`, syscall.KEY_READ)
+	if err != nil {
+		t.Fatalf("failed to open LanmanServer key: %v", err)
+	}
+	defer k.Close()
+	raw, err := k.GetString("DisplayName")
+	if err != nil {
+		t.Fatalf("failed to read LanmanServer display name: %v", err)
+	}
+	if !strings.HasPrefix(raw, "@") {
+		t.Skipf("LanmanServer display name %q is not indirect", raw)
+	}
+	s, err = mgr.ResolveString(raw)
+	if err != nil {
+		t.Fatalf("ResolveString(%q) failed: %v", raw, err)
+	}
+	if s == "" || strings.HasPrefix(s, "@") {
+		t.Errorf("ResolveString(%q) = %q", raw, s)
+	}
+	if want := "@dll,-100"; mgr.IndirectString("dll", 100) != want {
+		t.Errorf("IndirectString returned %q, want %q", mgr.IndirectString("dll", 100), want)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	m, err := mgr.ConnectAccess("", mgr.ManagerReadOnly)
+	if err != nil {
+		t.Fatalf("SCM connection failed: %s", err)
+	}
+	defer m.Disconnect()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	events, err := m.Watch(ctx, "LanmanServer")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	e, ok := <-events
+	if !ok {
+		t.Fatal("no current status sent")
+	}
+	if e.Name != "LanmanServer" || e.Err != nil || e.Status.State == 0 {
+		t.Errorf("first event is %+v", e)
+	}
+	cancel()
+	for range events {
+	}
+	_, err = m.Watch(ctx, "LanmanServer", "NoSuchService")
+	if err == nil {
+		t.Error("Watch of missing service succeeded")
+	}
+}
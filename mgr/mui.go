@@ -0,0 +1,56 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// IndirectString returns reference to string resource id of dll,
+// like "@%SystemRoot%\system32\srvsvc.dll,-100". Use it as
+// Config.DisplayName or Config.Description, so services.msc shows
+// them in language of the user, as found in dll MUI resources.
+func IndirectString(dll string, id uint32) string {
+	return "@" + dll + ",-" + strconv.FormatUint(uint64(id), 10)
+}
+
+// ResolveString returns string s refers to, if s is indirect string
+// (see IndirectString), and s itself otherwise.
+func ResolveString(s string) (string, error) {
+	if !strings.HasPrefix(s, "@") {
+		return s, nil
+	}
+	p, err := syscall.UTF16PtrFromString(s)
+	if err != nil {
+		return "", err
+	}
+	b := make([]uint16, 1024)
+	err = winapi.SHLoadIndirectString(p, &b[0], uint32(len(b)), 0)
+	if err != nil {
+		return "", err
+	}
+	return syscall.UTF16ToString(b), nil
+}
+
+// Localized returns c with DisplayName and
+// Description indirect strings resolved.
+func (c Config) Localized() (Config, error) {
+	var err error
+	c.DisplayName, err = ResolveString(c.DisplayName)
+	if err != nil {
+		return c, err
+	}
+	c.Description, err = ResolveString(c.Description)
+	if err != nil {
+		return c, err
+	}
+	return c, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/multiplay/winsvc/svc"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// SetPassword changes password of the account service s runs under.
+// It leaves all other service configuration parameters as they are.
+func (s *Service) SetPassword(password string) error {
+	return s.sys().ChangeServiceConfig(s.Handle, winapi.SERVICE_NO_CHANGE,
+		winapi.SERVICE_NO_CHANGE, winapi.SERVICE_NO_CHANGE, nil, nil,
+		nil, nil, nil, syscall.StringToUTF16Ptr(password), nil)
+}
+
+// RotatePassword changes the password stored for account in every
+// service listed in names to newPassword. All services must run under
+// account, otherwise nothing is changed. Running services are restarted
+// and stopped services, unless disabled, are started and stopped again,
+// to verify they can log on with the new password. If any of that fails
+// within timeout, every service gets oldPassword back, and services that
+// were running are started again.
+func (m *Mgr) RotatePassword(names []string, account, oldPassword, newPassword string, timeout time.Duration) error {
+	type entry struct {
+		s       *Service
+		c       Config
+		running bool
+	}
+	var es []entry
+	defer func() {
+		for _, e := range es {
+			e.s.Close()
+		}
+	}()
+	for _, name := range names {
+		s, err := m.OpenService(name)
+		if err != nil {
+			return err
+		}
+		es = append(es, entry{s: s})
+		e := &es[len(es)-1]
+		e.c, err = s.Config()
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(e.c.ServiceStartName, account) {
+			return fmt.Errorf("service %s runs as %s, not %s", name, e.c.ServiceStartName, account)
+		}
+		status, err := s.Query()
+		if err != nil {
+			return err
+		}
+		e.running = status.State != svc.Stopped
+	}
+
+	rollback := func(n int, cause error) error {
+		for _, e := range es[:n] {
+			e.s.SetPassword(oldPassword)
+			if e.running {
+				restart(e.s, timeout)
+			}
+		}
+		return cause
+	}
+	for i, e := range es {
+		err := e.s.SetPassword(newPassword)
+		if err != nil {
+			return rollback(i, err)
+		}
+	}
+	for _, e := range es {
+		var err error
+		switch {
+		case e.running:
+			err = restart(e.s, timeout)
+		case e.c.StartType != StartDisabled:
+			err = restart(e.s, timeout)
+			if err == nil {
+				err = e.s.StopAndWait(timeout)
+			}
+		}
+		if err != nil {
+			return rollback(len(es), fmt.Errorf("service %s failed with new password: %v", e.s.Name, err))
+		}
+	}
+	return nil
+}
+
+// restart stops service s, if running, and starts it again.
+func restart(s *Service, timeout time.Duration) error {
+	err := s.StopAndWait(timeout)
+	if err != nil {
+		return err
+	}
+	err = s.Start(nil)
+	if err != nil {
+		return err
+	}
+	_, err = s.WaitState(svc.Running, timeout)
+	return err
+}
@@ -0,0 +1,60 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// enablePrivilege enables privilege name in token of the current
+// process, and returns function that restores its previous state.
+func enablePrivilege(name string) (restore func(), err error) {
+	h, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return nil, err
+	}
+	// OpenCurrentProcessToken opens token for query only
+	var t syscall.Token
+	err = syscall.OpenProcessToken(h, winapi.TOKEN_ADJUST_PRIVILEGES|winapi.TOKEN_QUERY, &t)
+	if err != nil {
+		return nil, err
+	}
+	tp := winapi.TOKEN_PRIVILEGES{PrivilegeCount: 1}
+	err = winapi.LookupPrivilegeValue(nil, syscall.StringToUTF16Ptr(name), &tp.Privileges[0].Luid)
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+	tp.Privileges[0].Attributes = winapi.SE_PRIVILEGE_ENABLED
+	var prev winapi.TOKEN_PRIVILEGES
+	var n uint32
+	err = winapi.AdjustTokenPrivileges(t, false, &tp, uint32(unsafe.Sizeof(prev)), &prev, &n)
+	if err != nil {
+		t.Close()
+		return nil, fmt.Errorf("cannot enable %s, account does not hold it: %w", name, err)
+	}
+	return func() {
+		if prev.PrivilegeCount > 0 {
+			winapi.AdjustTokenPrivileges(t, false, &prev, 0, nil, nil)
+		}
+		t.Close()
+	}, nil
+}
+
+// hasReboot reports whether actions include ComputerReboot.
+func hasReboot(actions []RecoveryAction) bool {
+	for _, a := range actions {
+		if a.Type == ComputerReboot {
+			return true
+		}
+	}
+	return false
+}
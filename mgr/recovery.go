@@ -0,0 +1,187 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"errors"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+const (
+	// Possible recovery actions that the service control manager can perform.
+	NoAction       = winapi.SC_ACTION_NONE        // no action
+	ComputerReboot = winapi.SC_ACTION_REBOOT      // reboot the computer
+	ServiceRestart = winapi.SC_ACTION_RESTART     // restart the service
+	RunCommand     = winapi.SC_ACTION_RUN_COMMAND // run a command
+)
+
+// RecoveryAction represents an action that the service control manager can perform when service fails.
+// A service is considered failed when it terminates without reporting a status of Stopped to the service controller.
+type RecoveryAction struct {
+	Type  int           // one of NoAction, ComputerReboot, ServiceRestart or RunCommand
+	Delay time.Duration // the time to wait before performing the specified action
+}
+
+// SetRecoveryActions sets actions that service controller performs when service fails and
+// the time after which to reset the service failure count to zero if there are no failures, in seconds.
+// Specify INFINITE to indicate that service failure count should never be reset.
+// ComputerReboot action requires SeShutdownPrivilege, which is enabled for the call.
+// Administrators hold it, but it is disabled by default.
+func (s *Service) SetRecoveryActions(recoveryActions []RecoveryAction, resetPeriod uint32) error {
+	if recoveryActions == nil {
+		return errors.New("recoveryActions cannot be nil")
+	}
+	if hasReboot(recoveryActions) && !isDryRun(s.sys()) {
+		restore, err := enablePrivilege(winapi.SE_SHUTDOWN_NAME)
+		if err != nil {
+			return err
+		}
+		defer restore()
+	}
+	actions := toSCActions(recoveryActions)
+	rActions := winapi.SERVICE_FAILURE_ACTIONS{
+		ActionsCount: uint32(len(actions)),
+		ResetPeriod:  resetPeriod,
+	}
+	if len(actions) > 0 {
+		rActions.Actions = &actions[0]
+	}
+	return s.sys().ChangeServiceConfig2(s.Handle, winapi.SERVICE_CONFIG_FAILURE_ACTIONS, (*byte)(unsafe.Pointer(&rActions)))
+}
+
+// failureActions retrieves failure actions configuration of service s.
+func (s *Service) failureActions() (*winapi.SERVICE_FAILURE_ACTIONS, error) {
+	b, err := s.queryServiceConfig2(winapi.SERVICE_CONFIG_FAILURE_ACTIONS)
+	if err != nil {
+		return nil, err
+	}
+	return (*winapi.SERVICE_FAILURE_ACTIONS)(unsafe.Pointer(&b[0])), nil
+}
+
+// RecoveryActions returns actions that service controller performs when service fails.
+// The service control manager counts the number of times service s has failed since the system booted.
+// The count is reset to 0 if the service has not failed for ResetPeriod seconds.
+// When the service fails for the Nth time, the service controller performs the action specified in element [N-1] of returned slice.
+// If N is greater than slice length, the service controller repeats the last action in the slice.
+func (s *Service) RecoveryActions() ([]RecoveryAction, error) {
+	p, err := s.failureActions()
+	if err != nil {
+		return nil, err
+	}
+	if p.Actions == nil {
+		return nil, nil
+	}
+	return fromSCActions(unsafe.Slice(p.Actions, p.ActionsCount)), nil
+}
+
+// toSCActions converts recovery actions into SC_ACTION array.
+func toSCActions(recoveryActions []RecoveryAction) []winapi.SC_ACTION {
+	actions := []winapi.SC_ACTION{}
+	for _, a := range recoveryActions {
+		action := winapi.SC_ACTION{
+			Type:  uint32(a.Type),
+			Delay: uint32(a.Delay.Nanoseconds() / 1000000),
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+// fromSCActions converts SC_ACTION array into recovery actions.
+func fromSCActions(actions []winapi.SC_ACTION) []RecoveryAction {
+	var recoveryActions []RecoveryAction
+	for _, action := range actions {
+		recoveryActions = append(recoveryActions, RecoveryAction{Type: int(action.Type), Delay: time.Duration(action.Delay) * time.Millisecond})
+	}
+	return recoveryActions
+}
+
+// ResetRecoveryActions deletes both reset period and array of failure actions.
+func (s *Service) ResetRecoveryActions() error {
+	actions := make([]winapi.SC_ACTION, 1)
+	rActions := winapi.SERVICE_FAILURE_ACTIONS{
+		Actions: &actions[0],
+	}
+	return s.sys().ChangeServiceConfig2(s.Handle, winapi.SERVICE_CONFIG_FAILURE_ACTIONS, (*byte)(unsafe.Pointer(&rActions)))
+}
+
+// ResetPeriod is the time after which to reset the service failure
+// count to zero if there are no failures, in seconds.
+func (s *Service) ResetPeriod() (uint32, error) {
+	p, err := s.failureActions()
+	if err != nil {
+		return 0, err
+	}
+	return p.ResetPeriod, nil
+}
+
+// SetRebootMessage sets service s reboot message.
+// If msg is "", the reboot message is deleted and no message is broadcast.
+func (s *Service) SetRebootMessage(msg string) error {
+	rActions := winapi.SERVICE_FAILURE_ACTIONS{
+		RebootMsg: syscall.StringToUTF16Ptr(msg),
+	}
+	return s.sys().ChangeServiceConfig2(s.Handle, winapi.SERVICE_CONFIG_FAILURE_ACTIONS, (*byte)(unsafe.Pointer(&rActions)))
+}
+
+// RebootMessage is broadcast to server users before rebooting in response to the ComputerReboot service controller action.
+func (s *Service) RebootMessage() (string, error) {
+	p, err := s.failureActions()
+	if err != nil {
+		return "", err
+	}
+	return toString(p.RebootMsg), nil
+}
+
+// SetRecoveryCommand sets the command line of the process to execute in response to the RunCommand service controller action.
+// If cmd is "", the command is deleted and no program is run when the service fails.
+func (s *Service) SetRecoveryCommand(cmd string) error {
+	rActions := winapi.SERVICE_FAILURE_ACTIONS{
+		Command: syscall.StringToUTF16Ptr(cmd),
+	}
+	return s.sys().ChangeServiceConfig2(s.Handle, winapi.SERVICE_CONFIG_FAILURE_ACTIONS, (*byte)(unsafe.Pointer(&rActions)))
+}
+
+// RecoveryCommand is the command line of the process to execute in response to the RunCommand service controller action. This process runs under the same account as the service.
+func (s *Service) RecoveryCommand() (string, error) {
+	p, err := s.failureActions()
+	if err != nil {
+		return "", err
+	}
+	return toString(p.Command), nil
+}
+
+// SetRecoveryActionsOnNonCrashFailures sets the failure actions flag. If the
+// flag is set to false, recovery actions will only be performed if the service
+// terminates without reporting a status of Stopped. If the flag is set to true,
+// recovery actions are also performed if the service stops with a nonzero exit
+// code.
+func (s *Service) SetRecoveryActionsOnNonCrashFailures(flag bool) error {
+	var setting winapi.SERVICE_FAILURE_ACTIONS_FLAG
+	if flag {
+		setting.FailureActionsOnNonCrashFailures = 1
+	}
+	return s.sys().ChangeServiceConfig2(s.Handle, winapi.SERVICE_CONFIG_FAILURE_ACTIONS_FLAG, (*byte)(unsafe.Pointer(&setting)))
+}
+
+// RecoveryActionsOnNonCrashFailures returns the current value of the failure
+// actions flag. If the flag is set to false, recovery actions will only be
+// performed if the service terminates without reporting a status of Stopped.
+// If the flag is set to true, recovery actions are also performed if the
+// service stops with a nonzero exit code.
+func (s *Service) RecoveryActionsOnNonCrashFailures() (bool, error) {
+	b, err := s.queryServiceConfig2(winapi.SERVICE_CONFIG_FAILURE_ACTIONS_FLAG)
+	if err != nil {
+		return false, err
+	}
+	p := (*winapi.SERVICE_FAILURE_ACTIONS_FLAG)(unsafe.Pointer(&b[0]))
+	return p.FailureActionsOnNonCrashFailures != 0, nil
+}
@@ -0,0 +1,259 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"time"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// ErrorClass tells what retrying call that failed with error could achieve.
+type ErrorClass int
+
+const (
+	Permanent ErrorClass = iota // retry fails the same way
+	Network                     // remote computer cannot be reached, or RPC failed
+	Busy                        // service control manager or service is busy
+)
+
+// Classify returns class of error err returned by the service control manager.
+func Classify(err error) ErrorClass {
+	switch {
+	case errors.Is(err, winapi.RPC_S_SERVER_UNAVAILABLE),
+		errors.Is(err, winapi.RPC_S_CALL_FAILED):
+		return Network
+	case errors.Is(err, winapi.RPC_S_SERVER_TOO_BUSY),
+		errors.Is(err, winapi.ERROR_SERVICE_DATABASE_LOCKED),
+		errors.Is(err, winapi.ERROR_SERVICE_CANNOT_ACCEPT_CTRL):
+		return Busy
+	}
+	return Permanent
+}
+
+// RetryPolicy describes how calls to the service control manager,
+// that fail with transient errors, are retried.
+type RetryPolicy struct {
+	// Attempts is the maximum number of calls made, 3 if 0.
+	Attempts int
+
+	// Backoff is time to wait before the second call, 100ms if 0.
+	// It doubles after every call, up to MaxBackoff, if not 0.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// Retry lists error classes retried, Network and Busy if empty.
+	// Classify, if not nil, replaces default Classify.
+	Retry    []ErrorClass
+	Classify func(error) ErrorClass
+}
+
+func (p *RetryPolicy) retries(err error) bool {
+	classify := p.Classify
+	if classify == nil {
+		classify = Classify
+	}
+	c := classify(err)
+	if len(p.Retry) == 0 {
+		return c == Network || c == Busy
+	}
+	for _, r := range p.Retry {
+		if r == c {
+			return true
+		}
+	}
+	return false
+}
+
+// do calls f, until it succeeds, fails with error p does not
+// retry, or p runs out of attempts, and returns its last error.
+// It returns ctx.Err(), if ctx is done, while it waits to retry.
+func (p *RetryPolicy) do(ctx context.Context, f func() error) error {
+	attempts := p.Attempts
+	if attempts <= 0 {
+		attempts = 3
+	}
+	backoff := p.Backoff
+	if backoff <= 0 {
+		backoff = 100 * time.Millisecond
+	}
+	for i := 1; ; i++ {
+		err := f()
+		if err == nil || i >= attempts || !p.retries(err) {
+			return err
+		}
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+// RetrySCM returns SCM, that calls scm, retrying calls that fail with
+// transient errors, as p says. Use it with ConnectWith. Retried call
+// might have succeeded, before its RPC failed, so retry of StartService
+// or CreateService can fail with ErrServiceAlreadyRunning or
+// ErrServiceExists. Use Mgr.WithContext to limit total time of retries;
+// waits between retries end, once its context is done.
+func RetrySCM(scm SCM, p RetryPolicy) SCM {
+	ctx := context.Background()
+	if c, ok := scm.(contextSCM); ok {
+		ctx = c.ctx
+	}
+	return retrySCM{p, ctx, scm}
+}
+
+// WithRetry returns copy of m, that retries calls, like RetrySCM.
+// Services opened or created with the copy retry calls too. The copy
+// shares handle with m, so only one of them should be disconnected.
+func (m *Mgr) WithRetry(p RetryPolicy) *Mgr {
+	return &Mgr{Handle: m.Handle, scm: RetrySCM(m.sys(), p)}
+}
+
+// WithRetry returns copy of s, that retries calls, like RetrySCM.
+// The copy shares handle with s, so only one of them should be closed.
+func (s *Service) WithRetry(p RetryPolicy) *Service {
+	return &Service{Name: s.Name, Handle: s.Handle, scm: RetrySCM(s.sys(), p)}
+}
+
+type retrySCM struct {
+	p   RetryPolicy
+	ctx context.Context // ends waits between retries
+	SCM
+}
+
+func (r retrySCM) unwrap() SCM { return r.SCM }
+
+func (r retrySCM) do(f func() error) error {
+	return r.p.do(r.ctx, f)
+}
+
+func (r retrySCM) open(f func() (syscall.Handle, error)) (h syscall.Handle, err error) {
+	err = r.do(func() error {
+		h, err = f()
+		return err
+	})
+	return h, err
+}
+
+func (r retrySCM) OpenSCManager(machineName *uint16, databaseName *uint16, access uint32) (syscall.Handle, error) {
+	return r.open(func() (syscall.Handle, error) {
+		return r.SCM.OpenSCManager(machineName, databaseName, access)
+	})
+}
+
+func (r retrySCM) CreateService(mgr syscall.Handle, serviceName *uint16, displayName *uint16, access uint32, srvType uint32, startType uint32, errCtl uint32, pathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16) (syscall.Handle, error) {
+	return r.open(func() (syscall.Handle, error) {
+		return r.SCM.CreateService(mgr, serviceName, displayName, access, srvType, startType, errCtl, pathName, loadOrderGroup, tagId, dependencies, serviceStartName, password)
+	})
+}
+
+func (r retrySCM) OpenService(mgr syscall.Handle, serviceName *uint16, access uint32) (syscall.Handle, error) {
+	return r.open(func() (syscall.Handle, error) {
+		return r.SCM.OpenService(mgr, serviceName, access)
+	})
+}
+
+func (r retrySCM) DeleteService(service syscall.Handle) error {
+	return r.do(func() error {
+		return r.SCM.DeleteService(service)
+	})
+}
+
+func (r retrySCM) StartService(service syscall.Handle, numArgs uint32, argVectors **uint16) error {
+	return r.do(func() error {
+		return r.SCM.StartService(service, numArgs, argVectors)
+	})
+}
+
+func (r retrySCM) ControlService(service syscall.Handle, control uint32, status *winapi.SERVICE_STATUS) error {
+	return r.do(func() error {
+		return r.SCM.ControlService(service, control, status)
+	})
+}
+
+func (r retrySCM) ControlServiceEx(service syscall.Handle, control uint32, infoLevel uint32, params *byte) error {
+	return r.do(func() error {
+		return r.SCM.ControlServiceEx(service, control, infoLevel, params)
+	})
+}
+
+func (r retrySCM) QueryServiceStatusEx(service syscall.Handle, infoLevel uint32, buff *byte, buffSize uint32, bytesNeeded *uint32) error {
+	return r.do(func() error {
+		return r.SCM.QueryServiceStatusEx(service, infoLevel, buff, buffSize, bytesNeeded)
+	})
+}
+
+func (r retrySCM) QueryServiceConfig(service syscall.Handle, serviceConfig *winapi.QUERY_SERVICE_CONFIG, bufSize uint32, bytesNeeded *uint32) error {
+	return r.do(func() error {
+		return r.SCM.QueryServiceConfig(service, serviceConfig, bufSize, bytesNeeded)
+	})
+}
+
+func (r retrySCM) ChangeServiceConfig(service syscall.Handle, serviceType uint32, startType uint32, errorControl uint32, binaryPathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16, displayName *uint16) error {
+	return r.do(func() error {
+		return r.SCM.ChangeServiceConfig(service, serviceType, startType, errorControl, binaryPathName, loadOrderGroup, tagId, dependencies, serviceStartName, password, displayName)
+	})
+}
+
+func (r retrySCM) QueryServiceConfig2(service syscall.Handle, infoLevel uint32, buff *byte, buffSize uint32, bytesNeeded *uint32) error {
+	return r.do(func() error {
+		return r.SCM.QueryServiceConfig2(service, infoLevel, buff, buffSize, bytesNeeded)
+	})
+}
+
+func (r retrySCM) ChangeServiceConfig2(service syscall.Handle, infoLevel uint32, info *byte) error {
+	return r.do(func() error {
+		return r.SCM.ChangeServiceConfig2(service, infoLevel, info)
+	})
+}
+
+func (r retrySCM) EnumDependentServices(service syscall.Handle, serviceState uint32, services *winapi.ENUM_SERVICE_STATUS, bufSize uint32, bytesNeeded *uint32, servicesReturned *uint32) error {
+	return r.do(func() error {
+		return r.SCM.EnumDependentServices(service, serviceState, services, bufSize, bytesNeeded, servicesReturned)
+	})
+}
+
+func (r retrySCM) EnumServicesStatusEx(mgr syscall.Handle, infoLevel uint32, serviceType uint32, serviceState uint32, services *byte, bufSize uint32, bytesNeeded *uint32, servicesReturned *uint32, resumeHandle *uint32, groupName *uint16) error {
+	return r.do(func() error {
+		return r.SCM.EnumServicesStatusEx(mgr, infoLevel, serviceType, serviceState, services, bufSize, bytesNeeded, servicesReturned, resumeHandle, groupName)
+	})
+}
+
+func (r retrySCM) QueryServiceObjectSecurity(service syscall.Handle, securityInformation uint32, sd *byte, bufSize uint32, bytesNeeded *uint32) error {
+	return r.do(func() error {
+		return r.SCM.QueryServiceObjectSecurity(service, securityInformation, sd, bufSize, bytesNeeded)
+	})
+}
+
+func (r retrySCM) SetServiceObjectSecurity(service syscall.Handle, securityInformation uint32, sd *byte) error {
+	return r.do(func() error {
+		return r.SCM.SetServiceObjectSecurity(service, securityInformation, sd)
+	})
+}
+
+func (r retrySCM) GetServiceDisplayName(mgr syscall.Handle, serviceName *uint16, displayName *uint16, bufSize *uint32) error {
+	return r.do(func() error {
+		return r.SCM.GetServiceDisplayName(mgr, serviceName, displayName, bufSize)
+	})
+}
+
+func (r retrySCM) GetServiceKeyName(mgr syscall.Handle, displayName *uint16, serviceName *uint16, bufSize *uint32) error {
+	return r.do(func() error {
+		return r.SCM.GetServiceKeyName(mgr, displayName, serviceName, bufSize)
+	})
+}
@@ -0,0 +1,99 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+const defaultSecurityInformation = winapi.OWNER_SECURITY_INFORMATION |
+	winapi.GROUP_SECURITY_INFORMATION | winapi.DACL_SECURITY_INFORMATION
+
+// Security returns security descriptor of service s in SDDL form.
+// It includes owner, group and DACL of the service.
+func (s *Service) Security() (string, error) {
+	var n uint32
+	err := s.sys().QueryServiceObjectSecurity(s.Handle, defaultSecurityInformation, nil, 0, &n)
+	if !errors.Is(err, syscall.ERROR_INSUFFICIENT_BUFFER) {
+		return "", err
+	}
+	b := make([]byte, n)
+	err = s.sys().QueryServiceObjectSecurity(s.Handle, defaultSecurityInformation, &b[0], n, &n)
+	if err != nil {
+		return "", err
+	}
+	var p *uint16
+	err = winapi.ConvertSecurityDescriptorToStringSecurityDescriptor(&b[0],
+		winapi.SDDL_REVISION_1, defaultSecurityInformation, &p, nil)
+	if err != nil {
+		return "", err
+	}
+	defer syscall.LocalFree(syscall.Handle(unsafe.Pointer(p)))
+	return toString(p), nil
+}
+
+// SetSecurity replaces security descriptor of service s
+// with sddl. Only parts present in sddl (owner, group or
+// DACL) are changed.
+func (s *Service) SetSecurity(sddl string) error {
+	str, err := syscall.UTF16PtrFromString(sddl)
+	if err != nil {
+		return err
+	}
+	var sd *byte
+	err = winapi.ConvertStringSecurityDescriptorToSecurityDescriptor(str,
+		winapi.SDDL_REVISION_1, &sd, nil)
+	if err != nil {
+		return err
+	}
+	defer syscall.LocalFree(syscall.Handle(unsafe.Pointer(sd)))
+	info, err := securityInformation(sd)
+	if err != nil {
+		return err
+	}
+	if info == 0 {
+		return errors.New("no owner, group or DACL in security descriptor " + sddl)
+	}
+	return s.sys().SetServiceObjectSecurity(s.Handle, info, sd)
+}
+
+// securityInformation returns which of owner, group and
+// DACL are present in security descriptor sd.
+func securityInformation(sd *byte) (uint32, error) {
+	var info uint32
+	var sid *syscall.SID
+	var defaulted int32
+	err := winapi.GetSecurityDescriptorOwner(sd, &sid, &defaulted)
+	if err != nil {
+		return 0, err
+	}
+	if sid != nil {
+		info |= winapi.OWNER_SECURITY_INFORMATION
+	}
+	sid = nil
+	err = winapi.GetSecurityDescriptorGroup(sd, &sid, &defaulted)
+	if err != nil {
+		return 0, err
+	}
+	if sid != nil {
+		info |= winapi.GROUP_SECURITY_INFORMATION
+	}
+	var present int32
+	var dacl uintptr
+	err = winapi.GetSecurityDescriptorDacl(sd, &present, &dacl, &defaulted)
+	if err != nil {
+		return 0, err
+	}
+	if present != 0 {
+		info |= winapi.DACL_SECURITY_INFORMATION
+	}
+	return info, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"syscall"
+	"time"
+)
+
+// AddArgs appends arguments args, escaped as required,
+// to command line c.BinaryPathName.
+func (c *Config) AddArgs(args ...string) {
+	for _, a := range args {
+		c.BinaryPathName += " " + syscall.EscapeArg(a)
+	}
+}
+
+// Reconfigure reads configuration of service s, passes it to update
+// to change, and saves it. Nothing is saved, if update fails. Account
+// service runs under is only saved, if update changes it.
+func (s *Service) Reconfigure(update func(c *Config) error) error {
+	c, err := s.Config()
+	if err != nil {
+		return err
+	}
+	account := c.ServiceStartName
+	err = update(&c)
+	if err != nil {
+		return err
+	}
+	if c.ServiceStartName == account && c.Password == "" {
+		c.ServiceStartName = ""
+	}
+	return s.UpdateConfig(c)
+}
+
+// ScheduleRestart makes the service control manager start service s
+// again, delay after it stops with non zero exit code. It replaces
+// recovery actions of s with single ServiceRestart action, and enables
+// recovery actions on non crash failures. Reset period is kept.
+func (s *Service) ScheduleRestart(delay time.Duration) error {
+	reset, err := s.ResetPeriod()
+	if err != nil {
+		return err
+	}
+	err = s.SetRecoveryActions([]RecoveryAction{{Type: ServiceRestart, Delay: delay}}, reset)
+	if err != nil {
+		return err
+	}
+	return s.SetRecoveryActionsOnNonCrashFailures(true)
+}
+
+// UpdateSelf is called by running service name to change its own
+// configuration, for settings that only take effect when service
+// starts, like arguments or delayed auto start. It applies update
+// to service configuration and schedules restart (see ScheduleRestart).
+// Service then stops itself with non zero exit code, and is started
+// again, delay later, with new configuration.
+func UpdateSelf(name string, update func(c *Config) error, delay time.Duration) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	err = s.Reconfigure(update)
+	if err != nil {
+		return err
+	}
+	return s.ScheduleRestart(delay)
+}
@@ -7,30 +7,30 @@
 package mgr
 
 import (
+	"context"
+	"errors"
 	"github.com/multiplay/winsvc/svc"
 	"github.com/multiplay/winsvc/winapi"
 	"syscall"
+	"time"
+	"unsafe"
 )
 
-// TODO(brainman): use EnumDependentServices to enumerate dependent services
-
-// TODO(brainman): use EnumServicesStatus to enumerates services
-//                 in the specified service control manager database
-
 // Service is used to access Windows service.
 type Service struct {
 	Name   string
 	Handle syscall.Handle
+	scm    SCM
 }
 
 // Delete marks service s for deletion from the service control manager database.
 func (s *Service) Delete() error {
-	return winapi.DeleteService(s.Handle)
+	return s.sys().DeleteService(s.Handle)
 }
 
 // Close relinquish access to service s.
 func (s *Service) Close() error {
-	return winapi.CloseServiceHandle(s.Handle)
+	return s.sys().CloseServiceHandle(s.Handle)
 }
 
 // Start starts service s.
@@ -43,13 +43,13 @@ func (s *Service) Start(args []string) error {
 		}
 		p = &vs[0]
 	}
-	return winapi.StartService(s.Handle, uint32(len(args)), p)
+	return s.sys().StartService(s.Handle, uint32(len(args)), p)
 }
 
 // Control sends state change request c to servce s.
 func (s *Service) Control(c svc.Cmd) (svc.Status, error) {
 	var t winapi.SERVICE_STATUS
-	err := winapi.ControlService(s.Handle, uint32(c), &t)
+	err := s.sys().ControlService(s.Handle, uint32(c), &t)
 	if err != nil {
 		return svc.Status{}, err
 	}
@@ -59,15 +59,163 @@ func (s *Service) Control(c svc.Cmd) (svc.Status, error) {
 	}, nil
 }
 
+// Reload asks service s to reload its configuration without restart,
+// by sending it svc.Reload request. See svc.WithReload. Reload returns
+// once request is delivered, not once configuration is reloaded.
+// It needs ServiceUserDefinedControl access right.
+func (s *Service) Reload() error {
+	_, err := s.Control(svc.Reload)
+	return err
+}
+
+// ControlWithReason sends state change request c to service s, and
+// records reason and comment for it in the system event log. Reason
+// is a combination of winapi.SERVICE_STOP_REASON_* flag, major and
+// minor reason. Windows only accepts reasons with Stop request.
+func (s *Service) ControlWithReason(c svc.Cmd, reason uint32, comment string) (svc.Status, error) {
+	p := winapi.SERVICE_CONTROL_STATUS_REASON_PARAMS{Reason: reason}
+	if comment != "" {
+		p.Comment = syscall.StringToUTF16Ptr(comment)
+	}
+	err := s.sys().ControlServiceEx(s.Handle, uint32(c),
+		winapi.SERVICE_CONTROL_STATUS_REASON_INFO, (*byte)(unsafe.Pointer(&p)))
+	if err != nil {
+		return svc.Status{}, err
+	}
+	return svc.Status{
+		State:     svc.State(p.ServiceStatus.CurrentState),
+		Accepts:   svc.Accepted(p.ServiceStatus.ControlsAccepted),
+		ProcessId: p.ServiceStatus.ProcessId,
+	}, nil
+}
+
 // Query returns current status of service s.
 func (s *Service) Query() (svc.Status, error) {
-	var t winapi.SERVICE_STATUS
-	err := winapi.QueryServiceStatus(s.Handle, &t)
+	var t winapi.SERVICE_STATUS_PROCESS
+	var needed uint32
+	err := s.sys().QueryServiceStatusEx(s.Handle, winapi.SC_STATUS_PROCESS_INFO,
+		(*byte)(unsafe.Pointer(&t)), uint32(unsafe.Sizeof(t)), &needed)
 	if err != nil {
 		return svc.Status{}, err
 	}
 	return svc.Status{
-		State:   svc.State(t.CurrentState),
-		Accepts: svc.Accepted(t.ControlsAccepted),
+		State:      svc.State(t.CurrentState),
+		Accepts:    svc.Accepted(t.ControlsAccepted),
+		CheckPoint: t.CheckPoint,
+		WaitHint:   t.WaitHint,
+		ProcessId:  t.ProcessId,
 	}, nil
 }
+
+// WaitState polls service s until it reaches state want, and
+// returns its last status. It fails if service s has not reached
+// state want within timeout.
+func (s *Service) WaitState(want svc.State, timeout time.Duration) (svc.Status, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	status, err := s.waitState(ctx, want)
+	if err == context.DeadlineExceeded {
+		return status, errors.New("timeout waiting for service " + s.Name + " to change state")
+	}
+	return status, err
+}
+
+// WaitStateContext is like WaitState, but waits until ctx is done,
+// rather than for timeout, and gives up calls to the service control
+// manager then, as WithContext does. It returns ctx.Err() then.
+func (s *Service) WaitStateContext(ctx context.Context, want svc.State) (svc.Status, error) {
+	return s.WithContext(ctx).waitState(ctx, want)
+}
+
+// waitState polls service s until it reaches state want, or ctx is done.
+func (s *Service) waitState(ctx context.Context, want svc.State) (svc.Status, error) {
+	for {
+		status, err := s.Query()
+		if err != nil {
+			return status, err
+		}
+		if status.State == want {
+			return status, nil
+		}
+		t := time.NewTimer(300 * time.Millisecond)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return status, ctx.Err()
+		}
+	}
+}
+
+// StopAndWait stops service s, unless it is stopped or stopping
+// already, and waits up to timeout for it to stop.
+func (s *Service) StopAndWait(timeout time.Duration) error {
+	return s.stopAndWait(func() error {
+		_, err := s.WaitState(svc.Stopped, timeout)
+		return err
+	})
+}
+
+// StopAndWaitContext is like StopAndWait, but waits until ctx
+// is done, as WaitStateContext does.
+func (s *Service) StopAndWaitContext(ctx context.Context) error {
+	cs := s.WithContext(ctx)
+	return cs.stopAndWait(func() error {
+		_, err := cs.waitState(ctx, svc.Stopped)
+		return err
+	})
+}
+
+// stopAndWait stops service s, unless it is stopped or
+// stopping already, and waits for it to stop with wait.
+func (s *Service) stopAndWait(wait func() error) error {
+	status, err := s.Query()
+	if err != nil {
+		return err
+	}
+	if status.State == svc.Stopped {
+		return nil
+	}
+	if status.State != svc.StopPending {
+		_, err = s.Control(svc.Stop)
+		if errors.Is(err, ErrServiceNotActive) {
+			// stopped since queried
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return wait()
+}
+
+// ListDependentServices returns names of services that depend on
+// service s, and are in state state. Use winapi.SERVICE_ACTIVE,
+// winapi.SERVICE_INACTIVE or winapi.SERVICE_STATE_ALL for state.
+// Services are listed in reverse order of start, so they can be
+// stopped in order returned.
+func (s *Service) ListDependentServices(state uint32) ([]string, error) {
+	var n, count uint32
+	err := s.sys().EnumDependentServices(s.Handle, state, nil, 0, &n, &count)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, syscall.ERROR_MORE_DATA) {
+		return nil, err
+	}
+	b := make([]byte, n)
+	p := (*winapi.ENUM_SERVICE_STATUS)(unsafe.Pointer(&b[0]))
+	err = s.sys().EnumDependentServices(s.Handle, state, p, n, &n, &count)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	services := unsafe.Slice((*winapi.ENUM_SERVICE_STATUS)(unsafe.Pointer(p)), count)
+	names := make([]string, count)
+	for i, s := range services {
+		names[i] = toString(s.ServiceName)
+	}
+	return names, nil
+}
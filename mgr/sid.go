@@ -0,0 +1,59 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+const (
+	// Service SID types. Service SID is added to the service
+	// process token, if service SID type is not SIDTypeNone.
+	SIDTypeNone         = winapi.SERVICE_SID_TYPE_NONE
+	SIDTypeUnrestricted = winapi.SERVICE_SID_TYPE_UNRESTRICTED
+	SIDTypeRestricted   = winapi.SERVICE_SID_TYPE_RESTRICTED
+)
+
+// SetSIDType sets service s SID type. Firewall rules and ACLs can
+// only be scoped to a service, if its SID type is not SIDTypeNone.
+func (s *Service) SetSIDType(t uint32) error {
+	info := winapi.SERVICE_SID_INFO{ServiceSidType: t}
+	return s.sys().ChangeServiceConfig2(s.Handle, winapi.SERVICE_CONFIG_SERVICE_SID_INFO, (*byte)(unsafe.Pointer(&info)))
+}
+
+// SIDType returns service s SID type.
+func (s *Service) SIDType() (uint32, error) {
+	b, err := s.queryServiceConfig2(winapi.SERVICE_CONFIG_SERVICE_SID_INFO)
+	if err != nil {
+		return 0, err
+	}
+	return (*winapi.SERVICE_SID_INFO)(unsafe.Pointer(&b[0])).ServiceSidType, nil
+}
+
+// ServiceSID returns SID of service name (account "NT SERVICE\name")
+// in string form. The SID is derived from the service name, so it
+// is known before the service is installed.
+func ServiceSID(name string) string {
+	u := utf16.Encode([]rune(strings.ToUpper(name)))
+	b := make([]byte, 2*len(u))
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+	h := sha1.Sum(b)
+	sid := "S-1-5-80"
+	for i := 0; i < 5; i++ {
+		sid += "-" + strconv.FormatUint(uint64(binary.LittleEndian.Uint32(h[4*i:])), 10)
+	}
+	return sid
+}
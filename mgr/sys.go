@@ -0,0 +1,151 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"syscall"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// SCM is the part of Windows API used by Mgr and Service to talk to
+// the service control manager. Methods have the same parameters and
+// meaning as winapi functions they are named after. Use ConnectWith
+// to replace it, for example, with a fake in tests of code that
+// installs or updates services.
+type SCM interface {
+	OpenSCManager(machineName *uint16, databaseName *uint16, access uint32) (syscall.Handle, error)
+	CloseServiceHandle(handle syscall.Handle) error
+	CreateService(mgr syscall.Handle, serviceName *uint16, displayName *uint16, access uint32, srvType uint32, startType uint32, errCtl uint32, pathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16) (syscall.Handle, error)
+	OpenService(mgr syscall.Handle, serviceName *uint16, access uint32) (syscall.Handle, error)
+	DeleteService(service syscall.Handle) error
+	StartService(service syscall.Handle, numArgs uint32, argVectors **uint16) error
+	ControlService(service syscall.Handle, control uint32, status *winapi.SERVICE_STATUS) error
+	ControlServiceEx(service syscall.Handle, control uint32, infoLevel uint32, params *byte) error
+	QueryServiceStatusEx(service syscall.Handle, infoLevel uint32, buff *byte, buffSize uint32, bytesNeeded *uint32) error
+	QueryServiceConfig(service syscall.Handle, serviceConfig *winapi.QUERY_SERVICE_CONFIG, bufSize uint32, bytesNeeded *uint32) error
+	ChangeServiceConfig(service syscall.Handle, serviceType uint32, startType uint32, errorControl uint32, binaryPathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16, displayName *uint16) error
+	QueryServiceConfig2(service syscall.Handle, infoLevel uint32, buff *byte, buffSize uint32, bytesNeeded *uint32) error
+	ChangeServiceConfig2(service syscall.Handle, infoLevel uint32, info *byte) error
+	EnumDependentServices(service syscall.Handle, serviceState uint32, services *winapi.ENUM_SERVICE_STATUS, bufSize uint32, bytesNeeded *uint32, servicesReturned *uint32) error
+	EnumServicesStatusEx(mgr syscall.Handle, infoLevel uint32, serviceType uint32, serviceState uint32, services *byte, bufSize uint32, bytesNeeded *uint32, servicesReturned *uint32, resumeHandle *uint32, groupName *uint16) error
+	QueryServiceObjectSecurity(service syscall.Handle, securityInformation uint32, sd *byte, bufSize uint32, bytesNeeded *uint32) error
+	SetServiceObjectSecurity(service syscall.Handle, securityInformation uint32, sd *byte) error
+	GetServiceDisplayName(mgr syscall.Handle, serviceName *uint16, displayName *uint16, bufSize *uint32) error
+	GetServiceKeyName(mgr syscall.Handle, displayName *uint16, serviceName *uint16, bufSize *uint32) error
+	SubscribeServiceChangeNotifications(service syscall.Handle, eventType uint32, callback uintptr, context uintptr, subscription *uintptr) error
+	UnsubscribeServiceChangeNotifications(subscription uintptr)
+}
+
+// SystemSCM is the real service control manager. It is
+// used by Connect, ConnectRemote and ConnectAccess.
+var SystemSCM SCM = systemSCM{}
+
+type systemSCM struct{}
+
+func (systemSCM) OpenSCManager(machineName *uint16, databaseName *uint16, access uint32) (syscall.Handle, error) {
+	return winapi.OpenSCManager(machineName, databaseName, access)
+}
+
+func (systemSCM) CloseServiceHandle(handle syscall.Handle) error {
+	return winapi.CloseServiceHandle(handle)
+}
+
+func (systemSCM) CreateService(mgr syscall.Handle, serviceName *uint16, displayName *uint16, access uint32, srvType uint32, startType uint32, errCtl uint32, pathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16) (syscall.Handle, error) {
+	return winapi.CreateService(mgr, serviceName, displayName, access, srvType, startType, errCtl, pathName, loadOrderGroup, tagId, dependencies, serviceStartName, password)
+}
+
+func (systemSCM) OpenService(mgr syscall.Handle, serviceName *uint16, access uint32) (syscall.Handle, error) {
+	return winapi.OpenService(mgr, serviceName, access)
+}
+
+func (systemSCM) DeleteService(service syscall.Handle) error {
+	return winapi.DeleteService(service)
+}
+
+func (systemSCM) StartService(service syscall.Handle, numArgs uint32, argVectors **uint16) error {
+	return winapi.StartService(service, numArgs, argVectors)
+}
+
+func (systemSCM) ControlService(service syscall.Handle, control uint32, status *winapi.SERVICE_STATUS) error {
+	return winapi.ControlService(service, control, status)
+}
+
+func (systemSCM) ControlServiceEx(service syscall.Handle, control uint32, infoLevel uint32, params *byte) error {
+	return winapi.ControlServiceEx(service, control, infoLevel, params)
+}
+
+func (systemSCM) QueryServiceStatusEx(service syscall.Handle, infoLevel uint32, buff *byte, buffSize uint32, bytesNeeded *uint32) error {
+	return winapi.QueryServiceStatusEx(service, infoLevel, buff, buffSize, bytesNeeded)
+}
+
+func (systemSCM) QueryServiceConfig(service syscall.Handle, serviceConfig *winapi.QUERY_SERVICE_CONFIG, bufSize uint32, bytesNeeded *uint32) error {
+	return winapi.QueryServiceConfig(service, serviceConfig, bufSize, bytesNeeded)
+}
+
+func (systemSCM) ChangeServiceConfig(service syscall.Handle, serviceType uint32, startType uint32, errorControl uint32, binaryPathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16, displayName *uint16) error {
+	return winapi.ChangeServiceConfig(service, serviceType, startType, errorControl, binaryPathName, loadOrderGroup, tagId, dependencies, serviceStartName, password, displayName)
+}
+
+func (systemSCM) QueryServiceConfig2(service syscall.Handle, infoLevel uint32, buff *byte, buffSize uint32, bytesNeeded *uint32) error {
+	return winapi.QueryServiceConfig2(service, infoLevel, buff, buffSize, bytesNeeded)
+}
+
+func (systemSCM) ChangeServiceConfig2(service syscall.Handle, infoLevel uint32, info *byte) error {
+	return winapi.ChangeServiceConfig2(service, infoLevel, info)
+}
+
+func (systemSCM) EnumDependentServices(service syscall.Handle, serviceState uint32, services *winapi.ENUM_SERVICE_STATUS, bufSize uint32, bytesNeeded *uint32, servicesReturned *uint32) error {
+	return winapi.EnumDependentServices(service, serviceState, services, bufSize, bytesNeeded, servicesReturned)
+}
+
+func (systemSCM) EnumServicesStatusEx(mgr syscall.Handle, infoLevel uint32, serviceType uint32, serviceState uint32, services *byte, bufSize uint32, bytesNeeded *uint32, servicesReturned *uint32, resumeHandle *uint32, groupName *uint16) error {
+	return winapi.EnumServicesStatusEx(mgr, infoLevel, serviceType, serviceState, services, bufSize, bytesNeeded, servicesReturned, resumeHandle, groupName)
+}
+
+func (systemSCM) QueryServiceObjectSecurity(service syscall.Handle, securityInformation uint32, sd *byte, bufSize uint32, bytesNeeded *uint32) error {
+	return winapi.QueryServiceObjectSecurity(service, securityInformation, sd, bufSize, bytesNeeded)
+}
+
+func (systemSCM) SetServiceObjectSecurity(service syscall.Handle, securityInformation uint32, sd *byte) error {
+	return winapi.SetServiceObjectSecurity(service, securityInformation, sd)
+}
+
+func (systemSCM) GetServiceDisplayName(mgr syscall.Handle, serviceName *uint16, displayName *uint16, bufSize *uint32) error {
+	return winapi.GetServiceDisplayName(mgr, serviceName, displayName, bufSize)
+}
+
+func (systemSCM) GetServiceKeyName(mgr syscall.Handle, displayName *uint16, serviceName *uint16, bufSize *uint32) error {
+	return winapi.GetServiceKeyName(mgr, displayName, serviceName, bufSize)
+}
+
+func (systemSCM) SubscribeServiceChangeNotifications(service syscall.Handle, eventType uint32, callback uintptr, context uintptr, subscription *uintptr) error {
+	if !winapi.CapServiceNotify.Available() {
+		return winapi.CapServiceNotify.Check()
+	}
+	return winapi.SubscribeServiceChangeNotifications(service, eventType, callback, context, subscription)
+}
+
+func (systemSCM) UnsubscribeServiceChangeNotifications(subscription uintptr) {
+	winapi.UnsubscribeServiceChangeNotifications(subscription)
+}
+
+// sys returns service control manager used by m.
+func (m *Mgr) sys() SCM {
+	if m.scm == nil {
+		return SystemSCM
+	}
+	return m.scm
+}
+
+// sys returns service control manager used by s.
+func (s *Service) sys() SCM {
+	if s.scm == nil {
+		return SystemSCM
+	}
+	return s.scm
+}
@@ -0,0 +1,531 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/mgr"
+	"github.com/multiplay/winsvc/svc"
+	"github.com/multiplay/winsvc/tracing"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// fakeSCM knows single running service named "fake".
+// Methods it does not implement panic.
+type fakeSCM struct {
+	mgr.SCM
+	state    uint32
+	controls []uint32
+	closed   int
+}
+
+const (
+	fakeMgrHandle     = syscall.Handle(1)
+	fakeServiceHandle = syscall.Handle(2)
+)
+
+func (f *fakeSCM) OpenSCManager(machineName *uint16, databaseName *uint16, access uint32) (syscall.Handle, error) {
+	return fakeMgrHandle, nil
+}
+
+func (f *fakeSCM) CloseServiceHandle(h syscall.Handle) error {
+	f.closed++
+	return nil
+}
+
+func (f *fakeSCM) OpenService(m syscall.Handle, name *uint16, access uint32) (syscall.Handle, error) {
+	if m != fakeMgrHandle {
+		return 0, syscall.EINVAL
+	}
+	if winapi.UTF16PtrToString(name, 64) != "fake" {
+		return 0, winapi.ERROR_SERVICE_DOES_NOT_EXIST
+	}
+	return fakeServiceHandle, nil
+}
+
+func (f *fakeSCM) QueryServiceStatusEx(h syscall.Handle, infoLevel uint32, buff *byte, buffSize uint32, bytesNeeded *uint32) error {
+	t := (*winapi.SERVICE_STATUS_PROCESS)(unsafe.Pointer(buff))
+	*t = winapi.SERVICE_STATUS_PROCESS{CurrentState: f.state, ControlsAccepted: winapi.SERVICE_ACCEPT_STOP, ProcessId: 42}
+	return nil
+}
+
+func (f *fakeSCM) ControlService(h syscall.Handle, control uint32, status *winapi.SERVICE_STATUS) error {
+	f.controls = append(f.controls, control)
+	if control == winapi.SERVICE_CONTROL_STOP {
+		f.state = winapi.SERVICE_STOP_PENDING
+	}
+	status.CurrentState = f.state
+	return nil
+}
+
+func TestConnectWith(t *testing.T) {
+	f := &fakeSCM{state: winapi.SERVICE_RUNNING}
+	m, err := mgr.ConnectWith(f, "", mgr.ManagerAllAccess)
+	if err != nil {
+		t.Fatalf("ConnectWith failed: %s", err)
+	}
+	defer m.Disconnect()
+
+	_, err = m.OpenService("other")
+	if !errors.Is(err, mgr.ErrServiceDoesNotExist) {
+		t.Fatalf("OpenService(other) returned %v, want %v", err, mgr.ErrServiceDoesNotExist)
+	}
+
+	s, err := m.OpenService("fake")
+	if err != nil {
+		t.Fatalf("OpenService(fake) failed: %s", err)
+	}
+	status, err := s.Query()
+	if err != nil {
+		t.Fatalf("Query failed: %s", err)
+	}
+	if status.State != svc.Running || status.ProcessId != 42 {
+		t.Errorf("Query returned %+v, want running process 42", status)
+	}
+	status, err = s.Control(svc.Stop)
+	if err != nil {
+		t.Fatalf("Control failed: %s", err)
+	}
+	if status.State != svc.StopPending {
+		t.Errorf("Control returned state %d, want %d", status.State, svc.StopPending)
+	}
+	if len(f.controls) != 1 || f.controls[0] != winapi.SERVICE_CONTROL_STOP {
+		t.Errorf("fake received controls %v", f.controls)
+	}
+	s.Close()
+	if f.closed != 1 {
+		t.Errorf("%d handles closed, want 1", f.closed)
+	}
+}
+
+// brokenConfigSCM is fakeSCM, that creates services,
+// but fails to change their optional settings.
+type brokenConfigSCM struct {
+	*fakeSCM
+	deleted int
+}
+
+func (b *brokenConfigSCM) CreateService(m syscall.Handle, name *uint16, displayName *uint16, access uint32, srvType uint32, startType uint32, errCtl uint32, pathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16) (syscall.Handle, error) {
+	return fakeServiceHandle, nil
+}
+
+func (b *brokenConfigSCM) ChangeServiceConfig2(h syscall.Handle, infoLevel uint32, info *byte) error {
+	return syscall.ERROR_ACCESS_DENIED
+}
+
+func (b *brokenConfigSCM) DeleteService(h syscall.Handle) error {
+	b.deleted++
+	return nil
+}
+
+func TestCreateServiceCleanup(t *testing.T) {
+	f := &brokenConfigSCM{fakeSCM: &fakeSCM{}}
+	m, err := mgr.ConnectWith(f, "", mgr.ManagerAllAccess)
+	if err != nil {
+		t.Fatalf("ConnectWith failed: %s", err)
+	}
+	defer m.Disconnect()
+	_, err = m.CreateService("fake", `C:ake.exe`, mgr.Config{Description: "fake service"})
+	if !errors.Is(err, mgr.ErrAccessDenied) {
+		t.Fatalf("CreateService returned %v, want %v", err, mgr.ErrAccessDenied)
+	}
+	if f.deleted != 1 || f.closed != 1 {
+		t.Errorf("half created service deleted %d and closed %d times, want 1 and 1", f.deleted, f.closed)
+	}
+}
+
+// hungSCM is fakeSCM, that does not return from ControlService,
+// until release is closed.
+type hungSCM struct {
+	*fakeSCM
+	release chan struct{}
+}
+
+func (h *hungSCM) ControlService(s syscall.Handle, control uint32, status *winapi.SERVICE_STATUS) error {
+	<-h.release
+	return nil
+}
+
+func TestWithContext(t *testing.T) {
+	f := &hungSCM{fakeSCM: &fakeSCM{state: winapi.SERVICE_RUNNING}, release: make(chan struct{})}
+	defer close(f.release)
+	m, err := mgr.ConnectWith(f, "", mgr.ManagerAllAccess)
+	if err != nil {
+		t.Fatalf("ConnectWith failed: %s", err)
+	}
+	defer m.Disconnect()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s, err := m.WithContext(ctx).OpenService("fake")
+	if err != nil {
+		t.Fatalf("OpenService(fake) failed: %s", err)
+	}
+	defer s.Close()
+	_, err = s.Control(svc.Stop)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Control of hung service returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	_, err = s.Query()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Query after deadline returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	_, err = s.WithContext(context.Background()).Query()
+	if err != nil {
+		t.Errorf("Query with new context failed: %v", err)
+	}
+	// service never stops
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	status, err := s.WaitStateContext(ctx, svc.Stopped)
+	if !errors.Is(err, context.DeadlineExceeded) || status.State != svc.Running {
+		t.Errorf("WaitStateContext returned %v in state %v, want %v in Running", err, status.State, context.DeadlineExceeded)
+	}
+}
+
+// flakySCM is fakeSCM, that fails OpenService with error err, n times.
+type flakySCM struct {
+	*fakeSCM
+	err   error
+	n     int
+	calls int
+}
+
+func (f *flakySCM) OpenService(m syscall.Handle, name *uint16, access uint32) (syscall.Handle, error) {
+	f.calls++
+	if f.calls <= f.n {
+		return 0, f.err
+	}
+	return f.fakeSCM.OpenService(m, name, access)
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		err   error
+		n     int
+		ok    bool
+		calls int
+	}{
+		{winapi.RPC_S_SERVER_UNAVAILABLE, 2, true, 3},
+		{winapi.ERROR_SERVICE_DATABASE_LOCKED, 1, true, 2},
+		{winapi.RPC_S_SERVER_UNAVAILABLE, 5, false, 3},
+		{syscall.ERROR_ACCESS_DENIED, 1, false, 1},
+	}
+	for _, test := range tests {
+		f := &flakySCM{fakeSCM: &fakeSCM{}, err: test.err, n: test.n}
+		m, err := mgr.ConnectWith(mgr.RetrySCM(f, mgr.RetryPolicy{Backoff: time.Millisecond}), "", mgr.ManagerAllAccess)
+		if err != nil {
+			t.Fatalf("ConnectWith failed: %s", err)
+		}
+		s, err := m.OpenService("fake")
+		if test.ok != (err == nil) || f.calls != test.calls {
+			t.Errorf("%v %d times: OpenService returned %v after %d calls, want success %v after %d calls",
+				test.err, test.n, err, f.calls, test.ok, test.calls)
+		}
+		if err == nil {
+			s.Close()
+		}
+		m.Disconnect()
+	}
+	// waits for retry end with context
+	f := &flakySCM{fakeSCM: &fakeSCM{}, err: winapi.RPC_S_SERVER_UNAVAILABLE, n: 5}
+	m, err := mgr.ConnectWith(f, "", mgr.ManagerAllAccess)
+	if err != nil {
+		t.Fatalf("ConnectWith failed: %s", err)
+	}
+	defer m.Disconnect()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = m.WithContext(ctx).WithRetry(mgr.RetryPolicy{Backoff: time.Hour}).OpenService("fake")
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 5*time.Second {
+		t.Errorf("OpenService returned %v after %v, want %v, once context is done", err, time.Since(start), context.DeadlineExceeded)
+	}
+	if c := mgr.Classify(&winapi.CallError{Func: "OpenService", Errno: winapi.RPC_S_CALL_FAILED}); c != mgr.Network {
+		t.Errorf("RPC_S_CALL_FAILED class is %d, want %d", c, mgr.Network)
+	}
+}
+
+// recorder records spans, as "name attr=value ...".
+type recorder struct {
+	spans []string
+}
+
+func (r *recorder) Tracer(name string) tracing.Tracer {
+	return r
+}
+
+func (r *recorder) Start(ctx context.Context, name string) (context.Context, tracing.Span) {
+	r.spans = append(r.spans, name)
+	return ctx, &recordedSpan{r, len(r.spans) - 1}
+}
+
+type recordedSpan struct {
+	r *recorder
+	i int
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...tracing.Attr) {
+	for _, a := range attrs {
+		s.r.spans[s.i] += fmt.Sprintf(" %s=%v", a.Key, a.Value)
+	}
+}
+
+func (s *recordedSpan) AddEvent(name string, attrs ...tracing.Attr) {}
+
+func (s *recordedSpan) RecordError(err error) {
+	s.r.spans[s.i] += " error=" + err.Error()
+}
+
+func (s *recordedSpan) End() {}
+
+func TestTracing(t *testing.T) {
+	f := &fakeSCM{state: winapi.SERVICE_RUNNING}
+	r := &recorder{}
+	m, err := mgr.ConnectWith(f, "", mgr.ManagerAllAccess)
+	if err != nil {
+		t.Fatalf("ConnectWith failed: %s", err)
+	}
+	defer m.Disconnect()
+	s, err := m.WithTracing(context.Background(), r).OpenService("fake")
+	if err != nil {
+		t.Fatalf("OpenService failed: %s", err)
+	}
+	defer s.Close()
+	_, err = s.Query()
+	if err != nil {
+		t.Fatalf("Query failed: %s", err)
+	}
+	_, err = s.Control(svc.Stop)
+	if err != nil {
+		t.Fatalf("Control failed: %s", err)
+	}
+	want := []string{"mgr.ControlService service.name=fake control=1"}
+	if !reflect.DeepEqual(r.spans, want) {
+		t.Errorf("spans are %q, want %q", r.spans, want)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	f := &fakeSCM{state: winapi.SERVICE_RUNNING}
+	m, err := mgr.ConnectWith(f, "", mgr.ManagerAllAccess)
+	if err != nil {
+		t.Fatalf("ConnectWith failed: %s", err)
+	}
+	defer m.Disconnect()
+	p := &mgr.Plan{}
+	d := m.WithDryRun(p)
+	s, err := d.OpenService("fake")
+	if err != nil {
+		t.Fatalf("OpenService failed: %s", err)
+	}
+	defer s.Close()
+	_, err = s.Control(svc.Stop)
+	if err != nil {
+		t.Fatalf("Control failed: %s", err)
+	}
+	err = s.Delete()
+	if err != nil {
+		t.Fatalf("Delete failed: %s", err)
+	}
+	n, err := d.CreateService("new", `C:\new.exe`, mgr.Config{
+		StartType:   mgr.StartAutomatic,
+		Description: "new service",
+		Password:    "secret",
+	})
+	if err != nil {
+		t.Fatalf("CreateService failed: %s", err)
+	}
+	// dry run is found through other SCMs wrapping it
+	err = n.WithRetry(mgr.RetryPolicy{}).SetEnvironment([]string{"A=1", "B=2"})
+	if err != nil {
+		t.Fatalf("SetEnvironment failed: %s", err)
+	}
+	closed := f.closed
+	n.Close()
+	if f.closed != closed {
+		t.Errorf("handle of service created in dry run was closed by SCM")
+	}
+	if len(f.controls) != 0 {
+		t.Errorf("dry run sent controls %v", f.controls)
+	}
+	want := []string{
+		"fake: ControlService Stop",
+		"fake: DeleteService",
+		`new: CreateService C:\new.exe`,
+		`new: ChangeServiceConfig ServiceType "" -> "own-process"`,
+		`new: ChangeServiceConfig StartType "" -> "auto"`,
+		`new: ChangeServiceConfig ErrorControl "" -> "normal"`,
+		`new: ChangeServiceConfig BinaryPathName "" -> "C:\\new.exe"`,
+		`new: ChangeServiceConfig Password "" -> "(set)"`,
+		`new: ChangeServiceConfig2 Description "" -> "new service"`,
+		`new: SetEnvironment Environment "" -> "A=1,B=2"`,
+	}
+	var got []string
+	for _, c := range p.Changes() {
+		got = append(got, c.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("plan is\n%s\nwant\n%q", p, want)
+	}
+}
+
+// configSCM is fakeSCM, that has configuration of service "fake".
+type configSCM struct {
+	*fakeSCM
+}
+
+var (
+	fakePath        = syscall.StringToUTF16Ptr(`C:\fake.exe`)
+	fakeStartName   = syscall.StringToUTF16Ptr("LocalSystem")
+	fakeDisplayName = syscall.StringToUTF16Ptr("Fake")
+	fakeDescription = syscall.StringToUTF16Ptr("fake service")
+)
+
+func (f configSCM) QueryServiceConfig(service syscall.Handle, serviceConfig *winapi.QUERY_SERVICE_CONFIG, bufSize uint32, bytesNeeded *uint32) error {
+	*serviceConfig = winapi.QUERY_SERVICE_CONFIG{
+		ServiceType:      winapi.SERVICE_WIN32_OWN_PROCESS,
+		StartType:        winapi.SERVICE_DEMAND_START,
+		ErrorControl:     winapi.SERVICE_ERROR_NORMAL,
+		BinaryPathName:   fakePath,
+		ServiceStartName: fakeStartName,
+		DisplayName:      fakeDisplayName,
+	}
+	return nil
+}
+
+func (f configSCM) QueryServiceConfig2(service syscall.Handle, infoLevel uint32, buff *byte, buffSize uint32, bytesNeeded *uint32) error {
+	switch infoLevel {
+	case winapi.SERVICE_CONFIG_DESCRIPTION:
+		(*winapi.SERVICE_DESCRIPTION)(unsafe.Pointer(buff)).Description = fakeDescription
+	case winapi.SERVICE_CONFIG_DELAYED_AUTO_START_INFO:
+		(*winapi.SERVICE_DELAYED_AUTO_START_INFO)(unsafe.Pointer(buff)).IsDelayedAutoStartUp = 0
+	default:
+		return syscall.EINVAL
+	}
+	return nil
+}
+
+func TestEnsureService(t *testing.T) {
+	m, err := mgr.ConnectWith(configSCM{&fakeSCM{}}, "", mgr.ManagerAllAccess)
+	if err != nil {
+		t.Fatalf("ConnectWith failed: %s", err)
+	}
+	defer m.Disconnect()
+	p := &mgr.Plan{}
+	r := &recorder{}
+	d := m.WithDryRun(p).WithTracing(context.Background(), r)
+	c := mgr.Config{StartType: mgr.StartAutomatic, DisplayName: "Fake", Description: "fake service"}
+	s, err := d.EnsureService("fake", `C:\fake.exe`, c)
+	if err != nil {
+		t.Fatalf("EnsureService of fake failed: %s", err)
+	}
+	s.Close()
+	n, err := d.EnsureService("new", `C:\new.exe`, c)
+	if err != nil {
+		t.Fatalf("EnsureService of new failed: %s", err)
+	}
+	n.Close()
+	want := []string{
+		`fake: ChangeServiceConfig StartType "demand" -> "auto"`,
+		`new: CreateService C:\new.exe`,
+	}
+	var got []string
+	for _, c := range p.Changes() {
+		if c.Service == "fake" || c.Op == "CreateService" {
+			got = append(got, c.String())
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("plan is\n%s\nwant only %q of fake and creation of new", p, want)
+	}
+	if len(r.spans) == 0 || r.spans[0] != "mgr.ChangeServiceConfig service.name=fake start_type=2" {
+		t.Errorf("spans are %q, want ChangeServiceConfig of fake with start_type=2 first", r.spans)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	f := &fakeSCM{state: winapi.SERVICE_RUNNING}
+	m, err := mgr.ConnectWith(f, "", mgr.ManagerAllAccess)
+	if err != nil {
+		t.Fatalf("ConnectWith failed: %s", err)
+	}
+	defer m.Disconnect()
+	var ops []*mgr.Operation
+	s, err := m.WithAudit(func(o *mgr.Operation) {
+		ops = append(ops, o)
+	}).OpenService("fake")
+	if err != nil {
+		t.Fatalf("OpenService failed: %s", err)
+	}
+	defer s.Close()
+	_, err = s.Query()
+	if err != nil {
+		t.Fatalf("Query failed: %s", err)
+	}
+	_, err = s.Control(svc.Stop)
+	if err != nil {
+		t.Fatalf("Control failed: %s", err)
+	}
+	if len(ops) != 1 {
+		t.Fatalf("audited %d operations, want 1", len(ops))
+	}
+	o := ops[0]
+	want := map[string]string{"control": "Stop"}
+	if o.Op != "ControlService" || o.Service != "fake" || !reflect.DeepEqual(o.Params, want) || o.Err != nil {
+		t.Errorf("audited %s, want ControlService of fake with %v", o, want)
+	}
+	if o.Start.IsZero() || o.Duration < 0 {
+		t.Errorf("audited operation has start %v and duration %v", o.Start, o.Duration)
+	}
+
+	// settings of ChangeServiceConfig2 and SetEnvironment are reported
+	ops = nil
+	p := &mgr.Plan{}
+	n, err := m.WithDryRun(p).WithAudit(func(o *mgr.Operation) {
+		ops = append(ops, o)
+	}).CreateService("new", `C:\new.exe`, mgr.Config{Description: "new service"})
+	if err != nil {
+		t.Fatalf("CreateService failed: %s", err)
+	}
+	defer n.Close()
+	err = n.SetEnvironment([]string{"A=1"})
+	if err != nil {
+		t.Fatalf("SetEnvironment failed: %s", err)
+	}
+	params := make(map[string]map[string]string)
+	for _, o := range ops {
+		params[o.Op] = o.Params
+	}
+	if d := params["ChangeServiceConfig2"]["description"]; d != "new service" {
+		t.Errorf("audited description %q, want %q", d, "new service")
+	}
+	if e := params["SetEnvironment"]["environment"]; e != "A=1" {
+		t.Errorf("audited environment %q, want %q", e, "A=1")
+	}
+	if s := p.String(); !strings.Contains(s, `new: SetEnvironment Environment "" -> "A=1"`) {
+		t.Errorf("plan is\n%s\nwant SetEnvironment of new", s)
+	}
+
+	// fake SCM never writes environment into the registry
+	s, err = m.WithAudit(func(*mgr.Operation) {}).OpenService("fake")
+	if err != nil {
+		t.Fatalf("OpenService failed: %s", err)
+	}
+	defer s.Close()
+	if err := s.SetEnvironment([]string{"A=1"}); err == nil {
+		t.Errorf("SetEnvironment of fake SCM service succeeded")
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/multiplay/winsvc/tracing"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// tracerName is name of Tracer requested by TraceSCM.
+const tracerName = "github.com/multiplay/winsvc/mgr"
+
+// TraceSCM returns SCM, that calls scm, and reports calls changing
+// services, like CreateService, StartService, ControlService and
+// DeleteService, as spans of tracer returned by tp, children of span
+// in ctx, if any. Spans are named after the call, like
+// "mgr.StartService", and have service.name attribute, if service
+// was opened or created through returned SCM. Queries are not traced.
+func TraceSCM(ctx context.Context, scm SCM, tp tracing.TracerProvider) SCM {
+	return tracingSCM{ctx, tp.Tracer(tracerName), &handleNames{}, scm}
+}
+
+// WithTracing returns copy of m, that traces calls, like TraceSCM.
+// Services opened or created with the copy are traced too. The copy
+// shares handle with m, so only one of them should be disconnected.
+func (m *Mgr) WithTracing(ctx context.Context, tp tracing.TracerProvider) *Mgr {
+	return &Mgr{Handle: m.Handle, scm: TraceSCM(ctx, m.sys(), tp)}
+}
+
+// WithTracing returns copy of s, that traces calls, like TraceSCM.
+// The copy shares handle with s, so only one of them should be closed.
+func (s *Service) WithTracing(ctx context.Context, tp tracing.TracerProvider) *Service {
+	t := TraceSCM(ctx, s.sys(), tp).(tracingSCM)
+	t.names.set(s.Handle, s.Name)
+	return &Service{Name: s.Name, Handle: s.Handle, scm: t}
+}
+
+// handleNames maps service handles to service names.
+type handleNames struct {
+	mu sync.Mutex
+	m  map[syscall.Handle]string
+}
+
+func (n *handleNames) set(h syscall.Handle, name string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.m == nil {
+		n.m = make(map[syscall.Handle]string)
+	}
+	n.m[h] = name
+}
+
+func (n *handleNames) get(h syscall.Handle) (string, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	name, ok := n.m[h]
+	return name, ok
+}
+
+func (n *handleNames) remove(h syscall.Handle) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.m, h)
+}
+
+type tracingSCM struct {
+	ctx   context.Context
+	t     tracing.Tracer
+	names *handleNames
+	SCM
+}
+
+func (t tracingSCM) unwrap() SCM { return t.SCM }
+
+// span runs f as span op of service name, if not "".
+func (t tracingSCM) span(op, name string, f func() error, attrs ...tracing.Attr) error {
+	_, sp := t.t.Start(t.ctx, "mgr."+op)
+	defer sp.End()
+	if name != "" {
+		sp.SetAttributes(tracing.String("service.name", name))
+	}
+	sp.SetAttributes(attrs...)
+	err := f()
+	if err != nil {
+		sp.RecordError(err)
+	}
+	return err
+}
+
+// call is like span, but for service handle h.
+func (t tracingSCM) call(op string, h syscall.Handle, f func() error, attrs ...tracing.Attr) error {
+	name, _ := t.names.get(h)
+	return t.span(op, name, f, attrs...)
+}
+
+func (t tracingSCM) CloseServiceHandle(handle syscall.Handle) error {
+	t.names.remove(handle)
+	return t.SCM.CloseServiceHandle(handle)
+}
+
+func (t tracingSCM) CreateService(mgr syscall.Handle, serviceName *uint16, displayName *uint16, access uint32, srvType uint32, startType uint32, errCtl uint32, pathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16) (h syscall.Handle, err error) {
+	name := toString(serviceName)
+	err = t.span("CreateService", name, func() error {
+		h, err = t.SCM.CreateService(mgr, serviceName, displayName, access, srvType, startType, errCtl, pathName, loadOrderGroup, tagId, dependencies, serviceStartName, password)
+		return err
+	}, tracing.String("service.path", toString(pathName)), tracing.Int("start_type", int64(startType)))
+	if err == nil {
+		t.names.set(h, name)
+	}
+	return h, err
+}
+
+func (t tracingSCM) OpenService(mgr syscall.Handle, serviceName *uint16, access uint32) (syscall.Handle, error) {
+	h, err := t.SCM.OpenService(mgr, serviceName, access)
+	if err == nil {
+		t.names.set(h, toString(serviceName))
+	}
+	return h, err
+}
+
+func (t tracingSCM) DeleteService(service syscall.Handle) error {
+	return t.call("DeleteService", service, func() error {
+		return t.SCM.DeleteService(service)
+	})
+}
+
+func (t tracingSCM) StartService(service syscall.Handle, numArgs uint32, argVectors **uint16) error {
+	return t.call("StartService", service, func() error {
+		return t.SCM.StartService(service, numArgs, argVectors)
+	})
+}
+
+func (t tracingSCM) ControlService(service syscall.Handle, control uint32, status *winapi.SERVICE_STATUS) error {
+	return t.call("ControlService", service, func() error {
+		return t.SCM.ControlService(service, control, status)
+	}, tracing.Int("control", int64(control)))
+}
+
+func (t tracingSCM) ControlServiceEx(service syscall.Handle, control uint32, infoLevel uint32, params *byte) error {
+	return t.call("ControlService", service, func() error {
+		return t.SCM.ControlServiceEx(service, control, infoLevel, params)
+	}, tracing.Int("control", int64(control)))
+}
+
+func (t tracingSCM) ChangeServiceConfig(service syscall.Handle, serviceType uint32, startType uint32, errorControl uint32, binaryPathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16, displayName *uint16) error {
+	// only settings changed are recorded, and password never is
+	var attrs []tracing.Attr
+	for _, a := range []struct {
+		key string
+		v   uint32
+	}{{"service_type", serviceType}, {"start_type", startType}, {"error_control", errorControl}} {
+		if a.v != winapi.SERVICE_NO_CHANGE {
+			attrs = append(attrs, tracing.Int(a.key, int64(a.v)))
+		}
+	}
+	for _, a := range []struct {
+		key string
+		v   *uint16
+	}{{"service.path", binaryPathName}, {"load_order_group", loadOrderGroup}, {"start_name", serviceStartName}, {"display_name", displayName}} {
+		if a.v != nil {
+			attrs = append(attrs, tracing.String(a.key, toString(a.v)))
+		}
+	}
+	if dependencies != nil {
+		attrs = append(attrs, tracing.String("dependencies", strings.Join(winapi.UTF16PtrToStrings(dependencies), ",")))
+	}
+	if password != nil {
+		attrs = append(attrs, tracing.Bool("password_set", true))
+	}
+	return t.call("ChangeServiceConfig", service, func() error {
+		return t.SCM.ChangeServiceConfig(service, serviceType, startType, errorControl, binaryPathName, loadOrderGroup, tagId, dependencies, serviceStartName, password, displayName)
+	}, attrs...)
+}
+
+func (t tracingSCM) ChangeServiceConfig2(service syscall.Handle, infoLevel uint32, info *byte) error {
+	return t.call("ChangeServiceConfig2", service, func() error {
+		return t.SCM.ChangeServiceConfig2(service, infoLevel, info)
+	}, tracing.Int("info_level", int64(infoLevel)))
+}
+
+func (t tracingSCM) SetServiceObjectSecurity(service syscall.Handle, securityInformation uint32, sd *byte) error {
+	return t.call("SetServiceObjectSecurity", service, func() error {
+		return t.SCM.SetServiceObjectSecurity(service, securityInformation, sd)
+	})
+}
@@ -0,0 +1,119 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+const (
+	// Possible actions taken when trigger event occurs.
+	TriggerStart = winapi.SERVICE_TRIGGER_ACTION_SERVICE_START // start the service
+	TriggerStop  = winapi.SERVICE_TRIGGER_ACTION_SERVICE_STOP  // stop the service
+)
+
+// TriggerData is trigger specific data item,
+// like device interface id or network port.
+type TriggerData struct {
+	Type uint32 // one of winapi.SERVICE_TRIGGER_DATA_TYPE_*
+	Data []byte
+}
+
+// Trigger is an event that starts or stops service.
+type Trigger struct {
+	Type    uint32 // one of winapi.SERVICE_TRIGGER_TYPE_*
+	Action  uint32 // TriggerStart or TriggerStop
+	Subtype winapi.GUID
+	Data    []TriggerData
+}
+
+// SetTriggers replaces triggers of service s with triggers.
+// Use nil triggers to delete them all.
+func (s *Service) SetTriggers(triggers []Trigger) error {
+	info := toTriggerInfo(triggers)
+	return s.sys().ChangeServiceConfig2(s.Handle,
+		winapi.SERVICE_CONFIG_TRIGGER_INFO, (*byte)(unsafe.Pointer(info)))
+}
+
+// Triggers returns triggers of service s.
+func (s *Service) Triggers() ([]Trigger, error) {
+	b, err := s.queryServiceConfig2(winapi.SERVICE_CONFIG_TRIGGER_INFO)
+	if err != nil {
+		return nil, err
+	}
+	return fromTriggerInfo((*winapi.SERVICE_TRIGGER_INFO)(unsafe.Pointer(&b[0]))), nil
+}
+
+// toTriggerInfo converts triggers into SERVICE_TRIGGER_INFO.
+// All memory referenced by returned structure is allocated
+// by Go, and stays valid for as long as it is referenced.
+func toTriggerInfo(triggers []Trigger) *winapi.SERVICE_TRIGGER_INFO {
+	info := &winapi.SERVICE_TRIGGER_INFO{}
+	if len(triggers) == 0 {
+		return info
+	}
+	ts := make([]winapi.SERVICE_TRIGGER, len(triggers))
+	for i, t := range triggers {
+		subtype := t.Subtype
+		ts[i] = winapi.SERVICE_TRIGGER{
+			TriggerType:    t.Type,
+			Action:         t.Action,
+			TriggerSubtype: &subtype,
+		}
+		if len(t.Data) == 0 {
+			continue
+		}
+		items := make([]winapi.SERVICE_TRIGGER_SPECIFIC_DATA_ITEM, len(t.Data))
+		for j, d := range t.Data {
+			items[j] = winapi.SERVICE_TRIGGER_SPECIFIC_DATA_ITEM{
+				DataType: d.Type,
+				DataSize: uint32(len(d.Data)),
+			}
+			if len(d.Data) > 0 {
+				items[j].Data = &d.Data[0]
+			}
+		}
+		ts[i].DataItemsCount = uint32(len(items))
+		ts[i].DataItems = &items[0]
+	}
+	info.TriggersCount = uint32(len(ts))
+	info.Triggers = &ts[0]
+	return info
+}
+
+// fromTriggerInfo converts SERVICE_TRIGGER_INFO into triggers.
+// Returned triggers do not reference memory pointed by info.
+func fromTriggerInfo(info *winapi.SERVICE_TRIGGER_INFO) []Trigger {
+	if info.TriggersCount == 0 || info.Triggers == nil {
+		return nil
+	}
+	ts := unsafe.Slice(info.Triggers, info.TriggersCount)
+	triggers := make([]Trigger, len(ts))
+	for i, t := range ts {
+		triggers[i] = Trigger{
+			Type:   t.TriggerType,
+			Action: t.Action,
+		}
+		if t.TriggerSubtype != nil {
+			triggers[i].Subtype = *t.TriggerSubtype
+		}
+		if t.DataItemsCount == 0 || t.DataItems == nil {
+			continue
+		}
+		items := unsafe.Slice(t.DataItems, t.DataItemsCount)
+		triggers[i].Data = make([]TriggerData, len(items))
+		for j, d := range items {
+			triggers[i].Data[j].Type = d.DataType
+			if d.DataSize > 0 && d.Data != nil {
+				triggers[i].Data[j].Data = append([]byte(nil), unsafe.Slice(d.Data, d.DataSize)...)
+			}
+		}
+	}
+	return triggers
+}
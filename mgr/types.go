@@ -0,0 +1,236 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// StartType tells when service is started.
+type StartType uint32
+
+const (
+	StartBoot      = StartType(winapi.SERVICE_BOOT_START)   // driver started by the system loader
+	StartSystem    = StartType(winapi.SERVICE_SYSTEM_START) // driver started during kernel initialization
+	StartAutomatic = StartType(winapi.SERVICE_AUTO_START)   // the service will start by itself whenever the computer reboots
+	StartManual    = StartType(winapi.SERVICE_DEMAND_START) // the service must be started manually
+	StartDisabled  = StartType(winapi.SERVICE_DISABLED)     // the service cannot be started
+)
+
+// ErrorControl is the severity of the error, and action
+// taken, if service fails to start.
+type ErrorControl uint32
+
+const (
+	ErrorIgnore   = ErrorControl(winapi.SERVICE_ERROR_IGNORE)
+	ErrorNormal   = ErrorControl(winapi.SERVICE_ERROR_NORMAL)
+	ErrorSevere   = ErrorControl(winapi.SERVICE_ERROR_SEVERE)
+	ErrorCritical = ErrorControl(winapi.SERVICE_ERROR_CRITICAL)
+)
+
+// ServiceType is kind of service. TypeInteractive can be
+// combined with TypeOwnProcess and TypeShareProcess.
+type ServiceType uint32
+
+const (
+	TypeKernelDriver     = ServiceType(winapi.SERVICE_KERNEL_DRIVER)
+	TypeFileSystemDriver = ServiceType(winapi.SERVICE_FILE_SYSTEM_DRIVER)
+	TypeOwnProcess       = ServiceType(winapi.SERVICE_WIN32_OWN_PROCESS)
+	TypeShareProcess     = ServiceType(winapi.SERVICE_WIN32_SHARE_PROCESS)
+	TypeUserOwnProcess   = ServiceType(winapi.SERVICE_USER_OWN_PROCESS)
+	TypeUserShareProcess = ServiceType(winapi.SERVICE_USER_SHARE_PROCESS)
+	TypeInteractive      = ServiceType(winapi.SERVICE_INTERACTIVE_PROCESS)
+)
+
+// enum lists names of values of one of the types. The first
+// name of every value is its canonical name, returned by String;
+// others are accepted by parsing.
+type enum []struct {
+	v     uint32
+	names []string
+}
+
+var startTypes = enum{
+	{winapi.SERVICE_BOOT_START, []string{"boot"}},
+	{winapi.SERVICE_SYSTEM_START, []string{"system"}},
+	{winapi.SERVICE_AUTO_START, []string{"auto", "automatic"}},
+	{winapi.SERVICE_DEMAND_START, []string{"demand", "manual"}},
+	{winapi.SERVICE_DISABLED, []string{"disabled"}},
+}
+
+var errorControls = enum{
+	{winapi.SERVICE_ERROR_IGNORE, []string{"ignore"}},
+	{winapi.SERVICE_ERROR_NORMAL, []string{"normal"}},
+	{winapi.SERVICE_ERROR_SEVERE, []string{"severe"}},
+	{winapi.SERVICE_ERROR_CRITICAL, []string{"critical"}},
+}
+
+var serviceTypes = enum{
+	{winapi.SERVICE_KERNEL_DRIVER, []string{"kernel-driver", "kernel"}},
+	{winapi.SERVICE_FILE_SYSTEM_DRIVER, []string{"filesystem-driver", "filesys"}},
+	{winapi.SERVICE_WIN32_OWN_PROCESS, []string{"own-process", "own"}},
+	{winapi.SERVICE_WIN32_SHARE_PROCESS, []string{"share-process", "share"}},
+	{winapi.SERVICE_USER_OWN_PROCESS, []string{"user-own-process", "userown"}},
+	{winapi.SERVICE_USER_SHARE_PROCESS, []string{"user-share-process", "usershare"}},
+	{winapi.SERVICE_INTERACTIVE_PROCESS, []string{"interactive", "interact"}},
+}
+
+func (e enum) name(v uint32) (string, bool) {
+	for _, x := range e {
+		if x.v == v {
+			return x.names[0], true
+		}
+	}
+	return "", false
+}
+
+// parse returns value named s, ignoring case. Numbers of known
+// values are accepted too, so raw values from older configuration
+// still work.
+func (e enum) parse(kind, s string) (uint32, error) {
+	for _, x := range e {
+		for _, n := range x.names {
+			if strings.EqualFold(s, n) {
+				return x.v, nil
+			}
+		}
+	}
+	if v, err := strconv.ParseUint(s, 0, 32); err == nil {
+		if _, ok := e.name(uint32(v)); ok {
+			return uint32(v), nil
+		}
+	}
+	return 0, fmt.Errorf("mgr: unknown %s %q", kind, s)
+}
+
+// unmarshalJSON decodes JSON string or number b.
+func (e enum) unmarshalJSON(kind string, b []byte) (uint32, error) {
+	var s string
+	if json.Unmarshal(b, &s) != nil {
+		// numbers are parsed as strings, so they are checked too
+		s = string(b)
+	}
+	return e.parse(kind, s)
+}
+
+func (t StartType) String() string {
+	if n, ok := startTypes.name(uint32(t)); ok {
+		return n
+	}
+	return fmt.Sprintf("StartType(%d)", uint32(t))
+}
+
+// ParseStartType returns start type named s, like "auto" or
+// "demand". As Windows records delayed start separately from start
+// type, "delayed-auto" (or "delayed") returns StartAutomatic and
+// delayed set, to be stored in Config.DelayedAutoStart.
+func ParseStartType(s string) (t StartType, delayed bool, err error) {
+	if strings.EqualFold(s, "delayed-auto") || strings.EqualFold(s, "delayed") {
+		return StartAutomatic, true, nil
+	}
+	v, err := startTypes.parse("start type", s)
+	return StartType(v), false, err
+}
+
+func (t StartType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON accepts start type name or number. It refuses
+// "delayed-auto", which StartType cannot hold; use ParseStartType.
+func (t *StartType) UnmarshalJSON(b []byte) error {
+	v, err := startTypes.unmarshalJSON("start type", b)
+	if err != nil {
+		return err
+	}
+	*t = StartType(v)
+	return nil
+}
+
+func (c ErrorControl) String() string {
+	if n, ok := errorControls.name(uint32(c)); ok {
+		return n
+	}
+	return fmt.Sprintf("ErrorControl(%d)", uint32(c))
+}
+
+// ParseErrorControl returns error control named s, like "normal".
+func ParseErrorControl(s string) (ErrorControl, error) {
+	v, err := errorControls.parse("error control", s)
+	return ErrorControl(v), err
+}
+
+func (c ErrorControl) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+func (c *ErrorControl) UnmarshalJSON(b []byte) error {
+	v, err := errorControls.unmarshalJSON("error control", b)
+	if err != nil {
+		return err
+	}
+	*c = ErrorControl(v)
+	return nil
+}
+
+// String returns t as names joined with "|", like
+// "own-process|interactive".
+func (t ServiceType) String() string {
+	if n, ok := serviceTypes.name(uint32(t)); ok {
+		return n
+	}
+	if t&TypeInteractive != 0 {
+		if n, ok := serviceTypes.name(uint32(t &^ TypeInteractive)); ok {
+			return n + "|interactive"
+		}
+	}
+	return fmt.Sprintf("ServiceType(%#x)", uint32(t))
+}
+
+// ParseServiceType returns service type named s, like
+// "own-process" or "share-process|interactive".
+func ParseServiceType(s string) (ServiceType, error) {
+	var t ServiceType
+	if v, err := strconv.ParseUint(s, 0, 32); err == nil {
+		// number of combined flags
+		t = ServiceType(v)
+	} else {
+		for _, n := range strings.Split(s, "|") {
+			v, err := serviceTypes.parse("service type", strings.TrimSpace(n))
+			if err != nil {
+				return 0, err
+			}
+			t |= ServiceType(v)
+		}
+	}
+	if strings.HasPrefix(t.String(), "ServiceType(") {
+		return 0, fmt.Errorf("mgr: invalid service type %q", s)
+	}
+	return t, nil
+}
+
+func (t ServiceType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (t *ServiceType) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) != nil {
+		s = string(b)
+	}
+	v, err := ParseServiceType(s)
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseStartType(t *testing.T) {
+	tests := []struct {
+		s       string
+		t       StartType
+		delayed bool
+	}{
+		{"auto", StartAutomatic, false},
+		{"Automatic", StartAutomatic, false},
+		{"demand", StartManual, false},
+		{"manual", StartManual, false},
+		{"disabled", StartDisabled, false},
+		{"delayed-auto", StartAutomatic, true},
+		{"2", StartAutomatic, false},
+	}
+	for _, test := range tests {
+		st, delayed, err := ParseStartType(test.s)
+		if err != nil {
+			t.Errorf("ParseStartType(%q) failed: %v", test.s, err)
+			continue
+		}
+		if st != test.t || delayed != test.delayed {
+			t.Errorf("ParseStartType(%q) = %v, %v, want %v, %v", test.s, st, delayed, test.t, test.delayed)
+		}
+	}
+	for _, s := range []string{"", "sometimes", "7"} {
+		if _, _, err := ParseStartType(s); err == nil {
+			t.Errorf("ParseStartType(%q) should fail", s)
+		}
+	}
+}
+
+func TestTypesJSON(t *testing.T) {
+	c := struct {
+		ServiceType  ServiceType
+		StartType    StartType
+		ErrorControl ErrorControl
+	}{TypeShareProcess | TypeInteractive, StartDisabled, ErrorSevere}
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"ServiceType":"share-process|interactive","StartType":"disabled","ErrorControl":"severe"}`
+	if string(b) != want {
+		t.Errorf("config is encoded as %s, want %s", b, want)
+	}
+	c.ServiceType, c.StartType, c.ErrorControl = 0, 0, 0
+	err = json.Unmarshal([]byte(`{"ServiceType":16,"StartType":"Demand","ErrorControl":3}`), &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ServiceType != TypeOwnProcess || c.StartType != StartManual || c.ErrorControl != ErrorCritical {
+		t.Errorf("config is decoded as %v, %v, %v", c.ServiceType, c.StartType, c.ErrorControl)
+	}
+	for _, s := range []string{`{"StartType":9}`, `{"ErrorControl":"fatal"}`, `{"ServiceType":"own|driver"}`, `{"ServiceType":3}`} {
+		if json.Unmarshal([]byte(s), &c) == nil {
+			t.Errorf("%s is decoded", s)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/multiplay/winsvc/svc"
+)
+
+// minWaitHint is used, when pending service reports no wait hint.
+const minWaitHint = 2 * time.Second
+
+// StuckError is returned by Unstick, when service stopped making
+// progress in pending state.
+type StuckError struct {
+	Name      string
+	Status    svc.Status    // last status reported
+	For       time.Duration // time since CheckPoint last changed
+	Restarted bool          // process was killed and service started again
+}
+
+func (e *StuckError) Error() string {
+	msg := fmt.Sprintf("service %s is stuck in state %v at checkpoint %d for %v, wait hint is %dms",
+		e.Name, e.Status.State, e.Status.CheckPoint, e.For.Round(time.Millisecond), e.Status.WaitHint)
+	if e.Restarted {
+		msg += ", process was killed and service restarted"
+	}
+	return msg
+}
+
+// progress watches status of pending service.
+type progress struct {
+	last  svc.Status
+	since time.Time // when last.CheckPoint was reported first
+}
+
+// stuck records status st seen at now, and returns how long service
+// has not made progress, if that is longer than its wait hint.
+func (p *progress) stuck(st svc.Status, now time.Time) (time.Duration, bool) {
+	if p.since.IsZero() || st.State != p.last.State || st.CheckPoint != p.last.CheckPoint {
+		p.last, p.since = st, now
+		return 0, false
+	}
+	p.last = st
+	hint := time.Duration(st.WaitHint) * time.Millisecond
+	if hint < minWaitHint {
+		hint = minWaitHint
+	}
+	d := now.Sub(p.since)
+	return d, d > hint
+}
+
+func pending(st svc.State) bool {
+	return st == svc.StartPending || st == svc.StopPending ||
+		st == svc.ContinuePending || st == svc.PausePending
+}
+
+// Unstick watches service s while it is in pending state, like
+// StartPending, and returns *StuckError, if its CheckPoint does not
+// advance within its WaitHint. If restart is set, process of stuck
+// service is killed, and the service is started again, when it was
+// starting. Do not restart services sharing process with other
+// services, they are killed too. Unstick returns nil, once service leaves pending state,
+// and ctx.Err(), if ctx is done first.
+func (s *Service) Unstick(ctx context.Context, restart bool) error {
+	var p progress
+	for {
+		st, err := s.Query()
+		if err != nil {
+			return err
+		}
+		if !pending(st.State) {
+			return nil
+		}
+		if d, ok := p.stuck(st, time.Now()); ok {
+			e := &StuckError{Name: s.Name, Status: st, For: d}
+			if !restart {
+				return e
+			}
+			err = s.kill(st)
+			if err != nil {
+				return fmt.Errorf("%v, and it cannot be restarted: %w", e, err)
+			}
+			e.Restarted = true
+			return e
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(300 * time.Millisecond):
+		}
+	}
+}
+
+// kill terminates process of service s with status st, and
+// starts the service again, if it was starting.
+func (s *Service) kill(st svc.Status) error {
+	if st.ProcessId == 0 {
+		return fmt.Errorf("service %s has no process", s.Name)
+	}
+	h, err := syscall.OpenProcess(syscall.PROCESS_TERMINATE, false, st.ProcessId)
+	if err != nil {
+		return err
+	}
+	err = syscall.TerminateProcess(h, 1)
+	syscall.CloseHandle(h)
+	if err != nil {
+		return err
+	}
+	_, err = s.WaitState(svc.Stopped, 30*time.Second)
+	if err != nil {
+		return err
+	}
+	if st.State != svc.StartPending {
+		return nil
+	}
+	return s.Start(nil)
+}
@@ -0,0 +1,41 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/multiplay/winsvc/svc"
+)
+
+func TestProgress(t *testing.T) {
+	start := time.Now()
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	status := func(cp uint32) svc.Status {
+		return svc.Status{State: svc.StartPending, CheckPoint: cp, WaitHint: 5000}
+	}
+	var p progress
+	tests := []struct {
+		st    svc.Status
+		at    time.Duration
+		stuck bool
+	}{
+		{status(1), 0, false},
+		{status(1), 4 * time.Second, false},
+		{status(2), 8 * time.Second, false}, // checkpoint advanced
+		{status(2), 12 * time.Second, false},
+		{status(2), 14 * time.Second, true},
+		{svc.Status{State: svc.StopPending, CheckPoint: 2}, 15 * time.Second, false},
+		{svc.Status{State: svc.StopPending, CheckPoint: 2}, 18 * time.Second, true}, // minWaitHint applies
+	}
+	for i, test := range tests {
+		if _, stuck := p.stuck(test.st, at(test.at)); stuck != test.stuck {
+			t.Errorf("%d: stuck is %v, want %v", i, stuck, test.stuck)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/svc"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// UserService describes one instance of per-user service. Windows
+// creates instance of per-user service template for every logon
+// session, and names it after the template and logon session id,
+// like CDPUserSvc_3a4f1.
+type UserService struct {
+	Name      string
+	Template  string
+	LogonId   uint64     // id (LUID) of the logon session instance runs for
+	Status    svc.Status // status at the time of listing
+	SessionId uint32     // terminal services session of the logon session
+	User      string     // like DOMAIN\user
+}
+
+// parseInstanceName splits name of per-user service instance
+// into its template name and logon session id.
+func parseInstanceName(name string) (template string, logonId uint64, ok bool) {
+	i := strings.LastIndexByte(name, '_')
+	if i <= 0 || i == len(name)-1 {
+		return "", 0, false
+	}
+	id, err := strconv.ParseUint(name[i+1:], 16, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return name[:i], id, true
+}
+
+// UserServices returns instances of per-user service template.
+// Template "" returns instances of all templates. Logon session
+// details are left empty for sessions that are gone, or cannot be
+// queried, because the caller is not an administrator.
+func (m *Mgr) UserServices(template string) ([]UserService, error) {
+	var us []UserService
+	err := m.enum(winapi.SERVICE_WIN32|winapi.SERVICE_USER_SERVICE|winapi.SERVICE_USERSERVICE_INSTANCE,
+		func(e *winapi.ENUM_SERVICE_STATUS_PROCESS) {
+			t := &e.ServiceStatusProcess
+			if t.ServiceType&winapi.SERVICE_USERSERVICE_INSTANCE == 0 {
+				return
+			}
+			name := toString(e.ServiceName)
+			tmpl, id, ok := parseInstanceName(name)
+			if !ok || (template != "" && !strings.EqualFold(tmpl, template)) {
+				return
+			}
+			us = append(us, UserService{
+				Name:     name,
+				Template: tmpl,
+				LogonId:  id,
+				Status: svc.Status{
+					State:      svc.State(t.CurrentState),
+					Accepts:    svc.Accepted(t.ControlsAccepted),
+					CheckPoint: t.CheckPoint,
+					WaitHint:   t.WaitHint,
+					ProcessId:  t.ProcessId,
+				},
+			})
+		})
+	if err != nil {
+		return nil, err
+	}
+	for i := range us {
+		us[i].SessionId, us[i].User, _ = logonSession(systemLSA{}, us[i].LogonId)
+	}
+	return us, nil
+}
+
+// OpenUserService opens instance of per-user service template,
+// that runs for user logged on to terminal services session id.
+// It returns ErrServiceDoesNotExist, if there is no such instance.
+// Start and stop the instance, as any other service.
+func (m *Mgr) OpenUserService(template string, sessionId uint32) (*Service, error) {
+	us, err := m.UserServices(template)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range us {
+		if u.User != "" && u.SessionId == sessionId {
+			return m.OpenService(u.Name)
+		}
+	}
+	return nil, fmt.Errorf("no instance of %s in session %d: %w", template, sessionId, ErrServiceDoesNotExist)
+}
+
+// lsa is the part of LSA API used to look logon sessions up.
+// It is replaced in tests.
+type lsa interface {
+	LsaGetLogonSessionData(logonId *winapi.LUID, data **winapi.SECURITY_LOGON_SESSION_DATA) (status uint32)
+	LsaFreeReturnBuffer(buffer uintptr) (status uint32)
+}
+
+type systemLSA struct{}
+
+func (systemLSA) LsaGetLogonSessionData(logonId *winapi.LUID, data **winapi.SECURITY_LOGON_SESSION_DATA) uint32 {
+	return winapi.LsaGetLogonSessionData(logonId, data)
+}
+
+func (systemLSA) LsaFreeReturnBuffer(buffer uintptr) uint32 {
+	return winapi.LsaFreeReturnBuffer(buffer)
+}
+
+// logonSession returns terminal services session and
+// user name of logon session id.
+func logonSession(l lsa, id uint64) (uint32, string, error) {
+	luid := winapi.LUID{LowPart: uint32(id), HighPart: int32(id >> 32)}
+	var d *winapi.SECURITY_LOGON_SESSION_DATA
+	status := l.LsaGetLogonSessionData(&luid, &d)
+	if status != 0 {
+		return 0, "", &winapi.CallError{Func: "LsaGetLogonSessionData",
+			Errno: syscall.Errno(winapi.LsaNtStatusToWinError(status))}
+	}
+	if d == nil {
+		return 0, "", fmt.Errorf("logon session %#x has no data", id)
+	}
+	defer l.LsaFreeReturnBuffer(uintptr(unsafe.Pointer(d)))
+	user := lsaString(d.UserName)
+	if domain := lsaString(d.LogonDomain); domain != "" {
+		user = domain + `\` + user
+	}
+	return d.Session, user, nil
+}
+
+func lsaString(s winapi.LSA_UNICODE_STRING) string {
+	return syscall.UTF16ToString(winapi.UTF16Slice(s.Buffer, int(s.Length/2)))
+}
@@ -0,0 +1,69 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"testing"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+func TestParseInstanceName(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		id       uint64
+		ok       bool
+	}{
+		{"CDPUserSvc_3a4f1", "CDPUserSvc", 0x3a4f1, true},
+		{"my_svc_1f", "my_svc", 0x1f, true},
+		{"CDPUserSvc", "", 0, false},
+		{"CDPUserSvc_", "", 0, false},
+		{"_3a4f1", "", 0, false},
+		{"my_svc", "", 0, false},
+	}
+	for _, test := range tests {
+		template, id, ok := parseInstanceName(test.name)
+		if template != test.template || id != test.id || ok != test.ok {
+			t.Errorf("parseInstanceName(%q) = %q, %#x, %v, want %q, %#x, %v",
+				test.name, template, id, ok, test.template, test.id, test.ok)
+		}
+	}
+}
+
+// emptySessionLSA reports logon sessions, that have no data.
+type emptySessionLSA struct {
+	lsa
+}
+
+func (emptySessionLSA) LsaGetLogonSessionData(logonId *winapi.LUID, data **winapi.SECURITY_LOGON_SESSION_DATA) uint32 {
+	return 0
+}
+
+func TestLogonSessionNoData(t *testing.T) {
+	_, _, err := logonSession(emptySessionLSA{}, 0x3a4f1)
+	if err == nil {
+		t.Error("logonSession should fail, when session has no data")
+	}
+}
+
+func TestLSAString(t *testing.T) {
+	u := []uint16{'a', 'b', 'c', 0}
+	tests := []struct {
+		s    winapi.LSA_UNICODE_STRING
+		want string
+	}{
+		{winapi.LSA_UNICODE_STRING{}, ""},
+		{winapi.LSA_UNICODE_STRING{Length: 6, MaximumLength: 8, Buffer: &u[0]}, "abc"},
+		{winapi.LSA_UNICODE_STRING{Length: 2, MaximumLength: 8, Buffer: &u[0]}, "a"},
+	}
+	for _, test := range tests {
+		if got := lsaString(test.s); got != test.want {
+			t.Errorf("lsaString(%+v) = %q, want %q", test.s, got, test.want)
+		}
+	}
+}
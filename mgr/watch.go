@@ -0,0 +1,154 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package mgr
+
+import (
+	"context"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/multiplay/winsvc/svc"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// Event reports status of service Name, when it changes. Err is set,
+// if service cannot be queried any more, for example, because it was
+// deleted. No further events are sent for that service then.
+type Event struct {
+	Name   string
+	Status svc.Status
+	Err    error
+}
+
+// watchPoll is how often services are queried, when
+// service change notifications are not available.
+const watchPoll = time.Second
+
+// Watchers are found by id passed to notification callback,
+// as Go pointers cannot be kept by Windows.
+var (
+	watchMu       sync.Mutex
+	watchers      = make(map[uintptr]chan<- struct{})
+	watchLastID   uintptr
+	watchCallback uintptr
+)
+
+// watchNotify is called by Windows on thread pool thread.
+func watchNotify(mask uint32, id uintptr) uintptr {
+	watchMu.Lock()
+	wake := watchers[id]
+	watchMu.Unlock()
+	select {
+	case wake <- struct{}{}:
+	default:
+		// watcher is about to query services anyway
+	}
+	return 0
+}
+
+// subscribe asks service control manager to signal wake whenever
+// status of service s changes, and returns function that cancels subscription.
+func subscribe(s *Service, wake chan<- struct{}) (func(), error) {
+	watchMu.Lock()
+	if watchCallback == 0 {
+		watchCallback = syscall.NewCallback(watchNotify)
+	}
+	watchLastID++
+	id := watchLastID
+	watchers[id] = wake
+	watchMu.Unlock()
+	var sub uintptr
+	err := s.sys().SubscribeServiceChangeNotifications(s.Handle, winapi.SC_EVENT_STATUS_CHANGE, watchCallback, id, &sub)
+	if err != nil {
+		watchMu.Lock()
+		delete(watchers, id)
+		watchMu.Unlock()
+		return nil, err
+	}
+	return func() {
+		s.sys().UnsubscribeServiceChangeNotifications(sub)
+		watchMu.Lock()
+		delete(watchers, id)
+		watchMu.Unlock()
+	}, nil
+}
+
+// Watch sends current status of services names, and then a new
+// Event each time status of one of them changes, until ctx is done.
+// The channel returned is closed then. Watch uses service change
+// notifications, where available, and polls services otherwise.
+func (m *Mgr) Watch(ctx context.Context, names ...string) (<-chan Event, error) {
+	var ss []*Service
+	for _, name := range names {
+		s, err := m.OpenServiceAccess(name, ServiceQueryStatus)
+		if err != nil {
+			for _, s := range ss {
+				s.Close()
+			}
+			return nil, err
+		}
+		ss = append(ss, s)
+	}
+	wake := make(chan struct{}, 1)
+	var cancels []func()
+	poll := false
+	for _, s := range ss {
+		cancel, err := subscribe(s, wake)
+		if err != nil {
+			poll = true
+			continue
+		}
+		cancels = append(cancels, cancel)
+	}
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		defer func() {
+			for _, cancel := range cancels {
+				cancel()
+			}
+			for _, s := range ss {
+				s.Close()
+			}
+		}()
+		var tick <-chan time.Time
+		if poll {
+			t := time.NewTicker(watchPoll)
+			defer t.Stop()
+			tick = t.C
+		}
+		last := make([]*svc.Status, len(ss))
+		gone := make([]bool, len(ss))
+		for {
+			for i, s := range ss {
+				if gone[i] {
+					continue
+				}
+				e := Event{Name: s.Name}
+				e.Status, e.Err = s.Query()
+				if e.Err == nil && last[i] != nil && *last[i] == e.Status {
+					continue
+				}
+				last[i] = &e.Status
+				gone[i] = e.Err != nil
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-wake:
+			case <-tick:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
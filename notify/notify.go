@@ -0,0 +1,30 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package notify tells service manager about state of the current
+// service, so server code shared between platforms needs no platform
+// branches. On Windows, it reports status of service run by
+// svc.Service to the service control manager. Elsewhere, it sends
+// sd_notify messages to systemd, if NOTIFY_SOCKET is set, and does
+// nothing otherwise, like when not run by systemd.
+//
+package notify
+
+// Ready tells service manager, that service has started
+// and is ready to serve.
+func Ready() error {
+	return ready()
+}
+
+// Stopping tells service manager, that service is stopping.
+func Stopping() error {
+	return stopping()
+}
+
+// Status tells service manager, what service is doing, like
+// "loading cache". Windows service has no status text, so
+// Status only reports progress of pending operation there.
+func Status(msg string) error {
+	return status(msg)
+}
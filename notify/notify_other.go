@@ -0,0 +1,43 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package notify
+
+import (
+	"net"
+	"os"
+)
+
+func ready() error {
+	return send("READY=1")
+}
+
+func stopping() error {
+	return send("STOPPING=1")
+}
+
+func status(msg string) error {
+	return send("STATUS=" + msg)
+}
+
+// send sends sd_notify state to socket NOTIFY_SOCKET, if set.
+func send(state string) error {
+	name := os.Getenv("NOTIFY_SOCKET")
+	if name == "" {
+		return nil
+	}
+	if name[0] == '@' {
+		// abstract socket
+		name = "\x00" + name[1:]
+	}
+	c, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: name, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	_, err = c.Write([]byte(state))
+	return err
+}
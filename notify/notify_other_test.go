@@ -0,0 +1,52 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package notify_test
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/multiplay/winsvc/notify"
+)
+
+func TestSdNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	err := notify.Ready()
+	if err != nil {
+		t.Fatalf("Ready with no NOTIFY_SOCKET failed: %v", err)
+	}
+
+	name := filepath.Join(t.TempDir(), "notify")
+	c, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: name, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("ListenUnixgram failed: %v", err)
+	}
+	defer c.Close()
+	t.Setenv("NOTIFY_SOCKET", name)
+	b := make([]byte, 64)
+	for _, test := range []struct {
+		f    func() error
+		want string
+	}{
+		{notify.Ready, "READY=1"},
+		{func() error { return notify.Status("loading cache") }, "STATUS=loading cache"},
+		{notify.Stopping, "STOPPING=1"},
+	} {
+		err = test.f()
+		if err != nil {
+			t.Fatalf("sending %s failed: %v", test.want, err)
+		}
+		n, err := c.Read(b)
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if got := string(b[:n]); got != test.want {
+			t.Errorf("received %q, want %q", got, test.want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package notify
+
+import "github.com/multiplay/winsvc/svc"
+
+// Accepts lists requests service accepts, once Ready reports it
+// Running. Handler can report other requests itself, before or
+// after Ready, which does not change them, if service is running.
+var Accepts = svc.AcceptStop | svc.AcceptShutdown
+
+// ready reports service Running, unless it is running already.
+func ready() error {
+	s := svc.Current()
+	if s == nil || s.Status().State == svc.Running {
+		return nil
+	}
+	s.Report(svc.Status{State: svc.Running, Accepts: Accepts})
+	return nil
+}
+
+func stopping() error {
+	if s := svc.Current(); s != nil {
+		s.Report(svc.Status{State: svc.StopPending})
+	}
+	return nil
+}
+
+// status advances CheckPoint of pending operation, like svc.Service.Tick.
+func status(msg string) error {
+	if s := svc.Current(); s != nil {
+		s.Tick()
+	}
+	return nil
+}
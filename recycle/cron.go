@@ -0,0 +1,166 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package recycle
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a schedule in crontab format.
+type Cron struct {
+	minute, hour, dom, month, dow uint64 // bit set for every value listed
+
+	// day matches if either of day of month or day of week
+	// matches, unless one of them is *, as in cron
+	domAny, dowAny bool
+}
+
+var months = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+// ParseCron parses schedule s. It has five fields, as in crontab:
+// minute, hour, day of month, month and day of week. Every field is
+// *, or a comma separated list of values and ranges, like "1-5",
+// each optionally followed by step, as in "*/15". Months and week
+// days can also be named, like "Jan" or "Mon-Fri". Sunday is 0 or 7.
+func ParseCron(s string) (*Cron, error) {
+	f := strings.Fields(s)
+	if len(f) != 5 {
+		return nil, errors.New("recycle: cron schedule " + s + " does not have 5 fields")
+	}
+	days := make(map[string]int)
+	for n, d := range weekdays {
+		days[n] = int(d)
+	}
+	var c Cron
+	var err error
+	if c.minute, err = parseCronField(f[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(f[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(f[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(f[3], 1, 12, months); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(f[4], 0, 7, days); err != nil {
+		return nil, err
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domAny = f[2] == "*"
+	c.dowAny = f[4] == "*"
+	return &c, nil
+}
+
+// parseCronField parses cron field s of values from min to max,
+// that can be named as in names.
+func parseCronField(s string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, r := range strings.Split(s, ",") {
+		step := 1
+		if i := strings.Index(r, "/"); i >= 0 {
+			n, err := strconv.Atoi(r[i+1:])
+			if err != nil || n <= 0 {
+				return 0, errors.New("recycle: invalid cron step " + r)
+			}
+			step = n
+			r = r[:i]
+		}
+		from, to := min, max
+		if r != "*" {
+			v := strings.SplitN(r, "-", 2)
+			var err error
+			from, err = parseCronValue(v[0], min, max, names)
+			if err != nil {
+				return 0, err
+			}
+			to = from
+			if len(v) == 2 {
+				to, err = parseCronValue(v[1], min, max, names)
+				if err != nil {
+					return 0, err
+				}
+			}
+			if to < from {
+				return 0, errors.New("recycle: invalid cron range " + r)
+			}
+		}
+		for i := from; i <= to; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(s string, min, max int, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToLower(s)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, errors.New("recycle: invalid cron value " + s)
+	}
+	return n, nil
+}
+
+func (c *Cron) day(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// Next returns the first time after t that c lists, or zero time,
+// if there is none within 5 years, as in "0 0 30 Feb *". Times are
+// taken at wall clock time of t location.
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	y, m, d := t.Date()
+	h, min := t.Hour(), t.Minute()
+	for end := y + 5; y <= end; {
+		if min > 59 {
+			h, min = h+1, 0
+		}
+		if h > 23 {
+			d, h, min = d+1, 0, 0
+		}
+		at := time.Date(y, m, d, h, min, 0, 0, loc)
+		switch {
+		case at.Day() != d || c.month&(1<<uint(m)) == 0:
+			// past end of month, or month is not listed
+			d, h, min = 1, 0, 0
+			if m++; m > 12 {
+				y, m = y+1, 1
+			}
+		case !c.day(at):
+			d, h, min = d+1, 0, 0
+		case c.hour&(1<<uint(h)) == 0:
+			h, min = h+1, 0
+		case c.minute&(1<<uint(min)) == 0:
+			min++
+		default:
+			return at
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,147 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Package recycle restarts a service regularly during a maintenance
+// window, or at times given by cron like schedule. It is meant for
+// services that cannot run indefinitely, for example, because of
+// memory leaks in third party code.
+//
+// When the time comes, the service handler is asked to drain its work
+// and is then sent a Stop request. Once it returns, the service stops
+// with a service specific exit code. For the service to be started
+// again, its recovery actions must restart it on failure, including
+// failures without a crash, see install.Definition.RecoveryOnNonCrashFailures.
+//
+package recycle
+
+import (
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/multiplay/winsvc/svc"
+)
+
+// DefaultExitCode is reported when Schedule.ExitCode is 0.
+const DefaultExitCode = 1
+
+// Schedule describes when and how service is recycled.
+type Schedule struct {
+	Window Window
+
+	// Cron, if not nil, is used instead of Window, and
+	// service is recycled at times it lists.
+	Cron *Cron
+
+	// Jitter is the maximum random delay added to the window start,
+	// so that a fleet of services is not restarted all at once.
+	// It is limited to the window length, unless Cron is used.
+	Jitter time.Duration
+
+	// Drain, if not nil, is called before the service is sent Stop.
+	// It runs on its own goroutine, while control requests are still
+	// passed to the handler.
+	Drain func()
+
+	// ExitCode is the service specific exit code reported
+	// when the service is recycled.
+	ExitCode uint32
+}
+
+// next returns the time the service should be recycled after t,
+// or zero time, if it should not be.
+func (s *Schedule) next(t time.Time, rnd *rand.Rand) time.Time {
+	var start time.Time
+	j := s.Jitter
+	if s.Cron != nil {
+		start = s.Cron.Next(t)
+		if start.IsZero() {
+			return start
+		}
+	} else {
+		start = s.Window.Next(t)
+		if j > s.Window.Length {
+			j = s.Window.Length
+		}
+	}
+	if j > 0 {
+		start = start.Add(time.Duration(rnd.Int63n(int64(j))))
+	}
+	return start
+}
+
+// Handler returns handler that runs h, and stops it
+// according to schedule s.
+func Handler(h svc.Handler, s Schedule) svc.Handler {
+	if s.ExitCode == 0 {
+		s.ExitCode = DefaultExitCode
+	}
+	return &handler{h: h, s: s}
+}
+
+type handler struct {
+	h svc.Handler
+	s Schedule
+}
+
+func (r *handler) Execute(args []string, req <-chan svc.ChangeRequest, s chan<- svc.Status) (bool, uint32) {
+	seed := time.Now().UnixNano() + int64(os.Getpid())
+	at := r.s.next(time.Now(), rand.New(rand.NewSource(seed)))
+	var due <-chan time.Time // nil, if schedule has no time left
+	if !at.IsZero() {
+		timer := time.NewTimer(at.Sub(time.Now()))
+		defer timer.Stop()
+		due = timer.C
+	}
+
+	in := make(chan svc.ChangeRequest)
+	done := make(chan struct{})
+	recycled := make(chan bool, 1)
+	go func() {
+		var status svc.Status
+		// closed once Drain returns; Drain runs on its own
+		// goroutine, so requests are passed on meanwhile
+		var drained chan struct{}
+		for {
+			select {
+			case c := <-req:
+				status = c.CurrentStatus
+				select {
+				case in <- c:
+				case <-done:
+					return
+				}
+			case <-due:
+				drained = make(chan struct{})
+				go func(drained chan struct{}) {
+					defer close(drained)
+					if r.s.Drain != nil {
+						r.s.Drain()
+					}
+				}(drained)
+			case <-drained:
+				drained = nil
+				recycled <- true
+				select {
+				case in <- svc.ChangeRequest{Cmd: svc.Stop, CurrentStatus: status}:
+				case <-done:
+					return
+				}
+				// handler may still take requests while it stops
+			case <-done:
+				return
+			}
+		}
+	}()
+	ssec, errno := r.h.Execute(args, in, s)
+	close(done)
+	select {
+	case <-recycled:
+		return true, r.s.ExitCode
+	default:
+	}
+	return ssec, errno
+}
@@ -0,0 +1,124 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package recycle
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		window string
+		now    string
+		next   string
+	}{
+		{"02:00-04:00", "2014-03-05 01:00", "2014-03-05 02:00"},
+		{"02:00-04:00", "2014-03-05 02:00", "2014-03-06 02:00"},
+		{"02:00-04:00", "2014-03-05 03:00", "2014-03-06 02:00"},
+		{"Sat,Sun 03:30-04:00", "2014-03-05 12:00", "2014-03-08 03:30"},
+		{"Mon-Fri 23:00-01:00", "2014-03-07 23:30", "2014-03-10 23:00"},
+		{"Fri-Mon 23:00-01:00", "2014-03-09 23:30", "2014-03-10 23:00"},
+	}
+	const layout = "2006-01-02 15:04"
+	for _, test := range tests {
+		w, err := ParseWindow(test.window)
+		if err != nil {
+			t.Fatalf("ParseWindow(%q) failed: %v", test.window, err)
+		}
+		now, _ := time.Parse(layout, test.now)
+		next := w.Next(now).Format(layout)
+		if next != test.next {
+			t.Errorf("%q.Next(%s) = %s, want %s", test.window, test.now, next, test.next)
+		}
+	}
+}
+
+func TestNextDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("time zone data is not available: %v", err)
+	}
+	w, err := ParseWindow("03:00-04:00")
+	if err != nil {
+		t.Fatalf("ParseWindow failed: %v", err)
+	}
+	// clocks go forward at 01:00 on 2014-03-30
+	now := time.Date(2014, 3, 30, 0, 30, 0, 0, loc)
+	next := w.Next(now)
+	if want := time.Date(2014, 3, 30, 3, 0, 0, 0, loc); !next.Equal(want) {
+		t.Errorf("Next(%v) = %v, want %v", now, next, want)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	w, err := ParseWindow("02:00-02:30")
+	if err != nil {
+		t.Fatalf("ParseWindow failed: %v", err)
+	}
+	s := Schedule{Window: w, Jitter: time.Hour}
+	now := time.Date(2014, 3, 5, 1, 0, 0, 0, time.UTC)
+	start := w.Next(now)
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		at := s.next(now, rnd)
+		if at.Before(start) || !at.Before(start.Add(w.Length)) {
+			t.Fatalf("next returned %v, outside of window starting at %v", at, start)
+		}
+	}
+}
+
+func TestParseWindowErrors(t *testing.T) {
+	for _, s := range []string{"", "02:00", "Xyz 02:00-03:00", "25:00-26:00", "Mon 02:00-03:00 x"} {
+		_, err := ParseWindow(s)
+		if err == nil {
+			t.Errorf("ParseWindow(%q) succeeded, but should have failed", s)
+		}
+	}
+}
+
+func TestCron(t *testing.T) {
+	tests := []struct {
+		cron string
+		now  string
+		next string
+	}{
+		{"30 2 * * *", "2014-03-05 01:00", "2014-03-05 02:30"},
+		{"30 2 * * *", "2014-03-05 02:30", "2014-03-06 02:30"},
+		{"*/15 * * * *", "2014-03-05 01:50", "2014-03-05 02:00"},
+		{"0 3 * * Sat,Sun", "2014-03-05 12:00", "2014-03-08 03:00"},
+		{"0 3 * * 7", "2014-03-05 12:00", "2014-03-09 03:00"},
+		{"0 23 * * Mon-Fri", "2014-03-07 23:30", "2014-03-10 23:00"},
+		{"0 0 31 * *", "2014-04-01 00:00", "2014-05-31 00:00"},
+		{"0 0 1 Jan *", "2014-03-05 12:00", "2015-01-01 00:00"},
+		{"0 0 13 * Fri", "2014-03-05 12:00", "2014-03-07 00:00"},
+		{"0 4 29 Feb *", "2014-03-05 12:00", "2016-02-29 04:00"},
+		{"0 0 30 Feb *", "2014-03-05 12:00", "0001-01-01 00:00"},
+	}
+	const layout = "2006-01-02 15:04"
+	for _, test := range tests {
+		c, err := ParseCron(test.cron)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) failed: %v", test.cron, err)
+		}
+		now, _ := time.Parse(layout, test.now)
+		next := c.Next(now).Format(layout)
+		if next != test.next {
+			t.Errorf("%q.Next(%s) = %s, want %s", test.cron, test.now, next, test.next)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, s := range []string{"", "0 3 * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * Xyz *", "5-1 * * * *", "*/0 * * * *", "* * * * 8"} {
+		_, err := ParseCron(s)
+		if err == nil {
+			t.Errorf("ParseCron(%q) succeeded, but should have failed", s)
+		}
+	}
+}
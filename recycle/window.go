@@ -0,0 +1,116 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package recycle
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Window is a maintenance window repeated on selected days of the week.
+type Window struct {
+	Start  time.Duration // start time, as offset from midnight
+	Length time.Duration
+	Days   [7]bool // indexed by time.Weekday, window is used every day if all false
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseWindow parses window description s. It has form
+// "[days] HH:MM-HH:MM", where optional days is a comma separated
+// list of week days or day ranges, like "Mon-Fri" or "Sat,Sun".
+// Window can span midnight, as in "23:00-01:00".
+func ParseWindow(s string) (Window, error) {
+	var w Window
+	f := strings.Fields(s)
+	switch len(f) {
+	case 1:
+	case 2:
+		for _, d := range strings.Split(f[0], ",") {
+			r := strings.SplitN(d, "-", 2)
+			from, ok := weekdays[strings.ToLower(r[0])]
+			if !ok {
+				return w, errors.New("recycle: invalid week day " + r[0])
+			}
+			to := from
+			if len(r) == 2 {
+				to, ok = weekdays[strings.ToLower(r[1])]
+				if !ok {
+					return w, errors.New("recycle: invalid week day " + r[1])
+				}
+			}
+			for d := from; ; d = (d + 1) % 7 {
+				w.Days[d] = true
+				if d == to {
+					break
+				}
+			}
+		}
+		f = f[1:]
+	default:
+		return w, errors.New("recycle: invalid window " + s)
+	}
+	r := strings.SplitN(f[0], "-", 2)
+	if len(r) != 2 {
+		return w, errors.New("recycle: invalid window " + s)
+	}
+	start, err := parseClock(r[0])
+	if err != nil {
+		return w, err
+	}
+	end, err := parseClock(r[1])
+	if err != nil {
+		return w, err
+	}
+	w.Start = start
+	w.Length = end - start
+	if w.Length <= 0 {
+		w.Length += 24 * time.Hour
+	}
+	return w, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.New("recycle: invalid time of day " + s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (w *Window) everyDay() bool {
+	for _, d := range w.Days {
+		if d {
+			return false
+		}
+	}
+	return true
+}
+
+// Next returns start of the first window that begins after t.
+// Window starts at wall clock time of day, also on days daylight
+// saving time begins or ends.
+func (w *Window) Next(t time.Time) time.Time {
+	y, m, d := t.Date()
+	hour, min, sec := int(w.Start/time.Hour), int(w.Start/time.Minute%60), int(w.Start/time.Second%60)
+	for i := 0; i <= 7; i++ {
+		start := time.Date(y, m, d+i, hour, min, sec, 0, t.Location())
+		if start.After(t) && (w.everyDay() || w.Days[start.Weekday()]) {
+			return start
+		}
+	}
+	panic("unreachable")
+}
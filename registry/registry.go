@@ -9,8 +9,10 @@
 package registry
 
 import (
+	"errors"
 	"github.com/multiplay/winsvc/winapi"
 	"syscall"
+	"unicode/utf16"
 	"unsafe"
 )
 
@@ -19,16 +21,32 @@ type Key struct {
 }
 
 func OpenKey(parent syscall.Handle, path string) (*Key, error) {
+	return OpenKeyAccess(parent, path, syscall.KEY_ALL_ACCESS)
+}
+
+// OpenKeyAccess opens key path with access rights access,
+// for example syscall.KEY_READ.
+func OpenKeyAccess(parent syscall.Handle, path string, access uint32) (*Key, error) {
 	var h syscall.Handle
 	e := syscall.RegOpenKeyEx(
 		parent, syscall.StringToUTF16Ptr(path),
-		0, syscall.KEY_ALL_ACCESS, &h)
+		0, access, &h)
 	if e != nil {
 		return nil, e
 	}
 	return &Key{Handle: h}, nil
 }
 
+// servicesKeyName is key of services
+// registered with service control manager.
+const servicesKeyName = `SYSTEM\CurrentControlSet\Services`
+
+// OpenServiceParameters opens Parameters key of service name, where
+// service keeps its own settings, with access rights access.
+func OpenServiceParameters(name string, access uint32) (*Key, error) {
+	return OpenKeyAccess(syscall.HKEY_LOCAL_MACHINE, servicesKeyName+`\`+name+`\Parameters`, access)
+}
+
 func (k *Key) Close() error {
 	return syscall.RegCloseKey(k.Handle)
 }
@@ -50,6 +68,11 @@ func (k *Key) DeleteSubKey(name string) error {
 	return winapi.RegDeleteKey(k.Handle, syscall.StringToUTF16Ptr(name))
 }
 
+// DeleteSubKeyTree deletes subkey name together with all its subkeys and values.
+func (k *Key) DeleteSubKeyTree(name string) error {
+	return winapi.RegDeleteTree(k.Handle, syscall.StringToUTF16Ptr(name))
+}
+
 func (k *Key) SetUInt32(name string, value uint32) error {
 	return winapi.RegSetValueEx(
 		k.Handle, syscall.StringToUTF16Ptr(name),
@@ -72,3 +95,109 @@ func (k *Key) SetString(name string, value string) error {
 func (k *Key) SetStringExpand(name string, value string) error {
 	return k.setString(name, value, syscall.REG_EXPAND_SZ)
 }
+
+// SetStrings stores value as REG_MULTI_SZ. None of the strings
+// may be empty, because empty string terminates the list.
+func (k *Key) SetStrings(name string, value []string) error {
+	var buf []uint16
+	for _, s := range value {
+		if s == "" {
+			return errors.New("registry: empty string in REG_MULTI_SZ value " + name)
+		}
+		buf = append(buf, utf16.Encode([]rune(s))...)
+		buf = append(buf, 0)
+	}
+	buf = append(buf, 0)
+	return winapi.RegSetValueEx(
+		k.Handle, syscall.StringToUTF16Ptr(name),
+		0, syscall.REG_MULTI_SZ,
+		(*byte)(unsafe.Pointer(&buf[0])), uint32(len(buf)*2))
+}
+
+// SetBinary sets REG_BINARY value name to value.
+func (k *Key) SetBinary(name string, value []byte) error {
+	var p *byte
+	if len(value) > 0 {
+		p = &value[0]
+	}
+	return winapi.RegSetValueEx(
+		k.Handle, syscall.StringToUTF16Ptr(name),
+		0, syscall.REG_BINARY, p, uint32(len(value)))
+}
+
+func (k *Key) DeleteValue(name string) error {
+	return winapi.RegDeleteValue(k.Handle, syscall.StringToUTF16Ptr(name))
+}
+
+// getValue retrieves type and data of value name.
+func (k *Key) getValue(name string) (uint32, []byte, error) {
+	pname := syscall.StringToUTF16Ptr(name)
+	var t uint32
+	n := uint32(64)
+	for {
+		buf := make([]byte, n)
+		e := syscall.RegQueryValueEx(k.Handle, pname, nil, &t, &buf[0], &n)
+		if e == nil {
+			return t, buf[:n], nil
+		}
+		if e != syscall.ERROR_MORE_DATA {
+			return 0, nil, e
+		}
+	}
+}
+
+// toUTF16 converts registry value data b to uint16 slice.
+func toUTF16(b []byte) []uint16 {
+	if len(b) < 2 {
+		return nil
+	}
+	return winapi.UTF16Slice((*uint16)(unsafe.Pointer(&b[0])), len(b)/2)
+}
+
+func (k *Key) GetUInt32(name string) (uint32, error) {
+	t, b, err := k.getValue(name)
+	if err != nil {
+		return 0, err
+	}
+	if t != syscall.REG_DWORD || len(b) != 4 {
+		return 0, errors.New("registry: value " + name + " is not REG_DWORD")
+	}
+	return *(*uint32)(unsafe.Pointer(&b[0])), nil
+}
+
+// GetString retrieves REG_SZ or REG_EXPAND_SZ value name.
+// Environment variables in REG_EXPAND_SZ value are not expanded.
+func (k *Key) GetString(name string) (string, error) {
+	t, b, err := k.getValue(name)
+	if err != nil {
+		return "", err
+	}
+	if t != syscall.REG_SZ && t != syscall.REG_EXPAND_SZ {
+		return "", errors.New("registry: value " + name + " is not a string")
+	}
+	return syscall.UTF16ToString(toUTF16(b)), nil
+}
+
+// GetStrings retrieves REG_MULTI_SZ value name.
+func (k *Key) GetStrings(name string) ([]string, error) {
+	t, b, err := k.getValue(name)
+	if err != nil {
+		return nil, err
+	}
+	if t != syscall.REG_MULTI_SZ {
+		return nil, errors.New("registry: value " + name + " is not REG_MULTI_SZ")
+	}
+	return winapi.UTF16ToStrings(toUTF16(b)), nil
+}
+
+// GetBinary retrieves REG_BINARY value name.
+func (k *Key) GetBinary(name string) ([]byte, error) {
+	t, b, err := k.getValue(name)
+	if err != nil {
+		return nil, err
+	}
+	if t != syscall.REG_BINARY {
+		return nil, errors.New("registry: value " + name + " is not REG_BINARY")
+	}
+	return b, nil
+}
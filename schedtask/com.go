@@ -0,0 +1,115 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package schedtask
+
+import (
+	"errors"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// Task Scheduler interface methods, numbered in vtable order,
+// after IUnknown and IDispatch methods.
+const (
+	taskServiceGetFolder = 7
+	taskServiceConnect   = 10
+
+	taskFolderGetTask      = 13
+	taskFolderDeleteTask   = 15
+	taskFolderRegisterTask = 16
+
+	registeredTaskState          = 9
+	registeredTaskRun            = 12
+	registeredTaskLastTaskResult = 16
+	registeredTaskStop           = 23
+)
+
+// bstr returns s as BSTR, to be freed with SysFreeString.
+func bstr(s string) (*uint16, error) {
+	p, err := syscall.UTF16PtrFromString(s)
+	if err != nil {
+		return nil, err
+	}
+	b := winapi.SysAllocString(p)
+	if b == nil {
+		return nil, winapi.ERROR_NOT_ENOUGH_MEMORY
+	}
+	return b, nil
+}
+
+// variant returns BSTR b as VARIANT.
+func variant(b *uint16) winapi.VARIANT {
+	return winapi.VARIANT{VT: winapi.VT_BSTR, Val: uintptr(unsafe.Pointer(b))}
+}
+
+// withFolder connects to local Task Scheduler, and calls f with its
+// root folder. COM objects are only used on one locked thread.
+func withFolder(f func(folder unsafe.Pointer) error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	err := winapi.CoInitializeEx(0, winapi.COINIT_MULTITHREADED)
+	switch {
+	case err == nil:
+		defer winapi.CoUninitialize()
+	case errors.Is(err, winapi.RPC_E_CHANGED_MODE):
+		// thread has joined single threaded apartment already
+	default:
+		return err
+	}
+	var ts unsafe.Pointer
+	err = winapi.CoCreateInstance(&winapi.CLSID_TaskScheduler, 0, winapi.CLSCTX_INPROC_SERVER, &winapi.IID_ITaskService, &ts)
+	if err != nil {
+		return err
+	}
+	defer winapi.ComRelease(ts)
+	// empty server, user, domain and password connect to local
+	// Task Scheduler as the current user
+	var empty winapi.VARIANT
+	var p runtime.Pinner
+	defer p.Unpin()
+	var args []uintptr
+	for i := 0; i < 4; i++ {
+		args = append(args, empty.Args(&p)...)
+	}
+	err = winapi.ComCall("ITaskService.Connect", ts, taskServiceConnect, args...)
+	if err != nil {
+		return err
+	}
+	root, err := bstr(`\`)
+	if err != nil {
+		return err
+	}
+	defer winapi.SysFreeString(root)
+	var folder unsafe.Pointer
+	err = winapi.ComCall("ITaskService.GetFolder", ts, taskServiceGetFolder, uintptr(unsafe.Pointer(root)), uintptr(unsafe.Pointer(&folder)))
+	if err != nil {
+		return err
+	}
+	defer winapi.ComRelease(folder)
+	return f(folder)
+}
+
+// with calls f with registered task t.
+func (t *Task) with(f func(task unsafe.Pointer) error) error {
+	return withFolder(func(folder unsafe.Pointer) error {
+		path, err := bstr(t.Name)
+		if err != nil {
+			return err
+		}
+		defer winapi.SysFreeString(path)
+		var task unsafe.Pointer
+		err = winapi.ComCall("ITaskFolder.GetTask", folder, taskFolderGetTask, uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(&task)))
+		if err != nil {
+			return err
+		}
+		defer winapi.ComRelease(task)
+		return f(task)
+	})
+}
@@ -0,0 +1,323 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Package schedtask runs a program at system startup as scheduled task,
+// where registering a service is not permitted. Tasks are registered
+// with Task Scheduler, and are installed, started, stopped and removed
+// much like services with mgr.
+//
+// Task Scheduler knows nothing about service control requests. Program
+// run as task is not a service, so it should run its handler with
+// debug.RunOrConsole, and Stop terminates it without notice.
+//
+package schedtask
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/svc"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// ErrTaskNotFound is returned, when task does not exist.
+var ErrTaskNotFound error = syscall.ERROR_FILE_NOT_FOUND
+
+// Definition describes task to be installed.
+type Definition struct {
+	// Name is task path, like `\Vendor\Name`. Task
+	// is created in root folder, if path has no folder.
+	Name        string
+	ExePath     string
+	Args        []string
+	Description string
+
+	// Account is user task runs as, LocalSystem if "". Password
+	// is not needed for LocalSystem, NT AUTHORITY\LocalService and
+	// NT AUTHORITY\NetworkService. Task of other user, registered
+	// without password, cannot access network resources.
+	Account  string
+	Password string
+
+	// Delay is time task waits after system startup.
+	Delay time.Duration
+
+	// RestartCount, if not 0, is how many times task is restarted,
+	// when it fails, RestartInterval apart. Task Scheduler requires
+	// the interval of at least a minute, and uses a minute, if 0.
+	RestartCount    int
+	RestartInterval time.Duration
+}
+
+// Task is a scheduled task.
+type Task struct {
+	Name string
+}
+
+// Install registers task described by d, replacing task
+// of the same name, if any.
+func Install(d *Definition) (*Task, error) {
+	b, err := d.xml()
+	if err != nil {
+		return nil, err
+	}
+	account, logonType := d.logon()
+	err = withFolder(func(folder unsafe.Pointer) error {
+		path, err := bstr(d.Name)
+		if err != nil {
+			return err
+		}
+		defer winapi.SysFreeString(path)
+		text, err := bstr(string(b))
+		if err != nil {
+			return err
+		}
+		defer winapi.SysFreeString(text)
+		user, err := bstr(account)
+		if err != nil {
+			return err
+		}
+		defer winapi.SysFreeString(user)
+		var password winapi.VARIANT
+		if d.Password != "" {
+			b, err := bstr(d.Password)
+			if err != nil {
+				return err
+			}
+			defer winapi.SysFreeString(b)
+			password = variant(b)
+		}
+		var sddl winapi.VARIANT
+		var p runtime.Pinner
+		defer p.Unpin()
+		var task unsafe.Pointer
+		p.Pin(&task)
+		args := []uintptr{uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(text)), winapi.TASK_CREATE_OR_UPDATE}
+		args = append(args, variant(user).Args(&p)...)
+		args = append(args, password.Args(&p)...)
+		args = append(args, uintptr(logonType))
+		args = append(args, sddl.Args(&p)...)
+		args = append(args, uintptr(unsafe.Pointer(&task)))
+		err = winapi.ComCall("ITaskFolder.RegisterTask", folder, taskFolderRegisterTask, args...)
+		if err != nil {
+			return err
+		}
+		winapi.ComRelease(task)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Task{Name: d.Name}, nil
+}
+
+// Open returns task name, if it exists.
+func Open(name string) (*Task, error) {
+	t := &Task{Name: name}
+	err := t.with(func(unsafe.Pointer) error {
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Start runs task t now, unless it is running already.
+func (t *Task) Start() error {
+	return t.with(func(task unsafe.Pointer) error {
+		var params winapi.VARIANT
+		var p runtime.Pinner
+		defer p.Unpin()
+		var running unsafe.Pointer
+		p.Pin(&running)
+		args := append(params.Args(&p), uintptr(unsafe.Pointer(&running)))
+		err := winapi.ComCall("IRegisteredTask.Run", task, registeredTaskRun, args...)
+		if err != nil {
+			return err
+		}
+		winapi.ComRelease(running)
+		return nil
+	})
+}
+
+// Stop terminates all running instances of task t.
+func (t *Task) Stop() error {
+	return t.with(func(task unsafe.Pointer) error {
+		return winapi.ComCall("IRegisteredTask.Stop", task, registeredTaskStop, 0)
+	})
+}
+
+// Delete removes task t. Running instances keep running.
+func (t *Task) Delete() error {
+	return withFolder(func(folder unsafe.Pointer) error {
+		path, err := bstr(t.Name)
+		if err != nil {
+			return err
+		}
+		defer winapi.SysFreeString(path)
+		return winapi.ComCall("ITaskFolder.DeleteTask", folder, taskFolderDeleteTask, uintptr(unsafe.Pointer(path)), 0)
+	})
+}
+
+// Query returns current state of task t, as Running, StartPending,
+// if it is queued to run, or Stopped. Status has nothing else set.
+func (t *Task) Query() (svc.Status, error) {
+	var state uint32
+	err := t.with(func(task unsafe.Pointer) error {
+		return winapi.ComCall("IRegisteredTask.get_State", task, registeredTaskState, uintptr(unsafe.Pointer(&state)))
+	})
+	if err != nil {
+		return svc.Status{}, err
+	}
+	switch state {
+	case winapi.TASK_STATE_RUNNING:
+		return svc.Status{State: svc.Running}, nil
+	case winapi.TASK_STATE_QUEUED:
+		return svc.Status{State: svc.StartPending}, nil
+	}
+	return svc.Status{State: svc.Stopped}, nil
+}
+
+// ExitCode returns exit code of the last run of task t.
+func (t *Task) ExitCode() (uint32, error) {
+	var ec int32
+	err := t.with(func(task unsafe.Pointer) error {
+		return winapi.ComCall("IRegisteredTask.get_LastTaskResult", task, registeredTaskLastTaskResult, uintptr(unsafe.Pointer(&ec)))
+	})
+	return uint32(ec), err
+}
+
+// builtinAccounts maps names of accounts, that need no
+// password, to names accepted by Task Scheduler.
+var builtinAccounts = map[string]string{
+	"":                             "SYSTEM",
+	"localsystem":                  "SYSTEM",
+	`.\localsystem`:                "SYSTEM",
+	"system":                       "SYSTEM",
+	`nt authority\system`:          "SYSTEM",
+	`nt authority\localservice`:    `NT AUTHORITY\LocalService`,
+	`nt authority\local service`:   `NT AUTHORITY\LocalService`,
+	`nt authority\networkservice`:  `NT AUTHORITY\NetworkService`,
+	`nt authority\network service`: `NT AUTHORITY\NetworkService`,
+}
+
+// logon returns account task d runs as, and its logon type.
+func (d *Definition) logon() (string, int) {
+	if a, ok := builtinAccounts[strings.ToLower(d.Account)]; ok {
+		return a, winapi.TASK_LOGON_SERVICE_ACCOUNT
+	}
+	if d.Password != "" {
+		return d.Account, winapi.TASK_LOGON_PASSWORD
+	}
+	return d.Account, winapi.TASK_LOGON_S4U
+}
+
+type taskXML struct {
+	XMLName     xml.Name     `xml:"Task"`
+	Version     string       `xml:"version,attr"`
+	Namespace   string       `xml:"xmlns,attr"`
+	Description string       `xml:"RegistrationInfo>Description,omitempty"`
+	Trigger     bootXML      `xml:"Triggers>BootTrigger"`
+	Principal   principalXML `xml:"Principals>Principal"`
+	Settings    settingsXML  `xml:"Settings"`
+	Actions     actionsXML   `xml:"Actions"`
+}
+
+type bootXML struct {
+	Enabled bool   `xml:"Enabled"`
+	Delay   string `xml:"Delay,omitempty"`
+}
+
+type principalXML struct {
+	ID       string `xml:"id,attr"`
+	RunLevel string `xml:"RunLevel"`
+}
+
+type settingsXML struct {
+	MultipleInstancesPolicy    string      `xml:"MultipleInstancesPolicy"`
+	DisallowStartIfOnBatteries bool        `xml:"DisallowStartIfOnBatteries"`
+	StopIfGoingOnBatteries     bool        `xml:"StopIfGoingOnBatteries"`
+	ExecutionTimeLimit         string      `xml:"ExecutionTimeLimit"`
+	Priority                   int         `xml:"Priority"`
+	RestartOnFailure           *restartXML `xml:"RestartOnFailure,omitempty"`
+	StartWhenAvailable         bool        `xml:"StartWhenAvailable"`
+	AllowStartOnDemand         bool        `xml:"AllowStartOnDemand"`
+	Enabled                    bool        `xml:"Enabled"`
+}
+
+type restartXML struct {
+	Interval string `xml:"Interval"`
+	Count    int    `xml:"Count"`
+}
+
+type actionsXML struct {
+	Context string  `xml:"Context,attr"`
+	Exec    execXML `xml:"Exec"`
+}
+
+type execXML struct {
+	Command          string `xml:"Command"`
+	Arguments        string `xml:"Arguments,omitempty"`
+	WorkingDirectory string `xml:"WorkingDirectory"`
+}
+
+// xmlDuration formats d as XML duration, like PT90S.
+func xmlDuration(d time.Duration) string {
+	return fmt.Sprintf("PT%dS", int64(d/time.Second))
+}
+
+// xml returns task d in Task Scheduler XML format.
+func (d *Definition) xml() ([]byte, error) {
+	if d.Name == "" || d.ExePath == "" {
+		return nil, errors.New("schedtask: task name or program path is empty")
+	}
+	args := make([]string, len(d.Args))
+	for i, a := range d.Args {
+		args[i] = syscall.EscapeArg(a)
+	}
+	t := taskXML{
+		Version:     "1.2",
+		Namespace:   "http://schemas.microsoft.com/windows/2004/02/mit/task",
+		Description: d.Description,
+		Trigger:     bootXML{Enabled: true},
+		Principal:   principalXML{ID: "Author", RunLevel: "HighestAvailable"},
+		Settings: settingsXML{
+			MultipleInstancesPolicy: "IgnoreNew",
+			ExecutionTimeLimit:      xmlDuration(0), // no limit
+			Priority:                4,              // normal, like services
+			StartWhenAvailable:      true,
+			AllowStartOnDemand:      true,
+			Enabled:                 true,
+		},
+		Actions: actionsXML{
+			Context: "Author",
+			Exec: execXML{
+				Command:          d.ExePath,
+				Arguments:        strings.Join(args, " "),
+				WorkingDirectory: filepath.Dir(d.ExePath),
+			},
+		},
+	}
+	if d.Delay > 0 {
+		t.Trigger.Delay = xmlDuration(d.Delay)
+	}
+	if d.RestartCount > 0 {
+		interval := d.RestartInterval
+		if interval < time.Minute {
+			interval = time.Minute
+		}
+		t.Settings.RestartOnFailure = &restartXML{Interval: xmlDuration(interval), Count: d.RestartCount}
+	}
+	return xml.MarshalIndent(&t, "", "  ")
+}
@@ -0,0 +1,72 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package schedtask
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+func TestXML(t *testing.T) {
+	d := &Definition{
+		Name:         `\Example\echo`,
+		ExePath:      `C:\Program Files\Example\echo.exe`,
+		Args:         []string{"run", "a b"},
+		Description:  "Echo <server>",
+		Delay:        90 * time.Second,
+		RestartCount: 3,
+	}
+	b, err := d.xml()
+	if err != nil {
+		t.Fatalf("xml failed: %v", err)
+	}
+	s := string(b)
+	for _, want := range []string{
+		`<Description>Echo &lt;server&gt;</Description>`,
+		`<Delay>PT90S</Delay>`,
+		`<RestartOnFailure>`,
+		`<Interval>PT60S</Interval>`,
+		`<Count>3</Count>`,
+		`<ExecutionTimeLimit>PT0S</ExecutionTimeLimit>`,
+		`<Command>C:\Program Files\Example\echo.exe</Command>`,
+		`<Arguments>run &#34;a b&#34;</Arguments>`,
+		`<WorkingDirectory>C:\Program Files\Example</WorkingDirectory>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("task XML has no %s:\n%s", want, s)
+		}
+	}
+
+	_, err = (&Definition{Name: "x"}).xml()
+	if err == nil {
+		t.Error("xml of task without program should fail")
+	}
+}
+
+func TestLogon(t *testing.T) {
+	tests := []struct {
+		account, password string
+		user              string
+		logonType         int
+	}{
+		{"", "", "SYSTEM", winapi.TASK_LOGON_SERVICE_ACCOUNT},
+		{"LocalSystem", "", "SYSTEM", winapi.TASK_LOGON_SERVICE_ACCOUNT},
+		{`NT AUTHORITY\NetworkService`, "", `NT AUTHORITY\NetworkService`, winapi.TASK_LOGON_SERVICE_ACCOUNT},
+		{`DOMAIN\user`, "secret", `DOMAIN\user`, winapi.TASK_LOGON_PASSWORD},
+		{`DOMAIN\user`, "", `DOMAIN\user`, winapi.TASK_LOGON_S4U},
+	}
+	for _, test := range tests {
+		d := &Definition{Account: test.account, Password: test.password}
+		user, logonType := d.logon()
+		if user != test.user || logonType != test.logonType {
+			t.Errorf("%q logs on as %q with type %d, want %q with type %d", test.account, user, logonType, test.user, test.logonType)
+		}
+	}
+}
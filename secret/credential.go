@@ -0,0 +1,89 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package secret
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// ErrCredentialNotFound is returned, wrapped, when
+// Credential Manager has no credential for target.
+var ErrCredentialNotFound error = winapi.ERROR_NOT_FOUND
+
+// Credential is a generic credential kept by Windows Credential
+// Manager. Credentials belong to the account that writes them, so
+// credentials written by service are only seen by its account.
+type Credential struct {
+	Target   string // name credential is looked up by, like "myservice/api"
+	UserName string
+	Secret   []byte // at most 2560 bytes
+	Comment  string
+}
+
+// WriteCredential creates or replaces credential c.Target.
+// Credential persists across logon sessions and reboots.
+func WriteCredential(c *Credential) error {
+	if c.Target == "" {
+		return errors.New("secret: credential has no target")
+	}
+	if len(c.Secret) > winapi.CRED_MAX_CREDENTIAL_BLOB_SIZE {
+		return errors.New("secret: credential " + c.Target + " secret is too long")
+	}
+	cred := winapi.CREDENTIAL{
+		Type:               winapi.CRED_TYPE_GENERIC,
+		TargetName:         syscall.StringToUTF16Ptr(c.Target),
+		CredentialBlobSize: uint32(len(c.Secret)),
+		Persist:            winapi.CRED_PERSIST_LOCAL_MACHINE,
+	}
+	if len(c.Secret) > 0 {
+		cred.CredentialBlob = &c.Secret[0]
+	}
+	if c.UserName != "" {
+		cred.UserName = syscall.StringToUTF16Ptr(c.UserName)
+	}
+	if c.Comment != "" {
+		cred.Comment = syscall.StringToUTF16Ptr(c.Comment)
+	}
+	return winapi.CredWrite(&cred, 0)
+}
+
+// ReadCredential returns credential target. It fails with
+// ErrCredentialNotFound, if there is no such credential.
+func ReadCredential(target string) (*Credential, error) {
+	var p *winapi.CREDENTIAL
+	err := winapi.CredRead(syscall.StringToUTF16Ptr(target), winapi.CRED_TYPE_GENERIC, 0, &p)
+	if err != nil {
+		return nil, err
+	}
+	defer winapi.CredFree(uintptr(unsafe.Pointer(p)))
+	c := &Credential{
+		Target:   winapi.UTF16PtrToString(p.TargetName, 1<<15),
+		UserName: toString(p.UserName),
+		Comment:  toString(p.Comment),
+	}
+	if p.CredentialBlob != nil {
+		c.Secret = append([]byte(nil), unsafe.Slice(p.CredentialBlob, p.CredentialBlobSize)...)
+	}
+	return c, nil
+}
+
+// DeleteCredential deletes credential target. It fails with
+// ErrCredentialNotFound, if there is no such credential.
+func DeleteCredential(target string) error {
+	return winapi.CredDelete(syscall.StringToUTF16Ptr(target), winapi.CRED_TYPE_GENERIC, 0)
+}
+
+func toString(p *uint16) string {
+	if p == nil {
+		return ""
+	}
+	return winapi.UTF16PtrToString(p, 1<<15)
+}
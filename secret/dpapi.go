@@ -0,0 +1,124 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Package secret keeps service secrets, like connection strings and
+// passwords, out of plain text. Protect and Unprotect encrypt data
+// with DPAPI, and SetParameter and Parameter store encrypted values
+// in the service Parameters registry key. WriteCredential and
+// ReadCredential keep credentials in Windows Credential Manager.
+// StorePrivateData keeps install time secrets as LSA private data,
+// and GrantRights grants account rights, like SeServiceLogonRight.
+//
+package secret
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/registry"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// Scope decides who can decrypt protected data.
+type Scope int
+
+const (
+	// UserScope lets only the account that protected data decrypt it.
+	// When service protects data, that is the service account.
+	UserScope Scope = iota
+
+	// MachineScope lets any process on the computer decrypt data.
+	MachineScope
+)
+
+func newBlob(b []byte) *winapi.DATA_BLOB {
+	if len(b) == 0 {
+		return &winapi.DATA_BLOB{}
+	}
+	return &winapi.DATA_BLOB{Size: uint32(len(b)), Data: &b[0]}
+}
+
+// flags returns CryptProtectData flags for scope s.
+func (s Scope) flags() uint32 {
+	f := uint32(winapi.CRYPTPROTECT_UI_FORBIDDEN)
+	if s == MachineScope {
+		f |= winapi.CRYPTPROTECT_LOCAL_MACHINE
+	}
+	return f
+}
+
+// takeBlob returns copy of blob b allocated by DPAPI, and frees b.
+func takeBlob(b *winapi.DATA_BLOB) []byte {
+	if b.Data == nil {
+		return nil
+	}
+	defer syscall.LocalFree(syscall.Handle(unsafe.Pointer(b.Data)))
+	return append([]byte(nil), unsafe.Slice(b.Data, b.Size)...)
+}
+
+// Protect encrypts data, so only scope can decrypt it with Unprotect.
+func Protect(data []byte, scope Scope) ([]byte, error) {
+	var out winapi.DATA_BLOB
+	err := winapi.CryptProtectData(newBlob(data), nil, nil, 0, 0, scope.flags(), &out)
+	if err != nil {
+		return nil, err
+	}
+	return takeBlob(&out), nil
+}
+
+// Unprotect decrypts data encrypted by Protect.
+func Unprotect(data []byte) ([]byte, error) {
+	var out winapi.DATA_BLOB
+	err := winapi.CryptUnprotectData(newBlob(data), nil, nil, 0, 0, winapi.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	if err != nil {
+		return nil, err
+	}
+	return takeBlob(&out), nil
+}
+
+const servicesKeyName = `SYSTEM\CurrentControlSet\Services`
+
+// SetParameter encrypts value with scope, and stores it as name in
+// Parameters registry key of service, creating the key as required.
+// Use MachineScope, when installer sets parameters for service that
+// runs under another account.
+func SetParameter(service, name string, value []byte, scope Scope) error {
+	b, err := Protect(value, scope)
+	if err != nil {
+		return err
+	}
+	k, err := registry.OpenKey(syscall.HKEY_LOCAL_MACHINE, servicesKeyName+`\`+service)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	p, _, err := k.CreateSubKey("Parameters")
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	return p.SetBinary(name, b)
+}
+
+// Parameter returns value name stored by SetParameter
+// in Parameters registry key of service, decrypted.
+func Parameter(service, name string) ([]byte, error) {
+	k, err := registry.OpenKeyAccess(syscall.HKEY_LOCAL_MACHINE,
+		servicesKeyName+`\`+service+`\Parameters`, syscall.KEY_READ)
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+	b, err := k.GetBinary(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("secret: parameter " + name + " is empty")
+	}
+	return Unprotect(b)
+}
@@ -0,0 +1,136 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package secret
+
+import (
+	"errors"
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// SeServiceLogonRight is the right to log on as a service. Account
+// service runs under must have it, GrantRights gives it.
+const SeServiceLogonRight = "SeServiceLogonRight"
+
+// ErrPrivateDataNotFound is returned, wrapped, by
+// RetrievePrivateData, when there is no data stored for key.
+var ErrPrivateDataNotFound error = syscall.ERROR_FILE_NOT_FOUND
+
+// lsaError returns error of LSA function fn, that returned status,
+// or nil, if status reports success.
+func lsaError(fn string, status uint32) error {
+	if status == 0 {
+		return nil
+	}
+	return &winapi.CallError{Func: fn, Errno: syscall.Errno(winapi.LsaNtStatusToWinError(status))}
+}
+
+// newLSAString returns s as LSA_UNICODE_STRING.
+func newLSAString(s string) *winapi.LSA_UNICODE_STRING {
+	u := utf16.Encode([]rune(s))
+	return newLSABytes(u)
+}
+
+func newLSABytes(u []uint16) *winapi.LSA_UNICODE_STRING {
+	if len(u) == 0 {
+		return &winapi.LSA_UNICODE_STRING{}
+	}
+	return &winapi.LSA_UNICODE_STRING{
+		Length:        uint16(2 * len(u)),
+		MaximumLength: uint16(2 * len(u)),
+		Buffer:        &u[0],
+	}
+}
+
+// openPolicy opens LSA policy object of local computer.
+func openPolicy(access uint32) (syscall.Handle, error) {
+	var attrs winapi.LSA_OBJECT_ATTRIBUTES
+	var h syscall.Handle
+	err := lsaError("LsaOpenPolicy", winapi.LsaOpenPolicy(nil, &attrs, access, &h))
+	return h, err
+}
+
+// StorePrivateData stores data as LSA private data under key, where
+// only administrators and LocalSystem can read it. Keys starting with
+// "L$" are never replicated off the computer. Nil data deletes key.
+// Data is stored in 2 byte units, so odd length data is padded with 0.
+func StorePrivateData(key string, data []byte) error {
+	h, err := openPolicy(winapi.POLICY_CREATE_SECRET)
+	if err != nil {
+		return err
+	}
+	defer winapi.LsaClose(h)
+	var p *winapi.LSA_UNICODE_STRING
+	if data != nil {
+		if len(data) > 0xfffe {
+			return errors.New("secret: private data for " + key + " is too long")
+		}
+		u := make([]uint16, (len(data)+1)/2)
+		if len(u) > 0 {
+			copy(unsafe.Slice((*byte)(unsafe.Pointer(&u[0])), 2*len(u)), data)
+		}
+		p = newLSABytes(u)
+	}
+	return lsaError("LsaStorePrivateData", winapi.LsaStorePrivateData(h, newLSAString(key), p))
+}
+
+// RetrievePrivateData returns data stored under key by StorePrivateData.
+func RetrievePrivateData(key string) ([]byte, error) {
+	h, err := openPolicy(winapi.POLICY_GET_PRIVATE_INFORMATION)
+	if err != nil {
+		return nil, err
+	}
+	defer winapi.LsaClose(h)
+	var p *winapi.LSA_UNICODE_STRING
+	err = lsaError("LsaRetrievePrivateData", winapi.LsaRetrievePrivateData(h, newLSAString(key), &p))
+	if err != nil {
+		return nil, err
+	}
+	defer winapi.LsaFreeMemory(uintptr(unsafe.Pointer(p)))
+	if p.Buffer == nil {
+		return []byte{}, nil
+	}
+	return append([]byte(nil), unsafe.Slice((*byte)(unsafe.Pointer(p.Buffer)), p.Length)...), nil
+}
+
+// accountRights adds or removes rights of account.
+func accountRights(account string, rights []string, add bool) error {
+	sid, _, _, err := syscall.LookupSID("", account)
+	if err != nil {
+		return err
+	}
+	h, err := openPolicy(winapi.POLICY_CREATE_ACCOUNT | winapi.POLICY_LOOKUP_NAMES)
+	if err != nil {
+		return err
+	}
+	defer winapi.LsaClose(h)
+	rs := make([]winapi.LSA_UNICODE_STRING, len(rights))
+	for i, r := range rights {
+		rs[i] = *newLSAString(r)
+	}
+	if len(rs) == 0 {
+		return nil
+	}
+	if add {
+		return lsaError("LsaAddAccountRights", winapi.LsaAddAccountRights(h, sid, &rs[0], uint32(len(rs))))
+	}
+	return lsaError("LsaRemoveAccountRights", winapi.LsaRemoveAccountRights(h, sid, false, &rs[0], uint32(len(rs))))
+}
+
+// GrantRights grants account rights, like SeServiceLogonRight,
+// the way ntrights.exe does.
+func GrantRights(account string, rights ...string) error {
+	return accountRights(account, rights, true)
+}
+
+// RevokeRights removes rights granted by GrantRights from account.
+func RevokeRights(account string, rights ...string) error {
+	return accountRights(account, rights, false)
+}
@@ -0,0 +1,94 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package secret_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"github.com/multiplay/winsvc/secret"
+)
+
+func TestProtect(t *testing.T) {
+	data := []byte("Server=db;Password=hunter2")
+	for _, scope := range []secret.Scope{secret.UserScope, secret.MachineScope} {
+		b, err := secret.Protect(data, scope)
+		if err != nil {
+			t.Fatalf("Protect failed: %v", err)
+		}
+		if bytes.Contains(b, data) {
+			t.Fatalf("protected data contains plain text")
+		}
+		got, err := secret.Unprotect(b)
+		if err != nil {
+			t.Fatalf("Unprotect failed: %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("Unprotect returned %q, want %q", got, data)
+		}
+	}
+}
+
+func TestCredential(t *testing.T) {
+	c := &secret.Credential{
+		Target:   "winsvc-test/credential",
+		UserName: "api",
+		Secret:   []byte("token"),
+		Comment:  "written by winsvc test",
+	}
+	err := secret.WriteCredential(c)
+	if err != nil {
+		t.Fatalf("WriteCredential failed: %v", err)
+	}
+	defer secret.DeleteCredential(c.Target)
+	got, err := secret.ReadCredential(c.Target)
+	if err != nil {
+		t.Fatalf("ReadCredential failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, c) {
+		t.Errorf("ReadCredential returned %+v, want %+v", got, c)
+	}
+	err = secret.DeleteCredential(c.Target)
+	if err != nil {
+		t.Fatalf("DeleteCredential failed: %v", err)
+	}
+	_, err = secret.ReadCredential(c.Target)
+	if !errors.Is(err, secret.ErrCredentialNotFound) {
+		t.Errorf("ReadCredential of deleted credential returned %v, want %v", err, secret.ErrCredentialNotFound)
+	}
+}
+
+func TestPrivateData(t *testing.T) {
+	const key = "L$winsvc-test"
+	data := []byte("hunter2!")
+	err := secret.StorePrivateData(key, data)
+	if errors.Is(err, syscall.ERROR_ACCESS_DENIED) {
+		t.Skip("storing LSA private data requires administrator")
+	}
+	if err != nil {
+		t.Fatalf("StorePrivateData failed: %v", err)
+	}
+	defer secret.StorePrivateData(key, nil)
+	got, err := secret.RetrievePrivateData(key)
+	if err != nil {
+		t.Fatalf("RetrievePrivateData failed: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("RetrievePrivateData returned %q, want %q", got, data)
+	}
+	err = secret.StorePrivateData(key, nil)
+	if err != nil {
+		t.Fatalf("deleting private data failed: %v", err)
+	}
+	_, err = secret.RetrievePrivateData(key)
+	if !errors.Is(err, secret.ErrPrivateDataNotFound) {
+		t.Errorf("RetrievePrivateData of deleted key returned %v, want %v", err, secret.ErrPrivateDataNotFound)
+	}
+}
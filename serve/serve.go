@@ -0,0 +1,237 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Package serve runs network servers, like net/http and gRPC servers,
+// as service handler. Once service is asked to stop, servers are shut
+// down gracefully, while StopPending progress is reported, and closed,
+// if they do not finish in time.
+//
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/multiplay/winsvc/svc"
+)
+
+// DefaultTimeout is time servers have to shut down, if
+// Options.Timeout is 0. It leaves a few seconds of 20 seconds
+// Windows waits for services to stop on system shutdown.
+const DefaultTimeout = 15 * time.Second
+
+// progressInterval is how often StopPending progress is reported.
+const progressInterval = time.Second
+
+// Server is network server run by Handler.
+type Server interface {
+	// Serve serves requests until server is shut down or closed,
+	// and returns nil then.
+	Serve() error
+
+	// Shutdown stops server gracefully, and returns once it has
+	// stopped, or ctx is done.
+	Shutdown(ctx context.Context) error
+
+	// Close stops server immediately.
+	Close() error
+}
+
+// HTTP returns Server that runs srv on listener ln.
+func HTTP(srv *http.Server, ln net.Listener) Server {
+	return &httpServer{srv, ln}
+}
+
+type httpServer struct {
+	srv *http.Server
+	ln  net.Listener
+}
+
+func (s *httpServer) Serve() error {
+	err := s.srv.Serve(s.ln)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (s *httpServer) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+func (s *httpServer) Close() error {
+	return s.srv.Close()
+}
+
+// GRPCServer is the part of *grpc.Server used by GRPC.
+type GRPCServer interface {
+	Serve(ln net.Listener) error
+	GracefulStop()
+	Stop()
+}
+
+// GRPC returns Server that runs gRPC server srv on listener ln.
+// Shutdown calls srv.GracefulStop, and Close calls srv.Stop.
+func GRPC(srv GRPCServer, ln net.Listener) Server {
+	return &grpcServer{srv, ln}
+}
+
+type grpcServer struct {
+	srv GRPCServer
+	ln  net.Listener
+}
+
+func (s *grpcServer) Serve() error {
+	return s.srv.Serve(s.ln)
+}
+
+func (s *grpcServer) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *grpcServer) Close() error {
+	s.srv.Stop()
+	return nil
+}
+
+// Options configure Handler.
+type Options struct {
+	// Timeout is time servers have to shut down gracefully,
+	// before they are closed, DefaultTimeout if 0.
+	Timeout time.Duration
+
+	// Accepts lists stop requests service accepts, besides Stop.
+	// Set it to svc.AcceptShutdown or svc.AcceptPreShutdown, so
+	// servers are shut down gracefully on system shutdown too.
+	Accepts svc.Accepted
+
+	// Log, if not nil, receives errors of servers.
+	Log svc.Logger
+}
+
+// Handler returns service handler, that runs servers, until it is
+// asked to stop. Service stops with service specific exit code 1, if
+// any server fails, after the rest of servers are shut down.
+func Handler(opts Options, servers ...Server) svc.Handler {
+	return &handler{opts: opts, servers: servers}
+}
+
+type handler struct {
+	opts    Options
+	servers []Server
+}
+
+func (h *handler) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+	errc := make(chan error, len(h.servers))
+	var wg sync.WaitGroup
+	for _, s := range h.servers {
+		wg.Add(1)
+		go func(s Server) {
+			defer wg.Done()
+			err := s.Serve()
+			if err != nil {
+				errc <- err
+			}
+		}(s)
+	}
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | h.opts.Accepts}
+	var ec uint32
+loop:
+	for {
+		select {
+		case err := <-errc:
+			h.logError("server failed: " + err.Error())
+			ec = 1
+			break loop
+		case <-stopped:
+			h.logError("servers stopped unexpectedly")
+			ec = 1
+			break loop
+		case c := <-r:
+			switch c.Cmd {
+			case svc.Interrogate:
+				changes <- c.CurrentStatus
+			case svc.Stop, svc.Shutdown, svc.PreShutdown:
+				break loop
+			}
+		}
+	}
+	h.shutdown(r, changes, stopped)
+	return ec != 0, ec
+}
+
+// shutdown shuts servers down, reporting StopPending progress,
+// and waits for all of them to return from Serve.
+func (h *handler) shutdown(r <-chan svc.ChangeRequest, changes chan<- svc.Status, stopped <-chan struct{}) {
+	timeout := h.opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, s := range h.servers {
+		wg.Add(1)
+		go func(s Server) {
+			defer wg.Done()
+			err := s.Shutdown(ctx)
+			if err != nil {
+				h.logError(fmt.Sprintf("server did not shut down gracefully: %v", err))
+				s.Close()
+			}
+		}(s)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		<-stopped
+		close(done)
+	}()
+	status := svc.Status{State: svc.StopPending, CheckPoint: 1, WaitHint: uint32(3 * progressInterval / time.Millisecond)}
+	changes <- status
+	tick := time.NewTicker(progressInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-tick.C:
+			status.CheckPoint++
+			changes <- status
+		case c := <-r:
+			if c.Cmd == svc.Interrogate {
+				changes <- c.CurrentStatus
+			}
+		}
+	}
+}
+
+func (h *handler) logError(msg string) {
+	if h.opts.Log != nil {
+		h.opts.Log.Error(1, msg)
+	}
+}
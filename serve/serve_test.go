@@ -0,0 +1,116 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package serve_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/multiplay/winsvc/serve"
+	"github.com/multiplay/winsvc/svc"
+	"github.com/multiplay/winsvc/svctest"
+)
+
+func listen(t *testing.T) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	return ln
+}
+
+func TestHTTP(t *testing.T) {
+	ln := listen(t)
+	served := make(chan struct{})
+	release := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(served)
+		<-release
+		io.WriteString(w, "done")
+	})}
+	got := make(chan string, 1)
+	go func() {
+		<-time.After(100 * time.Millisecond)
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			got <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		got <- string(b)
+	}()
+	go func() {
+		<-served
+		// request in flight must finish, once service is asked to stop
+		time.Sleep(1500 * time.Millisecond)
+		close(release)
+	}()
+	script := svctest.Script{
+		Steps: []svctest.Step{
+			{Expect: []svc.State{svc.StartPending, svc.Running}},
+			{After: 500 * time.Millisecond, Cmd: svc.Stop, Expect: []svc.State{svc.StopPending, svc.Stopped}},
+		},
+	}
+	script.Run(t, serve.Handler(serve.Options{}, serve.HTTP(srv, ln)))
+	if s := <-got; s != "done" {
+		t.Errorf("response is %q, want %q", s, "done")
+	}
+}
+
+// fakeGRPC never stops gracefully, GracefulStop
+// returns only once Stop is called.
+type fakeGRPC struct {
+	stop chan struct{}
+}
+
+func (s *fakeGRPC) Serve(ln net.Listener) error {
+	<-s.stop
+	return nil
+}
+
+func (s *fakeGRPC) GracefulStop() {
+	<-s.stop
+}
+
+func (s *fakeGRPC) Stop() {
+	close(s.stop)
+}
+
+func TestGRPCTimeout(t *testing.T) {
+	s := &fakeGRPC{stop: make(chan struct{})}
+	script := svctest.Script{
+		Steps: []svctest.Step{
+			{Expect: []svc.State{svc.StartPending, svc.Running}},
+			{Cmd: svc.Stop, Expect: []svc.State{svc.StopPending, svc.Stopped}},
+		},
+	}
+	script.Run(t, serve.Handler(serve.Options{Timeout: 100 * time.Millisecond}, serve.GRPC(s, nil)))
+}
+
+// failing fails to serve.
+type failing struct{}
+
+func (failing) Serve() error                       { return errors.New("failed") }
+func (failing) Shutdown(ctx context.Context) error { return nil }
+func (failing) Close() error                       { return nil }
+
+func TestServerFailure(t *testing.T) {
+	s := &fakeGRPC{stop: make(chan struct{})}
+	script := svctest.Script{
+		Steps: []svctest.Step{
+			{Expect: []svc.State{svc.StartPending, svc.Running, svc.StopPending, svc.Stopped}},
+		},
+		ExitCode: 1,
+	}
+	script.Run(t, serve.Handler(serve.Options{Timeout: 100 * time.Millisecond}, failing{}, serve.GRPC(s, nil)))
+}
@@ -0,0 +1,201 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package session
+
+import (
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// State describes connection state of a session.
+type State uint32
+
+const (
+	Active       = State(winapi.WTSActive)
+	Connected    = State(winapi.WTSConnected)
+	Disconnected = State(winapi.WTSDisconnected)
+	Idle         = State(winapi.WTSIdle)
+	Listen       = State(winapi.WTSListen)
+)
+
+// Session describes a session on local computer.
+type Session struct {
+	ID    uint32
+	Name  string // window station name, like "Console" or "RDP-Tcp#3"
+	State State
+}
+
+// List returns all sessions on local computer.
+func List() ([]Session, error) {
+	var p *winapi.WTS_SESSION_INFO
+	var n uint32
+	err := winapi.WTSEnumerateSessions(winapi.WTS_CURRENT_SERVER_HANDLE, 0, 1, &p, &n)
+	if err != nil {
+		return nil, err
+	}
+	defer winapi.WTSFreeMemory(uintptr(unsafe.Pointer(p)))
+	if n == 0 {
+		return nil, nil
+	}
+	infos := unsafe.Slice(p, n)
+	ss := make([]Session, n)
+	for i, info := range infos {
+		ss[i] = Session{
+			ID:    info.SessionID,
+			Name:  winapi.UTF16PtrToString(info.WinStationName, 1<<10),
+			State: State(info.State),
+		}
+	}
+	return ss, nil
+}
+
+// User returns name of user logged on to session id in
+// DOMAIN\user form. It returns empty string, if nobody is logged on.
+func User(id uint32) (string, error) {
+	user, err := queryString(id, winapi.WTSUserName)
+	if err != nil || user == "" {
+		return "", err
+	}
+	domain, err := queryString(id, winapi.WTSDomainName)
+	if err != nil {
+		return "", err
+	}
+	if domain == "" {
+		return user, nil
+	}
+	return domain + `\` + user, nil
+}
+
+func queryString(id uint32, class uint32) (string, error) {
+	var p *uint16
+	var n uint32
+	err := winapi.WTSQuerySessionInformation(winapi.WTS_CURRENT_SERVER_HANDLE, id, class, &p, &n)
+	if err != nil {
+		return "", err
+	}
+	defer winapi.WTSFreeMemory(uintptr(unsafe.Pointer(p)))
+	if n < 2 {
+		return "", nil
+	}
+	return syscall.UTF16ToString(winapi.UTF16Slice(p, int(n/2))), nil
+}
+
+// Buttons selects buttons displayed in a message box.
+type Buttons uint32
+
+const (
+	OK               = Buttons(winapi.MB_OK)
+	OKCancel         = Buttons(winapi.MB_OKCANCEL)
+	YesNo            = Buttons(winapi.MB_YESNO)
+	YesNoCancel      = Buttons(winapi.MB_YESNOCANCEL)
+	RetryCancel      = Buttons(winapi.MB_RETRYCANCEL)
+	AbortRetryIgnore = Buttons(winapi.MB_ABORTRETRYIGNORE)
+)
+
+// Icon selects icon displayed in a message box.
+type Icon uint32
+
+const (
+	NoIcon          = Icon(0)
+	InformationIcon = Icon(winapi.MB_ICONINFORMATION)
+	WarningIcon     = Icon(winapi.MB_ICONWARNING)
+	ErrorIcon       = Icon(winapi.MB_ICONERROR)
+	QuestionIcon    = Icon(winapi.MB_ICONQUESTION)
+)
+
+// Response is the user's reply to a message.
+type Response uint32
+
+const (
+	ResponseOK      = Response(winapi.IDOK)
+	ResponseCancel  = Response(winapi.IDCANCEL)
+	ResponseAbort   = Response(winapi.IDABORT)
+	ResponseRetry   = Response(winapi.IDRETRY)
+	ResponseIgnore  = Response(winapi.IDIGNORE)
+	ResponseYes     = Response(winapi.IDYES)
+	ResponseNo      = Response(winapi.IDNO)
+	ResponseTimeout = Response(winapi.IDTIMEOUT) // message was closed after Message.Timeout
+	ResponseAsync   = Response(winapi.IDASYNC)   // message was sent without waiting
+)
+
+// Message is a message box displayed in a user session.
+type Message struct {
+	Title   string
+	Text    string
+	Buttons Buttons
+	Icon    Icon
+
+	// Timeout closes the message box, if user does not respond in time.
+	// It is rounded up to whole seconds. Zero means wait forever.
+	Timeout time.Duration
+
+	// Wait makes Send wait for the user response.
+	Wait bool
+}
+
+// Send displays message m in session id, and returns user's response.
+// The response is ResponseAsync, unless m.Wait is set.
+func Send(id uint32, m Message) (Response, error) {
+	title := syscall.StringToUTF16(m.Title)
+	text := syscall.StringToUTF16(m.Text)
+	style := uint32(m.Buttons) | uint32(m.Icon) | winapi.MB_SETFOREGROUND | winapi.MB_TOPMOST
+	var r uint32
+	err := winapi.WTSSendMessage(winapi.WTS_CURRENT_SERVER_HANDLE, id,
+		&title[0], uint32((len(title)-1)*2), &text[0], uint32((len(text)-1)*2),
+		style, timeoutSeconds(m.Timeout), &r, m.Wait)
+	if err != nil {
+		return 0, err
+	}
+	return Response(r), nil
+}
+
+// timeoutSeconds returns d in whole seconds, rounded up,
+// as 0 seconds makes WTSSendMessage wait forever.
+func timeoutSeconds(d time.Duration) uint32 {
+	if d <= 0 {
+		return 0
+	}
+	return uint32((d + time.Second - 1) / time.Second)
+}
+
+// Broadcast displays message m in all active sessions, and returns
+// responses keyed by session id. Messages are sent concurrently,
+// so waiting for one user does not delay others.
+func Broadcast(m Message) (map[uint32]Response, error) {
+	ss, err := List()
+	if err != nil {
+		return nil, err
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	rs := make(map[uint32]Response)
+	for _, s := range ss {
+		if s.State != Active {
+			continue
+		}
+		wg.Add(1)
+		go func(id uint32) {
+			defer wg.Done()
+			r, err2 := Send(id, m)
+			mu.Lock()
+			defer mu.Unlock()
+			if err2 != nil {
+				if err == nil {
+					err = err2
+				}
+				return
+			}
+			rs[id] = r
+		}(s.ID)
+	}
+	wg.Wait()
+	return rs, err
+}
@@ -0,0 +1,82 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Package session lets a service interact with user sessions.
+// Services run in session 0 and cannot show any user interface
+// themselves. Instead they can start a helper program in the
+// session of a logged on user.
+//
+package session
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// ErrNoConsoleSession is returned by ActiveConsole,
+// if there is no session attached to the physical console.
+var ErrNoConsoleSession = errors.New("no session is attached to the console")
+
+// ActiveConsole returns id of the session attached to the physical console.
+func ActiveConsole() (uint32, error) {
+	id := winapi.WTSGetActiveConsoleSessionId()
+	if id == winapi.NO_ACTIVE_CONSOLE_SESSION {
+		return 0, ErrNoConsoleSession
+	}
+	return id, nil
+}
+
+// StartProcess starts program exe with arguments args in session id,
+// running as the user logged on to that session. The process gets
+// that user's environment and interactive desktop. If dir is not empty,
+// it becomes working directory of the new process. The caller must be
+// running as LocalSystem.
+func StartProcess(id uint32, exe string, args []string, dir string) (*os.Process, error) {
+	var t syscall.Token
+	err := winapi.WTSQueryUserToken(id, &t)
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+
+	var env *uint16
+	err = winapi.CreateEnvironmentBlock(&env, t, false)
+	if err != nil {
+		return nil, err
+	}
+	defer winapi.DestroyEnvironmentBlock(env)
+
+	cmdline := syscall.EscapeArg(exe)
+	for _, a := range args {
+		cmdline += " " + syscall.EscapeArg(a)
+	}
+	var pdir *uint16
+	if dir != "" {
+		pdir = syscall.StringToUTF16Ptr(dir)
+	}
+	si := syscall.StartupInfo{
+		Desktop: syscall.StringToUTF16Ptr(`winsta0\default`),
+	}
+	si.Cb = uint32(unsafe.Sizeof(si))
+	var pi syscall.ProcessInformation
+	err = syscall.CreateProcessAsUser(t, syscall.StringToUTF16Ptr(exe),
+		syscall.StringToUTF16Ptr(cmdline), nil, nil, false,
+		winapi.CREATE_UNICODE_ENVIRONMENT|winapi.CREATE_NEW_CONSOLE,
+		env, pdir, &si, &pi)
+	if err != nil {
+		return nil, err
+	}
+	syscall.CloseHandle(pi.Thread)
+	// pi.Process is held open until the process is found by its id,
+	// so the id cannot be reused by another process meanwhile
+	p, err := os.FindProcess(int(pi.ProcessId))
+	syscall.CloseHandle(pi.Process)
+	return p, err
+}
@@ -0,0 +1,64 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"os"
+	"strings"
+)
+
+// Args tells apart arguments service gets from two sources. Handler
+// receives args of ServiceMain: service name first, followed by
+// arguments passed to StartService, like "sc start name -x". Arguments
+// set at install time, in service ImagePath (command line), are not
+// there; they are in os.Args, like for any other process.
+type Args struct {
+	Name  string   // service name, as passed to ServiceMain
+	Start []string // arguments passed to StartService
+	Image []string // arguments from ImagePath, without program name
+}
+
+// ParseArgs returns Args for args, that handler received in Execute.
+func ParseArgs(args []string) Args {
+	a := Args{Image: os.Args[1:]}
+	if len(args) > 0 {
+		a.Name = args[0]
+		a.Start = args[1:]
+	}
+	return a
+}
+
+// InstanceArg is image argument, that names instance of service
+// installed more than once from the same executable, like
+// "-instance=eu1". install.Definition.Instance adds it.
+const InstanceArg = "-instance"
+
+// InstanceSeparator separates base name and instance in service
+// name, like "game@eu1". install.Template names instances with it.
+const InstanceSeparator = "@"
+
+// Instance returns name of instance service runs as, when the same
+// executable is installed as several services, like one per game
+// title. It is value of InstanceArg among image arguments, if present.
+// Otherwise it is derived from service name, as part of a.Name after
+// base and InstanceSeparator, like "eu1" of "game@eu1". Instance
+// returns "", if service name is base itself, or does not start with it.
+func (a Args) Instance(base string) string {
+	for i, s := range a.Image {
+		switch {
+		case strings.HasPrefix(s, InstanceArg+"="):
+			return s[len(InstanceArg)+1:]
+		case s == InstanceArg && i+1 < len(a.Image):
+			return a.Image[i+1]
+		}
+	}
+	prefix := base + InstanceSeparator
+	if len(a.Name) <= len(prefix) || !strings.EqualFold(a.Name[:len(prefix)], prefix) {
+		return ""
+	}
+	return a.Name[len(prefix):]
+}
@@ -0,0 +1,202 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"errors"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// Windows calls serviceMain and ctlHandler with no way to pass
+// Go pointers to them, so they find their service in current.
+// There is one service running per process.
+var (
+	currentMu sync.Mutex
+	current   *service
+
+	winMain func(args []string) // passed to SystemSCM Dispatch
+	winCtl  ControlHandler      // passed to SystemSCM Register
+)
+
+// setCurrent makes s the service callbacks are delivered to.
+// Use nil s, once s is stopped.
+func setCurrent(s *service) error {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	if s != nil && current != nil {
+		return errors.New("svc: service " + current.name + " is running already")
+	}
+	current = s
+	return nil
+}
+
+func currentService() *service {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	return current
+}
+
+// setWinCallbacks sets functions, that Windows callbacks
+// call, to main and ctl, unless they are nil.
+func setWinCallbacks(main func(args []string), ctl ControlHandler) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	if main != nil {
+		winMain = main
+	}
+	if ctl != nil {
+		winCtl = ctl
+	}
+}
+
+// Callbacks are created once, because
+// a process can only create a limited number.
+var (
+	callbacksOnce       sync.Once
+	serviceMainCallback uintptr
+	ctlHandlerCallback  uintptr
+	callbacksErr        error
+)
+
+// callbacks returns serviceMain and winCtlHandler as Windows callbacks.
+func callbacks() (main, ctl uintptr, err error) {
+	callbacksOnce.Do(func() {
+		serviceMainCallback, callbacksErr = newCallback(serviceMain)
+		if callbacksErr != nil {
+			return
+		}
+		ctlHandlerCallback, callbacksErr = newCallback(winCtlHandler)
+	})
+	return serviceMainCallback, ctlHandlerCallback, callbacksErr
+}
+
+func newCallback(fn interface{}) (cb uintptr, err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		cb = 0
+		switch v := r.(type) {
+		case string:
+			err = errors.New(v)
+		case error:
+			err = v
+		default:
+			err = errors.New("unexpected panic in syscall.NewCallback")
+		}
+	}()
+	return syscall.NewCallback(fn), nil
+}
+
+// serviceMain is LPSERVICE_MAIN_FUNCTIONW, that SystemSCM
+// Dispatch passes to Windows. It calls main passed to Dispatch.
+func serviceMain(argc uint32, argv **uint16) uintptr {
+	currentMu.Lock()
+	main := winMain
+	currentMu.Unlock()
+	if main != nil {
+		main(serviceArgs(argc, argv))
+	}
+	return 0
+}
+
+// winCtlHandler is LPHANDLER_FUNCTION_EX, that SystemSCM Register
+// passes to Windows. It calls handler passed to Register.
+func winCtlHandler(ctl uint32, eventType uint32, eventData uintptr, context uintptr) uintptr {
+	currentMu.Lock()
+	h := winCtl
+	currentMu.Unlock()
+	if h == nil {
+		return 0
+	}
+	return h(ctl, eventType, eventData, context)
+}
+
+// main is called by the service control manager, once service s
+// is started. It registers control handler and runs service loop.
+func (s *service) main(args []string) {
+	s.lc.begin = time.Now()
+	s.recordStartup()
+	h, err := s.scm.Register(s.name, ctlHandler)
+	if err != nil {
+		s.fail(errors.New("service " + s.name + " failed to register control handler: " + err.Error()))
+		close(s.done)
+		return
+	}
+	s.recordStartReason(h)
+	s.loop(h, args)
+}
+
+// ctlHandler is control handler of service, see ControlHandler.
+// It queues control request for service loop, see ctlQueue.
+func ctlHandler(ctl uint32, eventType uint32, eventData uintptr, context uintptr) uintptr {
+	s := currentService()
+	if s == nil {
+		return 0
+	}
+	// We assume that this callback function is running on
+	// the same thread as Run. Nowhere in MS documentation
+	// I could find statement to guarantee that. So putting
+	// check here to verify, otherwise things will go bad
+	// quickly, if ignored.
+	if s.tid != 0 && winapi.GetCurrentThreadId() != s.tid {
+		s.queue.fail(uint32(ExitNewThreadInCallback))
+		return 0
+	}
+	s.queue.push(Cmd(ctl), eventType, eventData, context)
+	return 0
+}
+
+// maxEventData limits size of event data queued with control
+// request. It is large enough for session change and power setting
+// events, and device interface names. Larger event data is dropped.
+const maxEventData = 1 << 10
+
+// eventDataSize returns size of event data p passed with control
+// request c of event type t, or 0, if c has no data known, or its
+// data is larger than maxEventData.
+func eventDataSize(c Cmd, t uint32, p uintptr) uint32 {
+	if p == 0 {
+		return 0
+	}
+	ptr := eventDataPtr(p)
+	var n uint32
+	switch {
+	case c == SessionChange:
+		n = uint32(unsafe.Sizeof(winapi.WTS_SESSION_NOTIFICATION{}))
+	case c == DeviceEvent:
+		n = (*winapi.DEV_BROADCAST_HDR)(ptr).Size
+	case c == PowerEvent && t == winapi.PBT_POWERSETTINGCHANGE:
+		// checked first, so large length does not wrap around
+		if l := (*winapi.POWERBROADCAST_SETTING)(ptr).DataLength; l <= maxEventData {
+			n = uint32(unsafe.Offsetof(winapi.POWERBROADCAST_SETTING{}.Data)) + l
+		}
+	}
+	if n > maxEventData {
+		return 0
+	}
+	return n
+}
+
+// serviceArgs returns arguments passed to serviceMain.
+func serviceArgs(argc uint32, argv **uint16) []string {
+	var ps []*uint16
+	if argv != nil {
+		ps = unsafe.Slice(argv, argc)
+	}
+	args := make([]string, len(ps))
+	for i, a := range ps {
+		args[i] = winapi.UTF16PtrToString(a, 1<<15)
+	}
+	return args
+}
@@ -0,0 +1,41 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// WithPickupTimeout sets how long handler has to receive control
+// request, once it is delivered. If handler does not receive it in
+// time, error is logged to service Logger, together with stacks of
+// all goroutines, so handlers that ignore requests can be found.
+// Request is still delivered, when handler receives it late. There
+// is no timeout, if d is 0, which is the default.
+func WithPickupTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.pickupTimeout = d
+	}
+}
+
+// maxStackDump limits size of goroutine stacks logged. Event log
+// messages can not be much longer than 32K characters.
+const maxStackDump = 24 << 10
+
+// logMissedPickup logs that handler did not
+// receive control request c in time.
+func (s *service) logMissedPickup(c Cmd) {
+	if s.log == nil {
+		return
+	}
+	b := make([]byte, maxStackDump)
+	b = b[:runtime.Stack(b, true)]
+	s.log.Error(1, fmt.Sprintf("service %s handler did not receive control request %d within %v; goroutines:\n%s",
+		s.name, c, s.pickupTimeout, b))
+}
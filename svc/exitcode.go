@@ -0,0 +1,74 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// ExitCode is service specific exit code, that the package reports
+// itself, when service fails for reasons other than its handler.
+// Codes have APPLICATION_ERROR bit set, so they do not clash with
+// Windows error codes. ExitCode implements error.
+type ExitCode uint32
+
+const (
+	// ExitSetServiceStatusFailed is reported, when SetServiceStatus
+	// fails without Windows error code to report instead.
+	ExitSetServiceStatusFailed = ExitCode(syscall.APPLICATION_ERROR) + iota
+
+	// ExitNewThreadInCallback is reported, when the service control
+	// manager calls control handler on thread other than the one
+	// running Run, which the package relies on.
+	ExitNewThreadInCallback
+
+	// ExitHandlerPanicked is reported, when service handler, or
+	// worker started by Service.Go, panics. See PanicError.
+	ExitHandlerPanicked
+)
+
+// exitCodes describes every ExitCode.
+var exitCodes = map[ExitCode]struct{ name, msg string }{
+	ExitSetServiceStatusFailed: {"ExitSetServiceStatusFailed", "service failed to report its status"},
+	ExitNewThreadInCallback:    {"ExitNewThreadInCallback", "control handler called on unexpected thread"},
+	ExitHandlerPanicked:        {"ExitHandlerPanicked", "service handler panicked"},
+}
+
+func (c ExitCode) String() string {
+	if d, ok := exitCodes[c]; ok {
+		return d.name
+	}
+	return fmt.Sprintf("ExitCode(%d)", uint32(c))
+}
+
+func (c ExitCode) Error() string {
+	if d, ok := exitCodes[c]; ok {
+		return d.msg
+	}
+	return fmt.Sprintf("service specific error %d", uint32(c))
+}
+
+// ExitError returns exit code of stopped service, as reported in
+// SERVICE_STATUS Win32ExitCode and ServiceSpecificExitCode, and
+// shown by the service control manager in the system event log,
+// as error. It returns ExitCode for service specific exit codes,
+// syscall.Errno for others, and nil, if service exited cleanly.
+func ExitError(win32, specific uint32) error {
+	switch {
+	case win32 == winapi.NO_ERROR:
+		return nil
+	case win32 == uint32(winapi.ERROR_SERVICE_SPECIFIC_ERROR):
+		if specific == winapi.NO_ERROR {
+			return nil
+		}
+		return ExitCode(specific)
+	}
+	return syscall.Errno(win32)
+}
@@ -0,0 +1,35 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+// ControlFunc handles control request c.
+type ControlFunc func(c ChangeRequest)
+
+// Interceptor wraps control handling next, like HTTP middleware.
+// The ControlFunc it returns sees every control request before
+// service Handler does. It may pass request on by calling next,
+// possibly with changed Cmd, or drop it by not calling next.
+// Dropped requests never reach Handler, so service status does
+// not change. ControlFunc is called by service run loop, and
+// must not block.
+type Interceptor func(next ControlFunc) ControlFunc
+
+// WithInterceptors makes service pass control requests through
+// interceptors is. The first interceptor sees requests first.
+func WithInterceptors(is ...Interceptor) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, is...)
+	}
+}
+
+// chain returns final wrapped by interceptors is.
+func chain(is []Interceptor, final ControlFunc) ControlFunc {
+	for i := len(is) - 1; i >= 0; i-- {
+		final = is[i](final)
+	}
+	return final
+}
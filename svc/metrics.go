@@ -0,0 +1,155 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Metrics receives service lifecycle timings and control requests,
+// so services, whose startup creeps toward the service control
+// manager timeout, can be spotted. Metrics methods are called by
+// service run loop, and must not block.
+type Metrics interface {
+	// Started is called once service reports Running for the
+	// first time, with time passed since service was started.
+	Started(d time.Duration)
+
+	// Stopped is called once service reports Stopped, with time
+	// passed since it received first Stop, Shutdown or PreShutdown
+	// request, or Run context was done. It is not called, if service
+	// stops by itself.
+	Stopped(d time.Duration)
+
+	// Control is called for every control request service receives
+	// from the service control manager, before it is passed to
+	// interceptors and handler. Service.Controls counts c already.
+	// Stop sent when Run context is done is not reported.
+	Control(c Cmd)
+}
+
+// WithMetrics makes service report its lifecycle to m. Lifecycle
+// timings and control counts are also logged to service Logger.
+func WithMetrics(m Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+// isStop reports whether c asks service to stop.
+func isStop(c Cmd) bool {
+	return c == Stop || c == Shutdown || c == PreShutdown
+}
+
+// lifecycle tracks timings of service run.
+type lifecycle struct {
+	begin        time.Time     // serviceMain was called
+	processStart time.Time     // process was created, if known
+	pipeTimeout  time.Duration // ServicesPipeTimeout
+	running      bool          // Running was reported
+	stopping     time.Time     // first stop request was received
+}
+
+// ControlStat counts control requests of one kind.
+type ControlStat struct {
+	Count int       // requests received
+	Last  time.Time // when last request was received
+}
+
+// ControlStats are counts of control requests received by service
+// from the service control manager, by request. Interceptors and
+// handler see requests after they are counted.
+type ControlStats map[Cmd]ControlStat
+
+// Controls returns counts of control requests received by service
+// s, during its current or last Run. It is safe to call from any
+// goroutine, like handler, Metrics implementation or diagnostics
+// handler, while service runs.
+func (s *Service) Controls() ControlStats {
+	s.mu.Lock()
+	srv := s.srv
+	s.mu.Unlock()
+	if srv == nil {
+		return ControlStats{}
+	}
+	return srv.controlStats()
+}
+
+func (s *service) controlStats() ControlStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cs := make(ControlStats, len(s.controls))
+	for c, st := range s.controls {
+		cs[c] = st
+	}
+	return cs
+}
+
+// control records control request c received.
+func (s *service) control(c Cmd) {
+	s.mu.Lock()
+	if s.controls == nil {
+		s.controls = make(ControlStats)
+	}
+	st := s.controls[c]
+	st.Count++
+	st.Last = time.Now()
+	s.controls[c] = st
+	s.mu.Unlock()
+	s.stopRequested(c)
+	if s.metrics != nil {
+		s.metrics.Control(c)
+	}
+	s.traceControl(c)
+}
+
+// stopRequested records when service was first asked to stop,
+// if c asks it to.
+func (s *service) stopRequested(c Cmd) {
+	if isStop(c) && s.lc.stopping.IsZero() {
+		s.lc.stopping = time.Now()
+	}
+}
+
+// reportedState records that service reported state st.
+func (s *service) reportedState(st State) {
+	s.traceState(st)
+	switch {
+	case st == Running && !s.lc.running:
+		s.lc.running = true
+		d := time.Since(s.lc.begin)
+		if s.metrics != nil {
+			s.metrics.Started(d)
+		}
+		s.logInfo("service " + s.name + " started in " + d.String())
+	case st == Stopped && !s.lc.stopping.IsZero():
+		d := time.Since(s.lc.stopping)
+		if s.metrics != nil {
+			s.metrics.Stopped(d)
+		}
+		s.logInfo("service " + s.name + " stopped in " + d.String() + controlCounts(s.controlStats()))
+	}
+}
+
+// controlCounts formats number of control requests of each kind.
+func controlCounts(m ControlStats) string {
+	if len(m) == 0 {
+		return ""
+	}
+	cs := make([]int, 0, len(m))
+	for c := range m {
+		cs = append(cs, int(c))
+	}
+	sort.Ints(cs)
+	s := ", controls received:"
+	for _, c := range cs {
+		s += fmt.Sprintf(" %v=%d", Cmd(c), m[Cmd(c)].Count)
+	}
+	return s
+}
@@ -0,0 +1,87 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// Mitigations lists process mitigation policies applied before
+// service handler runs. Services often run as LocalSystem, and
+// mitigations make exploiting them harder. Once applied, policies
+// cannot be removed for the life of the process.
+type Mitigations struct {
+	// ProhibitDynamicCode prevents process from generating
+	// code at run time, or modifying executable code.
+	ProhibitDynamicCode bool
+
+	// DisableExtensionPoints stops legacy extension points, like
+	// AppInit dlls and window hooks, from loading dlls into process.
+	DisableExtensionPoints bool
+
+	// NoRemoteImages prevents loading images from remote devices,
+	// NoLowLabelImages prevents loading images with low mandatory
+	// label, and PreferSystem32Images searches System32 for dlls
+	// before application directory.
+	NoRemoteImages       bool
+	NoLowLabelImages     bool
+	PreferSystem32Images bool
+}
+
+// StrictMitigations enables all mitigations Mitigations knows about.
+var StrictMitigations = Mitigations{
+	ProhibitDynamicCode:    true,
+	DisableExtensionPoints: true,
+	NoRemoteImages:         true,
+	NoLowLabelImages:       true,
+	PreferSystem32Images:   true,
+}
+
+// WithMitigations makes Service.Run apply mitigations m, before
+// service is started. Run fails, if any of them cannot be applied.
+func WithMitigations(m Mitigations) Option {
+	return func(o *options) {
+		o.mitigations = &m
+	}
+}
+
+// policies returns mitigation policies enabled in m, as
+// PROCESS_MITIGATION_POLICY values mapped to their flags.
+func (m *Mitigations) policies() map[uint32]uint32 {
+	p := make(map[uint32]uint32)
+	if m.ProhibitDynamicCode {
+		p[winapi.ProcessDynamicCodePolicy] |= winapi.PROCESS_MITIGATION_PROHIBIT_DYNAMIC_CODE
+	}
+	if m.DisableExtensionPoints {
+		p[winapi.ProcessExtensionPointDisablePolicy] |= winapi.PROCESS_MITIGATION_DISABLE_EXTENSION_POINTS
+	}
+	if m.NoRemoteImages {
+		p[winapi.ProcessImageLoadPolicy] |= winapi.PROCESS_MITIGATION_NO_REMOTE_IMAGES
+	}
+	if m.NoLowLabelImages {
+		p[winapi.ProcessImageLoadPolicy] |= winapi.PROCESS_MITIGATION_NO_LOW_MANDATORY_LABEL_IMAGES
+	}
+	if m.PreferSystem32Images {
+		p[winapi.ProcessImageLoadPolicy] |= winapi.PROCESS_MITIGATION_PREFER_SYSTEM32_IMAGES
+	}
+	return p
+}
+
+// apply applies mitigations m to the current process.
+func (m *Mitigations) apply() error {
+	for policy, flags := range m.policies() {
+		p := winapi.PROCESS_MITIGATION_POLICY_FLAGS{Flags: flags}
+		err := winapi.SetProcessMitigationPolicy(policy, (*byte)(unsafe.Pointer(&p)), unsafe.Sizeof(p))
+		if err != nil {
+			return fmt.Errorf("svc: failed to apply mitigation policy %d: %v", policy, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var stateNames = map[State]string{
+	Stopped:         "Stopped",
+	StartPending:    "StartPending",
+	StopPending:     "StopPending",
+	Running:         "Running",
+	ContinuePending: "ContinuePending",
+	PausePending:    "PausePending",
+	Paused:          "Paused",
+}
+
+func (s State) String() string {
+	if n, ok := stateNames[s]; ok {
+		return n
+	}
+	return fmt.Sprintf("State(%d)", uint32(s))
+}
+
+// MarshalJSON encodes s as its name, like "Running".
+func (s State) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON decodes s from its name, as MarshalJSON
+// encodes it, or from number.
+func (s *State) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalName(b, "State", func(name string) (uint32, bool) {
+		for st, n := range stateNames {
+			if n == name {
+				return uint32(st), true
+			}
+		}
+		return 0, false
+	})
+	if err != nil {
+		return err
+	}
+	*s = State(v)
+	return nil
+}
+
+var cmdNames = map[Cmd]string{
+	Stop:                  "Stop",
+	Pause:                 "Pause",
+	Continue:              "Continue",
+	Interrogate:           "Interrogate",
+	Shutdown:              "Shutdown",
+	PreShutdown:           "PreShutdown",
+	ParamChange:           "ParamChange",
+	NetBindAdd:            "NetBindAdd",
+	NetBindRemove:         "NetBindRemove",
+	NetBindEnable:         "NetBindEnable",
+	NetBindDisable:        "NetBindDisable",
+	DeviceEvent:           "DeviceEvent",
+	HardwareProfileChange: "HardwareProfileChange",
+	PowerEvent:            "PowerEvent",
+	SessionChange:         "SessionChange",
+	TriggerEvent:          "TriggerEvent",
+	Reload:                "Reload",
+}
+
+// String returns name of c, like "Stop". User defined commands,
+// from 128 to 255, are shown as numbers, except for Reload.
+func (c Cmd) String() string {
+	if n, ok := cmdNames[c]; ok {
+		return n
+	}
+	return fmt.Sprintf("Cmd(%d)", uint32(c))
+}
+
+// MarshalJSON encodes c as its name, like "Stop".
+func (c Cmd) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+// UnmarshalJSON decodes c from its name, as MarshalJSON
+// encodes it, or from number.
+func (c *Cmd) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalName(b, "Cmd", func(name string) (uint32, bool) {
+		for cmd, n := range cmdNames {
+			if n == name {
+				return uint32(cmd), true
+			}
+		}
+		return 0, false
+	})
+	if err != nil {
+		return err
+	}
+	*c = Cmd(v)
+	return nil
+}
+
+var acceptNames = []struct {
+	a    Accepted
+	name string
+}{
+	{AcceptStop, "Stop"},
+	{AcceptShutdown, "Shutdown"},
+	{AcceptPreShutdown, "PreShutdown"},
+	{AcceptPauseAndContinue, "PauseAndContinue"},
+	{AcceptParamChange, "ParamChange"},
+	{AcceptNetBindChange, "NetBindChange"},
+	{AcceptHardwareProfileChange, "HardwareProfileChange"},
+	{AcceptPowerEvent, "PowerEvent"},
+	{AcceptSessionChange, "SessionChange"},
+	{AcceptTriggerEvent, "TriggerEvent"},
+}
+
+// String returns names of commands accepted in a, joined
+// with "|", like "Stop|Shutdown", or "None".
+func (a Accepted) String() string {
+	if a == 0 {
+		return "None"
+	}
+	var names []string
+	for _, n := range acceptNames {
+		if a&n.a != 0 {
+			names = append(names, n.name)
+			a &^= n.a
+		}
+	}
+	if a != 0 {
+		names = append(names, fmt.Sprintf("%#x", uint32(a)))
+	}
+	return strings.Join(names, "|")
+}
+
+// MarshalJSON encodes a as its String.
+func (a Accepted) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON decodes a from its String, as MarshalJSON
+// encodes it, or from number.
+func (a *Accepted) UnmarshalJSON(b []byte) error {
+	var v uint32
+	if json.Unmarshal(b, &v) == nil {
+		*a = Accepted(v)
+		return nil
+	}
+	var s string
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+	var x Accepted
+	if s != "None" {
+	next:
+		for _, name := range strings.Split(s, "|") {
+			for _, n := range acceptNames {
+				if n.name == name {
+					x |= n.a
+					continue next
+				}
+			}
+			v, err := strconv.ParseUint(name, 0, 32)
+			if err != nil {
+				return fmt.Errorf("svc: unknown accepted command %q", name)
+			}
+			x |= Accepted(v)
+		}
+	}
+	*a = x
+	return nil
+}
+
+// unmarshalName decodes JSON b, that holds number, or name, that
+// lookup finds, or kind(number), as String shows unknown values.
+func unmarshalName(b []byte, kind string, lookup func(name string) (uint32, bool)) (uint32, error) {
+	var v uint32
+	if json.Unmarshal(b, &v) == nil {
+		return v, nil
+	}
+	var name string
+	err := json.Unmarshal(b, &name)
+	if err != nil {
+		return 0, err
+	}
+	if v, ok := lookup(name); ok {
+		return v, nil
+	}
+	if n := strings.TrimPrefix(name, kind+"("); n != name && strings.HasSuffix(n, ")") {
+		v, err := strconv.ParseUint(n[:len(n)-1], 10, 32)
+		if err == nil {
+			return uint32(v), nil
+		}
+	}
+	return 0, fmt.Errorf("svc: unknown %s %q", strings.ToLower(kind), name)
+}
+
+// String returns one line description of s, like
+// "Running, accepts Stop|Shutdown". Progress of pending
+// operation and process id are included, when they are set.
+func (s Status) String() string {
+	var b strings.Builder
+	b.WriteString(s.State.String())
+	if s.Accepts != 0 {
+		b.WriteString(", accepts " + s.Accepts.String())
+	}
+	if s.CheckPoint != 0 || s.WaitHint != 0 {
+		fmt.Fprintf(&b, ", checkpoint %d, wait hint %v", s.CheckPoint, time.Duration(s.WaitHint)*time.Millisecond)
+	}
+	if s.Stage != "" {
+		b.WriteString(", stage " + s.Stage)
+	}
+	if s.ProcessId != 0 {
+		fmt.Fprintf(&b, ", pid %d", s.ProcessId)
+	}
+	return b.String()
+}
@@ -0,0 +1,231 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/multiplay/winsvc/tracing"
+)
+
+// Logger receives messages about service run loop, like
+// failure to report status, or handler missing stop deadline.
+// debug.Log and eventlog.Log implement Logger.
+type Logger interface {
+	Info(eid uint32, msg string) error
+	Warning(eid uint32, msg string) error
+	Error(eid uint32, msg string) error
+}
+
+// Option configures Service created by New.
+type Option func(*options)
+
+type options struct {
+	log           Logger
+	stopTimeout   time.Duration
+	pickupTimeout time.Duration
+	interceptors  []Interceptor
+	metrics       Metrics
+	mitigations   *Mitigations
+	ready         func(ctx context.Context) error
+	readyWaitHint time.Duration
+	reload        func(ctx context.Context) error
+	tracer        tracing.Tracer
+	scm           SCM
+}
+
+// WithLogger makes service log run loop messages to l.
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		o.log = l
+	}
+}
+
+// WithStopTimeout sets how long handler has to return after it is
+// asked to stop (by Stop, Shutdown or PreShutdown request, or by Run
+// context being done). If handler does not return in time, service
+// reports Stopped with ERROR_SERVICE_REQUEST_TIMEOUT exit code, and
+// Run returns error. Handler may wait for as long as it likes, if
+// d is 0, which is the default.
+func WithStopTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.stopTimeout = d
+	}
+}
+
+// WithSCM makes service use service control manager m, rather
+// than SystemSCM. Use it to run service with fake service control
+// manager in tests.
+func WithSCM(m SCM) Option {
+	return func(o *options) {
+		o.scm = m
+	}
+}
+
+// Service is a Windows service, ready to be run.
+type Service struct {
+	name    string
+	handler Handler
+	opts    options
+
+	mu  sync.Mutex
+	srv *service // set by Run
+}
+
+// New returns service named name, that runs handler,
+// configured by options opts.
+func New(name string, handler Handler, opts ...Option) *Service {
+	s := &Service{name: name, handler: handler, opts: options{scm: SystemSCM}}
+	for _, o := range opts {
+		o(&s.opts)
+	}
+	return s
+}
+
+// Name returns name of service s.
+func (s *Service) Name() string {
+	return s.name
+}
+
+// Run runs service s, and returns once it is stopped. If ctx is done
+// while service is running, handler receives Stop request, as if it
+// was sent by the service control manager. See StopCause to record
+// why ctx is done. Run does not lock calling
+// goroutine to its OS thread; the service control manager dispatcher
+// runs on a separate locked thread, that is released when Run returns.
+// Handler runs on its own goroutine, that is not locked either.
+// Nothing of the service is left running, once Run returns, so Run
+// can be called again, for example, when dispatcher is restarted.
+func (s *Service) Run(ctx context.Context) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	if s.opts.mitigations != nil {
+		err = s.opts.mitigations.apply()
+		if err != nil {
+			return err
+		}
+	}
+	srv := newService(s.name, s.handler, s.opts.scm)
+	srv.owner = s
+	srv.log = s.opts.log
+	srv.stopTimeout = s.opts.stopTimeout
+	srv.pickupTimeout = s.opts.pickupTimeout
+	srv.interceptors = s.opts.interceptors
+	srv.metrics = s.opts.metrics
+	srv.readiness = readiness{ready: s.opts.ready, waitHint: s.opts.readyWaitHint}
+	srv.reloader.reload = s.opts.reload
+	s.mu.Lock()
+	s.srv = srv
+	s.mu.Unlock()
+	defer close(srv.ran)
+	err = setCurrent(srv)
+	if err != nil {
+		return err
+	}
+	defer setCurrent(nil)
+	srv.traceRun(ctx, s.opts.tracer)
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-ctx.Done():
+			select {
+			case srv.c <- ctlEvent{cmd: Stop, cause: stopCause(ctx), synthetic: true}:
+			case <-srv.done:
+			case <-finished:
+			}
+		case <-finished:
+		}
+	}()
+	err = dispatch(srv)
+	if err == nil {
+		err = srv.result()
+	}
+	srv.traceEnd(err)
+	return err
+}
+
+// Status returns status reported to the service control manager
+// last, as seen by it: State, Accepts, CheckPoint, WaitHint and
+// ProcessId, that is id of this process, unless Stopped. It
+// is safe to call from any goroutine, like health check or metrics
+// handler, while service runs. Status is Stopped with nothing
+// accepted, before Run reports anything.
+func (s *Service) Status() Status {
+	s.mu.Lock()
+	srv := s.srv
+	s.mu.Unlock()
+	if srv == nil {
+		return Status{State: Stopped}
+	}
+	return srv.status()
+}
+
+// Close stops service s, if it is running, as if Run context was
+// done, and waits for Run to return. Service s can be run again then.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	srv := s.srv
+	s.mu.Unlock()
+	if srv == nil {
+		return nil
+	}
+	select {
+	case srv.c <- ctlEvent{cmd: Stop, synthetic: true}:
+	case <-srv.done:
+	case <-srv.ran:
+	}
+	<-srv.ran
+	return nil
+}
+
+// dispatch runs service s dispatcher on a dedicated goroutine locked
+// to its OS thread, because the service control manager calls control
+// handler on the thread that started dispatcher. The thread is released
+// once dispatcher returns, and thread of Run caller is never locked.
+func dispatch(s *service) error {
+	errc := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		errc <- s.scm.Dispatch(s.name, s.main)
+	}()
+	return <-errc
+}
+
+// result returns error recorded by run loop, if any.
+func (s *service) result() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// fail records error err of run loop, and logs it.
+// Run returns err once service is stopped.
+func (s *service) fail(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+	s.logError(err.Error())
+}
+
+func (s *service) logError(msg string) {
+	if s.log != nil {
+		s.log.Error(1, msg)
+	}
+}
+
+func (s *service) logInfo(msg string) {
+	if s.log != nil {
+		s.log.Info(1, msg)
+	}
+}
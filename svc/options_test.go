@@ -0,0 +1,977 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/multiplay/winsvc/tracing"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+func TestRunContext(t *testing.T) {
+	f := newFakeSCM()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- New("fake", stoppable(false, 0), WithSCM(f)).Run(ctx)
+	}()
+	<-f.started
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("service did not stop after context was cancelled")
+	}
+	want := []uint32{
+		winapi.SERVICE_START_PENDING,
+		winapi.SERVICE_RUNNING,
+		winapi.SERVICE_STOP_PENDING,
+		winapi.SERVICE_STOPPED,
+	}
+	if got := f.states(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("reported states %v, want %v", got, want)
+	}
+
+	err := New("fake", stoppable(false, 0), WithSCM(f)).Run(ctx)
+	if err != context.Canceled {
+		t.Errorf("Run with cancelled context returns %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	f := newFakeSCM()
+	release := make(chan struct{})
+	defer close(release)
+	h := handlerFunc(func(args []string, r <-chan ChangeRequest, s chan<- Status) (bool, uint32) {
+		s <- Status{State: Running, Accepts: AcceptStop}
+		<-r
+		// ignore Stop
+		<-release
+		return false, 0
+	})
+	done := make(chan error)
+	go func() {
+		done <- New("fake", h, WithSCM(f), WithStopTimeout(100*time.Millisecond)).Run(context.Background())
+	}()
+	<-f.started
+	control(Stop)
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Run should fail, when handler misses stop deadline")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after stop deadline")
+	}
+	last := f.last()
+	if last.CurrentState != winapi.SERVICE_STOPPED || last.Win32ExitCode != uint32(winapi.ERROR_SERVICE_REQUEST_TIMEOUT) {
+		t.Errorf("last status is %+v, want stopped with ERROR_SERVICE_REQUEST_TIMEOUT", last)
+	}
+}
+
+// testLog records messages logged.
+type testLog struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *testLog) add(kind, msg string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs = append(l.msgs, kind+": "+msg)
+	return nil
+}
+
+// logged reports whether message starting with prefix is logged.
+func (l *testLog) logged(prefix string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, m := range l.msgs {
+		if strings.HasPrefix(m, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *testLog) Info(eid uint32, msg string) error    { return l.add("info", msg) }
+func (l *testLog) Warning(eid uint32, msg string) error { return l.add("warning", msg) }
+func (l *testLog) Error(eid uint32, msg string) error   { return l.add("error", msg) }
+
+func TestStopReason(t *testing.T) {
+	tests := []struct {
+		reason StopReason
+		ec     uint32
+		want   string
+	}{
+		{
+			StopReason{Planned: true, Code: winapi.SERVICE_STOP_REASON_MAJOR_APPLICATION | winapi.SERVICE_STOP_REASON_MINOR_MAINTENANCE, Comment: "idle"},
+			0,
+			"info: service fake stopped: planned stop, reason 0x40050002: idle",
+		},
+		{
+			StopReason{Code: winapi.SERVICE_STOP_REASON_MAJOR_SOFTWARE | winapi.SERVICE_STOP_REASON_MINOR_UNSTABLE},
+			3,
+			"error: service fake stopped: unplanned stop, reason 0x10040007 (exit code 3)",
+		},
+	}
+	for _, test := range tests {
+		l := &testLog{}
+		r := test.reason
+		ec := test.ec
+		h := handlerFunc(func(args []string, _ <-chan ChangeRequest, s chan<- Status) (bool, uint32) {
+			s <- Status{State: Running}
+			s <- Status{State: StopPending, Reason: &r}
+			return true, ec
+		})
+		err := New("fake", h, WithSCM(newFakeSCM()), WithLogger(l)).Run(context.Background())
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		// startup time is logged first
+		if len(l.msgs) != 2 || l.msgs[1] != test.want {
+			t.Errorf("logged %q, want %q", l.msgs, test.want)
+		}
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	var seen []string
+	logCmds := func(name string) Interceptor {
+		return func(next ControlFunc) ControlFunc {
+			return func(c ChangeRequest) {
+				seen = append(seen, fmt.Sprintf("%s:%d", name, c.Cmd))
+				next(c)
+			}
+		}
+	}
+	// reject first Stop, like during deploy
+	rejected := false
+	rejectStop := func(next ControlFunc) ControlFunc {
+		return func(c ChangeRequest) {
+			if c.Cmd == Stop && !rejected {
+				rejected = true
+				return
+			}
+			next(c)
+		}
+	}
+	f := newFakeSCM()
+	done := make(chan error)
+	go func() {
+		done <- New("fake", stoppable(false, 0), WithSCM(f),
+			WithInterceptors(logCmds("outer"), rejectStop, logCmds("inner"))).Run(context.Background())
+	}()
+	<-f.started
+	control(Stop)
+	control(Interrogate)
+	control(Stop)
+	err := <-done
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	want := []string{"outer:1", "outer:4", "inner:4", "outer:1", "inner:1"}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("interceptors saw %v, want %v", seen, want)
+	}
+	states := f.states()
+	if len(states) != 4 || states[2] != winapi.SERVICE_STOP_PENDING {
+		t.Errorf("reported states %v, want rejected Stop to have no effect", states)
+	}
+}
+
+// testMetrics records lifecycle reported.
+type testMetrics struct {
+	started, stopped []time.Duration
+	controls         []Cmd
+}
+
+func (m *testMetrics) Started(d time.Duration) { m.started = append(m.started, d) }
+func (m *testMetrics) Stopped(d time.Duration) { m.stopped = append(m.stopped, d) }
+func (m *testMetrics) Control(c Cmd)           { m.controls = append(m.controls, c) }
+
+func TestMetrics(t *testing.T) {
+	m := &testMetrics{}
+	l := &testLog{}
+	f := newFakeSCM()
+	done := make(chan error)
+	go func() {
+		done <- New("fake", stoppable(false, 0), WithSCM(f), WithMetrics(m), WithLogger(l)).Run(context.Background())
+	}()
+	<-f.started
+	control(Interrogate)
+	control(Interrogate)
+	control(Stop)
+	err := <-done
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(m.started) != 1 || len(m.stopped) != 1 {
+		t.Fatalf("started reported %d times and stopped %d times, want once each", len(m.started), len(m.stopped))
+	}
+	if want := []Cmd{Interrogate, Interrogate, Stop}; !reflect.DeepEqual(m.controls, want) {
+		t.Errorf("controls reported %v, want %v", m.controls, want)
+	}
+	if len(l.msgs) != 2 {
+		t.Fatalf("logged %q, want startup and stop times", l.msgs)
+	}
+	if want := "info: service fake started in "; !strings.HasPrefix(l.msgs[0], want) {
+		t.Errorf("logged %q, want %q prefix", l.msgs[0], want)
+	}
+	if want := ", controls received: Stop=1 Interrogate=2"; !strings.HasSuffix(l.msgs[1], want) {
+		t.Errorf("logged %q, want %q suffix", l.msgs[1], want)
+	}
+}
+
+func TestMetricsRunStop(t *testing.T) {
+	m := &testMetrics{}
+	f := newFakeSCM()
+	ctx, cancel := context.WithCancel(context.Background())
+	s := New("fake", stoppable(false, 0), WithSCM(f), WithMetrics(m))
+	done := make(chan error)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+	<-f.started
+	cancel()
+	<-done
+	if len(m.stopped) != 1 {
+		t.Errorf("stopped reported %d times, want once", len(m.stopped))
+	}
+	if len(m.controls) != 0 {
+		t.Errorf("controls reported %v, want none for Stop sent by Run", m.controls)
+	}
+	if cs := s.Controls(); len(cs) != 0 {
+		t.Errorf("Controls returned %v, want none for Stop sent by Run", cs)
+	}
+}
+
+func TestPickupTimeout(t *testing.T) {
+	l := &testLog{}
+	f := newFakeSCM()
+	release := make(chan struct{})
+	h := stoppable(false, 0)
+	done := make(chan error)
+	go func() {
+		done <- New("fake", handlerFunc(func(args []string, r <-chan ChangeRequest, s chan<- Status) (bool, uint32) {
+			<-release
+			return h.Execute(args, r, s)
+		}), WithSCM(f), WithLogger(l), WithPickupTimeout(10*time.Millisecond)).Run(context.Background())
+	}()
+	<-f.started
+	control(Stop)
+	for {
+		l.mu.Lock()
+		n := len(l.msgs)
+		l.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	err := <-done
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if s := f.last(); s.CurrentState != winapi.SERVICE_STOPPED {
+		t.Errorf("last state is %d, want stopped", s.CurrentState)
+	}
+	msg := l.msgs[0]
+	if want := "error: service fake handler did not receive control request 1 within 10ms; goroutines:\n"; !strings.HasPrefix(msg, want) {
+		t.Errorf("logged %q, want %q prefix", msg, want)
+	}
+	if !strings.Contains(msg, "TestPickupTimeout") {
+		t.Errorf("logged stacks do not include handler goroutine:\n%s", msg)
+	}
+}
+
+func TestStages(t *testing.T) {
+	l := &testLog{}
+	f := newFakeSCM()
+	var ran []string
+	stage := func(name string, err error) Stage {
+		return Stage{Name: name, WaitHint: 2 * time.Second, Run: func() error {
+			ran = append(ran, name)
+			return err
+		}}
+	}
+	stages := Stages{
+		stage("load config", nil),
+		stage("open DB", errors.New("connection refused")),
+		stage("listen", nil),
+	}
+	var stagesErr error
+	h := handlerFunc(func(args []string, r <-chan ChangeRequest, s chan<- Status) (bool, uint32) {
+		stagesErr = stages.Run(s)
+		if stagesErr != nil {
+			return true, 2
+		}
+		s <- Status{State: Running}
+		return false, 0
+	})
+	err := New("fake", h, WithSCM(f), WithLogger(l)).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if want := []string{"load config", "open DB"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("stages run %q, want %q", ran, want)
+	}
+	if want := "open DB: connection refused"; stagesErr == nil || stagesErr.Error() != want {
+		t.Errorf("Stages.Run returned %v, want %q", stagesErr, want)
+	}
+	first := f.statuses[0]
+	if first.CurrentState != winapi.SERVICE_START_PENDING || first.CheckPoint != 1 || first.WaitHint != 2000 {
+		t.Errorf("first status reported %+v, want start pending with checkpoint 1 and wait hint 2000", first)
+	}
+	want := `error: service fake failed to start during stage "open DB" (exit code 2)
+elapsed: `
+	if len(l.msgs) != 1 || !strings.HasPrefix(l.msgs[0], want) {
+		t.Fatalf("logged %q, want one message starting with %q", l.msgs, want)
+	}
+	for _, want := range []string{fmt.Sprintf("\nlast status: StartPending, checkpoint 1, wait hint 2s, pid %d\n", os.Getpid()), "\nServicesPipeTimeout: 30s"} {
+		if !strings.Contains(l.msgs[0], want) {
+			t.Errorf("logged %q, want it to include %q", l.msgs[0], want)
+		}
+	}
+}
+
+func TestMitigationPolicies(t *testing.T) {
+	m := Mitigations{DisableExtensionPoints: true, NoRemoteImages: true, PreferSystem32Images: true}
+	want := map[uint32]uint32{
+		winapi.ProcessExtensionPointDisablePolicy: winapi.PROCESS_MITIGATION_DISABLE_EXTENSION_POINTS,
+		winapi.ProcessImageLoadPolicy:             winapi.PROCESS_MITIGATION_NO_REMOTE_IMAGES | winapi.PROCESS_MITIGATION_PREFER_SYSTEM32_IMAGES,
+	}
+	if got := m.policies(); !reflect.DeepEqual(got, want) {
+		t.Errorf("policies are %v, want %v", got, want)
+	}
+	if got := (&Mitigations{}).policies(); len(got) != 0 {
+		t.Errorf("no mitigations give policies %v", got)
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{`C:\svc\fake.exe`, "-config", `C:\svc\fake.conf`}
+
+	f := newFakeSCM("fake", "-v")
+	var args Args
+	h := stoppable(false, 0)
+	_, done := runFake(t, f, handlerFunc(func(a []string, r <-chan ChangeRequest, s chan<- Status) (bool, uint32) {
+		args = ParseArgs(a)
+		return h.Execute(a, r, s)
+	}))
+	control(Stop)
+	<-done
+	want := Args{
+		Name:  "fake",
+		Start: []string{"-v"},
+		Image: []string{"-config", `C:\svc\fake.conf`},
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("ParseArgs returned %+v, want %+v", args, want)
+	}
+	if a := ParseArgs(nil); a.Name != "" || len(a.Start) != 0 {
+		t.Errorf("ParseArgs(nil) returned %+v", a)
+	}
+}
+
+func TestArgsInstance(t *testing.T) {
+	tests := []struct {
+		a    Args
+		want string
+	}{
+		{Args{Name: "game"}, ""},
+		{Args{Name: "game@eu1"}, "eu1"},
+		{Args{Name: "Game@eu1"}, "eu1"},
+		{Args{Name: "game_eu1"}, ""},
+		{Args{Name: "gamer"}, ""},
+		{Args{Name: "game@"}, ""},
+		{Args{Name: "other@eu1"}, ""},
+		{Args{Name: "game@eu1", Image: []string{"-instance=us1"}}, "us1"},
+		{Args{Name: "svc", Image: []string{"-v", "-instance", "us1"}}, "us1"},
+	}
+	for _, test := range tests {
+		if got := test.a.Instance("game"); got != test.want {
+			t.Errorf("Instance of %+v is %q, want %q", test.a, got, test.want)
+		}
+	}
+}
+
+func TestStatusSnapshot(t *testing.T) {
+	f := newFakeSCM()
+	s := New("fake", stoppable(false, 0), WithSCM(f))
+	if st := s.Status(); st.State != Stopped {
+		t.Errorf("status before Run is %+v, want stopped", st)
+	}
+	done := make(chan error)
+	go func() {
+		done <- s.Run(context.Background())
+	}()
+	<-f.started
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Status().State != Running {
+		if time.Now().After(deadline) {
+			t.Fatalf("status is %+v, want running", s.Status())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if a := s.Status().Accepts; a != AcceptStop {
+		t.Errorf("running service accepts %#x, want %#x", a, AcceptStop)
+	}
+	if pid := s.Status().ProcessId; pid != uint32(os.Getpid()) {
+		t.Errorf("running service process id is %d, want %d", pid, os.Getpid())
+	}
+	control(Stop)
+	err := <-done
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if st := s.Status(); st.State != Stopped || st.Accepts != 0 || st.ProcessId != 0 {
+		t.Errorf("status after Run is %+v, want stopped", st)
+	}
+}
+
+func TestTick(t *testing.T) {
+	f := newFakeSCM()
+	var s *Service
+	h := handlerFunc(func(args []string, r <-chan ChangeRequest, c chan<- Status) (bool, uint32) {
+		c <- Status{State: StartPending, CheckPoint: 1, WaitHint: 1000}
+		s.Tick()
+		s.ExtendWait(5 * time.Second)
+		deadline := time.Now().Add(5 * time.Second)
+		for s.Status().CheckPoint != 3 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		c <- Status{State: Running}
+		s.Tick() // not pending, ignored
+		return false, 0
+	})
+	s = New("fake", h, WithSCM(f))
+	err := s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	var pending []winapi.SERVICE_STATUS
+	for _, st := range f.statuses {
+		if st.CurrentState == winapi.SERVICE_START_PENDING {
+			pending = append(pending, st)
+		}
+	}
+	last := pending[len(pending)-1]
+	if last.CheckPoint != 3 || last.WaitHint != 5000 {
+		t.Errorf("last start pending status reported %+v, want checkpoint 3 and wait hint 5000", last)
+	}
+	if got, want := f.states(), []uint32{winapi.SERVICE_RUNNING, winapi.SERVICE_STOPPED}; !reflect.DeepEqual(got[len(got)-2:], want) {
+		t.Errorf("reported states %v, want them to end with %v", got, want)
+	}
+	s.Tick() // service stopped, ignored
+
+	// Run returns without starting service, Tick must not block
+	s = New("fake", h, WithSCM(&nopSCM{}))
+	s.Run(context.Background())
+	s.Tick()
+}
+
+func TestSequentialRun(t *testing.T) {
+	f := newFakeSCM()
+	// slow to stop, until it is released
+	release := make(chan struct{})
+	returned := make(chan struct{})
+	slow := handlerFunc(func(args []string, r <-chan ChangeRequest, s chan<- Status) (bool, uint32) {
+		defer close(returned)
+		s <- Status{State: Running, Accepts: AcceptStop}
+		<-r
+		s <- Status{State: StopPending}
+		<-release
+		// reported after service stopped
+		s <- Status{State: StopPending, CheckPoint: 1}
+		return false, 0
+	})
+	s := New("fake", slow, WithSCM(f), WithStopTimeout(10*time.Millisecond))
+	done := make(chan error)
+	go func() {
+		done <- s.Run(context.Background())
+	}()
+	<-f.started
+	control(Stop)
+	if err := <-done; err == nil {
+		t.Fatal("Run should fail, when handler misses stop deadline")
+	}
+	close(release)
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler was left running after Run returned")
+	}
+
+	for i := 0; i < 3; i++ {
+		s = New("fake", stoppable(false, 0), WithSCM(f))
+		go func() {
+			done <- s.Run(context.Background())
+		}()
+		<-f.started
+		err := s.Close()
+		if err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		if err := <-done; err != nil {
+			t.Fatalf("run %d failed: %v", i, err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Close of stopped service failed: %v", err)
+	}
+}
+
+// recorder records spans started and ended, and events of all spans.
+type recorder struct {
+	mu      sync.Mutex
+	started []string
+	ended   []string
+	events  []string
+}
+
+func (r *recorder) Tracer(name string) tracing.Tracer {
+	return r
+}
+
+func (r *recorder) Start(ctx context.Context, name string) (context.Context, tracing.Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started = append(r.started, name)
+	return ctx, &recordedSpan{r, name}
+}
+
+type recordedSpan struct {
+	r    *recorder
+	name string
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...tracing.Attr) {}
+
+func (s *recordedSpan) AddEvent(name string, attrs ...tracing.Attr) {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	for _, a := range attrs {
+		name += fmt.Sprintf(" %s=%v", a.Key, a.Value)
+	}
+	s.r.events = append(s.r.events, name)
+}
+
+func (s *recordedSpan) RecordError(err error) {}
+
+func (s *recordedSpan) End() {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.r.ended = append(s.r.ended, s.name)
+}
+
+func TestTracing(t *testing.T) {
+	f := newFakeSCM()
+	r := &recorder{}
+	s := New("fake", stoppable(false, 0), WithSCM(f), WithTracerProvider(r))
+	done := make(chan error)
+	go func() {
+		done <- s.Run(context.Background())
+	}()
+	<-f.started
+	for s.Status().State != Running {
+		time.Sleep(time.Millisecond)
+	}
+	control(Stop)
+	err := <-done
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if want := []string{"svc.Run", "svc.Start", "svc.Stop"}; !reflect.DeepEqual(r.started, want) {
+		t.Errorf("started spans %q, want %q", r.started, want)
+	}
+	if want := []string{"svc.Start", "svc.Stop", "svc.Run"}; !reflect.DeepEqual(r.ended, want) {
+		t.Errorf("ended spans %q, want %q", r.ended, want)
+	}
+	want := []string{
+		"status state=2", // StartPending
+		"status state=4", // Running
+		"control cmd=1",  // Stop
+		"status state=3", // StopPending
+		"status state=1", // Stopped
+	}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Errorf("events are %q, want %q", r.events, want)
+	}
+}
+
+func TestControls(t *testing.T) {
+	f := newFakeSCM()
+	s := New("fake", stoppable(false, 0), WithSCM(f))
+	if cs := s.Controls(); len(cs) != 0 {
+		t.Errorf("service has received %v before Run", cs)
+	}
+	done := make(chan error)
+	go func() {
+		done <- s.Run(context.Background())
+	}()
+	<-f.started
+	before := time.Now()
+	control(Interrogate)
+	control(Interrogate)
+	control(Stop)
+	err := <-done
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	cs := s.Controls()
+	if len(cs) != 2 || cs[Interrogate].Count != 2 || cs[Stop].Count != 1 {
+		t.Fatalf("service has received %v, want 2 Interrogate and 1 Stop", cs)
+	}
+	if last := cs[Stop].Last; last.Before(before) || last.Before(cs[Interrogate].Last) {
+		t.Errorf("Stop was last received at %v, want after %v", last, cs[Interrogate].Last)
+	}
+}
+
+func TestStartReason(t *testing.T) {
+	f := newFakeSCM()
+	f.reason = StartReasonTrigger | StartReasonDemand
+	s := New("fake", stoppable(false, 0), WithSCM(f))
+	_, err := s.StartReason()
+	if err == nil {
+		t.Error("StartReason before Run should fail")
+	}
+	done := make(chan error)
+	go func() {
+		done <- s.Run(context.Background())
+	}()
+	<-f.started
+	for s.Status().State != Running {
+		time.Sleep(time.Millisecond)
+	}
+	r, err := s.StartReason()
+	control(Stop)
+	<-done
+	if err != nil {
+		t.Fatalf("StartReason failed: %v", err)
+	}
+	if r != f.reason {
+		t.Errorf("start reason is %#x, want %#x", r, f.reason)
+	}
+}
+
+func TestStopCause(t *testing.T) {
+	f := newFakeSCM()
+	l := &testLog{}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- New("fake", stoppable(false, 0), WithSCM(f), WithLogger(l)).Run(ctx)
+	}()
+	<-f.started
+	cause := &StopCause{
+		Reason:   StopReason{Code: winapi.SERVICE_STOP_REASON_MAJOR_APPLICATION | winapi.SERVICE_STOP_REASON_MINOR_RECONFIG, Comment: "bad config"},
+		ExitCode: 2,
+	}
+	cancel(fmt.Errorf("reload: %w", cause))
+	err := <-done
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	last := f.last()
+	if last.Win32ExitCode != uint32(winapi.ERROR_SERVICE_SPECIFIC_ERROR) || last.ServiceSpecificExitCode != 2 {
+		t.Errorf("service stopped with (%d, %d), want service specific exit code 2", last.Win32ExitCode, last.ServiceSpecificExitCode)
+	}
+	want := "error: service fake stopped: unplanned stop, reason 0x10050005: bad config (exit code 2)"
+	if len(l.msgs) == 0 || l.msgs[len(l.msgs)-1] != want {
+		t.Errorf("logged %q, want %q last", l.msgs, want)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	f := newFakeSCM()
+	release := make(chan error)
+	ready := func(ctx context.Context) error {
+		select {
+		case err := <-release:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	s := New("fake", stoppable(false, 0), WithSCM(f), WithReadiness(ready, 20*time.Millisecond))
+	done := make(chan error)
+	go func() {
+		done <- s.Run(context.Background())
+	}()
+	<-f.started
+	// wait for StartPending to be reported and advanced while not ready
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Status().CheckPoint < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if st := s.Status(); st.State != StartPending || st.CheckPoint < 3 || st.WaitHint != 20 {
+		t.Fatalf("status while not ready is %v, want start pending with advancing checkpoint and wait hint 20ms", st)
+	}
+	release <- nil
+	for s.Status().State != Running && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if st := s.Status(); st.State != Running || st.Accepts != AcceptStop {
+		t.Fatalf("status once ready is %v, want running accepting stop", st)
+	}
+	control(Stop)
+	err := <-done
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	// service that does not become ready stops
+	l := &testLog{}
+	f = newFakeSCM()
+	s = New("fake", stoppable(false, 0), WithSCM(f), WithLogger(l), WithReadiness(ready, time.Second))
+	go func() {
+		done <- s.Run(context.Background())
+	}()
+	<-f.started
+	release <- errors.New("connection refused")
+	err = <-done
+	if want := "service fake did not become ready: connection refused"; err == nil || err.Error() != want {
+		t.Errorf("Run returned %v, want %q", err, want)
+	}
+	for _, st := range f.statuses {
+		if st.CurrentState == winapi.SERVICE_RUNNING {
+			t.Errorf("service that is not ready reported running")
+		}
+	}
+	if last := f.last(); last.CurrentState != winapi.SERVICE_STOPPED || last.Win32ExitCode != uint32(winapi.ERROR_SERVICE_START_HANG) {
+		t.Errorf("last status reported %+v, want stopped with ERROR_SERVICE_START_HANG", last)
+	}
+	if want := `error: service fake failed to start during stage "readiness" (exit code 1070)` + "\n"; len(l.msgs) == 0 || !strings.HasPrefix(l.msgs[len(l.msgs)-1], want) {
+		t.Errorf("logged %q, want %q last", l.msgs, want)
+	}
+}
+
+func TestReport(t *testing.T) {
+	if Current() != nil {
+		t.Fatalf("Current returned service, while none is running")
+	}
+	f := newFakeSCM()
+	h := handlerFunc(func(args []string, r <-chan ChangeRequest, s chan<- Status) (bool, uint32) {
+		s <- Status{State: StartPending}
+		for c := range r {
+			if c.Cmd == Stop {
+				return false, 0
+			}
+		}
+		return false, 0
+	})
+	s := New("fake", h, WithSCM(f))
+	done := make(chan error)
+	go func() {
+		done <- s.Run(context.Background())
+	}()
+	<-f.started
+	// Report replaces status handler reported
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Status().State != StartPending && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if c := Current(); c != s {
+		t.Fatalf("Current returned %p, want %p", c, s)
+	}
+	Current().Report(Status{State: Running, Accepts: AcceptStop})
+	for s.Status().State != Running && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if st := s.Status(); st.State != Running || st.Accepts != AcceptStop {
+		t.Errorf("status after Report is %v, want running accepting stop", st)
+	}
+	control(Stop)
+	err := <-done
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	s.Report(Status{State: Running}) // service stopped, ignored
+	if Current() != nil {
+		t.Errorf("Current returned service, once it stopped")
+	}
+
+	// Run returns without starting service, Report must not block
+	s = New("fake", h, WithSCM(&nopSCM{}))
+	s.Run(context.Background())
+	s.Report(Status{State: Running})
+}
+
+func TestReload(t *testing.T) {
+	l := &testLog{}
+	f := newFakeSCM()
+	reloaded := make(chan int, 10)
+	release := make(chan error)
+	reload := func(ctx context.Context) error {
+		reloaded <- 1
+		select {
+		case err := <-release:
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	var cmds []Cmd
+	h := handlerFunc(func(args []string, r <-chan ChangeRequest, s chan<- Status) (bool, uint32) {
+		s <- Status{State: Running, Accepts: AcceptStop}
+		for c := range r {
+			cmds = append(cmds, c.Cmd)
+			if c.Cmd == Stop {
+				return false, 0
+			}
+		}
+		return false, 0
+	})
+	var intercepted []Cmd
+	record := func(next ControlFunc) ControlFunc {
+		return func(c ChangeRequest) {
+			intercepted = append(intercepted, c.Cmd)
+			next(c)
+		}
+	}
+	done := make(chan error)
+	go func() {
+		done <- New("fake", h, WithSCM(f), WithLogger(l), WithReload(reload), WithInterceptors(record)).Run(context.Background())
+	}()
+	<-f.started
+	control(Reload)
+	<-reloaded
+	// requests received meanwhile run reload once more
+	control(Reload)
+	control(Reload)
+	release <- errors.New("bad config")
+	<-reloaded
+	release <- nil
+	control(Stop)
+	err := <-done
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if want := []Cmd{Stop}; !reflect.DeepEqual(cmds, want) {
+		t.Errorf("handler received %v, want %v", cmds, want)
+	}
+	if want := []Cmd{Reload, Reload, Reload, Stop}; !reflect.DeepEqual(intercepted, want) {
+		t.Errorf("interceptor saw %v, want %v", intercepted, want)
+	}
+	var errs []string
+	for _, m := range l.msgs {
+		if strings.HasPrefix(m, "error: ") {
+			errs = append(errs, m)
+		}
+	}
+	want := []string{"error: service fake failed to reload: bad config"}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("logged %q, want %q", l.msgs, want)
+	}
+	if s := Reload.String(); s != "Reload" {
+		t.Errorf("Reload is named %q", s)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	for _, worker := range []bool{false, true} {
+		l := &testLog{}
+		f := newFakeSCM()
+		var s *Service
+		received := make(chan Cmd, 1) // by handler, once worker panicked
+		h := handlerFunc(func(args []string, r <-chan ChangeRequest, c chan<- Status) (bool, uint32) {
+			c <- Status{State: Running, Accepts: AcceptStop}
+			if worker {
+				s.Go(func() {
+					panic("worker failed")
+				})
+				received <- (<-r).Cmd
+				return false, 0
+			}
+			panic("handler failed")
+		})
+		s = New("fake", h, WithSCM(f), WithLogger(l))
+		err := s.Run(context.Background())
+		var p *PanicError
+		if !errors.As(err, &p) {
+			t.Fatalf("Run returned %v, want PanicError", err)
+		}
+		if worker {
+			select {
+			case c := <-received:
+				if c != Stop {
+					t.Errorf("handler received %v after worker panicked, want Stop", c)
+				}
+			case <-time.After(5 * time.Second):
+				t.Error("handler was not asked to stop after worker panicked")
+			}
+		}
+		want := "handler failed"
+		if worker {
+			want = "worker failed"
+		}
+		if p.Value != want || !strings.Contains(string(p.Stack), "TestPanic") || p.Version == "" {
+			t.Errorf("panic is %v, with version %q and stack:\n%s", p.Value, p.Version, p.Stack)
+		}
+		last := f.last()
+		if last.CurrentState != winapi.SERVICE_STOPPED || last.ServiceSpecificExitCode != uint32(ExitHandlerPanicked) {
+			t.Errorf("last status reported %+v, want stopped with ExitHandlerPanicked", last)
+		}
+		var logged string
+		for _, m := range l.msgs {
+			if strings.HasPrefix(m, "error: service fake panicked: "+want+"\nversion: ") {
+				logged = m
+			}
+		}
+		if !strings.Contains(logged, "\nuptime: ") || !strings.Contains(logged, "TestPanic") {
+			t.Errorf("logged %q, want panic with version, uptime and stack", l.msgs)
+		}
+	}
+
+	// Run returns without starting service, panic of
+	// worker must be logged, rather than block it
+	l := &testLog{}
+	s := New("fake", stoppable(false, 0), WithSCM(&nopSCM{}), WithLogger(l))
+	s.Run(context.Background())
+	s.Go(func() {
+		panic("late worker failed")
+	})
+	deadline := time.Now().Add(5 * time.Second)
+	for !l.logged("error: service fake panicked: late worker failed") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !l.logged("error: service fake panicked: late worker failed") {
+		t.Errorf("logged %q, want panic of late worker", l.msgs)
+	}
+}
+
+func TestSlowConnect(t *testing.T) {
+	l := &testLog{}
+	f := newFakeSCM()
+	f.processStart = time.Now().Add(-20 * time.Second)
+	h := handlerFunc(func(args []string, r <-chan ChangeRequest, s chan<- Status) (bool, uint32) {
+		return false, 0
+	})
+	err := New("fake", h, WithSCM(f), WithLogger(l)).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	want := "warning: service fake connected to the service control manager 20"
+	if len(l.msgs) == 0 || !strings.HasPrefix(l.msgs[0], want) || !strings.Contains(l.msgs[0], "ServicesPipeTimeout is 30s") {
+		t.Errorf("logged %q, want warning about ServicesPipeTimeout first", l.msgs)
+	}
+}
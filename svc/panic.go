@@ -0,0 +1,96 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// PanicError is returned by Run, when service handler, or worker
+// started by Service.Go, panics. Panic is recovered, logged to
+// service Logger, with stack trace, program version and service
+// uptime, and service is stopped with ExitHandlerPanicked exit code.
+type PanicError struct {
+	Value   interface{}   // value passed to panic
+	Stack   []byte        // stack of panicking goroutine
+	Version string        // program version, see BuildVersion
+	Uptime  time.Duration // since service was started
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("service panicked: %v", e.Value)
+}
+
+// Go runs f on new goroutine, as worker of service s. If f panics,
+// while s runs, service is stopped, as if its handler panicked. See
+// PanicError. Panics of workers, started while s does not run, are
+// not recovered.
+func (s *Service) Go(f func()) {
+	s.mu.Lock()
+	srv := s.srv
+	s.mu.Unlock()
+	go func() {
+		if srv != nil {
+			defer srv.recoverPanic()
+		}
+		f()
+	}()
+}
+
+// recoverPanic recovers panic, if any, and passes it to service loop,
+// or logs it, if service is stopped already, or Run failed before
+// loop started. It must be deferred.
+func (s *service) recoverPanic() {
+	v := recover()
+	if v == nil {
+		return
+	}
+	p := &PanicError{Value: v, Stack: debug.Stack(), Version: BuildVersion()}
+	if len(p.Stack) > maxStackDump {
+		p.Stack = p.Stack[:maxStackDump]
+	}
+	select {
+	case s.panics <- p:
+	case <-s.done:
+		s.logPanic(p)
+	case <-s.ran:
+		s.logPanic(p)
+	}
+}
+
+// logPanic logs panic p as single event.
+func (s *service) logPanic(p *PanicError) {
+	if !s.lc.begin.IsZero() {
+		p.Uptime = time.Since(s.lc.begin)
+	}
+	if s.log == nil {
+		return
+	}
+	s.log.Error(1, fmt.Sprintf("service %s panicked: %v\nversion: %s\nuptime: %v\n\n%s",
+		s.name, p.Value, p.Version, p.Uptime, p.Stack))
+}
+
+// BuildVersion returns version of running program, as recorded by
+// go build, like "example.com/game v1.2.0 go1.22.1 vcs.revision=a1b2c3".
+func BuildVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return runtime.Version()
+	}
+	v := []string{bi.Main.Path, bi.Main.Version, bi.GoVersion}
+	for _, st := range bi.Settings {
+		switch st.Key {
+		case "vcs.revision", "vcs.time", "vcs.modified":
+			v = append(v, st.Key+"="+st.Value)
+		}
+	}
+	return strings.TrimSpace(strings.Join(v, " "))
+}
@@ -0,0 +1,53 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import "time"
+
+// Tick reports progress of pending operation in progress, like long
+// database migration during StartPending, by incrementing CheckPoint
+// of status reported last. Handler does not need to build and send
+// the whole Status. Tick does nothing, if service is not pending.
+func (s *Service) Tick() {
+	s.progress(0)
+}
+
+// ExtendWait is like Tick, but also sets WaitHint to d, so the service
+// control manager waits for d before it expects next progress report.
+func (s *Service) ExtendWait(d time.Duration) {
+	if d <= 0 {
+		d = time.Millisecond
+	}
+	s.progress(d)
+}
+
+func (s *Service) progress(d time.Duration) {
+	s.mu.Lock()
+	srv := s.srv
+	s.mu.Unlock()
+	if srv == nil {
+		return
+	}
+	select {
+	case srv.progress <- d:
+	case <-srv.done:
+	case <-srv.ran: // Run failed before loop started
+	}
+}
+
+// advance returns status st with CheckPoint incremented and, if
+// d is not 0, WaitHint set to d. ok is false, if st is not pending.
+func advance(st Status, d time.Duration) (next Status, ok bool) {
+	if !isProgress(st, st) {
+		return st, false
+	}
+	st.CheckPoint++
+	if d != 0 {
+		st.WaitHint = uint32(d / time.Millisecond)
+	}
+	return st, true
+}
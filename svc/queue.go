@@ -0,0 +1,150 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"strconv"
+	"sync/atomic"
+	"unsafe"
+)
+
+// ctlQueueSize is number of control requests queued
+// before ctlHandler drops them.
+const ctlQueueSize = 32
+
+// ctlSlot is control request queued by ctlHandler.
+type ctlSlot struct {
+	e    ctlEvent
+	n    uint32 // length of event data kept in data
+	data [maxEventData]byte
+}
+
+// ctlQueue passes control requests from ctlHandler to service loop.
+// The service control manager calls ctlHandler on dispatcher thread,
+// one request at a time, and waits for it to return, so ctlHandler
+// must neither allocate nor block. Requests, with their event data,
+// are written to slots preallocated in ring, and published by
+// advancing tail, while loop, the only reader, advances head.
+// Interrogate requests, that the service control manager sends
+// repeatedly, are coalesced, while one is queued. Stop, Shutdown and
+// PreShutdown requests, that do not fit the ring, are latched, like
+// errno, and taken before other requests. Other requests that do not
+// fit are dropped and counted, so loop can report them.
+type ctlQueue struct {
+	slots [ctlQueueSize]ctlSlot
+	head  uint32 // next slot to read, advanced by loop
+	tail  uint32 // next slot to write, advanced by ctlHandler
+
+	interrogate uint32 // number of Interrogate requests, while one is queued
+	errno       uint32 // set, if ctlHandler is called on wrong thread
+	stop        uint32 // Stop, Shutdown or PreShutdown, that did not fit the ring
+	dropped     uint32 // number of requests dropped, as ring was full
+
+	ready chan struct{} // signalled, when request is queued
+}
+
+func newCtlQueue() *ctlQueue {
+	return &ctlQueue{ready: make(chan struct{}, 1)}
+}
+
+// signal wakes loop up, unless it is woken up already.
+func (q *ctlQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// push queues control request c of event type t with event
+// data p. It is called by ctlHandler.
+func (q *ctlQueue) push(c Cmd, t uint32, p uintptr, context uintptr) {
+	if c == Interrogate && atomic.AddUint32(&q.interrogate, 1) > 1 {
+		// loop answers queued one with current status
+		return
+	}
+	tail := atomic.LoadUint32(&q.tail)
+	if tail-atomic.LoadUint32(&q.head) == ctlQueueSize {
+		if isStop(c) {
+			atomic.StoreUint32(&q.stop, uint32(c))
+			q.signal()
+			return
+		}
+		if c == Interrogate {
+			atomic.StoreUint32(&q.interrogate, 0)
+		}
+		atomic.AddUint32(&q.dropped, 1)
+		q.signal()
+		return
+	}
+	s := &q.slots[tail%ctlQueueSize]
+	s.e = ctlEvent{cmd: c, eventType: EventType(t), context: context}
+	s.n = eventDataSize(c, t, p)
+	if s.n > 0 {
+		copy(s.data[:s.n], unsafe.Slice((*byte)(eventDataPtr(p)), s.n))
+	}
+	atomic.StoreUint32(&q.tail, tail+1)
+	q.signal()
+}
+
+// fail queues errno, that loop takes before any other request.
+func (q *ctlQueue) fail(errno uint32) {
+	atomic.StoreUint32(&q.errno, errno)
+	q.signal()
+}
+
+// pop returns request queued first. ok is false, if
+// there is none. It is called by loop only.
+func (q *ctlQueue) pop() (e ctlEvent, ok bool) {
+	if errno := atomic.SwapUint32(&q.errno, 0); errno != 0 {
+		return ctlEvent{errno: errno}, true
+	}
+	if c := atomic.SwapUint32(&q.stop, 0); c != 0 {
+		return ctlEvent{cmd: Cmd(c)}, true
+	}
+	head := atomic.LoadUint32(&q.head)
+	if head == atomic.LoadUint32(&q.tail) {
+		return ctlEvent{}, false
+	}
+	s := &q.slots[head%ctlQueueSize]
+	e = s.e
+	if s.n > 0 {
+		e.eventData = append([]byte(nil), s.data[:s.n]...)
+	}
+	s.e = ctlEvent{}
+	atomic.StoreUint32(&q.head, head+1)
+	if e.cmd == Interrogate {
+		e.coalesced = atomic.SwapUint32(&q.interrogate, 0) - 1
+	}
+	return e, true
+}
+
+// pending reports whether there are requests left to pop.
+func (q *ctlQueue) pending() bool {
+	return atomic.LoadUint32(&q.errno) != 0 || atomic.LoadUint32(&q.stop) != 0 ||
+		atomic.LoadUint32(&q.head) != atomic.LoadUint32(&q.tail)
+}
+
+// takeDropped returns number of requests dropped
+// since it was last called, and resets it.
+func (q *ctlQueue) takeDropped() uint32 {
+	return atomic.SwapUint32(&q.dropped, 0)
+}
+
+// logDropped logs that n control requests were dropped.
+func (s *service) logDropped(n uint32) {
+	if s.log != nil {
+		s.log.Warning(1, "service "+s.name+" dropped "+strconv.FormatUint(uint64(n), 10)+
+			" control requests, as its queue was full")
+	}
+}
+
+// eventDataPtr returns event data pointer p passed to ctlHandler.
+func eventDataPtr(p uintptr) unsafe.Pointer {
+	// p is reinterpreted, not converted, as go vet
+	// cannot tell p holds pointer passed by Windows
+	return *(*unsafe.Pointer)(unsafe.Pointer(&p))
+}
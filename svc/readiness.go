@@ -0,0 +1,96 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"context"
+	"time"
+)
+
+// readinessStage is startup stage reported while
+// service waits for readiness function.
+const readinessStage = "readiness"
+
+// defaultReadyWaitHint is WaitHint reported while service
+// waits for readiness function, if WithReadiness is given 0.
+const defaultReadyWaitHint = 10 * time.Second
+
+// WithReadiness delays Running status until service is ready to
+// serve, as decided by ready, like once listener is bound and database
+// is reachable. When handler reports Running for the first time,
+// StartPending is reported instead, and ready is called on its own
+// goroutine. Until ready returns, CheckPoint advances every half of
+// waitHint, which is reported as WaitHint. Running status handler
+// reported is reported once ready returns nil. If ready returns error,
+// service is stopped with ERROR_SERVICE_START_HANG exit code, and Run
+// returns the error. Context passed to ready is canceled, if handler
+// reports other state meanwhile, like StopPending, or service stops.
+// waitHint of 0 means 10 seconds.
+func WithReadiness(ready func(ctx context.Context) error, waitHint time.Duration) Option {
+	return func(o *options) {
+		o.ready = ready
+		o.readyWaitHint = waitHint
+	}
+}
+
+// readiness tracks service waiting for readiness function.
+type readiness struct {
+	ready    func(ctx context.Context) error // nil, if service is not gated
+	waitHint time.Duration
+
+	asked  bool             // ready was called
+	held   Status           // Running status to report once ready
+	result chan error       // receives ready result, nil when not waiting
+	tick   <-chan time.Time // fires when progress is due
+	ticker *time.Ticker
+	cancel context.CancelFunc
+}
+
+// gate calls ready, if it was not called yet, and holds Running
+// status c until it returns. It returns status to be reported
+// instead, StartPending advancing from last, or c, if not held.
+func (r *readiness) gate(c, last Status) Status {
+	if r.ready == nil || r.asked {
+		return c
+	}
+	r.asked = true
+	r.held = c
+	w := r.waitHint
+	if w <= 0 {
+		w = defaultReadyWaitHint
+	}
+	var ctx context.Context
+	ctx, r.cancel = context.WithCancel(context.Background())
+	r.result = make(chan error, 1)
+	go func(result chan<- error) {
+		result <- r.ready(ctx)
+	}(r.result)
+	r.ticker = time.NewTicker(w / 2)
+	r.tick = r.ticker.C
+	pending := Status{State: StartPending, CheckPoint: 1, WaitHint: uint32(w / time.Millisecond), Stage: readinessStage}
+	if last.State == StartPending {
+		pending.Accepts = last.Accepts
+		pending.CheckPoint = last.CheckPoint + 1
+	}
+	return pending
+}
+
+// waiting reports whether service waits for ready to return.
+func (r *readiness) waiting() bool {
+	return r.result != nil
+}
+
+// stop stops waiting for ready, and cancels its context.
+func (r *readiness) stop() {
+	if r.result == nil {
+		return
+	}
+	r.cancel()
+	r.ticker.Stop()
+	r.result = nil
+	r.tick = nil
+}
@@ -0,0 +1,35 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import "time"
+
+// Ready sends Running status to handler status channel s. Service
+// accepts all of accepts, or Stop and Shutdown, if there are none,
+// so it can still be stopped.
+func Ready(s chan<- Status, accepts ...Accepted) {
+	a := AcceptStop | AcceptShutdown
+	if len(accepts) > 0 {
+		a = 0
+	}
+	for _, x := range accepts {
+		a |= x
+	}
+	s <- Status{State: Running, Accepts: a}
+}
+
+// Stopping sends StopPending status to handler status channel s,
+// telling the service control manager to wait up to waitHint
+// before it expects next progress report. CheckPoint starts at 1,
+// and is incremented by every next Stopping, like Service.Tick does.
+func Stopping(s chan<- Status, waitHint time.Duration) {
+	st := Status{State: StopPending, advance: true}
+	if waitHint > 0 {
+		st.WaitHint = uint32(waitHint / time.Millisecond)
+	}
+	s <- st
+}
@@ -0,0 +1,104 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// StopReason describes why service stops itself. Handler attaches
+// it to status it reports, usually StopPending, and it is logged
+// to the service Logger once service is Stopped, so intentional
+// exits can be told from failures afterwards.
+type StopReason struct {
+	Planned bool
+	// Code is a combination of winapi.SERVICE_STOP_REASON_MAJOR_*
+	// and winapi.SERVICE_STOP_REASON_MINOR_* reason.
+	Code    uint32
+	Comment string
+}
+
+// Value returns r as a stop reason accepted by ControlServiceEx,
+// like reason passed to mgr.Service.ControlWithReason.
+func (r *StopReason) Value() uint32 {
+	if r.Planned {
+		return winapi.SERVICE_STOP_REASON_FLAG_PLANNED | r.Code
+	}
+	return winapi.SERVICE_STOP_REASON_FLAG_UNPLANNED | r.Code
+}
+
+func (r *StopReason) String() string {
+	kind := "unplanned"
+	if r.Planned {
+		kind = "planned"
+	}
+	s := fmt.Sprintf("%s stop, reason %#08x", kind, r.Value())
+	if r.Comment != "" {
+		s += ": " + r.Comment
+	}
+	return s
+}
+
+// StopCause is cause of Run context being done, that tells why service
+// stops, like:
+//
+//	ctx, cancel := context.WithCancelCause(ctx)
+//	...
+//	cancel(&svc.StopCause{Reason: svc.StopReason{Code: ...}, ExitCode: 2, Err: err})
+//
+// Reason is logged, once service is Stopped, unless handler reports
+// reason of its own. ExitCode, if not 0, is reported to the service
+// control manager as service specific exit code, unless handler returns
+// exit code other than 0, so service stopped because of broken config
+// can be told from one stopped by operator in the system event log.
+// Causes wrapping StopCause are recognized too.
+type StopCause struct {
+	Reason   StopReason
+	ExitCode uint32
+	Err      error // underlying error, if any
+}
+
+func (c *StopCause) Error() string {
+	s := "service stopped: " + c.Reason.String()
+	if c.Err != nil {
+		s += ": " + c.Err.Error()
+	}
+	return s
+}
+
+func (c *StopCause) Unwrap() error {
+	return c.Err
+}
+
+// stopCause returns StopCause of context ctx, or nil.
+func stopCause(ctx context.Context) *StopCause {
+	var c *StopCause
+	if errors.As(context.Cause(ctx), &c) {
+		return c
+	}
+	return nil
+}
+
+// logStop logs that service stopped for reason r with exit code ec.
+func (s *service) logStop(r *StopReason, ec exitCode) {
+	if s.log == nil {
+		return
+	}
+	msg := "service " + s.name + " stopped: " + r.String()
+	if ec.errno != 0 {
+		msg += fmt.Sprintf(" (exit code %d)", ec.errno)
+	}
+	if r.Planned && ec.errno == 0 {
+		s.log.Info(1, msg)
+	} else {
+		s.log.Error(1, msg)
+	}
+}
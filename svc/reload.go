@@ -0,0 +1,92 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"context"
+	"sync"
+)
+
+// Reload is user defined control request, that asks service to
+// reload its configuration without restart. mgr.Service.Reload
+// sends it. Unlike ParamChange, it needs no Accepts flag, as user
+// defined requests are always delivered.
+const Reload = Cmd(128)
+
+// WithReload makes service call reload on its own goroutine, when
+// Reload request is received, instead of passing the request to
+// handler. Reload requests pass interceptors first, like any other.
+// Requests received, while reload runs, make it run once more, after
+// it returns. Error reload returns is logged to service
+// Logger. Context passed to reload is canceled once service stops,
+// and Run waits for reload to return.
+func WithReload(reload func(ctx context.Context) error) Option {
+	return func(o *options) {
+		o.reload = reload
+	}
+}
+
+// reloader runs reload function of service, one call at a time.
+type reloader struct {
+	reload func(ctx context.Context) error // nil, if not set
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	running bool // reload runs
+	again   bool // Reload request came, while reload runs
+}
+
+// requestReload runs reload of service s, or, if it runs already,
+// makes it run again. It reports whether reload is set.
+func (s *service) requestReload() bool {
+	r := &s.reloader
+	if r.reload == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		r.again = true
+		return true
+	}
+	if r.ctx == nil {
+		r.ctx, r.cancel = context.WithCancel(context.Background())
+	}
+	r.running = true
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for {
+			err := r.reload(r.ctx)
+			if err != nil {
+				s.logError("service " + s.name + " failed to reload: " + err.Error())
+			}
+			r.mu.Lock()
+			if !r.again || r.ctx.Err() != nil {
+				r.running = false
+				r.mu.Unlock()
+				return
+			}
+			r.again = false
+			r.mu.Unlock()
+		}
+	}()
+	return true
+}
+
+// stopReload cancels reload of service s, and waits for it to return.
+func (s *service) stopReload() {
+	r := &s.reloader
+	r.mu.Lock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+}
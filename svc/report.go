@@ -0,0 +1,36 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+// Current returns service being run by Run in this process,
+// or nil, if there is none. There is one service running per
+// process, so code, that is not passed the service, like server
+// code shared with other platforms, can report its status.
+func Current() *Service {
+	srv := currentService()
+	if srv == nil {
+		return nil
+	}
+	return srv.owner
+}
+
+// Report reports status st, as if handler sent it, on behalf of code
+// that has no access to handler status channel. Status handler sends
+// later replaces st. Report does nothing, if service is not running.
+func (s *Service) Report(st Status) {
+	s.mu.Lock()
+	srv := s.srv
+	s.mu.Unlock()
+	if srv == nil {
+		return
+	}
+	select {
+	case srv.reports <- st:
+	case <-srv.done:
+	case <-srv.ran: // Run failed before loop started
+	}
+}
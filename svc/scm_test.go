@@ -0,0 +1,489 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+	"unicode/utf16"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// fakeSCM stands in for the service control manager. It starts
+// service on Dispatch, and records every status reported.
+type fakeSCM struct {
+	args    []string
+	started chan *service
+	fail    error // returned by SetStatus, if set
+
+	registerErr  error       // returned by Register, if set
+	reason       StartReason // returned by StartReason
+	processStart time.Time   // returned by Startup, if set
+
+	mu       sync.Mutex
+	statuses []winapi.SERVICE_STATUS
+}
+
+func newFakeSCM(args ...string) *fakeSCM {
+	return &fakeSCM{args: args, started: make(chan *service, 1)}
+}
+
+// Dispatch calls main, as the service control
+// manager does, with arguments f.args.
+func (f *fakeSCM) Dispatch(name string, main func(args []string)) error {
+	f.started <- currentService()
+	main(f.args)
+	return nil
+}
+
+func (f *fakeSCM) Register(name string, handler ControlHandler) (syscall.Handle, error) {
+	if f.registerErr != nil {
+		return 0, f.registerErr
+	}
+	return 1, nil
+}
+
+// control sends control request c, as the service control manager does.
+func control(c Cmd) {
+	ctlHandler(uint32(c), 0, 0, 0)
+}
+
+func (f *fakeSCM) StartReason(h syscall.Handle) (StartReason, error) {
+	return f.reason, nil
+}
+
+func (f *fakeSCM) Startup() (time.Time, time.Duration) {
+	if !f.processStart.IsZero() {
+		return f.processStart, defaultPipeTimeout
+	}
+	return time.Now(), defaultPipeTimeout
+}
+
+func (f *fakeSCM) SetStatus(h syscall.Handle, t *winapi.SERVICE_STATUS) error {
+	if h != 1 {
+		return syscall.Errno(6) // ERROR_INVALID_HANDLE
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statuses = append(f.statuses, *t)
+	return f.fail
+}
+
+func (f *fakeSCM) states() []uint32 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ss []uint32
+	for _, t := range f.statuses {
+		ss = append(ss, t.CurrentState)
+	}
+	return ss
+}
+
+func (f *fakeSCM) last() winapi.SERVICE_STATUS {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.statuses[len(f.statuses)-1]
+}
+
+// runFake runs handler h with fake service control manager f,
+// and returns service started and channel closed once it stops.
+func runFake(t *testing.T, f *fakeSCM, h Handler) (*service, <-chan struct{}) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := run(f, "fake", h)
+		if err != nil {
+			t.Errorf("run failed: %v", err)
+		}
+	}()
+	return <-f.started, done
+}
+
+type handlerFunc func(args []string, r <-chan ChangeRequest, s chan<- Status) (bool, uint32)
+
+func (f handlerFunc) Execute(args []string, r <-chan ChangeRequest, s chan<- Status) (bool, uint32) {
+	return f(args, r, s)
+}
+
+// stoppable reports start progress, runs until stopped,
+// and then exits with exit code ec.
+func stoppable(svcSpecific bool, ec uint32) Handler {
+	return handlerFunc(func(args []string, r <-chan ChangeRequest, s chan<- Status) (bool, uint32) {
+		s <- Status{State: StartPending, CheckPoint: 1, WaitHint: 3000}
+		s <- Status{State: Running, Accepts: AcceptStop}
+		for c := range r {
+			switch c.Cmd {
+			case Interrogate:
+				s <- c.CurrentStatus
+			case Stop:
+				s <- Status{State: StopPending}
+				return svcSpecific, ec
+			}
+		}
+		return svcSpecific, ec
+	})
+}
+
+func TestRunLoop(t *testing.T) {
+	f := newFakeSCM("fake", "-v")
+	var args []string
+	h := stoppable(false, 0)
+	_, done := runFake(t, f, handlerFunc(func(a []string, r <-chan ChangeRequest, st chan<- Status) (bool, uint32) {
+		args = a
+		return h.Execute(a, r, st)
+	}))
+	control(Interrogate)
+	control(Stop)
+	<-done
+
+	if want := []string{"fake", "-v"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("handler args are %q, want %q", args, want)
+	}
+	want := []uint32{
+		winapi.SERVICE_START_PENDING,
+		winapi.SERVICE_RUNNING, // answer to Interrogate is not reported again
+		winapi.SERVICE_STOP_PENDING,
+		winapi.SERVICE_STOPPED,
+	}
+	if got := f.states(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("reported states %v, want %v", got, want)
+	}
+	first := f.statuses[0]
+	if first.CheckPoint != 1 || first.WaitHint != 3000 {
+		t.Errorf("checkpoint %d and wait hint %d reported, want 1 and 3000", first.CheckPoint, first.WaitHint)
+	}
+	if c := f.statuses[1].ControlsAccepted; c != winapi.SERVICE_ACCEPT_STOP {
+		t.Errorf("running service accepts %#x, want %#x", c, winapi.SERVICE_ACCEPT_STOP)
+	}
+	if c := f.last().ControlsAccepted; c != 0 {
+		t.Errorf("stopped service accepts %#x, want 0", c)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		svcSpecific bool
+		ec          uint32
+		win32       uint32
+		specific    uint32
+	}{
+		{false, 0, winapi.NO_ERROR, winapi.NO_ERROR},
+		{true, 0, winapi.NO_ERROR, winapi.NO_ERROR},
+		{false, 5, 5, winapi.NO_ERROR},
+		{true, 5, uint32(winapi.ERROR_SERVICE_SPECIFIC_ERROR), 5},
+	}
+	for _, test := range tests {
+		f := newFakeSCM()
+		_, done := runFake(t, f, stoppable(test.svcSpecific, test.ec))
+		control(Stop)
+		<-done
+		last := f.last()
+		if last.CurrentState != winapi.SERVICE_STOPPED {
+			t.Errorf("last state is %d, want stopped", last.CurrentState)
+		}
+		if last.Win32ExitCode != test.win32 || last.ServiceSpecificExitCode != test.specific {
+			t.Errorf("(%v, %d) reported as (%d, %d), want (%d, %d)",
+				test.svcSpecific, test.ec, last.Win32ExitCode, last.ServiceSpecificExitCode, test.win32, test.specific)
+		}
+	}
+}
+
+func TestSetStatusFailure(t *testing.T) {
+	f := newFakeSCM()
+	f.fail = syscall.ERROR_ACCESS_DENIED
+	_, done := runFake(t, f, stoppable(false, 0))
+	<-done
+	last := f.last()
+	if last.CurrentState != winapi.SERVICE_STOPPED {
+		t.Fatalf("last state is %d, want stopped", last.CurrentState)
+	}
+	if last.ServiceSpecificExitCode != uint32(syscall.ERROR_ACCESS_DENIED) {
+		t.Errorf("exit code is %d, want %d", last.ServiceSpecificExitCode, syscall.ERROR_ACCESS_DENIED)
+	}
+}
+
+func TestCallbackOnWrongThread(t *testing.T) {
+	f := newFakeSCM()
+	s, done := runFake(t, f, stoppable(false, 0))
+	// no thread has odd id
+	s.tid = 1
+	control(Stop)
+	<-done
+	if ec := f.last().ServiceSpecificExitCode; ec != uint32(ExitNewThreadInCallback) {
+		t.Errorf("exit code is %d, want %d", ec, ExitNewThreadInCallback)
+	}
+}
+
+// TestServiceMain runs service through main and ctlHandler
+// callbacks, while control requests arrive from many threads at
+// once, so the race detector can check handoff between them.
+func TestServiceMain(t *testing.T) {
+	f := newFakeSCM("fake")
+	_, done := runFake(t, f, stoppable(false, 0))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				control(Interrogate)
+			}
+		}()
+	}
+	wg.Wait()
+	control(Stop)
+	<-done
+	// late requests must not block, once service is stopped
+	control(Interrogate)
+	if s := f.last(); s.CurrentState != winapi.SERVICE_STOPPED {
+		t.Errorf("last state is %d, want stopped", s.CurrentState)
+	}
+}
+
+func TestRegisterFailure(t *testing.T) {
+	f := newFakeSCM()
+	f.registerErr = syscall.ERROR_ACCESS_DENIED
+	err := run(f, "fake", stoppable(false, 0))
+	if err == nil {
+		t.Fatal("run should fail, when control handler cannot be registered")
+	}
+	if len(f.statuses) != 0 {
+		t.Errorf("%d statuses reported without service status handle", len(f.statuses))
+	}
+}
+
+// threadSCM checks that Dispatch stays on one OS thread.
+type threadSCM struct {
+	*fakeSCM
+	moved bool
+}
+
+func (m *threadSCM) Dispatch(name string, main func(args []string)) error {
+	tid := winapi.GetCurrentThreadId()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runtime.Gosched()
+		}()
+	}
+	wg.Wait()
+	err := m.fakeSCM.Dispatch(name, main)
+	m.moved = tid != winapi.GetCurrentThreadId()
+	return err
+}
+
+// TestDispatchThread checks threading contract of Run: dispatcher,
+// and so control handler, runs on goroutine locked to its thread,
+// while Run caller is not locked.
+func TestDispatchThread(t *testing.T) {
+	f := &threadSCM{fakeSCM: newFakeSCM()}
+	done := make(chan error)
+	go func() {
+		done <- run(f, "fake", stoppable(false, 0))
+	}()
+	<-f.started
+	control(Stop)
+	err := <-done
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if f.moved {
+		t.Error("dispatcher moved to another OS thread")
+	}
+}
+
+func TestEventData(t *testing.T) {
+	f := newFakeSCM()
+	got := make(chan ChangeRequest, 1)
+	_, done := runFake(t, f, handlerFunc(func(args []string, r <-chan ChangeRequest, s chan<- Status) (bool, uint32) {
+		s <- Status{State: Running, Accepts: AcceptStop | AcceptSessionChange}
+		for c := range r {
+			switch c.Cmd {
+			case SessionChange:
+				got <- c
+			case Stop:
+				return false, 0
+			}
+		}
+		return false, 0
+	}))
+	n := winapi.WTS_SESSION_NOTIFICATION{Size: 8, SessionID: 3}
+	ctlHandler(uint32(SessionChange), winapi.WTS_SESSION_LOGON, uintptr(unsafe.Pointer(&n)), 0)
+	// handler must not see changes made after ctlHandler returns
+	n.SessionID = 4
+	c := <-got
+	control(Stop)
+	<-done
+	if c.EventType != winapi.WTS_SESSION_LOGON {
+		t.Errorf("event type is %d, want %d", c.EventType, winapi.WTS_SESSION_LOGON)
+	}
+	if want := []byte{8, 0, 0, 0, 3, 0, 0, 0}; !reflect.DeepEqual(c.EventData, want) {
+		t.Errorf("event data is %v, want %v", c.EventData, want)
+	}
+	if a := f.statuses[0].ControlsAccepted; a != winapi.SERVICE_ACCEPT_STOP|winapi.SERVICE_ACCEPT_SESSIONCHANGE {
+		t.Errorf("service accepts %#x, want stop and session change", a)
+	}
+}
+
+func TestCtlHandlerAllocs(t *testing.T) {
+	s := newService("fake", nil, &nopSCM{})
+	err := setCurrent(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer setCurrent(nil)
+	n := winapi.WTS_SESSION_NOTIFICATION{Size: 8, SessionID: 3}
+	allocs := testing.AllocsPerRun(100, func() {
+		ctlHandler(uint32(Interrogate), 0, 0, 0)
+		ctlHandler(uint32(SessionChange), winapi.WTS_SESSION_LOGON, uintptr(unsafe.Pointer(&n)), 0)
+		// loop is not running, so take requests, as it would
+		atomic.StoreUint32(&s.queue.head, atomic.LoadUint32(&s.queue.tail))
+	})
+	if allocs != 0 {
+		t.Errorf("ctlHandler allocates %v times, want 0", allocs)
+	}
+}
+
+func TestCtlQueue(t *testing.T) {
+	q := newCtlQueue()
+	n := winapi.WTS_SESSION_NOTIFICATION{Size: 8}
+	// more than fit the ring, so the rest are dropped
+	for i := 0; i < 2*ctlQueueSize; i++ {
+		n.SessionID = uint32(i)
+		q.push(SessionChange, winapi.WTS_SESSION_LOGON, uintptr(unsafe.Pointer(&n)), 0)
+		q.push(Interrogate, 0, 0, 0)
+	}
+	if e, ok := q.pop(); !ok || e.cmd != SessionChange {
+		t.Fatalf("first request is %v, want SessionChange", e.cmd)
+	}
+	if e, ok := q.pop(); !ok || e.cmd != Interrogate || e.coalesced != 2*ctlQueueSize-1 {
+		t.Fatalf("got %v coalescing %d requests, want Interrogate coalescing %d", e.cmd, e.coalesced, 2*ctlQueueSize-1)
+	}
+	for i := 1; i < ctlQueueSize-1; i++ {
+		e, ok := q.pop()
+		if !ok || e.cmd != SessionChange {
+			t.Fatalf("request %d is %v, want SessionChange", i, e.cmd)
+		}
+		if id := binary.LittleEndian.Uint32(e.eventData[4:]); id != uint32(i) {
+			t.Fatalf("request %d is for session %d, want %d", i, id, i)
+		}
+	}
+	if q.pending() {
+		t.Fatal("requests are left in queue")
+	}
+	if n := q.takeDropped(); n != ctlQueueSize+1 {
+		t.Fatalf("dropped %d requests, want %d", n, ctlQueueSize+1)
+	}
+	if n := q.takeDropped(); n != 0 {
+		t.Fatalf("dropped count is %d after it was taken, want 0", n)
+	}
+	q.push(Stop, 0, 0, 0)
+	if e, ok := q.pop(); !ok || e.cmd != Stop {
+		t.Fatalf("request after drops is %v, want Stop", e.cmd)
+	}
+
+	// Shutdown is kept, even if ring is full
+	for i := 0; i < ctlQueueSize; i++ {
+		q.push(Pause, 0, 0, 0)
+	}
+	q.push(Shutdown, 0, 0, 0)
+	if n := q.takeDropped(); n != 0 {
+		t.Fatalf("dropped %d requests, want Shutdown kept", n)
+	}
+	if e, ok := q.pop(); !ok || e.cmd != Shutdown {
+		t.Fatalf("request with full ring is %v, want Shutdown first", e.cmd)
+	}
+	for i := 0; i < ctlQueueSize; i++ {
+		if e, ok := q.pop(); !ok || e.cmd != Pause {
+			t.Fatalf("request %d after Shutdown is %v, want Pause", i, e.cmd)
+		}
+	}
+	q.push(Interrogate, 0, 0, 0)
+	q.fail(uint32(ExitNewThreadInCallback))
+	if e, ok := q.pop(); !ok || e.errno != uint32(ExitNewThreadInCallback) {
+		t.Fatalf("first request has errno %d, want %d", e.errno, ExitNewThreadInCallback)
+	}
+	if e, ok := q.pop(); !ok || e.cmd != Interrogate || e.errno != 0 {
+		t.Fatalf("request after errno is %v with errno %d, want Interrogate", e.cmd, e.errno)
+	}
+}
+
+func FuzzEventDataSize(f *testing.F) {
+	f.Add(uint32(SessionChange), uint32(winapi.WTS_SESSION_LOGON), []byte{8, 0, 0, 0, 3, 0, 0, 0})
+	f.Add(uint32(DeviceEvent), uint32(winapi.DBT_DEVICEARRIVAL), []byte{12, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	f.Add(uint32(PowerEvent), uint32(winapi.PBT_POWERSETTINGCHANGE), append(make([]byte, 16), 4, 0, 0, 0, 1, 0, 0, 0))
+	f.Add(uint32(PowerEvent), uint32(winapi.PBT_POWERSETTINGCHANGE), append(make([]byte, 16), 0xf0, 0xff, 0xff, 0xff))
+	f.Fuzz(func(t *testing.T, c, et uint32, data []byte) {
+		// buffer is as large as push may read,
+		// whatever sizes data claims, so it only sees data
+		buf := make([]byte, maxEventData+64)
+		copy(buf, data)
+		q := newCtlQueue()
+		q.push(Cmd(c), et, uintptr(unsafe.Pointer(&buf[0])), 0)
+		e, _ := q.pop()
+		got := e.eventData
+
+		var want uint64
+		switch {
+		case Cmd(c) == SessionChange:
+			want = 8
+		case Cmd(c) == DeviceEvent:
+			want = uint64(binary.LittleEndian.Uint32(buf))
+		case Cmd(c) == PowerEvent && et == winapi.PBT_POWERSETTINGCHANGE:
+			want = 20 + uint64(binary.LittleEndian.Uint32(buf[16:]))
+		}
+		if want > maxEventData {
+			want = 0
+		}
+		if uint64(len(got)) != want {
+			t.Fatalf("queued %d bytes, want %d", len(got), want)
+		}
+		if !bytes.Equal(got, buf[:len(got)]) {
+			t.Fatal("queued data differs from event data")
+		}
+	})
+}
+
+func FuzzServiceArgs(f *testing.F) {
+	f.Add([]byte("s\x00v\x00c\x00\x00\x00-\x00v\x00"))
+	f.Add([]byte{0x3d, 0xd8, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		u := make([]uint16, len(data)/2)
+		for i := range u {
+			u[i] = binary.LittleEndian.Uint16(data[2*i:])
+		}
+		// each argument is null terminated, as passed by the
+		// service control manager
+		var argv []*uint16
+		var want []string
+		from := 0
+		for i := 0; i <= len(u); i++ {
+			if i < len(u) && u[i] != 0 {
+				continue
+			}
+			a := append(append([]uint16(nil), u[from:i]...), 0)
+			argv = append(argv, &a[0])
+			want = append(want, string(utf16.Decode(u[from:i])))
+			from = i + 1
+		}
+		got := serviceArgs(uint32(len(argv)), &argv[0])
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("arguments are %q, want %q", got, want)
+		}
+	})
+}
@@ -78,8 +78,7 @@ func IsAnInteractiveSession() (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	p := unsafe.Pointer(&gs.Groups[0])
-	groups := (*[2 << 20]syscall.SIDAndAttributes)(p)[:gs.GroupCount]
+	groups := unsafe.Slice(&gs.Groups[0], gs.GroupCount)
 	for _, g := range groups {
 		if winapi.EqualSid(g.Sid, interSid) {
 			return true, nil
@@ -6,14 +6,21 @@
 
 // Package svc provides everything required to build Windows service.
 //
+// New returns Service configured with options, like WithLogger or
+// WithStopTimeout, and Service.Run runs it until it is stopped or its
+// context is done. Run is shorthand for New without options.
+//
 package svc
 
 import (
+	"context"
 	"errors"
+	"github.com/multiplay/winsvc/tracing"
 	"github.com/multiplay/winsvc/winapi"
-	"runtime"
+	"os"
+	"sync"
 	"syscall"
-	"unsafe"
+	"time"
 )
 
 // State describes service execution state (Stopped, Running and so on).
@@ -41,6 +48,17 @@ const (
 	Interrogate = Cmd(winapi.SERVICE_CONTROL_INTERROGATE)
 	Shutdown    = Cmd(winapi.SERVICE_CONTROL_SHUTDOWN)
 	PreShutdown = Cmd(winapi.SERVICE_CONTROL_PRESHUTDOWN)
+
+	ParamChange           = Cmd(winapi.SERVICE_CONTROL_PARAMCHANGE)
+	NetBindAdd            = Cmd(winapi.SERVICE_CONTROL_NETBINDADD)
+	NetBindRemove         = Cmd(winapi.SERVICE_CONTROL_NETBINDREMOVE)
+	NetBindEnable         = Cmd(winapi.SERVICE_CONTROL_NETBINDENABLE)
+	NetBindDisable        = Cmd(winapi.SERVICE_CONTROL_NETBINDDISABLE)
+	DeviceEvent           = Cmd(winapi.SERVICE_CONTROL_DEVICEEVENT)
+	HardwareProfileChange = Cmd(winapi.SERVICE_CONTROL_HARDWAREPROFILECHANGE)
+	PowerEvent            = Cmd(winapi.SERVICE_CONTROL_POWEREVENT)
+	SessionChange         = Cmd(winapi.SERVICE_CONTROL_SESSIONCHANGE)
+	TriggerEvent          = Cmd(winapi.SERVICE_CONTROL_TRIGGEREVENT)
 )
 
 // Accepted is used to describe commands accepted by the service.
@@ -52,6 +70,13 @@ const (
 	AcceptShutdown         = Accepted(winapi.SERVICE_ACCEPT_SHUTDOWN)
 	AcceptPreShutdown      = Accepted(winapi.SERVICE_ACCEPT_PRESHUTDOWN)
 	AcceptPauseAndContinue = Accepted(winapi.SERVICE_ACCEPT_PAUSE_CONTINUE)
+
+	AcceptParamChange           = Accepted(winapi.SERVICE_ACCEPT_PARAMCHANGE)
+	AcceptNetBindChange         = Accepted(winapi.SERVICE_ACCEPT_NETBINDCHANGE)
+	AcceptHardwareProfileChange = Accepted(winapi.SERVICE_ACCEPT_HARDWAREPROFILECHANGE)
+	AcceptPowerEvent            = Accepted(winapi.SERVICE_ACCEPT_POWEREVENT)
+	AcceptSessionChange         = Accepted(winapi.SERVICE_ACCEPT_SESSIONCHANGE)
+	AcceptTriggerEvent          = Accepted(winapi.SERVICE_ACCEPT_TRIGGEREVENT)
 )
 
 // Status combines State and Accepted commands to fully describe running service.
@@ -60,12 +85,32 @@ type Status struct {
 	Accepts    Accepted
 	CheckPoint uint32 // used to report progress during a lengthy operation
 	WaitHint   uint32 // estimated time required for a pending operation, in milliseconds
+	ProcessId  uint32 // if the service is running, the process identifier of it, and otherwise zero
+
+	// Stage names startup phase in progress, when State is StartPending.
+	// It is logged, if service fails to start. See Stages.
+	Stage string
+
+	// Reason, if not nil, records why service stops itself.
+	// It is logged once service is Stopped.
+	Reason *StopReason
+
+	advance bool // CheckPoint is set to follow status reported last, see Stopping
 }
 
 // ChangeRequest is sent to service Handler to request service status change.
 type ChangeRequest struct {
 	Cmd           Cmd
 	CurrentStatus Status
+
+	// EventType and EventData describe DeviceEvent, PowerEvent and
+	// SessionChange requests. EventType is one of DBT_*, PBT_* or WTS_*
+	// constants of package winapi. EventData holds copy of structure
+	// the service control manager passed with request, like
+	// winapi.WTS_SESSION_NOTIFICATION, or nil, if it is not known
+	// or larger than 1KB.
+	EventType EventType
+	EventData []byte
 }
 
 // Handler is the interface that must be implemented to build Windows service.
@@ -80,72 +125,116 @@ type Handler interface {
 	// You can provide service exit code in exitCode return parameter,
 	// with 0 being "no error". You can also indicate if exit code,
 	// if any, is service specific or not by using svcSpecificEC
-	// parameter.
+	// parameter. r is never closed. If service stops before Execute
+	// returns, like when it misses stop deadline or its worker panics,
+	// Execute is sent Stop, unless it received one already, and should
+	// return.
 	Execute(args []string, r <-chan ChangeRequest, s chan<- Status) (svcSpecificEC bool, exitCode uint32)
 }
 
-var (
-	// These are used by asm code.
-	goWaitsH                       uintptr
-	cWaitsH                        uintptr
-	ssHandle                       uintptr
-	sName                          *uint16
-	sArgc                          uintptr
-	sArgv                          **uint16
-	ctlHandlerProc                 uintptr
-	cSetEvent                      uintptr
-	cWaitForSingleObject           uintptr
-	cRegisterServiceCtrlHandlerExW uintptr
-)
-
-func init() {
-	k := syscall.MustLoadDLL("kernel32.dll")
-	cSetEvent = k.MustFindProc("SetEvent").Addr()
-	cWaitForSingleObject = k.MustFindProc("WaitForSingleObject").Addr()
-	a := syscall.MustLoadDLL("advapi32.dll")
-	cRegisterServiceCtrlHandlerExW = a.MustFindProc("RegisterServiceCtrlHandlerExW").Addr()
-}
-
 type ctlEvent struct {
 	cmd       Cmd
 	eventType EventType
-	eventData uintptr
+	eventData []byte // copied, as system owns memory passed to ctlHandler
 	context   uintptr
 	errno     uint32
+	cause     *StopCause // why Run context is done, for Stop sent by Run
+	synthetic bool       // sent by Run or Close, not by the service control manager
+	coalesced uint32     // Interrogate requests answered by this one
+}
+
+// SCM is the service control manager, as seen by the service:
+// StartServiceCtrlDispatcher, RegisterServiceCtrlHandlerEx and
+// SetServiceStatus. SystemSCM is the real one. Tests replace it
+// with a fake, see WithSCM, so services can be exercised without
+// live service control manager.
+type SCM interface {
+	// Dispatch connects service name to the service control manager.
+	// It calls main with service arguments, once service is started,
+	// and returns once main returns. It is called on goroutine locked
+	// to its OS thread.
+	Dispatch(name string, main func(args []string)) error
+
+	// Register registers handler as control handler of service
+	// name, and returns service status handle. Handler is called
+	// for every control request, on thread that called Dispatch.
+	Register(name string, handler ControlHandler) (syscall.Handle, error)
+
+	// SetStatus reports service status t for service status handle h.
+	SetStatus(h syscall.Handle, t *winapi.SERVICE_STATUS) error
+
+	// StartReason is QueryServiceDynamicInformation
+	// of service with status handle h.
+	StartReason(h syscall.Handle) (StartReason, error)
+
+	// Startup returns when service process was created, zero
+	// time, if not known, and ServicesPipeTimeout.
+	Startup() (processStart time.Time, pipeTimeout time.Duration)
 }
 
+// ControlHandler is LPHANDLER_FUNCTION_EX. It
+// must return quickly, and must not allocate.
+type ControlHandler func(ctl uint32, eventType uint32, eventData uintptr, context uintptr) uintptr
+
+// SystemSCM is the service control manager of Windows.
+var SystemSCM SCM = winSCM{}
+
 // service provides access to windows service api.
 type service struct {
 	name    string
 	h       syscall.Handle
-	cWaits  *event
-	goWaits *event
-	c       chan ctlEvent
+	c       chan ctlEvent // Stop requests of Run and Close
+	queue   *ctlQueue     // control requests of ctlHandler
 	handler Handler
+	scm     SCM
+	done    chan struct{} // closed when loop exits
+	ran     chan struct{} // closed when Run returns
+
+	progress chan time.Duration // Tick and ExtendWait requests
+	reports  chan Status        // Report requests
+	panics   chan *PanicError   // recovered panics of handler and workers
+	owner    *Service           // Service that runs this one
+	tid      uint32             // id of dispatcher thread, if not 0
+
+	log           Logger        // may be nil
+	stopTimeout   time.Duration // 0 means no deadline
+	pickupTimeout time.Duration // 0 means no deadline
+
+	interceptors []Interceptor
+	metrics      Metrics // may be nil
+	lc           lifecycle
+	readiness    readiness
+	reloader     reloader
+
+	tracer tracing.Tracer // may be nil
+	trace  *spans         // nil, unless traced
+
+	t        winapi.SERVICE_STATUS // status reported last
+	reported bool                  // t is valid
+
+	mu       sync.Mutex
+	err      error        // set by loop
+	snapshot Status       // status successfully reported last
+	controls ControlStats // counts of control requests received
+
+	startReason    StartReason // set once handler is registered
+	startReasonErr error
 }
 
-func newService(name string, handler Handler) (*service, error) {
-	var s service
-	var err error
-	s.name = name
-	s.c = make(chan ctlEvent)
-	s.handler = handler
-	s.cWaits, err = newEvent()
-	if err != nil {
-		return nil, err
+func newService(name string, handler Handler, m SCM) *service {
+	return &service{
+		name:     name,
+		c:        make(chan ctlEvent),
+		queue:    newCtlQueue(),
+		done:     make(chan struct{}),
+		ran:      make(chan struct{}),
+		progress: make(chan time.Duration),
+		reports:  make(chan Status),
+		panics:   make(chan *PanicError),
+		handler:  handler,
+		scm:      m,
+		snapshot: Status{State: Stopped},
 	}
-	s.goWaits, err = newEvent()
-	if err != nil {
-		s.cWaits.Close()
-		return nil, err
-	}
-	return &s, nil
-}
-
-func (s *service) close() error {
-	s.cWaits.Close()
-	s.goWaits.Close()
-	return nil
 }
 
 type exitCode struct {
@@ -153,24 +242,24 @@ type exitCode struct {
 	errno         uint32
 }
 
+// acceptsMask lists commands that can be accepted. Accepted
+// values are SERVICE_ACCEPT_* flags, so no conversion is needed.
+const acceptsMask = AcceptStop | AcceptShutdown | AcceptPreShutdown | AcceptPauseAndContinue |
+	AcceptParamChange | AcceptNetBindChange | AcceptHardwareProfileChange | AcceptPowerEvent | AcceptSessionChange |
+	AcceptTriggerEvent
+
+// updateStatus reports status and exit code ec. Status
+// identical to the one reported last is not reported again.
 func (s *service) updateStatus(status *Status, ec *exitCode) error {
 	if s.h == 0 {
 		return errors.New("updateStatus with no service status handle")
 	}
-	var t winapi.SERVICE_STATUS
-	t.ServiceType = winapi.SERVICE_WIN32_OWN_PROCESS
-	t.CurrentState = uint32(status.State)
-	if status.Accepts&AcceptStop != 0 {
-		t.ControlsAccepted |= winapi.SERVICE_ACCEPT_STOP
-	}
-	if status.Accepts&AcceptShutdown != 0 {
-		t.ControlsAccepted |= winapi.SERVICE_ACCEPT_SHUTDOWN
-	}
-	if status.Accepts&AcceptPreShutdown != 0 {
-		t.ControlsAccepted |= winapi.SERVICE_ACCEPT_PRESHUTDOWN
-	}
-	if status.Accepts&AcceptPauseAndContinue != 0 {
-		t.ControlsAccepted |= winapi.SERVICE_ACCEPT_PAUSE_CONTINUE
+	t := winapi.SERVICE_STATUS{
+		ServiceType:      winapi.SERVICE_WIN32_OWN_PROCESS,
+		CurrentState:     uint32(status.State),
+		ControlsAccepted: uint32(status.Accepts & acceptsMask),
+		CheckPoint:       status.CheckPoint,
+		WaitHint:         status.WaitHint,
 	}
 	if ec.errno == 0 {
 		t.Win32ExitCode = winapi.NO_ERROR
@@ -182,90 +271,323 @@ func (s *service) updateStatus(status *Status, ec *exitCode) error {
 		t.Win32ExitCode = ec.errno
 		t.ServiceSpecificExitCode = winapi.NO_ERROR
 	}
-	t.CheckPoint = status.CheckPoint
-	t.WaitHint = status.WaitHint
-	return winapi.SetServiceStatus(s.h, &t)
+	if s.reported && t == s.t {
+		return nil
+	}
+	// s.t is reused, so reporting does not allocate
+	s.t = t
+	s.reported = true
+	err := s.scm.SetStatus(s.h, &s.t)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.snapshot = Status{
+		State:      State(t.CurrentState),
+		Accepts:    Accepted(t.ControlsAccepted),
+		CheckPoint: t.CheckPoint,
+		WaitHint:   t.WaitHint,
+	}
+	if t.CurrentState != winapi.SERVICE_STOPPED {
+		// as the service control manager reports it
+		s.snapshot.ProcessId = uint32(os.Getpid())
+	}
+	s.mu.Unlock()
+	return nil
 }
 
-const (
-	sysErrSetServiceStatusFailed = uint32(syscall.APPLICATION_ERROR) + iota
-	sysErrNewThreadInCallback
-)
+// status returns status successfully reported last.
+func (s *service) status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshot
+}
 
-func (s *service) run() {
-	s.goWaits.Wait()
-	s.h = syscall.Handle(ssHandle)
-	argv := (*[100]*int16)(unsafe.Pointer(sArgv))[:sArgc]
-	args := make([]string, len(argv))
-	for i, a := range argv {
-		args[i] = syscall.UTF16ToString((*[1 << 20]uint16)(unsafe.Pointer(a))[:])
+// statusInterval is the minimum time between reports of
+// progress (CheckPoint or WaitHint change) of pending operation.
+// More frequent progress updates are coalesced.
+const statusInterval = 100 * time.Millisecond
+
+// isProgress reports whether status c only updates
+// progress of pending operation in status last.
+func isProgress(c, last Status) bool {
+	if c.State != last.State || c.Accepts != last.Accepts {
+		return false
+	}
+	switch c.State {
+	case StartPending, StopPending, ContinuePending, PausePending:
+		return true
 	}
+	return false
+}
+
+// loop runs service handler, passing it control requests
+// and reporting status changes it makes, until it exits.
+// h is service status handle.
+func (s *service) loop(h syscall.Handle, args []string) {
+	defer close(s.done)
+	s.h = h
 
 	cmdsToHandler := make(chan ChangeRequest)
 	changesFromHandler := make(chan Status)
-	exitFromHandler := make(chan exitCode)
+	exitFromHandler := make(chan exitCode, 1)
 
 	go func() {
+		ec := exitCode{isSvcSpecific: true, errno: uint32(ExitHandlerPanicked)}
+		defer func() {
+			exitFromHandler <- ec
+		}()
+		defer s.recoverPanic()
 		ss, errno := s.handler.Execute(args, cmdsToHandler, changesFromHandler)
-		exitFromHandler <- exitCode{ss, errno}
+		ec = exitCode{ss, errno}
 	}()
 
 	status := Status{State: Stopped}
 	ec := exitCode{isSvcSpecific: true, errno: 0}
 	var outch chan ChangeRequest
 	inch := s.c
-	var cmd Cmd
+	queued := s.queue.ready
+	var req ChangeRequest // request to be passed to handler
+	var stopDeadline <-chan time.Time
+	var pickupDeadline <-chan time.Time // fires when handler is late to receive cmd
+	var lastReport time.Time
+	var held <-chan time.Time // fires when coalesced progress is due
+	var reason *StopReason
+	var cause *StopCause // of Run context, once it is done
+	var stage string     // startup stage reported last
+	var panicked *PanicError
+	exited := false   // handler returned
+	stopSent := false // handler received Stop, Shutdown or PreShutdown
+	// deliver is the last step of interceptor chain, it arranges
+	// for request to be passed to handler, or runs reload
+	forward := false
+	deliver := chain(s.interceptors, func(c ChangeRequest) {
+		if c.Cmd == Reload && s.requestReload() {
+			return
+		}
+		forward = true
+		req = c
+	})
+	report := func(c Status) bool {
+		held = nil
+		lastReport = time.Now()
+		err := s.updateStatus(&c, &ec)
+		if err != nil {
+			s.logError("service " + s.name + " failed to report status: " + err.Error())
+			// best suitable error number
+			ec.errno = uint32(ExitSetServiceStatusFailed)
+			var errno syscall.Errno
+			if errors.As(err, &errno) {
+				ec.errno = uint32(errno)
+			}
+			return false
+		}
+		s.reportedState(c.State)
+		return true
+	}
+	// change records status c, and reports it, unless
+	// it is progress update to be coalesced.
+	change := func(c Status) bool {
+		if c.Reason != nil {
+			reason = c.Reason
+		}
+		if c.State == StartPending && c.Stage != "" {
+			stage = c.Stage
+		}
+		if isProgress(c, status) && time.Since(lastReport) < statusInterval {
+			if held == nil {
+				held = time.After(statusInterval - time.Since(lastReport))
+			}
+			status = c
+			return true
+		}
+		status = c
+		return report(c)
+	}
+	// set records status c set by handler, or Report, unless
+	// it is Running to be held until service is ready.
+	set := func(c Status) bool {
+		if c.advance {
+			c.advance = false
+			c.CheckPoint = 1
+			if c.State == status.State {
+				c.CheckPoint = status.CheckPoint + 1
+			}
+		}
+		if s.readiness.waiting() {
+			switch c.State {
+			case Running:
+				// reported once service is ready
+				s.readiness.held = c
+				return true
+			case StartPending:
+				// progress, like answer to Interrogate
+			default:
+				// handler gave up starting, like when stopped
+				s.readiness.stop()
+			}
+		} else if c.State == Running {
+			c = s.readiness.gate(c, status)
+		}
+		return change(c)
+	}
+	// receive passes control request r to handler,
+	// unless it is handled here. It returns false,
+	// if service must stop.
+	receive := func(r ctlEvent) bool {
+		if r.errno != 0 {
+			ec.errno = r.errno
+			return false
+		}
+		if r.cause != nil {
+			cause = r.cause
+			if reason == nil {
+				reason = &cause.Reason
+			}
+		}
+		if r.synthetic {
+			// not counted, as it is not sent by
+			// the service control manager
+			s.stopRequested(r.cmd)
+			s.traceControl(r.cmd)
+		} else {
+			for i := uint32(0); i <= r.coalesced; i++ {
+				s.control(r.cmd)
+			}
+		}
+		forward = false
+		deliver(ChangeRequest{Cmd: r.cmd, CurrentStatus: status, EventType: r.eventType, EventData: r.eventData})
+		if forward {
+			inch = nil
+			queued = nil
+			outch = cmdsToHandler
+			if s.pickupTimeout > 0 {
+				pickupDeadline = time.After(s.pickupTimeout)
+			}
+		}
+		return true
+	}
 loop:
 	for {
 		select {
 		case r := <-inch:
-			if r.errno != 0 {
-				ec.errno = r.errno
+			if !receive(r) {
 				break loop
 			}
-			inch = nil
-			outch = cmdsToHandler
-			cmd = r.cmd
-		case outch <- ChangeRequest{cmd, status}:
+		case <-queued:
+			if n := s.queue.takeDropped(); n > 0 {
+				s.logDropped(n)
+			}
+			r, ok := s.queue.pop()
+			if !ok {
+				break
+			}
+			if s.queue.pending() {
+				// taken one at a time, as handler may be busy
+				s.queue.signal()
+			}
+			if !receive(r) {
+				break loop
+			}
+		case outch <- ChangeRequest{Cmd: req.Cmd, CurrentStatus: status, EventType: req.EventType, EventData: req.EventData}:
 			inch = s.c
+			queued = s.queue.ready
 			outch = nil
+			pickupDeadline = nil
+			if isStop(req.Cmd) {
+				stopSent = true
+				if stopDeadline == nil && s.stopTimeout > 0 {
+					stopDeadline = time.After(s.stopTimeout)
+				}
+			}
+		case <-pickupDeadline:
+			pickupDeadline = nil
+			s.logMissedPickup(req.Cmd)
+		case <-stopDeadline:
+			s.fail(errors.New("service " + s.name + " did not stop within " + s.stopTimeout.String()))
+			ec = exitCode{errno: uint32(winapi.ERROR_SERVICE_REQUEST_TIMEOUT)}
+			break loop
 		case c := <-changesFromHandler:
-			err := s.updateStatus(&c, &ec)
+			if !set(c) {
+				break loop
+			}
+		case c := <-s.reports:
+			if !set(c) {
+				break loop
+			}
+		case err := <-s.readiness.result:
+			s.readiness.stop()
 			if err != nil {
-				// best suitable error number
-				ec.errno = sysErrSetServiceStatusFailed
-				if err2, ok := err.(syscall.Errno); ok {
-					ec.errno = uint32(err2)
-				}
+				s.fail(errors.New("service " + s.name + " did not become ready: " + err.Error()))
+				ec = exitCode{errno: uint32(winapi.ERROR_SERVICE_START_HANG)}
+				break loop
+			}
+			if !change(s.readiness.held) {
+				break loop
+			}
+		case <-s.readiness.tick:
+			if c, ok := advance(status, 0); ok && !change(c) {
+				break loop
+			}
+		case d := <-s.progress:
+			if c, ok := advance(status, d); ok && !change(c) {
+				break loop
+			}
+		case <-held:
+			if !report(status) {
 				break loop
 			}
-			status = c
 		case ec = <-exitFromHandler:
+			exited = true
+			break loop
+		case panicked = <-s.panics:
+			ec = exitCode{isSvcSpecific: true, errno: uint32(ExitHandlerPanicked)}
 			break loop
 		}
 	}
+	s.readiness.stop()
+	s.stopReload()
+	if !exited {
+		// handler is still running, though service is stopped;
+		// ask it to stop, unless it was asked already, and let it
+		// return, so nothing is left behind for next Run
+		var stop chan ChangeRequest
+		if !stopSent {
+			stop = cmdsToHandler
+		}
+		current := status
+		go func() {
+			for {
+				select {
+				case stop <- ChangeRequest{Cmd: Stop, CurrentStatus: current}:
+					stop = nil
+				case <-changesFromHandler:
+				case <-exitFromHandler:
+					return
+				}
+			}
+		}()
+	}
 
+	if panicked != nil {
+		// logged before Stopped, as process may exit right after
+		s.logPanic(panicked)
+		s.mu.Lock()
+		s.err = panicked
+		s.mu.Unlock()
+	}
+	if cause != nil && ec.errno == 0 && cause.ExitCode != 0 {
+		ec = exitCode{isSvcSpecific: true, errno: cause.ExitCode}
+	}
+	last := s.status()
 	s.updateStatus(&Status{State: Stopped}, &ec)
-	s.cWaits.Set()
-}
-
-func newCallback(fn interface{}) (cb uintptr, err error) {
-	defer func() {
-		r := recover()
-		if r == nil {
-			return
-		}
-		cb = 0
-		switch v := r.(type) {
-		case string:
-			err = errors.New(v)
-		case error:
-			err = v
-		default:
-			err = errors.New("unexpected panic in syscall.NewCallback")
-		}
-	}()
-	return syscall.NewCallback(fn), nil
+	s.reportedState(Stopped)
+	if !s.lc.running && ec.errno != 0 {
+		s.logStartFailure(stage, last, ec)
+	}
+	if reason != nil {
+		s.logStop(reason, ec)
+	}
 }
 
 // BUG(brainman): There is no mechanism to run multiple services
@@ -273,51 +595,52 @@ func newCallback(fn interface{}) (cb uintptr, err error) {
 // using RegisterServiceCtrlHandlerEx Windows api.
 
 // Run executes service named name by calling appropriate handler function.
+// It is New(name, handler).Run(context.Background()).
 func Run(name string, handler Handler) error {
-	runtime.LockOSThread()
+	return New(name, handler).Run(context.Background())
+}
 
-	tid := winapi.GetCurrentThreadId()
+func run(m SCM, name string, handler Handler) error {
+	return New(name, handler, WithSCM(m)).Run(context.Background())
+}
 
-	s, err := newService(name, handler)
-	if err != nil {
-		return err
-	}
+// ErrNotService is returned, wrapped, by Run, when process is not
+// started by the service control manager, but, for example, from
+// console. Use errors.Is to test for it, or use debug.RunOrConsole.
+var ErrNotService error = winapi.ERROR_FAILED_SERVICE_CONTROLLER_CONNECT
 
-	ctlHandler := func(ctl uint32, event uint32, eventData uintptr, ctx uintptr) uintptr {
-		e := ctlEvent{cmd: Cmd(ctl), eventType: EventType(event), eventData: eventData, context: ctx}
-		// We assume that this callback function is running on
-		// the same thread as Run. Nowhere in MS documentation
-		// I could find statement to guarantee that. So putting
-		// check here to verify, otherwise things will go bad
-		// quickly, if ignored.
-		i := winapi.GetCurrentThreadId()
-		if i != tid {
-			e.errno = sysErrNewThreadInCallback
-		}
-		s.c <- e
-		return 0
-	}
+// winSCM is the real service control manager.
+type winSCM struct{}
 
-	var svcmain uintptr
-	getServiceMain(&svcmain)
-	t := []winapi.SERVICE_TABLE_ENTRY{
-		{syscall.StringToUTF16Ptr(s.name), svcmain},
-		{nil, 0},
+func (winSCM) SetStatus(h syscall.Handle, t *winapi.SERVICE_STATUS) error {
+	return winapi.SetServiceStatus(h, t)
+}
+
+func (winSCM) Register(name string, handler ControlHandler) (syscall.Handle, error) {
+	_, ctl, err := callbacks()
+	if err != nil {
+		return 0, err
 	}
+	setWinCallbacks(nil, handler)
+	return winapi.RegisterServiceCtrlHandlerEx(syscall.StringToUTF16Ptr(name), ctl, 0)
+}
 
-	goWaitsH = uintptr(s.goWaits.h)
-	cWaitsH = uintptr(s.cWaits.h)
-	sName = t[0].ServiceName
-	ctlHandlerProc, err = newCallback(ctlHandler)
+func (winSCM) Dispatch(name string, main func(args []string)) error {
+	callback, _, err := callbacks()
 	if err != nil {
 		return err
 	}
 
-	go s.run()
+	// control handler is called on the thread that calls
+	// StartServiceCtrlDispatcher, Dispatch runs locked to it
+	if s := currentService(); s != nil {
+		s.tid = winapi.GetCurrentThreadId()
+	}
 
-	err = winapi.StartServiceCtrlDispatcher(&t[0])
-	if err != nil {
-		return err
+	setWinCallbacks(main, nil)
+	t := []winapi.SERVICE_TABLE_ENTRY{
+		{syscall.StringToUTF16Ptr(name), callback},
+		{nil, 0},
 	}
-	return nil
+	return winapi.StartServiceCtrlDispatcher(&t[0])
 }
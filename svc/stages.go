@@ -0,0 +1,45 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"fmt"
+	"time"
+)
+
+// Stage is a named phase of service startup, like "load config",
+// "open database" or "listen".
+type Stage struct {
+	Name     string
+	WaitHint time.Duration // expected duration of the stage
+	Run      func() error
+}
+
+// Stages lists service startup phases in order they run.
+type Stages []Stage
+
+// Run runs stages ss in order, and returns error of the first stage
+// that fails, if any. Before each stage starts, StartPending status,
+// with advancing CheckPoint and the stage WaitHint, is sent to s.
+// If service stops with non zero exit code during startup, the stage
+// that was running is logged to the service Logger. Handler reports
+// Running itself, once Run returns with no error.
+func (ss Stages) Run(s chan<- Status) error {
+	for i, st := range ss {
+		s <- Status{
+			State:      StartPending,
+			CheckPoint: uint32(i + 1),
+			WaitHint:   uint32(st.WaitHint / time.Millisecond),
+			Stage:      st.Name,
+		}
+		err := st.Run()
+		if err != nil {
+			return fmt.Errorf("%s: %v", st.Name, err)
+		}
+	}
+	return nil
+}
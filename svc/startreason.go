@@ -0,0 +1,67 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// StartReason tells why service was started. It is a combination
+// of flags, as the service control manager may start service
+// for more than one reason at once.
+type StartReason uint32
+
+const (
+	StartReasonDemand           = StartReason(winapi.SERVICE_START_REASON_DEMAND)
+	StartReasonAuto             = StartReason(winapi.SERVICE_START_REASON_AUTO)
+	StartReasonTrigger          = StartReason(winapi.SERVICE_START_REASON_TRIGGER)
+	StartReasonRestartOnFailure = StartReason(winapi.SERVICE_START_REASON_RESTART_ON_FAILURE)
+	StartReasonDelayedAuto      = StartReason(winapi.SERVICE_START_REASON_DELAYEDAUTO)
+)
+
+// errNoStartReason is returned by StartReason
+// of service that has not been started.
+var errNoStartReason = errors.New("svc: service has not been started")
+
+// StartReason returns why service s was started, for example,
+// StartReasonTrigger, if its trigger, like device arrival, fired.
+// Handler can call it, once it is executed. Windows 7 and older
+// do not record start reason.
+func (s *Service) StartReason() (StartReason, error) {
+	s.mu.Lock()
+	srv := s.srv
+	s.mu.Unlock()
+	if srv == nil {
+		return 0, errNoStartReason
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.startReason, srv.startReasonErr
+}
+
+// recordStartReason asks the service control manager
+// why service with status handle h was started.
+func (s *service) recordStartReason(h syscall.Handle) {
+	r, err := s.scm.StartReason(h)
+	s.mu.Lock()
+	s.startReason, s.startReasonErr = r, err
+	s.mu.Unlock()
+}
+
+func (winSCM) StartReason(h syscall.Handle) (StartReason, error) {
+	var p *byte
+	err := winapi.QueryServiceDynamicInformation(h, winapi.SERVICE_DYNAMIC_INFORMATION_LEVEL_START_REASON, &p)
+	if err != nil {
+		return 0, err
+	}
+	defer syscall.LocalFree(syscall.Handle(unsafe.Pointer(p)))
+	return StartReason((*winapi.SERVICE_START_REASON)(unsafe.Pointer(p)).Reason), nil
+}
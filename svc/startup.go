@@ -0,0 +1,92 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"bytes"
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/multiplay/winsvc/registry"
+)
+
+// defaultPipeTimeout is how long the service control manager waits
+// for service process to connect to it, before it kills the process,
+// unless ServicesPipeTimeout registry value says otherwise.
+const defaultPipeTimeout = 30 * time.Second
+
+// Startup returns when current process was created, and
+// ServicesPipeTimeout, or zero time, if it is not known.
+func (winSCM) Startup() (processStart time.Time, pipeTimeout time.Duration) {
+	var creation, exit, kernel, user syscall.Filetime
+	h, err := syscall.GetCurrentProcess()
+	if err == nil {
+		err = syscall.GetProcessTimes(h, &creation, &exit, &kernel, &user)
+	}
+	if err == nil {
+		processStart = time.Unix(0, creation.Nanoseconds())
+	}
+	return processStart, servicesPipeTimeout()
+}
+
+// servicesPipeTimeout returns ServicesPipeTimeout registry value.
+func servicesPipeTimeout() time.Duration {
+	k, err := registry.OpenKeyAccess(syscall.HKEY_LOCAL_MACHINE,
+		`SYSTEM\CurrentControlSet\Control`, syscall.KEY_READ)
+	if err != nil {
+		return defaultPipeTimeout
+	}
+	defer k.Close()
+	ms, err := k.GetUInt32("ServicesPipeTimeout")
+	if err != nil || ms == 0 {
+		return defaultPipeTimeout
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// recordStartup records process start time and ServicesPipeTimeout,
+// and warns, if service main was called so late after process start,
+// that slower start would have made the service control manager kill
+// the process.
+func (s *service) recordStartup() {
+	s.lc.processStart, s.lc.pipeTimeout = s.scm.Startup()
+	if s.lc.processStart.IsZero() || s.log == nil {
+		return
+	}
+	d := s.lc.begin.Sub(s.lc.processStart)
+	if d < s.lc.pipeTimeout/2 {
+		return
+	}
+	s.log.Warning(1, fmt.Sprintf("service %s connected to the service control manager %v after process start, "+
+		"while ServicesPipeTimeout is %v; slower start gets process killed, "+
+		"move initialization from before Run into handler, and report StartPending progress",
+		s.name, d, s.lc.pipeTimeout))
+}
+
+// logStartFailure logs that service failed to start, with exit code
+// ec, while running startup stage stage, if not "". Status reported
+// last before Stopped, time spent, and ServicesPipeTimeout are
+// logged too, so failures of slow start can be told apart.
+func (s *service) logStartFailure(stage string, last Status, ec exitCode) {
+	if s.log == nil {
+		return
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "service %s failed to start", s.name)
+	if stage != "" {
+		fmt.Fprintf(&b, " during stage %q", stage)
+	}
+	fmt.Fprintf(&b, " (exit code %d)\n", ec.errno)
+	fmt.Fprintf(&b, "elapsed: %v since service main", time.Since(s.lc.begin))
+	if !s.lc.processStart.IsZero() {
+		fmt.Fprintf(&b, ", %v since process start", time.Since(s.lc.processStart))
+	}
+	fmt.Fprintf(&b, "\nlast status: %v\n", last)
+	fmt.Fprintf(&b, "ServicesPipeTimeout: %v", s.lc.pipeTimeout)
+	s.log.Error(1, b.String())
+}
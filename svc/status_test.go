@@ -0,0 +1,264 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// nopSCM accepts every status, and keeps nothing.
+type nopSCM struct {
+	n int
+}
+
+func (m *nopSCM) Dispatch(name string, main func(args []string)) error {
+	return nil
+}
+
+func (m *nopSCM) Register(name string, handler ControlHandler) (syscall.Handle, error) {
+	return 1, nil
+}
+
+func (m *nopSCM) SetStatus(h syscall.Handle, t *winapi.SERVICE_STATUS) error {
+	m.n++
+	return nil
+}
+
+func (m *nopSCM) StartReason(h syscall.Handle) (StartReason, error) {
+	return StartReasonDemand, nil
+}
+
+func (m *nopSCM) Startup() (time.Time, time.Duration) {
+	return time.Now(), defaultPipeTimeout
+}
+
+func TestUpdateStatusAllocs(t *testing.T) {
+	m := &nopSCM{}
+	s := newService("fake", nil, m)
+	s.h = 1
+	var ec exitCode
+	st := Status{State: StartPending, WaitHint: 3000}
+	allocs := testing.AllocsPerRun(100, func() {
+		st.CheckPoint++
+		s.updateStatus(&st, &ec)
+	})
+	if allocs != 0 {
+		t.Errorf("updateStatus allocates %v times, want 0", allocs)
+	}
+	n := m.n
+	s.updateStatus(&st, &ec)
+	if m.n != n {
+		t.Errorf("unchanged status is reported again")
+	}
+}
+
+func TestCoalesceProgress(t *testing.T) {
+	const n = 1000
+	f := newFakeSCM()
+	_, done := runFake(t, f, handlerFunc(func(args []string, r <-chan ChangeRequest, s chan<- Status) (bool, uint32) {
+		for i := 1; i <= n; i++ {
+			s <- Status{State: StartPending, CheckPoint: uint32(i), WaitHint: 3000}
+		}
+		s <- Status{State: Running, Accepts: AcceptStop}
+		<-r
+		return false, 0
+	}))
+	control(Stop)
+	<-done
+
+	var last uint32
+	pending := 0
+	for _, st := range f.statuses {
+		if st.CurrentState == winapi.SERVICE_START_PENDING {
+			pending++
+			if st.CheckPoint <= last {
+				t.Errorf("checkpoint %d reported after %d", st.CheckPoint, last)
+			}
+			last = st.CheckPoint
+		}
+	}
+	if pending == 0 || pending == n {
+		t.Errorf("%d of %d progress updates reported, want some coalesced", pending, n)
+	}
+	if f.statuses[0].CheckPoint != 1 {
+		t.Errorf("first checkpoint reported is %d, want 1", f.statuses[0].CheckPoint)
+	}
+	states := f.states()
+	if len(states) < 2 || states[len(states)-2] != winapi.SERVICE_RUNNING {
+		t.Errorf("Running is not reported after progress: %v", states)
+	}
+}
+
+func BenchmarkUpdateStatus(b *testing.B) {
+	s := newService("fake", nil, &nopSCM{})
+	s.h = 1
+	var ec exitCode
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		st := Status{State: StartPending, CheckPoint: uint32(i), WaitHint: 3000}
+		s.updateStatus(&st, &ec)
+	}
+}
+
+func BenchmarkProgress(b *testing.B) {
+	f := &nopSCM{}
+	h := handlerFunc(func(args []string, r <-chan ChangeRequest, s chan<- Status) (bool, uint32) {
+		for i := 0; i < b.N; i++ {
+			s <- Status{State: StartPending, CheckPoint: uint32(i), WaitHint: 3000}
+		}
+		return false, 0
+	})
+	s := newService("fake", h, f)
+	b.ReportAllocs()
+	b.ResetTimer()
+	s.loop(1, nil)
+	b.ReportMetric(float64(f.n)/float64(b.N), "reports/op")
+}
+
+func TestExitError(t *testing.T) {
+	tests := []struct {
+		win32, specific uint32
+		want            error
+	}{
+		{winapi.NO_ERROR, 0, nil},
+		{uint32(winapi.ERROR_SERVICE_SPECIFIC_ERROR), 0, nil},
+		{uint32(winapi.ERROR_SERVICE_SPECIFIC_ERROR), uint32(ExitNewThreadInCallback), ExitNewThreadInCallback},
+		{uint32(winapi.ERROR_SERVICE_SPECIFIC_ERROR), 3, ExitCode(3)},
+		{uint32(winapi.ERROR_SERVICE_REQUEST_TIMEOUT), 0, syscall.Errno(winapi.ERROR_SERVICE_REQUEST_TIMEOUT)},
+	}
+	for _, test := range tests {
+		if got := ExitError(test.win32, test.specific); got != test.want {
+			t.Errorf("ExitError(%d, %d) = %v, want %v", test.win32, test.specific, got, test.want)
+		}
+	}
+	if s := ExitSetServiceStatusFailed.String(); s != "ExitSetServiceStatusFailed" {
+		t.Errorf("exit code is named %s", s)
+	}
+	if s := ExitCode(3).Error(); s != "service specific error 3" {
+		t.Errorf("exit code 3 is described as %q", s)
+	}
+}
+
+func TestStatusString(t *testing.T) {
+	tests := []struct {
+		s    Status
+		want string
+	}{
+		{Status{State: Running, Accepts: AcceptStop | AcceptShutdown}, "Running, accepts Stop|Shutdown"},
+		{Status{State: StartPending, CheckPoint: 2, WaitHint: 3000, Stage: "config"}, "StartPending, checkpoint 2, wait hint 3s, stage config"},
+		{Status{State: Paused, Accepts: AcceptPauseAndContinue | 0x1000, ProcessId: 42}, "Paused, accepts PauseAndContinue|0x1000, pid 42"},
+		{Status{State: 99}, "State(99)"},
+	}
+	for _, test := range tests {
+		if got := test.s.String(); got != test.want {
+			t.Errorf("status is shown as %q, want %q", got, test.want)
+		}
+	}
+	b, err := json.Marshal(ChangeRequest{Cmd: Stop, CurrentStatus: Status{State: Running, Accepts: AcceptStop}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Cmd":"Stop","CurrentStatus":{"State":"Running","Accepts":"Stop","CheckPoint":0,"WaitHint":0,"ProcessId":0,"Stage":"","Reason":null},"EventType":0,"EventData":null}`
+	if string(b) != want {
+		t.Errorf("request is encoded as %s, want %s", b, want)
+	}
+}
+
+func TestStatusJSON(t *testing.T) {
+	for _, st := range []Status{
+		{State: Running, Accepts: AcceptStop | AcceptShutdown, ProcessId: 42},
+		{State: StartPending, CheckPoint: 2, WaitHint: 3000, Stage: "config"},
+		{State: Paused, Accepts: AcceptPauseAndContinue | 0x1000},
+		{State: 99},
+	} {
+		b, err := json.Marshal(st)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Status
+		err = json.Unmarshal(b, &got)
+		if err != nil {
+			t.Errorf("decoding %s failed: %v", b, err)
+			continue
+		}
+		if got != st {
+			t.Errorf("%s is decoded as %+v, want %+v", b, got, st)
+		}
+	}
+	for _, c := range []Cmd{Stop, Reload, Cmd(200)} {
+		b, err := json.Marshal(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Cmd
+		err = json.Unmarshal(b, &got)
+		if err != nil || got != c {
+			t.Errorf("%s is decoded as %v, %v, want %v", b, got, err, c)
+		}
+	}
+	// numbers are accepted too
+	var st Status
+	err := json.Unmarshal([]byte(`{"State":4,"Accepts":3}`), &st)
+	if err != nil || st.State != Running || st.Accepts != AcceptStop|AcceptPauseAndContinue {
+		t.Errorf("numeric status is decoded as %+v, %v", st, err)
+	}
+	err = json.Unmarshal([]byte(`{"State":"Sleeping"}`), &st)
+	if err == nil {
+		t.Error("unknown state should not be decoded")
+	}
+}
+
+func TestReadyStopping(t *testing.T) {
+	s := make(chan Status, 1)
+	Ready(s)
+	if st := <-s; st.State != Running || st.Accepts != AcceptStop|AcceptShutdown {
+		t.Errorf("Ready sent %+v, want Running accepting Stop and Shutdown", st)
+	}
+	Ready(s, AcceptStop, AcceptSessionChange)
+	if st := <-s; st.State != Running || st.Accepts != AcceptStop|AcceptSessionChange {
+		t.Errorf("Ready sent %+v, want Running accepting Stop and SessionChange", st)
+	}
+	Stopping(s, 2500*time.Millisecond)
+	if st := <-s; st.State != StopPending || st.WaitHint != 2500 || !st.advance {
+		t.Errorf("Stopping sent %+v, want StopPending advancing CheckPoint with WaitHint 2500", st)
+	}
+
+	// service loop increments CheckPoint of every next Stopping
+	f := newFakeSCM()
+	var srv *Service
+	h := handlerFunc(func(args []string, r <-chan ChangeRequest, c chan<- Status) (bool, uint32) {
+		Ready(c)
+		for i := 0; i < 3; i++ {
+			Stopping(c, time.Second)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for srv.Status().CheckPoint != 3 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		return false, 0
+	})
+	srv = New("fake", h, WithSCM(f))
+	err := srv.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	var checkPoints []uint32
+	for _, st := range f.statuses {
+		if st.CurrentState == winapi.SERVICE_STOP_PENDING {
+			checkPoints = append(checkPoints, st.CheckPoint)
+		}
+	}
+	if len(checkPoints) == 0 || checkPoints[0] != 1 || checkPoints[len(checkPoints)-1] != 3 {
+		t.Errorf("stop pending statuses reported checkpoints %v, want 1 first and 3 last", checkPoints)
+	}
+}
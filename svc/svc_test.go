@@ -7,6 +7,7 @@
 package svc_test
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -14,6 +15,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/multiplay/winsvc/debug"
 	"github.com/multiplay/winsvc/mgr"
 	"github.com/multiplay/winsvc/svc"
 )
@@ -112,3 +114,23 @@ func TestExample(t *testing.T) {
 		t.Fatalf("Delete failed: %s", err)
 	}
 }
+
+func TestNotService(t *testing.T) {
+	h := handlerFunc(func(args []string, r <-chan svc.ChangeRequest, s chan<- svc.Status) (bool, uint32) {
+		return false, 0
+	})
+	err := svc.Run("winsvc-test", h)
+	if !errors.Is(err, svc.ErrNotService) {
+		t.Fatalf("Run on console returned %v, want %v", err, svc.ErrNotService)
+	}
+	err = debug.RunOrConsole("winsvc-test", h)
+	if err != nil {
+		t.Fatalf("RunOrConsole failed: %v", err)
+	}
+}
+
+type handlerFunc func(args []string, r <-chan svc.ChangeRequest, s chan<- svc.Status) (bool, uint32)
+
+func (f handlerFunc) Execute(args []string, r <-chan svc.ChangeRequest, s chan<- svc.Status) (bool, uint32) {
+	return f(args, r, s)
+}
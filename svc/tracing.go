@@ -0,0 +1,109 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/multiplay/winsvc/tracing"
+)
+
+// tracerName is name of Tracer requested by services.
+const tracerName = "github.com/multiplay/winsvc/svc"
+
+// WithTracerProvider makes service report its lifecycle as spans of
+// tracers returned by tp. Run span covers whole Run, as child of span
+// in Run context, if any. Its children, Start and Stop spans, cover
+// time from service start until it reports Running, and from first
+// Stop, Shutdown or PreShutdown request until it reports Stopped.
+// Every control request and state reported is recorded as Run span
+// event.
+func WithTracerProvider(tp tracing.TracerProvider) Option {
+	return func(o *options) {
+		o.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// errNotStarted is recorded by Start span of
+// service that stopped before it was running.
+var errNotStarted = errors.New("svc: service stopped before it was running")
+
+// spans are spans of service run, nil unless service is traced.
+type spans struct {
+	ctx   context.Context // carries run span
+	run   tracing.Span
+	start tracing.Span
+	stop  tracing.Span
+}
+
+// traceRun starts run and start spans of s, as children of ctx.
+func (s *service) traceRun(ctx context.Context, t tracing.Tracer) {
+	if t == nil {
+		return
+	}
+	sp := &spans{}
+	sp.ctx, sp.run = t.Start(ctx, "svc.Run")
+	sp.run.SetAttributes(tracing.String("service.name", s.name))
+	_, sp.start = t.Start(sp.ctx, "svc.Start")
+	s.trace = sp
+	s.tracer = t
+}
+
+// traceControl records control request c.
+func (s *service) traceControl(c Cmd) {
+	if s.trace == nil {
+		return
+	}
+	s.trace.run.AddEvent("control", tracing.Int("cmd", int64(c)))
+	if isStop(c) && s.trace.stop == nil {
+		_, s.trace.stop = s.tracer.Start(s.trace.ctx, "svc.Stop")
+		s.trace.stop.SetAttributes(tracing.Int("cmd", int64(c)))
+	}
+}
+
+// traceState records that service reported state st.
+func (s *service) traceState(st State) {
+	if s.trace == nil {
+		return
+	}
+	s.trace.run.AddEvent("status", tracing.Int("state", int64(st)))
+	switch st {
+	case Running:
+		if s.trace.start != nil {
+			s.trace.start.End()
+			s.trace.start = nil
+		}
+	case Stopped:
+		if s.trace.stop != nil {
+			s.trace.stop.End()
+			s.trace.stop = nil
+		}
+	}
+}
+
+// traceEnd ends all spans of s, once Run returns error err.
+func (s *service) traceEnd(err error) {
+	sp := s.trace
+	if sp == nil {
+		return
+	}
+	if sp.start != nil {
+		sp.start.RecordError(errNotStarted)
+		sp.start.End()
+	}
+	if sp.stop != nil {
+		sp.stop.End()
+	}
+	sp.run.SetAttributes(
+		tracing.Int("exit_code", int64(s.t.Win32ExitCode)),
+		tracing.Int("service_specific_exit_code", int64(s.t.ServiceSpecificExitCode)))
+	if err != nil {
+		sp.run.RecordError(err)
+	}
+	sp.run.End()
+}
@@ -0,0 +1,86 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svctest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/multiplay/winsvc/svc"
+)
+
+var update = flag.Bool("svctest.update", false, "rewrite golden files checked by svctest.Script.Golden")
+
+// writeReport writes r to transcript b. Time of the report, and
+// process id are left out, and wait hint is only noted, when it is
+// set, so transcript of the same behaviour is always the same.
+func writeReport(b *bytes.Buffer, r report) {
+	b.WriteString("  " + r.status.State.String())
+	if r.exited {
+		fmt.Fprintf(b, " exit=%d\n", r.ec)
+		return
+	}
+	if a := r.status.Accepts; a != 0 {
+		b.WriteString(" accepts=" + a.String())
+	}
+	if cp := r.status.CheckPoint; cp != 0 {
+		fmt.Fprintf(b, " checkpoint=%d", cp)
+	}
+	if r.status.WaitHint != 0 {
+		b.WriteString(" waithint")
+	}
+	b.WriteString("\n")
+}
+
+// Golden is like Run, but also compares statuses handler reported,
+// step by step, to the transcript stored in file path, and fails t,
+// if they differ. Run tests with -svctest.update flag to write
+// the transcript to path instead, and review it with the change.
+func (s *Script) Golden(t T, h svc.Handler, path string) {
+	t.Helper()
+	var got bytes.Buffer
+	if !s.run(t, h, &got) {
+		return
+	}
+	if *update {
+		err := os.WriteFile(path, got.Bytes(), 0666)
+		if err != nil {
+			t.Fatalf("svctest: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("svctest: golden file %s does not exist, run with -svctest.update to create it\ngot:\n%s", path, got.String())
+		return
+	}
+	if err != nil {
+		t.Fatalf("svctest: %v", err)
+		return
+	}
+	// files checked out on Windows may have CRLF line endings
+	want = bytes.Replace(want, []byte("\r\n"), []byte("\n"), -1)
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Fatalf("svctest: statuses differ from golden file %s at line %d\ngot:\n%s\nwant:\n%s",
+			path, diffLine(got.String(), string(want)), got.String(), want)
+	}
+}
+
+// diffLine returns number of the first line that differs in a and b.
+func diffLine(a, b string) int {
+	al := strings.Split(a, "\n")
+	bl := strings.Split(b, "\n")
+	for i := range al {
+		if i >= len(bl) || al[i] != bl[i] {
+			return i + 1
+		}
+	}
+	return len(al) + 1
+}
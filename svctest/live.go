@@ -0,0 +1,146 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svctest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/multiplay/winsvc/mgr"
+	"github.com/multiplay/winsvc/svc"
+)
+
+// LiveEnv is environment variable, that must be set to 1, for
+// tests using Install to run. Otherwise they are skipped, so only
+// CI runners, that run tests as administrator, and do not mind
+// services being installed, run them.
+const LiveEnv = "WINSVC_LIVE_TESTS"
+
+// LiveTimeout is time Live waits for the service to change state.
+var LiveTimeout = 30 * time.Second
+
+// Live is throwaway service installed with the service control
+// manager for the duration of a test.
+type Live struct {
+	Name    string // unique name, starting with "svctest-"
+	ExePath string
+	Service *mgr.Service
+}
+
+// Install builds package pkg, like "github.com/multiplay/winsvc/example",
+// and installs it as new service with unique name, that runs it with
+// arguments args, and configuration c. The service is stopped and
+// deleted, once the test and all its subtests complete, even if they
+// fail. Install skips the test, unless LiveEnv is set to 1.
+func Install(t testing.TB, pkg string, c mgr.Config, args ...string) *Live {
+	t.Helper()
+	if os.Getenv(LiveEnv) != "1" {
+		t.Skip("set " + LiveEnv + "=1 to run tests against the service control manager")
+	}
+	var id [4]byte
+	_, err := rand.Read(id[:])
+	if err != nil {
+		t.Fatalf("svctest: %v", err)
+	}
+	l := &Live{
+		Name:    "svctest-" + hex.EncodeToString(id[:]),
+		ExePath: filepath.Join(t.TempDir(), "svc.exe"),
+	}
+	o, err := exec.Command("go", "build", "-o", l.ExePath, pkg).CombinedOutput()
+	if err != nil {
+		t.Fatalf("svctest: failed to build %s: %v\n%s", pkg, err, o)
+	}
+	m, err := mgr.Connect()
+	if err != nil {
+		t.Fatalf("svctest: %v", err)
+	}
+	t.Cleanup(func() { m.Disconnect() })
+	if c.DisplayName == "" {
+		c.DisplayName = l.Name
+	}
+	cmd := syscall.EscapeArg(l.ExePath)
+	for _, a := range args {
+		cmd += " " + syscall.EscapeArg(a)
+	}
+	l.Service, err = m.CreateService(l.Name, cmd, c)
+	if err != nil {
+		t.Fatalf("svctest: CreateService(%s) failed: %v", l.Name, err)
+	}
+	t.Cleanup(func() {
+		l.remove(t)
+	})
+	return l
+}
+
+// remove stops and deletes service l. It only reports failures,
+// so cleanup registered by the test after Install still runs.
+func (l *Live) remove(t testing.TB) {
+	t.Helper()
+	defer l.Service.Close()
+	err := l.Service.StopAndWait(LiveTimeout)
+	if err != nil {
+		t.Errorf("svctest: failed to stop %s: %v", l.Name, err)
+	}
+	err = l.Service.Delete()
+	if err != nil && !errors.Is(err, mgr.ErrServiceMarkedForDelete) {
+		t.Errorf("svctest: failed to delete %s: %v", l.Name, err)
+	}
+}
+
+// Start starts service l with arguments args, and
+// waits until it is running.
+func (l *Live) Start(t testing.TB, args ...string) {
+	t.Helper()
+	err := l.Service.Start(args)
+	if err != nil {
+		t.Fatalf("svctest: Start(%s) failed: %v", l.Name, err)
+	}
+	l.Wait(t, svc.Running)
+}
+
+// Control sends control request c to service l, and waits
+// until it reaches state want.
+func (l *Live) Control(t testing.TB, c svc.Cmd, want svc.State) svc.Status {
+	t.Helper()
+	_, err := l.Service.Control(c)
+	if err != nil {
+		t.Fatalf("svctest: %s request to %s failed: %v", c, l.Name, err)
+	}
+	return l.Wait(t, want)
+}
+
+// Wait waits until service l reaches state want,
+// and returns its status.
+func (l *Live) Wait(t testing.TB, want svc.State) svc.Status {
+	t.Helper()
+	status, err := l.Service.WaitState(want, LiveTimeout)
+	if err != nil {
+		t.Fatalf("svctest: %s is %s, want %s: %v", l.Name, status.State, want, err)
+	}
+	return status
+}
+
+// Stop stops service l, and checks that it exited with exit
+// code ec, as returned by mgr.Service.ExitCode.
+func (l *Live) Stop(t testing.TB, ec uint32) {
+	t.Helper()
+	l.Control(t, svc.Stop, svc.Stopped)
+	got, err := l.Service.ExitCode()
+	if err != nil {
+		t.Fatalf("svctest: %v", err)
+	}
+	if got != ec {
+		t.Fatalf("svctest: %s exited with %d, want %d", l.Name, got, ec)
+	}
+}
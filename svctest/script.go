@@ -0,0 +1,206 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Package svctest helps to test service handlers. Script plays timed
+// sequence of control requests to svc.Handler, and checks that it
+// reports expected states in time, without the service control manager.
+// Script.Golden also compares reported statuses to a golden file. Install
+// runs a real service, for integration tests on Windows CI runners.
+//
+package svctest
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/multiplay/winsvc/svc"
+)
+
+// DefaultWithin is time allowed for Step, if its Within is 0.
+const DefaultWithin = 5 * time.Second
+
+// Step waits for After, sends control request Cmd, if it is not 0,
+// and checks that handler reports states Expect, in order, within
+// Within. Repeated reports of the same state, like progress of pending
+// operation, are allowed. Handler returning counts as Stopped.
+type Step struct {
+	After  time.Duration
+	Cmd    svc.Cmd
+	Expect []svc.State
+	Within time.Duration
+}
+
+// Script describes test of service handler.
+type Script struct {
+	Args     []string // passed to handler
+	Steps    []Step
+	ExitCode uint32 // checked, if handler returns
+}
+
+// T is implemented by *testing.T and *testing.B.
+type T interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+}
+
+// report is status reported by handler, or its exit.
+type report struct {
+	status svc.Status
+	at     time.Duration // since script started
+	exited bool
+	ec     uint32
+}
+
+// pump receives reports of handler, so it never blocks,
+// and queues them for the script.
+type pump struct {
+	start   time.Time
+	changes chan svc.Status
+	exit    chan uint32
+	reports chan report
+	done    chan struct{}
+
+	mu     sync.Mutex
+	latest svc.Status
+}
+
+func (p *pump) run() {
+	var queue []report
+	for {
+		var out chan report
+		var next report
+		if len(queue) > 0 {
+			out = p.reports
+			next = queue[0]
+		}
+		select {
+		case st := <-p.changes:
+			p.mu.Lock()
+			p.latest = st
+			p.mu.Unlock()
+			queue = append(queue, report{status: st, at: time.Since(p.start)})
+		case ec := <-p.exit:
+			queue = append(queue, report{status: svc.Status{State: svc.Stopped}, at: time.Since(p.start), exited: true, ec: ec})
+		case out <- next:
+			queue = queue[1:]
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (p *pump) current() svc.Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.latest
+}
+
+// Run runs handler h, and plays script s to it. It fails t with
+// transcript of states reported, once handler does not behave as
+// expected. Handler keeps running, if script does not stop it.
+func (s *Script) Run(t T, h svc.Handler) {
+	t.Helper()
+	s.run(t, h, nil)
+}
+
+// run is Run, that also writes normalized transcript of
+// reports to rec, if it is not nil. It reports whether
+// handler behaved as expected.
+func (s *Script) run(t T, h svc.Handler, rec *bytes.Buffer) bool {
+	t.Helper()
+	p := &pump{
+		start:   time.Now(),
+		changes: make(chan svc.Status),
+		exit:    make(chan uint32, 1),
+		reports: make(chan report),
+		done:    make(chan struct{}),
+		latest:  svc.Status{State: svc.Stopped},
+	}
+	defer close(p.done)
+	go p.run()
+	cmds := make(chan svc.ChangeRequest)
+	go func() {
+		_, ec := h.Execute(s.Args, cmds, p.changes)
+		p.exit <- ec
+	}()
+
+	var seen bytes.Buffer
+	fail := func(i int, format string, args ...interface{}) {
+		t.Helper()
+		step := fmt.Sprintf("step %d", i)
+		if c := s.Steps[i].Cmd; c != 0 {
+			step += " (" + c.String() + ")"
+		}
+		t.Fatalf("svctest: %s: %s\nreported:\n%s", step, fmt.Sprintf(format, args...), seen.String())
+	}
+	prev := svc.State(0) // state seen last
+	exited := false
+	for i, step := range s.Steps {
+		within := step.Within
+		if within == 0 {
+			within = DefaultWithin
+		}
+		time.Sleep(step.After)
+		if rec != nil {
+			fmt.Fprintf(rec, "step %d", i)
+			if step.Cmd != 0 {
+				rec.WriteString(" " + step.Cmd.String())
+			}
+			rec.WriteString("\n")
+		}
+		deadline := time.After(within)
+		if step.Cmd != 0 {
+			if exited {
+				fail(i, "handler has returned already")
+				return false
+			}
+			select {
+			case cmds <- svc.ChangeRequest{Cmd: step.Cmd, CurrentStatus: p.current()}:
+			case <-deadline:
+				fail(i, "handler did not receive request within %v", within)
+				return false
+			}
+		}
+		for _, want := range step.Expect {
+			for matched := false; !matched; {
+				select {
+				case r := <-p.reports:
+					fmt.Fprintf(&seen, "  %v %s", r.at.Round(time.Millisecond), r.status.State)
+					if r.exited {
+						exited = true
+						fmt.Fprintf(&seen, " (handler returned %d)", r.ec)
+						if r.ec != s.ExitCode {
+							seen.WriteString("\n")
+							fail(i, "handler returned exit code %d, want %d", r.ec, s.ExitCode)
+							return false
+						}
+					}
+					seen.WriteString("\n")
+					if rec != nil {
+						writeReport(rec, r)
+					}
+					got := r.status.State
+					switch {
+					case got == want:
+						matched = true
+					case got == prev && !r.exited:
+						// progress of the same state
+					default:
+						fail(i, "want %s, got %s at %v", want, got, r.at.Round(time.Millisecond))
+						return false
+					}
+					prev = got
+				case <-deadline:
+					fail(i, "%s not reported within %v", want, within)
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
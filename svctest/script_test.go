@@ -0,0 +1,136 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package svctest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/multiplay/winsvc/mgr"
+	"github.com/multiplay/winsvc/svc"
+)
+
+type handlerFunc func(args []string, r <-chan svc.ChangeRequest, s chan<- svc.Status) (bool, uint32)
+
+func (f handlerFunc) Execute(args []string, r <-chan svc.ChangeRequest, s chan<- svc.Status) (bool, uint32) {
+	return f(args, r, s)
+}
+
+// pausable can be paused, but does not report Paused,
+// if it is continued within 10ms.
+var pausable = handlerFunc(func(args []string, r <-chan svc.ChangeRequest, s chan<- svc.Status) (bool, uint32) {
+	s <- svc.Status{State: svc.StartPending, CheckPoint: 1}
+	s <- svc.Status{State: svc.StartPending, CheckPoint: 2}
+	s <- svc.Status{State: svc.Running}
+	for c := range r {
+		switch c.Cmd {
+		case svc.Pause:
+			s <- svc.Status{State: svc.PausePending}
+			time.Sleep(10 * time.Millisecond)
+			s <- svc.Status{State: svc.Paused}
+		case svc.Continue:
+			s <- svc.Status{State: svc.Running}
+		case svc.Stop:
+			s <- svc.Status{State: svc.StopPending}
+			return false, 3
+		}
+	}
+	return false, 0
+})
+
+// fakeT records failure.
+type fakeT struct {
+	msg string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Fatalf(format string, args ...interface{}) {
+	t.msg = fmt.Sprintf(format, args...)
+}
+
+// pausableScript pauses, continues and stops pausable.
+var pausableScript = Script{
+	Steps: []Step{
+		{Expect: []svc.State{svc.StartPending, svc.Running}},
+		{Cmd: svc.Pause, Expect: []svc.State{svc.PausePending, svc.Paused}},
+		{After: 5 * time.Millisecond, Cmd: svc.Continue, Expect: []svc.State{svc.Running}},
+		{Cmd: svc.Stop, Expect: []svc.State{svc.StopPending, svc.Stopped}},
+	},
+	ExitCode: 3,
+}
+
+func TestScript(t *testing.T) {
+	pausableScript.Run(t, pausable)
+}
+
+func TestScriptFailure(t *testing.T) {
+	tests := []struct {
+		s    Script
+		want string
+	}{
+		{
+			Script{Steps: []Step{
+				{Expect: []svc.State{svc.StartPending, svc.Running}},
+				{Cmd: svc.Pause, Expect: []svc.State{svc.Paused}},
+			}},
+			"svctest: step 1 (Pause): want Paused, got PausePending",
+		},
+		{
+			Script{Steps: []Step{
+				{Expect: []svc.State{svc.StartPending, svc.Running}},
+				{Cmd: svc.Pause, Expect: []svc.State{svc.PausePending, svc.Paused}, Within: 5 * time.Millisecond},
+			}},
+			"svctest: step 1 (Pause): Paused not reported within 5ms",
+		},
+		{
+			Script{Steps: []Step{
+				{Expect: []svc.State{svc.StartPending, svc.Running}},
+				{Cmd: svc.Stop, Expect: []svc.State{svc.StopPending, svc.Stopped}},
+			}},
+			"svctest: step 1 (Stop): handler returned exit code 3, want 0",
+		},
+	}
+	for _, test := range tests {
+		var ft fakeT
+		test.s.Run(&ft, pausable)
+		if !strings.HasPrefix(ft.msg, test.want) {
+			t.Errorf("script failed with %q, want %q", ft.msg, test.want)
+		}
+	}
+}
+
+func TestGolden(t *testing.T) {
+	pausableScript.Golden(t, pausable, "testdata/pausable.golden")
+
+	b, err := os.ReadFile("testdata/pausable.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "changed.golden")
+	err = os.WriteFile(path, []byte(strings.Replace(string(b), "  Paused\n", "", 1)), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ft fakeT
+	pausableScript.Golden(&ft, pausable, path)
+	want := "svctest: statuses differ from golden file " + path + " at line 7"
+	if !strings.HasPrefix(ft.msg, want) {
+		t.Errorf("script failed with %q, want %q", ft.msg, want)
+	}
+}
+
+func TestLive(t *testing.T) {
+	l := Install(t, "github.com/multiplay/winsvc/example", mgr.Config{})
+	l.Start(t)
+	l.Control(t, svc.Interrogate, svc.Running)
+	l.Stop(t, 0)
+}
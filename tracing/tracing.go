@@ -0,0 +1,60 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Package tracing defines the small part of a tracing API, like
+// OpenTelemetry, that svc and mgr use to report spans of service
+// lifecycle and management calls. The interfaces follow OpenTelemetry
+// trace API closely, so adapting TracerProvider of OpenTelemetry SDK
+// takes a few lines, without this module depending on it.
+//
+package tracing
+
+import (
+	"context"
+)
+
+// TracerProvider returns Tracer for instrumented package name,
+// like "github.com/multiplay/winsvc/svc".
+type TracerProvider interface {
+	Tracer(name string) Tracer
+}
+
+// Tracer starts spans.
+type Tracer interface {
+	// Start starts span name, child of span in ctx, if any,
+	// and returns ctx carrying the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is an operation being traced. Its methods may be called
+// from any goroutine.
+type Span interface {
+	SetAttributes(attrs ...Attr)
+	AddEvent(name string, attrs ...Attr)
+	RecordError(err error)
+	End()
+}
+
+// Attr is a span or event attribute. Value is string, int64 or bool.
+type Attr struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns string attribute.
+func String(key, value string) Attr {
+	return Attr{key, value}
+}
+
+// Int returns integer attribute.
+func Int(key string, value int64) Attr {
+	return Attr{key, value}
+}
+
+// Bool returns boolean attribute.
+func Bool(key string, value bool) Attr {
+	return Attr{key, value}
+}
@@ -0,0 +1,213 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Package update replaces the executable of an installed service.
+// The new binary is verified, swapped in place of the old one and
+// the service is restarted. If the new version does not reach
+// Running state in time, the old binary is put back and started again.
+//
+// Update must be called from a process other than the service
+// being updated, for example a small helper the service spawns.
+//
+package update
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/multiplay/winsvc/mgr"
+	"github.com/multiplay/winsvc/svc"
+	"github.com/multiplay/winsvc/winapi"
+)
+
+// Options describes how new binary is verified and installed.
+type Options struct {
+	// SHA256 is expected hex encoded SHA-256 digest of the new binary.
+	SHA256 string
+
+	// Verify, if not nil, is called with the name of the staged
+	// binary, and can be used to check its signature.
+	Verify func(path string) error
+
+	// Timeout is how long the new version has to reach Running state.
+	// It defaults to 30 seconds.
+	Timeout time.Duration
+}
+
+// Update stops service name, replaces its executable with binary
+// newpath and starts it again. Old executable is restored, if service
+// fails to start.
+func Update(m *mgr.Mgr, name, newpath string, o Options) error {
+	if o.SHA256 == "" && o.Verify == nil {
+		return errors.New("update: no hash or verify function specified")
+	}
+	if o.Timeout == 0 {
+		o.Timeout = 30 * time.Second
+	}
+	s, err := m.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	c, err := s.Config()
+	if err != nil {
+		return err
+	}
+	exe := exePath(c.BinaryPathName)
+	staged := exe + ".new"
+	old := exe + ".old"
+
+	err = copyFile(staged, newpath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(staged)
+	err = verify(staged, o)
+	if err != nil {
+		return err
+	}
+
+	err = stopService(s, o.Timeout)
+	if err != nil {
+		return err
+	}
+	return swap(s, exe, staged, old, o.Timeout)
+}
+
+// These are replaced in tests.
+var (
+	moveFile     = move
+	startService = start
+	stopService  = (*mgr.Service).StopAndWait
+)
+
+// swap replaces stopped service s executable exe with staged binary,
+// keeping exe as old, and starts s. It restores old and starts s
+// again, if new version cannot be put in place or fails to start.
+func swap(s *mgr.Service, exe, staged, old string, timeout time.Duration) error {
+	err := moveFile(exe, old)
+	if err != nil {
+		err2 := startService(s, timeout)
+		if err2 != nil {
+			return fmt.Errorf("update: %v; old version failed to start: %v", err, err2)
+		}
+		return err
+	}
+	err = moveFile(staged, exe)
+	if err != nil {
+		return rollback(s, exe, old, timeout, err)
+	}
+	err = startService(s, timeout)
+	if err == nil {
+		os.Remove(old)
+		return nil
+	}
+
+	// new version is not working, roll back
+	err2 := stopService(s, timeout)
+	if err2 != nil {
+		return fmt.Errorf("update: new version failed: %v; rollback failed to stop it: %v", err, err2)
+	}
+	return rollback(s, exe, old, timeout, err)
+}
+
+// rollback puts old executable back as exe and starts service s
+// again, after new version failed with err.
+func rollback(s *mgr.Service, exe, old string, timeout time.Duration, err error) error {
+	err2 := moveFile(old, exe)
+	if err2 != nil {
+		return fmt.Errorf("update: new version failed: %v; rollback failed: %v", err, err2)
+	}
+	err2 = startService(s, timeout)
+	if err2 != nil {
+		return fmt.Errorf("update: new version failed: %v; old version failed to start: %v", err, err2)
+	}
+	return fmt.Errorf("update: new version failed, rolled back: %v", err)
+}
+
+// exePath extracts executable name from service command line cmd.
+func exePath(cmd string) string {
+	cmd = strings.TrimSpace(cmd)
+	if strings.HasPrefix(cmd, `"`) {
+		if i := strings.Index(cmd[1:], `"`); i >= 0 {
+			return cmd[1 : i+1]
+		}
+		return cmd[1:]
+	}
+	if i := strings.Index(strings.ToLower(cmd), ".exe"); i >= 0 {
+		return cmd[:i+4]
+	}
+	if i := strings.Index(cmd, " "); i >= 0 {
+		return cmd[:i]
+	}
+	return cmd
+}
+
+func verify(path string, o Options) error {
+	if o.SHA256 != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(hex.EncodeToString(h.Sum(nil)), o.SHA256) {
+			return errors.New("update: " + path + " SHA-256 mismatch")
+		}
+	}
+	if o.Verify != nil {
+		return o.Verify(path)
+	}
+	return nil
+}
+
+func copyFile(dst, src string) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	if err2 := w.Sync(); err == nil {
+		err = err2
+	}
+	if err2 := w.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func move(from, to string) error {
+	return winapi.MoveFileEx(syscall.StringToUTF16Ptr(from), syscall.StringToUTF16Ptr(to),
+		winapi.MOVEFILE_REPLACE_EXISTING|winapi.MOVEFILE_WRITE_THROUGH)
+}
+
+func start(s *mgr.Service, timeout time.Duration) error {
+	err := s.Start(nil)
+	if err != nil {
+		return err
+	}
+	status, err := s.WaitState(svc.Running, timeout)
+	if err != nil && status.State == svc.Stopped {
+		return errors.New("service stopped during start")
+	}
+	return err
+}
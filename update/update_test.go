@@ -0,0 +1,92 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package update
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/multiplay/winsvc/mgr"
+)
+
+func TestExePath(t *testing.T) {
+	tests := []struct {
+		cmd, exe string
+	}{
+		{`C:\svc\a.exe`, `C:\svc\a.exe`},
+		{`"C:\Program Files\svc\a.exe" -p 1`, `C:\Program Files\svc\a.exe`},
+		{`C:\Program Files\svc\a.exe -p 1`, `C:\Program Files\svc\a.exe`},
+		{`C:\svc\a -p 1`, `C:\svc\a`},
+	}
+	for _, test := range tests {
+		if exe := exePath(test.cmd); exe != test.exe {
+			t.Errorf("exePath(%q) = %q, want %q", test.cmd, exe, test.exe)
+		}
+	}
+}
+
+func TestSwapRollback(t *testing.T) {
+	defer func(m func(string, string) error, start, stop func(*mgr.Service, time.Duration) error) {
+		moveFile, startService, stopService = m, start, stop
+	}(moveFile, startService, stopService)
+
+	tests := []struct {
+		name       string
+		failMove   bool // move of staged binary fails
+		failStarts int  // number of starts that fail
+		stops      int
+		starts     int
+	}{
+		{name: "move", failMove: true, starts: 1},
+		{name: "start", failStarts: 1, stops: 1, starts: 2},
+		{name: "restart", failStarts: 2, stops: 1, starts: 2},
+	}
+	for _, test := range tests {
+		dir, err := ioutil.TempDir("", "update")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		exe := filepath.Join(dir, "a.exe")
+		staged, old := exe+".new", exe+".old"
+		ioutil.WriteFile(exe, []byte("old"), 0600)
+		ioutil.WriteFile(staged, []byte("new"), 0600)
+
+		var stops, starts int
+		moveFile = func(from, to string) error {
+			if test.failMove && from == staged {
+				return errors.New("access denied")
+			}
+			return os.Rename(from, to)
+		}
+		startService = func(*mgr.Service, time.Duration) error {
+			starts++
+			if starts <= test.failStarts {
+				return errors.New("service stopped during start")
+			}
+			return nil
+		}
+		stopService = func(*mgr.Service, time.Duration) error {
+			stops++
+			return nil
+		}
+		err = swap(nil, exe, staged, old, time.Second)
+		if err == nil {
+			t.Errorf("%s: swap succeeded", test.name)
+		}
+		if stops != test.stops || starts != test.starts {
+			t.Errorf("%s: service stopped %d and started %d times, want %d and %d", test.name, stops, starts, test.stops, test.starts)
+		}
+		if b, _ := ioutil.ReadFile(exe); string(b) != "old" {
+			t.Errorf("%s: executable is %q after rollback, want old", test.name, b)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi
+
+import "errors"
+
+// ErrUnavailable matches, with errors.Is, errors of functions
+// that are missing from this system. Minimal editions, like
+// Server Core and Nano Server, lack some dlls and functions.
+var ErrUnavailable = errors.New("function is not available on this system")
+
+// UnavailableError is returned by functions of this
+// package that are missing from this system.
+type UnavailableError struct {
+	Func string
+	Err  error // error of loading the dll or finding the function
+}
+
+func (e *UnavailableError) Error() string {
+	return e.Func + ": " + ErrUnavailable.Error() + ": " + e.Err.Error()
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+// unavailable returns UnavailableError for function fn,
+// that could not be found with error err.
+func unavailable(fn string, err error) error {
+	return &UnavailableError{Func: fn, Err: err}
+}
+
+// Capability is a group of related functions, that is
+// either present or missing from the system as a whole.
+type Capability struct {
+	Name  string
+	procs []*LazyProc
+}
+
+// Check returns UnavailableError for the first
+// function of c missing from the system, if any.
+func (c *Capability) Check() error {
+	for _, p := range c.procs {
+		err := p.Find()
+		if err != nil {
+			return unavailable(p.Name, err)
+		}
+	}
+	return nil
+}
+
+// Available reports whether all functions of c are present.
+func (c *Capability) Available() bool {
+	return c.Check() == nil
+}
+
+var (
+	// CapDesktop covers window stations and desktops.
+	CapDesktop = &Capability{"desktop", []*LazyProc{
+		procGetProcessWindowStation,
+		procGetThreadDesktop,
+		procGetUserObjectInformationW,
+	}}
+
+	// CapDeviceNotify covers device event notifications.
+	CapDeviceNotify = &Capability{"device notifications", []*LazyProc{
+		procRegisterDeviceNotificationW,
+		procUnregisterDeviceNotification,
+	}}
+
+	// CapPowerNotify covers power setting notifications.
+	CapPowerNotify = &Capability{"power notifications", []*LazyProc{
+		procRegisterPowerSettingNotification,
+		procUnregisterPowerSettingNotification,
+	}}
+
+	// CapSessions covers Terminal Services sessions
+	// and environment of their users.
+	CapSessions = &Capability{"sessions", []*LazyProc{
+		procWTSEnumerateSessionsW,
+		procWTSFreeMemory,
+		procWTSQuerySessionInformationW,
+		procWTSQueryUserToken,
+		procWTSSendMessageW,
+		procCreateEnvironmentBlock,
+		procDestroyEnvironmentBlock,
+	}}
+
+	// CapServiceNotify covers service change subscriptions.
+	CapServiceNotify = &Capability{"service change notifications", []*LazyProc{
+		procSubscribeServiceChangeNotifications,
+		procUnsubscribeServiceChangeNotifications,
+	}}
+
+	// CapEventLog covers classic event log.
+	CapEventLog = &Capability{"event log", []*LazyProc{
+		procRegisterEventSourceW,
+		procDeregisterEventSource,
+		procReportEventW,
+		procOpenEventLogW,
+		procReadEventLogW,
+		procCloseEventLog,
+	}}
+)
+
+// Capabilities lists all capabilities probed by this package.
+var Capabilities = []*Capability{
+	CapDesktop,
+	CapDeviceNotify,
+	CapPowerNotify,
+	CapSessions,
+	CapServiceNotify,
+	CapEventLog,
+}
@@ -0,0 +1,65 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi
+
+import "syscall"
+
+const (
+	X509_ASN_ENCODING   = 0x00000001
+	PKCS_7_ASN_ENCODING = 0x00010000
+
+	CERT_STORE_PROV_SYSTEM_W = 10
+
+	CERT_SYSTEM_STORE_CURRENT_USER  = 1 << 16
+	CERT_SYSTEM_STORE_LOCAL_MACHINE = 2 << 16
+
+	CERT_STORE_OPEN_EXISTING_FLAG = 0x00004000
+	CERT_STORE_READONLY_FLAG      = 0x00008000
+
+	CERT_FIND_ANY           = 0
+	CERT_FIND_SHA1_HASH     = 0x00010000
+	CERT_FIND_SUBJECT_STR_W = 0x00080007
+
+	CRYPT_E_NOT_FOUND syscall.Errno = 0x80092004
+)
+
+// CryptAcquireCertificatePrivateKey flags
+const (
+	CRYPT_ACQUIRE_CACHE_FLAG           = 0x00000001
+	CRYPT_ACQUIRE_SILENT_FLAG          = 0x00000040
+	CRYPT_ACQUIRE_ONLY_NCRYPT_KEY_FLAG = 0x00040000
+
+	CERT_NCRYPT_KEY_SPEC = 0xffffffff
+)
+
+// NCryptSignHash padding
+const (
+	BCRYPT_PAD_PKCS1 = 0x00000002
+	BCRYPT_PAD_PSS   = 0x00000008
+
+	NCRYPT_SILENT_FLAG = 0x00000040
+)
+
+type BCRYPT_PKCS1_PADDING_INFO struct {
+	AlgId *uint16
+}
+
+type BCRYPT_PSS_PADDING_INFO struct {
+	AlgId *uint16
+	Salt  uint32
+}
+
+// NCRYPT_SECURITY_DESCR_PROPERTY is NCryptGetProperty property,
+// that returns security descriptor of the key.
+const NCRYPT_SECURITY_DESCR_PROPERTY = "Security Descr"
+
+//sys	CertFindCertificateInStore(store syscall.Handle, encoding uint32, findFlags uint32, findType uint32, para uintptr, prev *syscall.CertContext) (cert *syscall.CertContext, err error) [failretval==nil] = crypt32.CertFindCertificateInStore
+//sys	CertDuplicateCertificateContext(cert *syscall.CertContext) (dup *syscall.CertContext) = crypt32.CertDuplicateCertificateContext
+//sys	CryptAcquireCertificatePrivateKey(cert *syscall.CertContext, flags uint32, para uintptr, key *uintptr, keySpec *uint32, callerFree *uint32) (err error) = crypt32.CryptAcquireCertificatePrivateKey
+//sys	NCryptSignHash(key uintptr, padding unsafe.Pointer, hash *byte, hashLen uint32, sig *byte, sigLen uint32, result *uint32, flags uint32) (status error) = ncrypt.NCryptSignHash
+//sys	NCryptGetProperty(object uintptr, property *uint16, buf *byte, bufLen uint32, result *uint32, flags uint32) (status error) = ncrypt.NCryptGetProperty
+//sys	NCryptFreeObject(object uintptr) (status error) = ncrypt.NCryptFreeObject
@@ -0,0 +1,90 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi
+
+import (
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	COINIT_MULTITHREADED = 0x0
+
+	CLSCTX_INPROC_SERVER = 0x1
+
+	ERROR_NOT_ENOUGH_MEMORY syscall.Errno = 8
+	RPC_E_CHANGED_MODE      syscall.Errno = 0x80010106
+
+	VT_EMPTY = 0
+	VT_BSTR  = 8
+)
+
+// VARIANT is OLE automation VARIANT. Only VT_EMPTY and VT_BSTR
+// values are used, with BSTR stored in Val.
+type VARIANT struct {
+	VT        uint16
+	reserved1 uint16
+	reserved2 uint16
+	reserved3 uint16
+	Val       uintptr
+	_         uintptr
+}
+
+// Args returns v as arguments of COM method taking VARIANT by value.
+// 64-bit Windows passes large structs by reference to a copy, that
+// is pinned by p, while 32-bit Windows pushes them on stack.
+func (v VARIANT) Args(p *runtime.Pinner) []uintptr {
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		c := new(VARIANT)
+		*c = v
+		p.Pin(c)
+		return []uintptr{uintptr(unsafe.Pointer(c))}
+	}
+	w := *(*[4]uintptr)(unsafe.Pointer(&v))
+	return w[:]
+}
+
+// hresultErr returns CallError for HRESULT hr returned by fn.
+// HRESULTs wrapping Win32 error codes are unwrapped, so they
+// match syscall.Errno constants.
+func hresultErr(fn string, hr uintptr) error {
+	e := syscall.Errno(uint32(hr))
+	if e&0xffff0000 == 0x80070000 {
+		e &= 0xffff
+	}
+	return &CallError{Func: fn, Errno: e}
+}
+
+// ComCall calls method number method of COM object obj, passing
+// it args. Methods are numbered in order of interface vtable,
+// starting with IUnknown QueryInterface, AddRef and Release. Failed
+// HRESULT is returned as error of function fn. Like syscall.Proc.Call,
+// pointers converted to uintptr in the call expression are kept alive
+// and not moved until the call returns; others must be pinned.
+//
+//go:uintptrescapes
+func ComCall(fn string, obj unsafe.Pointer, method int, args ...uintptr) error {
+	vtbl := *(*unsafe.Pointer)(obj)
+	m := *(*uintptr)(unsafe.Pointer(uintptr(vtbl) + uintptr(method)*unsafe.Sizeof(uintptr(0))))
+	hr, _, _ := syscall.SyscallN(m, append([]uintptr{uintptr(obj)}, args...)...)
+	if int32(hr) < 0 {
+		return hresultErr(fn, hr)
+	}
+	return nil
+}
+
+// ComRelease releases COM object obj.
+func ComRelease(obj unsafe.Pointer) {
+	ComCall("Release", obj, 2)
+}
+
+//sys	CoInitializeEx(reserved uintptr, coInit uint32) (hr error) [failretval&0x80000000 != 0] = ole32.CoInitializeEx
+//sys	CoUninitialize() = ole32.CoUninitialize
+//sys	CoCreateInstance(clsid *GUID, outer uintptr, clsContext uint32, iid *GUID, obj *unsafe.Pointer) (hr error) [failretval&0x80000000 != 0] = ole32.CoCreateInstance
+//sys	SysAllocString(s *uint16) (bstr *uint16) = oleaut32.SysAllocString
+//sys	SysFreeString(bstr *uint16) = oleaut32.SysFreeString
@@ -0,0 +1,41 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi
+
+import "syscall"
+
+const ERROR_NOT_FOUND syscall.Errno = 1168
+
+const (
+	CRED_TYPE_GENERIC = 1
+
+	CRED_PERSIST_SESSION       = 1
+	CRED_PERSIST_LOCAL_MACHINE = 2
+	CRED_PERSIST_ENTERPRISE    = 3
+
+	CRED_MAX_CREDENTIAL_BLOB_SIZE = 5 * 512
+)
+
+type CREDENTIAL struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+//sys	CredRead(target *uint16, ctype uint32, flags uint32, cred **CREDENTIAL) (err error) = advapi32.CredReadW
+//sys	CredWrite(cred *CREDENTIAL, flags uint32) (err error) = advapi32.CredWriteW
+//sys	CredDelete(target *uint16, ctype uint32, flags uint32) (err error) = advapi32.CredDeleteW
+//sys	CredFree(buffer uintptr) = advapi32.CredFree
@@ -0,0 +1,22 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi
+
+// CryptProtectData and CryptUnprotectData flags
+const (
+	CRYPTPROTECT_UI_FORBIDDEN  = 0x1
+	CRYPTPROTECT_LOCAL_MACHINE = 0x4
+	CRYPTPROTECT_AUDIT         = 0x10
+)
+
+type DATA_BLOB struct {
+	Size uint32
+	Data *byte
+}
+
+//sys	CryptProtectData(in *DATA_BLOB, description *uint16, entropy *DATA_BLOB, reserved uintptr, prompt uintptr, flags uint32, out *DATA_BLOB) (err error) = crypt32.CryptProtectData
+//sys	CryptUnprotectData(in *DATA_BLOB, description **uint16, entropy *DATA_BLOB, reserved uintptr, prompt uintptr, flags uint32, out *DATA_BLOB) (err error) = crypt32.CryptUnprotectData
@@ -0,0 +1,30 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi
+
+const (
+	// GetUserObjectInformation index values
+	UOI_FLAGS    = 1
+	UOI_NAME     = 2
+	UOI_TYPE     = 3
+	UOI_USER_SID = 4
+)
+
+// USEROBJECTFLAGS.Flags value of window station
+// that can display windows and receive user input
+const WSF_VISIBLE = 1
+
+type USEROBJECTFLAGS struct {
+	Inherit  int32
+	Reserved int32
+	Flags    uint32
+}
+
+//sys	GetProcessWindowStation() (h syscall.Handle, err error) = user32.GetProcessWindowStation
+//sys	GetThreadDesktop(threadId uint32) (h syscall.Handle, err error) = user32.GetThreadDesktop
+//sys	GetUserObjectInformation(obj syscall.Handle, index uint32, info *byte, length uint32, needed *uint32) (err error) = user32.GetUserObjectInformationW
+//sys	ProcessIdToSessionId(pid uint32, session *uint32) (err error) = kernel32.ProcessIdToSessionId
@@ -0,0 +1,98 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+const (
+	DEVICE_NOTIFY_ALL_INTERFACE_CLASSES = 0x00000004
+)
+
+// device events delivered with SERVICE_CONTROL_DEVICEEVENT
+const (
+	DBT_DEVICEARRIVAL           = 0x8000
+	DBT_DEVICEQUERYREMOVE       = 0x8001
+	DBT_DEVICEQUERYREMOVEFAILED = 0x8002
+	DBT_DEVICEREMOVEPENDING     = 0x8003
+	DBT_DEVICEREMOVECOMPLETE    = 0x8004
+	DBT_DEVICETYPESPECIFIC      = 0x8005
+	DBT_CUSTOMEVENT             = 0x8006
+)
+
+// device types stored in DEV_BROADCAST_HDR.DeviceType
+const (
+	DBT_DEVTYP_OEM             = 0x00000000
+	DBT_DEVTYP_VOLUME          = 0x00000002
+	DBT_DEVTYP_PORT            = 0x00000003
+	DBT_DEVTYP_DEVICEINTERFACE = 0x00000005
+	DBT_DEVTYP_HANDLE          = 0x00000006
+)
+
+// DEV_BROADCAST_HDR starts every DEV_BROADCAST_* structure.
+// Use DeviceType to decide which one event data points to.
+type DEV_BROADCAST_HDR struct {
+	Size       uint32
+	DeviceType uint32
+	Reserved   uint32
+}
+
+// DEV_BROADCAST_DEVICEINTERFACE is used as notification filter
+// for interface class ClassGuid (set Name to 0), and is sent for
+// DBT_DEVTYP_DEVICEINTERFACE events. Use DeviceInterfaceName to
+// read Name.
+type DEV_BROADCAST_DEVICEINTERFACE struct {
+	Size       uint32
+	DeviceType uint32
+	Reserved   uint32
+	ClassGuid  GUID
+	Name       [1]uint16
+}
+
+// DEV_BROADCAST_HANDLE is used as notification filter for file
+// handle Handle, and is sent for DBT_DEVTYP_HANDLE events.
+type DEV_BROADCAST_HANDLE struct {
+	Size       uint32
+	DeviceType uint32
+	Reserved   uint32
+	Handle     syscall.Handle
+	DevNotify  syscall.Handle
+	EventGuid  GUID
+	NameOffset int32
+	Data       [1]byte
+}
+
+// DEV_BROADCAST_VOLUME is sent for DBT_DEVTYP_VOLUME events.
+// Bit 0 of UnitMask is drive A:, bit 1 is drive B: and so on.
+type DEV_BROADCAST_VOLUME struct {
+	Size       uint32
+	DeviceType uint32
+	Reserved   uint32
+	UnitMask   uint32
+	Flags      uint16
+}
+
+// DeviceInterfaceName returns device interface name stored in p.
+// It does not read past p.Size bytes.
+func DeviceInterfaceName(p *DEV_BROADCAST_DEVICEINTERFACE) string {
+	off := unsafe.Offsetof(p.Name)
+	if uintptr(p.Size) <= off {
+		return ""
+	}
+	return UTF16PtrToString(&p.Name[0], int((uintptr(p.Size)-off)/2))
+}
+
+// RegisterDeviceNotification registers service status handle
+// recipient (returned by RegisterServiceCtrlHandlerEx) to receive
+// device events selected by filter, which points to one of
+// DEV_BROADCAST_DEVICEINTERFACE or DEV_BROADCAST_HANDLE. Use
+// DEVICE_NOTIFY_SERVICE_HANDLE for flags, optionally combined
+// with DEVICE_NOTIFY_ALL_INTERFACE_CLASSES.
+//sys	RegisterDeviceNotification(recipient syscall.Handle, filter *byte, flags uint32) (notify syscall.Handle, err error) [failretval==0] = user32.RegisterDeviceNotificationW
+//sys	UnregisterDeviceNotification(notify syscall.Handle) (err error) = user32.UnregisterDeviceNotification
@@ -0,0 +1,130 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi
+
+import (
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+// LOAD_LIBRARY_SEARCH_SYSTEM32 makes LoadLibraryEx search System32
+// directory only, for the dll and for dlls it depends on.
+const LOAD_LIBRARY_SEARCH_SYSTEM32 = 0x00000800
+
+// kernel32 is a known dll, it is always loaded from System32.
+var procLoadLibraryExW = syscall.NewLazyDLL("kernel32.dll").NewProc("LoadLibraryExW")
+
+// loadSystemLibrary loads dll name from System32 directory with
+// LoadLibraryEx. It fails, rather than searching elsewhere, on
+// systems that do not support LOAD_LIBRARY_SEARCH_SYSTEM32.
+func loadSystemLibrary(name string) (syscall.Handle, error) {
+	err := procLoadLibraryExW.Find()
+	if err != nil {
+		return 0, err
+	}
+	p, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return 0, err
+	}
+	r0, _, e1 := syscall.Syscall(procLoadLibraryExW.Addr(), 3, uintptr(unsafe.Pointer(p)), 0, LOAD_LIBRARY_SEARCH_SYSTEM32)
+	if r0 == 0 {
+		return 0, errnoErr("LoadLibraryExW", e1)
+	}
+	return syscall.Handle(r0), nil
+}
+
+// LazyDLL is a system dll, that is loaded on first use.
+type LazyDLL struct {
+	Name string
+
+	mu  sync.Mutex
+	dll *syscall.DLL // non nil once loaded
+}
+
+// NewSystemDLL returns lazily loaded system dll name. Unlike
+// syscall.NewLazyDLL, it always loads the dll, and dlls it depends
+// on, from System32 directory, and never searches application or
+// current directory, or PATH. That prevents dll preloading attacks
+// on programs that run with elevated privileges, like services.
+func NewSystemDLL(name string) *LazyDLL {
+	return &LazyDLL{Name: name}
+}
+
+// Load loads dll d, if it is not loaded yet.
+func (d *LazyDLL) Load() error {
+	if atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&d.dll))) != nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dll != nil {
+		return nil
+	}
+	h, err := loadSystemLibrary(d.Name)
+	if err != nil {
+		return &syscall.DLLError{Err: err, ObjName: d.Name, Msg: "Failed to load " + d.Name + ": " + err.Error()}
+	}
+	atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&d.dll)), unsafe.Pointer(&syscall.DLL{Name: d.Name, Handle: h}))
+	return nil
+}
+
+// NewProc returns procedure name of dll d.
+func (d *LazyDLL) NewProc(name string) *LazyProc {
+	return &LazyProc{Name: name, l: d}
+}
+
+// LazyProc is a procedure of LazyDLL, that is found on first use.
+type LazyProc struct {
+	Name string
+
+	mu   sync.Mutex
+	l    *LazyDLL
+	proc *syscall.Proc // non nil once found
+}
+
+// Find loads dll of p and finds p in it, if that is not done yet.
+func (p *LazyProc) Find() error {
+	if atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&p.proc))) != nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.proc != nil {
+		return nil
+	}
+	err := p.l.Load()
+	if err != nil {
+		return err
+	}
+	proc, err := p.l.dll.FindProc(p.Name)
+	if err != nil {
+		return err
+	}
+	atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&p.proc)), unsafe.Pointer(proc))
+	return nil
+}
+
+// Addr returns address of p. It panics, if p cannot be found.
+func (p *LazyProc) Addr() uintptr {
+	err := p.Find()
+	if err != nil {
+		panic(err)
+	}
+	return p.proc.Addr()
+}
+
+// Call calls p with arguments a, see syscall.Proc.Call.
+// It panics, if p cannot be found.
+func (p *LazyProc) Call(a ...uintptr) (r1, r2 uintptr, lastErr error) {
+	err := p.Find()
+	if err != nil {
+		panic(err)
+	}
+	return p.proc.Call(a...)
+}
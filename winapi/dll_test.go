@@ -0,0 +1,59 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+func TestNewSystemDLL(t *testing.T) {
+	err := winapi.NewSystemDLL("kernel32.dll").NewProc("GetTickCount").Find()
+	if err != nil {
+		t.Fatalf("failed to find GetTickCount: %v", err)
+	}
+	err = winapi.NewSystemDLL("kernel32.dll").NewProc("NoSuchFunction").Find()
+	if err == nil {
+		t.Fatal("found function kernel32 does not have")
+	}
+
+	// dll in current directory is not loaded
+	dir, err := ioutil.TempDir("", "winapi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "winapitest.dll"), b, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = winapi.NewSystemDLL("winapitest.dll").Load()
+	if err == nil {
+		t.Fatal("loaded dll from current directory")
+	}
+}
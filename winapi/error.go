@@ -0,0 +1,34 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi
+
+import "syscall"
+
+// CallError is returned by every failed function of this package.
+// It records name of the Windows API function and the error number
+// it failed with. Use errors.Is or errors.As to examine the error number.
+type CallError struct {
+	Func  string
+	Errno syscall.Errno
+}
+
+func (e *CallError) Error() string {
+	return e.Func + ": " + e.Errno.Error()
+}
+
+func (e *CallError) Unwrap() error {
+	return e.Errno
+}
+
+// errnoErr returns CallError for function fn failed with e.
+// Functions that fail without setting last-error report EINVAL.
+func errnoErr(fn string, e syscall.Errno) error {
+	if e == 0 {
+		e = syscall.EINVAL
+	}
+	return &CallError{Func: fn, Errno: e}
+}
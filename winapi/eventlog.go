@@ -15,6 +15,43 @@ const (
 	EVENTLOG_SUCCESS = 0
 )
 
+// read flags used with ReadEventLog
+const (
+	EVENTLOG_SEQUENTIAL_READ = 0x0001
+	EVENTLOG_SEEK_READ       = 0x0002
+	EVENTLOG_FORWARDS_READ   = 0x0004
+	EVENTLOG_BACKWARDS_READ  = 0x0008
+)
+
+// EVENTLOGRECORD is followed by source name, computer name,
+// user SID, insertion strings and event data, at offsets
+// recorded in it. Length is size of the whole record.
+type EVENTLOGRECORD struct {
+	Length              uint32
+	Reserved            uint32
+	RecordNumber        uint32
+	TimeGenerated       uint32
+	TimeWritten         uint32
+	EventID             uint32
+	EventType           uint16
+	NumStrings          uint16
+	EventCategory       uint16
+	ReservedFlags       uint16
+	ClosingRecordNumber uint32
+	StringOffset        uint32
+	UserSidLength       uint32
+	UserSidOffset       uint32
+	DataLength          uint32
+	DataOffset          uint32
+}
+
 //sys	RegisterEventSource(uncServerName *uint16, sourceName *uint16) (handle syscall.Handle, err error) [failretval==0] = advapi32.RegisterEventSourceW
 //sys	DeregisterEventSource(handle syscall.Handle) (err error) = advapi32.DeregisterEventSource
 //sys	ReportEvent(log syscall.Handle, etype uint16, category uint16, eventId uint32, usrSId uintptr, numStrings uint16, dataSize uint32, strings **uint16, rawData *byte) (err error) = advapi32.ReportEventW
+//sys	OpenEventLog(uncServerName *uint16, sourceName *uint16) (handle syscall.Handle, err error) [failretval==0] = advapi32.OpenEventLogW
+//sys	CloseEventLog(log syscall.Handle) (err error) = advapi32.CloseEventLog
+//sys	ReadEventLog(log syscall.Handle, flags uint32, offset uint32, buf *byte, bufSize uint32, bytesRead *uint32, minBytesNeeded *uint32) (err error) = advapi32.ReadEventLogW
+//sys	GetNumberOfEventLogRecords(log syscall.Handle, count *uint32) (err error) = advapi32.GetNumberOfEventLogRecords
+//sys	GetOldestEventLogRecord(log syscall.Handle, oldest *uint32) (err error) = advapi32.GetOldestEventLogRecord
+//sys	ClearEventLog(log syscall.Handle, backupFileName *uint16) (err error) = advapi32.ClearEventLogW
+//sys	BackupEventLog(log syscall.Handle, backupFileName *uint16) (err error) = advapi32.BackupEventLogW
@@ -0,0 +1,64 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi
+
+import "syscall"
+
+// LSA policy access rights
+const (
+	POLICY_GET_PRIVATE_INFORMATION = 0x00000004
+	POLICY_CREATE_ACCOUNT          = 0x00000010
+	POLICY_CREATE_SECRET           = 0x00000020
+	POLICY_LOOKUP_NAMES            = 0x00000800
+	POLICY_ALL_ACCESS              = 0x000f0fff
+)
+
+// NTSTATUS returned, if procedure cannot be found in its dll.
+const STATUS_PROCEDURE_NOT_FOUND = 0xC000007A
+
+type LSA_UNICODE_STRING struct {
+	Length        uint16 // in bytes, without terminating 0
+	MaximumLength uint16
+	Buffer        *uint16
+}
+
+type LSA_OBJECT_ATTRIBUTES struct {
+	Length                   uint32
+	RootDirectory            syscall.Handle
+	ObjectName               *LSA_UNICODE_STRING
+	Attributes               uint32
+	SecurityDescriptor       uintptr
+	SecurityQualityOfService uintptr
+}
+
+// SECURITY_LOGON_SESSION_DATA holds leading fields of the structure,
+// which is all this package needs. It is only read through pointer
+// returned by LsaGetLogonSessionData.
+type SECURITY_LOGON_SESSION_DATA struct {
+	Size                  uint32
+	LogonId               LUID
+	UserName              LSA_UNICODE_STRING
+	LogonDomain           LSA_UNICODE_STRING
+	AuthenticationPackage LSA_UNICODE_STRING
+	LogonType             uint32
+	Session               uint32
+	Sid                   *syscall.SID
+}
+
+// LSA functions return NTSTATUS, use LsaNtStatusToWinError to convert it.
+// They return STATUS_PROCEDURE_NOT_FOUND, if they cannot be loaded.
+
+//sys	LsaOpenPolicy(system *LSA_UNICODE_STRING, attrs *LSA_OBJECT_ATTRIBUTES, access uint32, policy *syscall.Handle) (status uint32) [unavailable=STATUS_PROCEDURE_NOT_FOUND] = advapi32.LsaOpenPolicy
+//sys	LsaClose(policy syscall.Handle) (status uint32) [unavailable=STATUS_PROCEDURE_NOT_FOUND] = advapi32.LsaClose
+//sys	LsaFreeMemory(buffer uintptr) (status uint32) [unavailable=STATUS_PROCEDURE_NOT_FOUND] = advapi32.LsaFreeMemory
+//sys	LsaStorePrivateData(policy syscall.Handle, key *LSA_UNICODE_STRING, data *LSA_UNICODE_STRING) (status uint32) [unavailable=STATUS_PROCEDURE_NOT_FOUND] = advapi32.LsaStorePrivateData
+//sys	LsaRetrievePrivateData(policy syscall.Handle, key *LSA_UNICODE_STRING, data **LSA_UNICODE_STRING) (status uint32) [unavailable=STATUS_PROCEDURE_NOT_FOUND] = advapi32.LsaRetrievePrivateData
+//sys	LsaAddAccountRights(policy syscall.Handle, sid *syscall.SID, rights *LSA_UNICODE_STRING, count uint32) (status uint32) [unavailable=STATUS_PROCEDURE_NOT_FOUND] = advapi32.LsaAddAccountRights
+//sys	LsaRemoveAccountRights(policy syscall.Handle, sid *syscall.SID, all bool, rights *LSA_UNICODE_STRING, count uint32) (status uint32) [unavailable=STATUS_PROCEDURE_NOT_FOUND] = advapi32.LsaRemoveAccountRights
+//sys	LsaNtStatusToWinError(status uint32) (errno uint32) [unavailable=uint32(syscall.ERROR_PROC_NOT_FOUND)] = advapi32.LsaNtStatusToWinError
+//sys	LsaGetLogonSessionData(logonId *LUID, data **SECURITY_LOGON_SESSION_DATA) (status uint32) [unavailable=STATUS_PROCEDURE_NOT_FOUND] = secur32.LsaGetLogonSessionData
+//sys	LsaFreeReturnBuffer(buffer uintptr) (status uint32) [unavailable=STATUS_PROCEDURE_NOT_FOUND] = secur32.LsaFreeReturnBuffer
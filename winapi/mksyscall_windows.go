@@ -0,0 +1,391 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build ignore
+
+/*
+mksyscall_windows generates windows system call bodies
+
+It parses all files specified on command line containing function
+prototypes (like syscall_windows.go) and prints system call bodies
+to standard output.
+
+The prototypes are marked by lines beginning with "//sys" and read
+like func declarations if //sys is replaced by func, but:
+
+  - The parameter lists must give a name for each argument. This
+    includes return parameters.
+
+  - The parameter lists must give a type for each argument:
+    the (x, y, z int) shorthand is not allowed.
+
+  - If the return parameter is an error number, it must be named err.
+
+  - If go func name needs to be different from it's winapi dll name,
+    the winapi name could be specified at the end, after "=" sign, like
+    //sys LoadLibrary(libname string) (handle uint32, err error) = LoadLibraryA
+
+  - Each function that returns err needs to supply a condition, that
+    return value of winapi will be tested against to detect failure.
+    This would set err to windows "last-error", otherwise it will be nil.
+    The value can be provided at end of //sys declaration, like
+    //sys LoadLibrary(libname string) (handle uint32, err error) [failretval==-1] = LoadLibraryA
+    and is [failretval==0] by default.
+
+  - A return parameter of type error with a name other than err holds
+    an error code returned directly by the function, as registry
+    functions do. It is set when the code is not 0, or, if condition is
+    given, when it holds, like [failretval&0x80000000 != 0] for HRESULT.
+
+  - A function that does not return error can supply value, that its
+    return parameter is set to, when the function cannot be loaded, like
+    //sys LsaClose(policy syscall.Handle) (status uint32) [unavailable=STATUS_PROCEDURE_NOT_FOUND] = advapi32.LsaClose
+    It must be given, if zero value of return parameter means success.
+
+Functions are looked up lazily, when first called, in dlls loaded from
+System32 directory only (see NewSystemDLL). Functions returning
+an error fail with *UnavailableError, if their dll or function
+cannot be loaded. Other functions do nothing and return zero values,
+or value given by unavailable, in that case, so programs degrade on
+minimal Windows editions, instead of panicking. Every other error
+returned by the generated functions is a *CallError, recording name
+of the failed function and the error number.
+
+Usage:
+
+	mksyscall_windows [flags] [path ...]
+
+The flags are:
+
+	-output
+		Specify output file name (outputs to console if blank).
+*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var filename = flag.String("output", "", "output file name (standard output if omitted)")
+
+// Param is function parameter
+type Param struct {
+	Name string
+	Type string
+}
+
+// Fn describes syscall function.
+type Fn struct {
+	Name        string
+	Params      []Param
+	Rets        []Param
+	FailCond    string
+	Unavailable string // value returned, if function cannot be loaded
+	DLL         string
+	DLLFunc     string
+	tmp         int
+}
+
+var sysRE = regexp.MustCompile(`^(\w+)\((.*?)\)\s*(\(.*?\))?\s*(\[(.*?)\])?\s*(=\s*(.*))?$`)
+
+func parseParams(s string) ([]Param, error) {
+	var ps []Param
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		f := strings.SplitN(p, " ", 2)
+		if len(f) != 2 {
+			return nil, errors.New("parameter " + p + " has no type")
+		}
+		ps = append(ps, Param{Name: f[0], Type: strings.TrimSpace(f[1])})
+	}
+	return ps, nil
+}
+
+func newFn(s string) (*Fn, error) {
+	m := sysRE.FindStringSubmatch(s)
+	if m == nil {
+		return nil, errors.New("could not parse " + s)
+	}
+	f := &Fn{Name: m[1], FailCond: m[5], DLL: "kernel32", DLLFunc: m[1]}
+	var err error
+	f.Params, err = parseParams(m[2])
+	if err != nil {
+		return nil, err
+	}
+	if m[3] != "" {
+		f.Rets, err = parseParams(m[3][1 : len(m[3])-1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	if v := strings.TrimPrefix(f.FailCond, "unavailable="); v != f.FailCond {
+		f.FailCond, f.Unavailable = "", strings.TrimSpace(v)
+		if f.returnsErr() || f.result() == nil || f.result().Type == "error" {
+			return nil, errors.New(f.Name + " returns no value to set, when it is unavailable")
+		}
+	}
+	if m[7] != "" {
+		d := strings.TrimSpace(m[7])
+		if i := strings.Index(d, "."); i >= 0 {
+			f.DLL = d[:i]
+			d = d[i+1:]
+		}
+		f.DLLFunc = d
+	}
+	return f, nil
+}
+
+// returnsErr reports whether f returns "last-error" in err.
+func (f *Fn) returnsErr() bool {
+	for _, r := range f.Rets {
+		if r.Name == "err" && r.Type == "error" {
+			return true
+		}
+	}
+	return false
+}
+
+// result returns named return value, other than err, if any.
+func (f *Fn) result() *Param {
+	for i, r := range f.Rets {
+		if r.Name != "err" || r.Type != "error" {
+			return &f.Rets[i]
+		}
+	}
+	return nil
+}
+
+func (f *Fn) newTmp() string {
+	s := fmt.Sprintf("_p%d", f.tmp)
+	f.tmp++
+	return s
+}
+
+// args returns code preparing syscall arguments and the arguments.
+func (f *Fn) args() (pre []string, args []string) {
+	for _, p := range f.Params {
+		switch {
+		case strings.HasPrefix(p.Type, "*"):
+			args = append(args, "uintptr(unsafe.Pointer("+p.Name+"))")
+		case p.Type == "bool":
+			v := f.newTmp()
+			pre = append(pre, "var "+v+" uint32", "if "+p.Name+" {", v+" = 1", "} else {", v+" = 0", "}")
+			args = append(args, "uintptr("+v+")")
+		case strings.HasPrefix(p.Type, "[]"):
+			v := f.newTmp()
+			pre = append(pre, "var "+v+" *"+p.Type[2:], "if len("+p.Name+") > 0 {", v+" = &"+p.Name+"[0]", "}")
+			args = append(args, "uintptr(unsafe.Pointer("+v+"))", "uintptr(len("+p.Name+"))")
+		default:
+			args = append(args, "uintptr("+p.Name+")")
+		}
+	}
+	return
+}
+
+func (f *Fn) failCond(retvar string) string {
+	if f.FailCond == "" {
+		return retvar + " == 0"
+	}
+	return strings.Replace(f.FailCond, "failretval", retvar, 1)
+}
+
+func (f *Fn) write(w *bytes.Buffer) error {
+	var sig []string
+	for _, p := range f.Params {
+		sig = append(sig, p.Name+" "+p.Type)
+	}
+	var rsig []string
+	for _, p := range f.Rets {
+		rsig = append(rsig, p.Name+" "+p.Type)
+	}
+	fmt.Fprintf(w, "\nfunc %s(%s)", f.Name, strings.Join(sig, ", "))
+	if len(rsig) > 0 {
+		fmt.Fprintf(w, " (%s)", strings.Join(rsig, ", "))
+	}
+	fmt.Fprintf(w, " {\n")
+
+	r := f.result()
+	reterr := f.returnsErr()
+	switch {
+	case reterr:
+		fmt.Fprintf(w, "if e := proc%s.Find(); e != nil {\nerr = unavailable(%q, e)\nreturn\n}\n", f.DLLFunc, f.DLLFunc)
+	case r != nil && r.Type == "error":
+		fmt.Fprintf(w, "if e := proc%s.Find(); e != nil {\n%s = unavailable(%q, e)\nreturn\n}\n", f.DLLFunc, r.Name, f.DLLFunc)
+	case f.Unavailable != "":
+		fmt.Fprintf(w, "if proc%s.Find() != nil {\n%s = %s\nreturn\n}\n", f.DLLFunc, r.Name, f.Unavailable)
+	default:
+		fmt.Fprintf(w, "if proc%s.Find() != nil {\nreturn\n}\n", f.DLLFunc)
+	}
+
+	pre, args := f.args()
+	for _, l := range pre {
+		fmt.Fprintln(w, l)
+	}
+	n := len(args)
+	var sc string
+	switch {
+	case n <= 3:
+		sc, n = "Syscall", 3
+	case n <= 6:
+		sc, n = "Syscall6", 6
+	case n <= 9:
+		sc, n = "Syscall9", 9
+	case n <= 12:
+		sc, n = "Syscall12", 12
+	case n <= 15:
+		sc, n = "Syscall15", 15
+	default:
+		return errors.New(f.Name + " has too many arguments")
+	}
+	nargs := len(args)
+	for len(args) < n {
+		args = append(args, "0")
+	}
+	call := fmt.Sprintf("syscall.%s(proc%s.Addr(), %d, %s)", sc, f.DLLFunc, nargs, strings.Join(args, ", "))
+
+	switch {
+	case r == nil && !reterr:
+		fmt.Fprintln(w, call)
+	case r == nil:
+		fmt.Fprintf(w, "r1, _, e1 := %s\n", call)
+		fmt.Fprintf(w, "if %s {\nerr = errnoErr(%q, e1)\n}\n", f.failCond("r1"), f.DLLFunc)
+	default:
+		errvar := "_"
+		if reterr {
+			errvar = "e1"
+		}
+		fmt.Fprintf(w, "r0, _, %s := %s\n", errvar, call)
+		switch {
+		case r.Type == "error":
+			cond := "r0 != 0"
+			if f.FailCond != "" {
+				cond = f.failCond("r0")
+			}
+			fmt.Fprintf(w, "if %s {\n%s = errnoErr(%q, syscall.Errno(r0))\n}\n", cond, r.Name, f.DLLFunc)
+		case strings.HasPrefix(r.Type, "*"):
+			// r0 is reinterpreted, not converted, as go vet
+			// cannot tell r0 holds pointer returned by Windows
+			fmt.Fprintf(w, "%s = (%s)(*(*unsafe.Pointer)(unsafe.Pointer(&r0)))\n", r.Name, r.Type)
+		case r.Type == "bool":
+			fmt.Fprintf(w, "%s = r0 != 0\n", r.Name)
+		default:
+			fmt.Fprintf(w, "%s = %s(r0)\n", r.Name, r.Type)
+		}
+		if reterr && r.Type != "error" {
+			fmt.Fprintf(w, "if %s {\nerr = errnoErr(%q, e1)\n}\n", f.failCond(r.Name), f.DLLFunc)
+		}
+	}
+	fmt.Fprintf(w, "return\n}\n")
+	return nil
+}
+
+func parseFile(path string) ([]*Fn, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var fns []*Fn
+	s := bufio.NewScanner(file)
+	for s.Scan() {
+		t := strings.TrimSpace(s.Text())
+		if !strings.HasPrefix(t, "//sys") {
+			continue
+		}
+		t = strings.TrimSpace(t[len("//sys"):])
+		f, err := newFn(t)
+		if err != nil {
+			return nil, err
+		}
+		fns = append(fns, f)
+	}
+	return fns, s.Err()
+}
+
+func generate(files []string) ([]byte, error) {
+	var fns []*Fn
+	for _, file := range files {
+		f, err := parseFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fns = append(fns, f...)
+	}
+	var dlls []string
+	seen := make(map[string]bool)
+	for _, f := range fns {
+		if !seen[f.DLL] {
+			seen[f.DLL] = true
+			dlls = append(dlls, f.DLL)
+		}
+	}
+
+	var w bytes.Buffer
+	fmt.Fprintf(&w, "// go run mksyscall_windows.go -output zwinapi_windows.go %s\n", strings.Join(files, " "))
+	fmt.Fprintf(&w, "// MACHINE GENERATED BY THE COMMAND ABOVE; DO NOT EDIT\n\n")
+	fmt.Fprintf(&w, "package winapi\n\nimport \"unsafe\"\nimport \"syscall\"\n\nvar (\n")
+	for _, d := range dlls {
+		fmt.Fprintf(&w, "mod%s = NewSystemDLL(%q)\n", d, d+".dll")
+	}
+	fmt.Fprintln(&w)
+	seen = make(map[string]bool)
+	for _, f := range fns {
+		if !seen[f.DLLFunc] {
+			seen[f.DLLFunc] = true
+			fmt.Fprintf(&w, "proc%s = mod%s.NewProc(%q)\n", f.DLLFunc, f.DLL, f.DLLFunc)
+		}
+	}
+	fmt.Fprintf(&w, ")\n")
+	for _, f := range fns {
+		err := f.write(&w)
+		if err != nil {
+			return nil, err
+		}
+	}
+	src, err := format.Source(w.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%v\n%s", err, w.Bytes())
+	}
+	return src, nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: mksyscall_windows [flags] [path ...]\n")
+	flag.PrintDefaults()
+	os.Exit(1)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if len(flag.Args()) <= 0 {
+		fmt.Fprintf(os.Stderr, "no files to parse provided\n")
+		usage()
+	}
+	src, err := generate(flag.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *filename == "" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = ioutil.WriteFile(*filename, src, 0644)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi
+
+const (
+	SERVICE_NOTIFY_STATUS_CHANGE = 2
+)
+
+// notify masks used with NotifyServiceStatusChange
+const (
+	SERVICE_NOTIFY_STOPPED          = 0x00000001
+	SERVICE_NOTIFY_START_PENDING    = 0x00000002
+	SERVICE_NOTIFY_STOP_PENDING     = 0x00000004
+	SERVICE_NOTIFY_RUNNING          = 0x00000008
+	SERVICE_NOTIFY_CONTINUE_PENDING = 0x00000010
+	SERVICE_NOTIFY_PAUSE_PENDING    = 0x00000020
+	SERVICE_NOTIFY_PAUSED           = 0x00000040
+	SERVICE_NOTIFY_CREATED          = 0x00000080
+	SERVICE_NOTIFY_DELETED          = 0x00000100
+	SERVICE_NOTIFY_DELETE_PENDING   = 0x00000200
+)
+
+const (
+	WAIT_IO_COMPLETION = 0x000000c0
+)
+
+// SERVICE_NOTIFY is SERVICE_NOTIFY_2 structure. Set Version to
+// SERVICE_NOTIFY_STATUS_CHANGE. NotifyCallback is called with
+// pointer to this structure as its only parameter.
+type SERVICE_NOTIFY struct {
+	Version               uint32
+	NotifyCallback        uintptr
+	Context               uintptr
+	NotificationStatus    uint32
+	ServiceStatus         SERVICE_STATUS_PROCESS
+	NotificationTriggered uint32
+	ServiceNames          *uint16
+}
+
+// NotifyServiceStatusChange registers notify to be told when service
+// (or service control manager) handle enters one of states in mask.
+// Note the requirements:
+//
+// The callback is delivered as an asynchronous procedure call (APC)
+// to the thread that called NotifyServiceStatusChange, and only while
+// that thread is in an alertable wait (SleepEx or WaitForSingleObjectEx
+// with alertable set). The calling goroutine must therefore hold its
+// thread with runtime.LockOSThread, and wait on it, until the callback
+// runs. NotifyCallback must be created with syscall.NewCallback. Their
+// number is limited, so create it once and reuse it.
+//
+// The notify structure must stay allocated and must not be reused
+// until the callback is called. Each registration fires once; register
+// again from the callback to keep watching. Closing the handle cancels
+// pending notification, but callback may still be queued, so drain APCs
+// with an alertable wait before releasing the memory.
+//
+// Returns ERROR_SERVICE_MARKED_FOR_DELETE, if the service is deleted.
+// In that case the handle must be closed.
+//sys	NotifyServiceStatusChange(service syscall.Handle, mask uint32, notify *SERVICE_NOTIFY) (ret error) = advapi32.NotifyServiceStatusChangeW
+
+//sys	SleepEx(milliseconds uint32, alertable bool) (ret uint32) = kernel32.SleepEx
+//sys	WaitForSingleObjectEx(handle syscall.Handle, milliseconds uint32, alertable bool) (event uint32, err error) [failretval==0xffffffff] = kernel32.WaitForSingleObjectEx
+
+// SC_EVENT_TYPE used with SubscribeServiceChangeNotifications
+const (
+	SC_EVENT_DATABASE_CHANGE = 0
+	SC_EVENT_PROPERTY_CHANGE = 1
+	SC_EVENT_STATUS_CHANGE   = 2
+)
+
+// SubscribeServiceChangeNotifications subscribes to service control
+// manager (for SC_EVENT_DATABASE_CHANGE) or service (for other event
+// types) notifications. Unlike NotifyServiceStatusChange, callback is
+// called on a thread pool thread, so there is no need for alertable
+// waits, and subscription stays active until unsubscribed. Callback
+// must be created with syscall.NewCallback, and is called with
+// notify mask (SERVICE_NOTIFY_*) and context. Available since Windows 8.
+//sys	SubscribeServiceChangeNotifications(service syscall.Handle, eventType uint32, callback uintptr, context uintptr, subscription *uintptr) (ret error) = sechost.SubscribeServiceChangeNotifications
+
+// UnsubscribeServiceChangeNotifications waits for callbacks
+// in progress to complete, so it must not be called from
+// within callback.
+//sys	UnsubscribeServiceChangeNotifications(subscription uintptr) = sechost.UnsubscribeServiceChangeNotifications
@@ -0,0 +1,56 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi
+
+const (
+	DEVICE_NOTIFY_WINDOW_HANDLE  = 0
+	DEVICE_NOTIFY_SERVICE_HANDLE = 1
+)
+
+// power events delivered with SERVICE_CONTROL_POWEREVENT
+const (
+	PBT_APMQUERYSUSPEND       = 0x0000
+	PBT_APMQUERYSTANDBY       = 0x0001
+	PBT_APMQUERYSUSPENDFAILED = 0x0002
+	PBT_APMQUERYSTANDBYFAILED = 0x0003
+	PBT_APMSUSPEND            = 0x0004
+	PBT_APMSTANDBY            = 0x0005
+	PBT_APMRESUMECRITICAL     = 0x0006
+	PBT_APMRESUMESUSPEND      = 0x0007
+	PBT_APMRESUMESTANDBY      = 0x0008
+	PBT_APMBATTERYLOW         = 0x0009
+	PBT_APMPOWERSTATUSCHANGE  = 0x000a
+	PBT_APMOEMEVENT           = 0x000b
+	PBT_APMRESUMEAUTOMATIC    = 0x0012
+	PBT_POWERSETTINGCHANGE    = 0x8013
+)
+
+// power setting GUIDs used with RegisterPowerSettingNotification
+var (
+	GUID_ACDC_POWER_SOURCE            = GUID{0x5d3e9a59, 0xe9d5, 0x4b00, [8]byte{0xa6, 0xbd, 0xff, 0x34, 0xff, 0x51, 0x65, 0x48}}
+	GUID_BATTERY_PERCENTAGE_REMAINING = GUID{0xa7ad8041, 0xb45a, 0x4cae, [8]byte{0x87, 0xa3, 0xee, 0xcb, 0xb4, 0x68, 0xa9, 0xe1}}
+	GUID_CONSOLE_DISPLAY_STATE        = GUID{0x6fe69556, 0x704a, 0x47a0, [8]byte{0x8f, 0x24, 0xc2, 0x8d, 0x93, 0x6f, 0xda, 0x47}}
+	GUID_LIDSWITCH_STATE_CHANGE       = GUID{0xba3e0f4d, 0xb817, 0x4094, [8]byte{0xa2, 0xd1, 0xd5, 0x63, 0x79, 0xe6, 0xa0, 0xf3}}
+	GUID_MONITOR_POWER_ON             = GUID{0x02731015, 0x4510, 0x4526, [8]byte{0x99, 0xe6, 0xe5, 0xa1, 0x7e, 0xbd, 0x1a, 0xea}}
+	GUID_POWERSCHEME_PERSONALITY      = GUID{0x245d8541, 0x3943, 0x4422, [8]byte{0xb0, 0x25, 0x13, 0xa7, 0x84, 0xf6, 0x79, 0xb7}}
+	GUID_SYSTEM_AWAYMODE              = GUID{0x98a7f580, 0x01f7, 0x48aa, [8]byte{0x9c, 0x0f, 0x44, 0x35, 0x2c, 0x29, 0xe5, 0xc0}}
+)
+
+// POWERBROADCAST_SETTING is passed as event data with
+// SERVICE_CONTROL_POWEREVENT, if event type is PBT_POWERSETTINGCHANGE.
+// Data holds DataLength bytes.
+type POWERBROADCAST_SETTING struct {
+	PowerSetting GUID
+	DataLength   uint32
+	Data         [1]byte
+}
+
+// RegisterPowerSettingNotification registers service status handle
+// recipient (returned by RegisterServiceCtrlHandlerEx) to receive
+// power setting changes. Use DEVICE_NOTIFY_SERVICE_HANDLE for flags.
+//sys	RegisterPowerSettingNotification(recipient syscall.Handle, setting *GUID, flags uint32) (notify syscall.Handle, err error) [failretval==0] = user32.RegisterPowerSettingNotification
+//sys	UnregisterPowerSettingNotification(notify syscall.Handle) (err error) = user32.UnregisterPowerSettingNotification
@@ -0,0 +1,43 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi
+
+// PROCESS_MITIGATION_POLICY values used with SetProcessMitigationPolicy
+const (
+	ProcessDynamicCodePolicy           = 2
+	ProcessExtensionPointDisablePolicy = 6
+	ProcessImageLoadPolicy             = 10
+)
+
+// PROCESS_MITIGATION_DYNAMIC_CODE_POLICY flags
+const (
+	PROCESS_MITIGATION_PROHIBIT_DYNAMIC_CODE  = 0x1
+	PROCESS_MITIGATION_ALLOW_THREAD_OPT_OUT   = 0x2
+	PROCESS_MITIGATION_ALLOW_REMOTE_DOWNGRADE = 0x4
+)
+
+// PROCESS_MITIGATION_EXTENSION_POINT_DISABLE_POLICY flags
+const (
+	PROCESS_MITIGATION_DISABLE_EXTENSION_POINTS = 0x1
+)
+
+// PROCESS_MITIGATION_IMAGE_LOAD_POLICY flags
+const (
+	PROCESS_MITIGATION_NO_REMOTE_IMAGES              = 0x1
+	PROCESS_MITIGATION_NO_LOW_MANDATORY_LABEL_IMAGES = 0x2
+	PROCESS_MITIGATION_PREFER_SYSTEM32_IMAGES        = 0x4
+)
+
+// PROCESS_MITIGATION_DYNAMIC_CODE_POLICY,
+// PROCESS_MITIGATION_EXTENSION_POINT_DISABLE_POLICY and
+// PROCESS_MITIGATION_IMAGE_LOAD_POLICY are all single DWORD of flags.
+type PROCESS_MITIGATION_POLICY_FLAGS struct {
+	Flags uint32
+}
+
+//sys	SetProcessMitigationPolicy(policy uint32, buffer *byte, length uintptr) (err error)
+//sys	GetProcessMitigationPolicy(process syscall.Handle, policy uint32, buffer *byte, length uintptr) (err error)
@@ -49,6 +49,54 @@ const (
 	SECURITY_NT_NON_UNIQUE_RID          = 0x15
 )
 
+// security information flags
+const (
+	OWNER_SECURITY_INFORMATION = 0x00000001
+	GROUP_SECURITY_INFORMATION = 0x00000002
+	DACL_SECURITY_INFORMATION  = 0x00000004
+	SACL_SECURITY_INFORMATION  = 0x00000008
+	LABEL_SECURITY_INFORMATION = 0x00000010
+)
+
+const (
+	SDDL_REVISION_1 = 1
+)
+
+// privileges
+const (
+	SE_SHUTDOWN_NAME = "SeShutdownPrivilege"
+
+	SE_PRIVILEGE_ENABLED = 0x00000002
+
+	TOKEN_QUERY             = 0x0008
+	TOKEN_ADJUST_PRIVILEGES = 0x0020
+)
+
+const ERROR_NOT_ALL_ASSIGNED syscall.Errno = 1300
+
+// TokenElevation is TOKEN_INFORMATION_CLASS of TOKEN_ELEVATION.
+const TokenElevation = 20
+
+type TOKEN_ELEVATION struct {
+	TokenIsElevated uint32
+}
+
+type LUID struct {
+	LowPart  uint32
+	HighPart int32
+}
+
+type LUIDAndAttributes struct {
+	Luid       LUID
+	Attributes uint32
+}
+
+// TOKEN_PRIVILEGES holds single privilege, which is all this package needs.
+type TOKEN_PRIVILEGES struct {
+	PrivilegeCount uint32
+	Privileges     [1]LUIDAndAttributes
+}
+
 type Tokengroups struct {
 	GroupCount uint32
 	Groups     [1]syscall.SIDAndAttributes
@@ -57,3 +105,17 @@ type Tokengroups struct {
 //sys	AllocateAndInitializeSid(identAuth *SidIdentifierAuthority, subAuth byte, subAuth0 uint32, subAuth1 uint32, subAuth2 uint32, subAuth3 uint32, subAuth4 uint32, subAuth5 uint32, subAuth6 uint32, subAuth7 uint32, sid **syscall.SID) (err error) = advapi32.AllocateAndInitializeSid
 //sys	FreeSid(sid *syscall.SID) (err error) [failretval!=0] = advapi32.FreeSid
 //sys	EqualSid(sid1 *syscall.SID, sid2 *syscall.SID) (isEqual bool) = advapi32.EqualSid
+//sys	ImpersonateNamedPipeClient(pipe syscall.Handle) (err error) = advapi32.ImpersonateNamedPipeClient
+//sys	ImpersonateLoggedOnUser(token syscall.Token) (err error) = advapi32.ImpersonateLoggedOnUser
+//sys	RevertToSelf() (err error) = advapi32.RevertToSelf
+//sys	RpcImpersonateClient(binding uintptr) (status error) = rpcrt4.RpcImpersonateClient
+//sys	RpcRevertToSelfEx(binding uintptr) (status error) = rpcrt4.RpcRevertToSelfEx
+//sys	QueryServiceObjectSecurity(service syscall.Handle, securityInformation uint32, sd *byte, bufSize uint32, bytesNeeded *uint32) (err error) = advapi32.QueryServiceObjectSecurity
+//sys	SetServiceObjectSecurity(service syscall.Handle, securityInformation uint32, sd *byte) (err error) = advapi32.SetServiceObjectSecurity
+//sys	ConvertSecurityDescriptorToStringSecurityDescriptor(sd *byte, revision uint32, securityInformation uint32, str **uint16, strLen *uint32) (err error) = advapi32.ConvertSecurityDescriptorToStringSecurityDescriptorW
+//sys	LookupPrivilegeValue(system *uint16, name *uint16, luid *LUID) (err error) = advapi32.LookupPrivilegeValueW
+//sys	AdjustTokenPrivileges(token syscall.Token, disableAll bool, newState *TOKEN_PRIVILEGES, bufLen uint32, prevState *TOKEN_PRIVILEGES, returnLen *uint32) (err error) [failretval==0 || e1==ERROR_NOT_ALL_ASSIGNED] = advapi32.AdjustTokenPrivileges
+//sys	ConvertStringSecurityDescriptorToSecurityDescriptor(str *uint16, revision uint32, sd **byte, size *uint32) (err error) = advapi32.ConvertStringSecurityDescriptorToSecurityDescriptorW
+//sys	GetSecurityDescriptorOwner(sd *byte, owner **syscall.SID, defaulted *int32) (err error) = advapi32.GetSecurityDescriptorOwner
+//sys	GetSecurityDescriptorGroup(sd *byte, group **syscall.SID, defaulted *int32) (err error) = advapi32.GetSecurityDescriptorGroup
+//sys	GetSecurityDescriptorDacl(sd *byte, present *int32, dacl *uintptr, defaulted *int32) (err error) = advapi32.GetSecurityDescriptorDacl
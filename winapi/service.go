@@ -29,6 +29,11 @@ const (
 	SERVICE_INTERACTIVE_PROCESS = 256
 	SERVICE_DRIVER              = SERVICE_KERNEL_DRIVER | SERVICE_FILE_SYSTEM_DRIVER | SERVICE_RECOGNIZER_DRIVER
 	SERVICE_TYPE_ALL            = SERVICE_WIN32 | SERVICE_ADAPTER | SERVICE_DRIVER | SERVICE_INTERACTIVE_PROCESS
+
+	SERVICE_USER_SERVICE         = 0x00000040
+	SERVICE_USERSERVICE_INSTANCE = 0x00000080
+	SERVICE_USER_OWN_PROCESS     = SERVICE_USER_SERVICE | SERVICE_WIN32_OWN_PROCESS
+	SERVICE_USER_SHARE_PROCESS   = SERVICE_USER_SERVICE | SERVICE_WIN32_SHARE_PROCESS
 )
 
 const (
@@ -50,6 +55,10 @@ const (
 	SC_STATUS_PROCESS_INFO = 0
 )
 
+const (
+	SC_ENUM_PROCESS_INFO = 0
+)
+
 const (
 	SERVICE_STOPPED = 1 + iota
 	SERVICE_START_PENDING
@@ -113,10 +122,105 @@ const (
 	SERVICE_PAUSE_CONTINUE
 	SERVICE_INTERROGATE
 	SERVICE_USER_DEFINED_CONTROL
-	SERVICE_ALL_ACCESS             = STANDARD_RIGHTS_REQUIRED | SERVICE_QUERY_CONFIG | SERVICE_CHANGE_CONFIG | SERVICE_QUERY_STATUS | SERVICE_ENUMERATE_DEPENDENTS | SERVICE_START | SERVICE_STOP | SERVICE_PAUSE_CONTINUE | SERVICE_INTERROGATE | SERVICE_USER_DEFINED_CONTROL
-	SERVICE_RUNS_IN_SYSTEM_PROCESS = 1
-	SERVICE_CONFIG_DESCRIPTION     = 1
-	SERVICE_CONFIG_FAILURE_ACTIONS = 2
+	SERVICE_ALL_ACCESS                     = STANDARD_RIGHTS_REQUIRED | SERVICE_QUERY_CONFIG | SERVICE_CHANGE_CONFIG | SERVICE_QUERY_STATUS | SERVICE_ENUMERATE_DEPENDENTS | SERVICE_START | SERVICE_STOP | SERVICE_PAUSE_CONTINUE | SERVICE_INTERROGATE | SERVICE_USER_DEFINED_CONTROL
+	SERVICE_RUNS_IN_SYSTEM_PROCESS         = 1
+	SERVICE_CONFIG_DESCRIPTION             = 1
+	SERVICE_CONFIG_FAILURE_ACTIONS         = 2
+	SERVICE_CONFIG_DELAYED_AUTO_START_INFO = 3
+	SERVICE_CONFIG_FAILURE_ACTIONS_FLAG    = 4
+	SERVICE_CONFIG_SERVICE_SID_INFO        = 5
+)
+
+const (
+	SERVICE_SID_TYPE_NONE         = 0
+	SERVICE_SID_TYPE_UNRESTRICTED = 1
+	SERVICE_SID_TYPE_RESTRICTED   = 2 | SERVICE_SID_TYPE_UNRESTRICTED
+)
+
+const (
+	SC_ACTION_NONE = iota
+	SC_ACTION_RESTART
+	SC_ACTION_REBOOT
+	SC_ACTION_RUN_COMMAND
+)
+
+const (
+	SERVICE_CONFIG_REQUIRED_PRIVILEGES_INFO = 6
+	SERVICE_CONFIG_PRESHUTDOWN_INFO         = 7
+	SERVICE_CONFIG_TRIGGER_INFO             = 8
+	SERVICE_CONFIG_PREFERRED_NODE           = 9
+	SERVICE_CONFIG_LAUNCH_PROTECTED         = 12
+)
+
+// trigger types, actions and data types used with SERVICE_TRIGGER
+const (
+	SERVICE_TRIGGER_TYPE_DEVICE_INTERFACE_ARRIVAL   = 1
+	SERVICE_TRIGGER_TYPE_IP_ADDRESS_AVAILABILITY    = 2
+	SERVICE_TRIGGER_TYPE_DOMAIN_JOIN                = 3
+	SERVICE_TRIGGER_TYPE_FIREWALL_PORT_EVENT        = 4
+	SERVICE_TRIGGER_TYPE_GROUP_POLICY               = 5
+	SERVICE_TRIGGER_TYPE_NETWORK_ENDPOINT           = 6
+	SERVICE_TRIGGER_TYPE_CUSTOM_SYSTEM_STATE_CHANGE = 7
+	SERVICE_TRIGGER_TYPE_CUSTOM                     = 20
+	SERVICE_TRIGGER_TYPE_AGGREGATE                  = 30
+
+	SERVICE_TRIGGER_ACTION_SERVICE_START = 1
+	SERVICE_TRIGGER_ACTION_SERVICE_STOP  = 2
+
+	SERVICE_TRIGGER_DATA_TYPE_BINARY      = 1
+	SERVICE_TRIGGER_DATA_TYPE_STRING      = 2
+	SERVICE_TRIGGER_DATA_TYPE_LEVEL       = 3
+	SERVICE_TRIGGER_DATA_TYPE_KEYWORD_ANY = 4
+	SERVICE_TRIGGER_DATA_TYPE_KEYWORD_ALL = 5
+)
+
+const (
+	SERVICE_LAUNCH_PROTECTED_NONE              = 0
+	SERVICE_LAUNCH_PROTECTED_WINDOWS           = 1
+	SERVICE_LAUNCH_PROTECTED_WINDOWS_LIGHT     = 2
+	SERVICE_LAUNCH_PROTECTED_ANTIMALWARE_LIGHT = 3
+)
+
+const (
+	SERVICE_CONTROL_STATUS_REASON_INFO = 1
+)
+
+// stop reason flags, major and minor reasons used with ControlServiceEx
+const (
+	SERVICE_STOP_REASON_FLAG_UNPLANNED = 0x10000000
+	SERVICE_STOP_REASON_FLAG_CUSTOM    = 0x20000000
+	SERVICE_STOP_REASON_FLAG_PLANNED   = 0x40000000
+
+	SERVICE_STOP_REASON_MAJOR_OTHER           = 0x00010000
+	SERVICE_STOP_REASON_MAJOR_HARDWARE        = 0x00020000
+	SERVICE_STOP_REASON_MAJOR_OPERATINGSYSTEM = 0x00030000
+	SERVICE_STOP_REASON_MAJOR_SOFTWARE        = 0x00040000
+	SERVICE_STOP_REASON_MAJOR_APPLICATION     = 0x00050000
+	SERVICE_STOP_REASON_MAJOR_NONE            = 0x00060000
+
+	SERVICE_STOP_REASON_MINOR_OTHER                     = 0x00000001
+	SERVICE_STOP_REASON_MINOR_MAINTENANCE               = 0x00000002
+	SERVICE_STOP_REASON_MINOR_INSTALLATION              = 0x00000003
+	SERVICE_STOP_REASON_MINOR_UPGRADE                   = 0x00000004
+	SERVICE_STOP_REASON_MINOR_RECONFIG                  = 0x00000005
+	SERVICE_STOP_REASON_MINOR_HUNG                      = 0x00000006
+	SERVICE_STOP_REASON_MINOR_UNSTABLE                  = 0x00000007
+	SERVICE_STOP_REASON_MINOR_DISK                      = 0x00000008
+	SERVICE_STOP_REASON_MINOR_NETWORKCARD               = 0x00000009
+	SERVICE_STOP_REASON_MINOR_ENVIRONMENT               = 0x0000000a
+	SERVICE_STOP_REASON_MINOR_HARDWARE_DRIVER           = 0x0000000b
+	SERVICE_STOP_REASON_MINOR_OTHERDRIVER               = 0x0000000c
+	SERVICE_STOP_REASON_MINOR_SERVICEPACK               = 0x0000000d
+	SERVICE_STOP_REASON_MINOR_SOFTWARE_UPDATE           = 0x0000000e
+	SERVICE_STOP_REASON_MINOR_SECURITYFIX               = 0x0000000f
+	SERVICE_STOP_REASON_MINOR_SECURITY                  = 0x00000010
+	SERVICE_STOP_REASON_MINOR_NETWORK_CONNECTIVITY      = 0x00000011
+	SERVICE_STOP_REASON_MINOR_WMI                       = 0x00000012
+	SERVICE_STOP_REASON_MINOR_SERVICEPACK_UNINSTALL     = 0x00000013
+	SERVICE_STOP_REASON_MINOR_SOFTWARE_UPDATE_UNINSTALL = 0x00000014
+	SERVICE_STOP_REASON_MINOR_SECURITYFIX_UNINSTALL     = 0x00000015
+	SERVICE_STOP_REASON_MINOR_MMC                       = 0x00000016
+	SERVICE_STOP_REASON_MINOR_NONE                      = 0x00000017
 )
 
 const (
@@ -133,6 +237,60 @@ type SERVICE_STATUS struct {
 	WaitHint                uint32
 }
 
+type ENUM_SERVICE_STATUS struct {
+	ServiceName   *uint16
+	DisplayName   *uint16
+	ServiceStatus SERVICE_STATUS
+}
+
+type ENUM_SERVICE_STATUS_PROCESS struct {
+	ServiceName          *uint16
+	DisplayName          *uint16
+	ServiceStatusProcess SERVICE_STATUS_PROCESS
+}
+
+type QUERY_SERVICE_LOCK_STATUS struct {
+	IsLocked     uint32
+	LockOwner    *uint16
+	LockDuration uint32
+}
+
+// QueryServiceDynamicInformation info levels
+const (
+	SERVICE_DYNAMIC_INFORMATION_LEVEL_START_REASON = 1
+)
+
+// SERVICE_START_REASON flags
+const (
+	SERVICE_START_REASON_DEMAND             = 0x00000001
+	SERVICE_START_REASON_AUTO               = 0x00000002
+	SERVICE_START_REASON_TRIGGER            = 0x00000004
+	SERVICE_START_REASON_RESTART_ON_FAILURE = 0x00000008
+	SERVICE_START_REASON_DELAYEDAUTO        = 0x00000010
+)
+
+type SERVICE_START_REASON struct {
+	Reason uint32
+}
+
+type SERVICE_STATUS_PROCESS struct {
+	ServiceType             uint32
+	CurrentState            uint32
+	ControlsAccepted        uint32
+	Win32ExitCode           uint32
+	ServiceSpecificExitCode uint32
+	CheckPoint              uint32
+	WaitHint                uint32
+	ProcessId               uint32
+	ServiceFlags            uint32
+}
+
+type SERVICE_CONTROL_STATUS_REASON_PARAMS struct {
+	Reason        uint32
+	Comment       *uint16
+	ServiceStatus SERVICE_STATUS_PROCESS
+}
+
 type SERVICE_TABLE_ENTRY struct {
 	ServiceName *uint16
 	ServiceProc uintptr
@@ -154,16 +312,97 @@ type SERVICE_DESCRIPTION struct {
 	Description *uint16
 }
 
+type SC_ACTION struct {
+	Type  uint32
+	Delay uint32
+}
+
+type SERVICE_FAILURE_ACTIONS struct {
+	ResetPeriod  uint32
+	RebootMsg    *uint16
+	Command      *uint16
+	ActionsCount uint32
+	Actions      *SC_ACTION
+}
+
+type SERVICE_FAILURE_ACTIONS_FLAG struct {
+	FailureActionsOnNonCrashFailures int32
+}
+
+type SERVICE_DELAYED_AUTO_START_INFO struct {
+	IsDelayedAutoStartUp uint32
+}
+
+type SERVICE_SID_INFO struct {
+	ServiceSidType uint32
+}
+
+type SERVICE_REQUIRED_PRIVILEGES_INFO struct {
+	RequiredPrivileges *uint16 // double null terminated list of privilege names
+}
+
+type SERVICE_PRESHUTDOWN_INFO struct {
+	PreshutdownTimeout uint32 // in milliseconds
+}
+
+type GUID struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
+
+type SERVICE_TRIGGER_SPECIFIC_DATA_ITEM struct {
+	DataType uint32
+	DataSize uint32
+	Data     *byte
+}
+
+type SERVICE_TRIGGER struct {
+	TriggerType    uint32
+	Action         uint32
+	TriggerSubtype *GUID
+	DataItemsCount uint32
+	DataItems      *SERVICE_TRIGGER_SPECIFIC_DATA_ITEM
+}
+
+type SERVICE_TRIGGER_INFO struct {
+	TriggersCount uint32
+	Triggers      *SERVICE_TRIGGER
+	Reserved      *byte
+}
+
+type SERVICE_PREFERRED_NODE_INFO struct {
+	PreferredNode uint16
+	Delete        bool // BOOLEAN, a single byte
+}
+
+type SERVICE_LAUNCH_PROTECTED_INFO struct {
+	LaunchProtected uint32
+}
+
 //sys	CloseServiceHandle(handle syscall.Handle) (err error) = advapi32.CloseServiceHandle
 //sys	CreateService(mgr syscall.Handle, serviceName *uint16, displayName *uint16, access uint32, srvType uint32, startType uint32, errCtl uint32, pathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16) (handle syscall.Handle, err error) [failretval==0] = advapi32.CreateServiceW
 //sys	OpenService(mgr syscall.Handle, serviceName *uint16, access uint32) (handle syscall.Handle, err error) [failretval==0] = advapi32.OpenServiceW
 //sys	DeleteService(service syscall.Handle) (err error) = advapi32.DeleteService
 //sys	StartService(service syscall.Handle, numArgs uint32, argVectors **uint16) (err error) = advapi32.StartServiceW
 //sys	QueryServiceStatus(service syscall.Handle, status *SERVICE_STATUS) (err error) = advapi32.QueryServiceStatus
+//sys	QueryServiceStatusEx(service syscall.Handle, infoLevel uint32, buff *byte, buffSize uint32, bytesNeeded *uint32) (err error) = advapi32.QueryServiceStatusEx
 //sys	ControlService(service syscall.Handle, control uint32, status *SERVICE_STATUS) (err error) = advapi32.ControlService
+//sys	ControlServiceEx(service syscall.Handle, control uint32, infoLevel uint32, params *byte) (err error) = advapi32.ControlServiceExW
 //sys	StartServiceCtrlDispatcher(serviceTable *SERVICE_TABLE_ENTRY) (err error) = advapi32.StartServiceCtrlDispatcherW
 //sys	SetServiceStatus(service syscall.Handle, serviceStatus *SERVICE_STATUS) (err error) = advapi32.SetServiceStatus
+//sys	RegisterServiceCtrlHandlerEx(serviceName *uint16, handlerProc uintptr, context uintptr) (handle syscall.Handle, err error) = advapi32.RegisterServiceCtrlHandlerExW
 //sys	ChangeServiceConfig(service syscall.Handle, serviceType uint32, startType uint32, errorControl uint32, binaryPathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16, displayName *uint16) (err error) = advapi32.ChangeServiceConfigW
 //sys	QueryServiceConfig(service syscall.Handle, serviceConfig *QUERY_SERVICE_CONFIG, bufSize uint32, bytesNeeded *uint32) (err error) = advapi32.QueryServiceConfigW
 //sys	ChangeServiceConfig2(service syscall.Handle, infoLevel uint32, info *byte) (err error) = advapi32.ChangeServiceConfig2W
 //sys	QueryServiceConfig2(service syscall.Handle, infoLevel uint32, buff *byte, buffSize uint32, bytesNeeded *uint32) (err error) = advapi32.QueryServiceConfig2W
+//sys	EnumDependentServices(service syscall.Handle, serviceState uint32, services *ENUM_SERVICE_STATUS, bufSize uint32, bytesNeeded *uint32, servicesReturned *uint32) (err error) = advapi32.EnumDependentServicesW
+//sys	EnumServicesStatusEx(mgr syscall.Handle, infoLevel uint32, serviceType uint32, serviceState uint32, services *byte, bufSize uint32, bytesNeeded *uint32, servicesReturned *uint32, resumeHandle *uint32, groupName *uint16) (err error) = advapi32.EnumServicesStatusExW
+//sys	GetServiceDisplayName(mgr syscall.Handle, serviceName *uint16, displayName *uint16, bufSize *uint32) (err error) = advapi32.GetServiceDisplayNameW
+//sys	GetServiceKeyName(mgr syscall.Handle, displayName *uint16, serviceName *uint16, bufSize *uint32) (err error) = advapi32.GetServiceKeyNameW
+//sys	LockServiceDatabase(mgr syscall.Handle) (lock syscall.Handle, err error) = advapi32.LockServiceDatabase
+//sys	UnlockServiceDatabase(lock syscall.Handle) (err error) = advapi32.UnlockServiceDatabase
+//sys	QueryServiceLockStatus(mgr syscall.Handle, lockStatus *QUERY_SERVICE_LOCK_STATUS, bufSize uint32, bytesNeeded *uint32) (err error) = advapi32.QueryServiceLockStatusW
+//sys	NotifyBootConfigStatus(bootAcceptable bool) (err error) = advapi32.NotifyBootConfigStatus
+//sys	QueryServiceDynamicInformation(serviceStatus syscall.Handle, infoLevel uint32, info **byte) (err error) = advapi32.QueryServiceDynamicInformation
@@ -0,0 +1,125 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi
+
+const (
+	// returned by WTSGetActiveConsoleSessionId, if no session
+	// is attached to the console, or if it cannot be loaded
+	NO_ACTIVE_CONSOLE_SESSION = 0xffffffff
+
+	CREATE_NEW_CONSOLE         = 0x00000010
+	CREATE_UNICODE_ENVIRONMENT = 0x00000400
+
+	WTS_CURRENT_SERVER_HANDLE = 0
+)
+
+// WTS_CONNECTSTATE_CLASS
+const (
+	WTSActive = iota
+	WTSConnected
+	WTSConnectQuery
+	WTSShadow
+	WTSDisconnected
+	WTSIdle
+	WTSListen
+	WTSReset
+	WTSDown
+	WTSInit
+)
+
+// WTS_INFO_CLASS
+const (
+	WTSInitialProgram = iota
+	WTSApplicationName
+	WTSWorkingDirectory
+	WTSOEMId
+	WTSSessionId
+	WTSUserName
+	WTSWinStationName
+	WTSDomainName
+	WTSConnectState
+	WTSClientBuildNumber
+	WTSClientName
+	WTSClientDirectory
+	WTSClientProductId
+	WTSClientHardwareId
+	WTSClientAddress
+	WTSClientDisplay
+	WTSClientProtocolType
+	WTSIdleTime
+	WTSLogonTime
+	WTSIncomingBytes
+	WTSOutgoingBytes
+	WTSIncomingFrames
+	WTSOutgoingFrames
+	WTSClientInfo
+	WTSSessionInfo
+)
+
+// session change events delivered with SERVICE_CONTROL_SESSIONCHANGE
+const (
+	WTS_CONSOLE_CONNECT        = 0x1
+	WTS_CONSOLE_DISCONNECT     = 0x2
+	WTS_REMOTE_CONNECT         = 0x3
+	WTS_REMOTE_DISCONNECT      = 0x4
+	WTS_SESSION_LOGON          = 0x5
+	WTS_SESSION_LOGOFF         = 0x6
+	WTS_SESSION_LOCK           = 0x7
+	WTS_SESSION_UNLOCK         = 0x8
+	WTS_SESSION_REMOTE_CONTROL = 0x9
+	WTS_SESSION_CREATE         = 0xa
+	WTS_SESSION_TERMINATE      = 0xb
+)
+
+// WTS_SESSION_NOTIFICATION is passed as event data
+// with SERVICE_CONTROL_SESSIONCHANGE.
+type WTS_SESSION_NOTIFICATION struct {
+	Size      uint32
+	SessionID uint32
+}
+
+type WTS_SESSION_INFO struct {
+	SessionID      uint32
+	WinStationName *uint16
+	State          uint32
+}
+
+// message box styles and responses used by WTSSendMessage
+const (
+	MB_OK                   = 0x00000000
+	MB_OKCANCEL             = 0x00000001
+	MB_ABORTRETRYIGNORE     = 0x00000002
+	MB_YESNOCANCEL          = 0x00000003
+	MB_YESNO                = 0x00000004
+	MB_RETRYCANCEL          = 0x00000005
+	MB_ICONERROR            = 0x00000010
+	MB_ICONQUESTION         = 0x00000020
+	MB_ICONWARNING          = 0x00000030
+	MB_ICONINFORMATION      = 0x00000040
+	MB_SETFOREGROUND        = 0x00010000
+	MB_TOPMOST              = 0x00040000
+	MB_SERVICE_NOTIFICATION = 0x00200000
+
+	IDOK      = 1
+	IDCANCEL  = 2
+	IDABORT   = 3
+	IDRETRY   = 4
+	IDIGNORE  = 5
+	IDYES     = 6
+	IDNO      = 7
+	IDTIMEOUT = 32000
+	IDASYNC   = 32001
+)
+
+//sys	WTSGetActiveConsoleSessionId() (id uint32) [unavailable=NO_ACTIVE_CONSOLE_SESSION] = kernel32.WTSGetActiveConsoleSessionId
+//sys	WTSQueryUserToken(session uint32, token *syscall.Token) (err error) = wtsapi32.WTSQueryUserToken
+//sys	CreateEnvironmentBlock(block **uint16, token syscall.Token, inherit bool) (err error) = userenv.CreateEnvironmentBlock
+//sys	DestroyEnvironmentBlock(block *uint16) (err error) = userenv.DestroyEnvironmentBlock
+//sys	WTSEnumerateSessions(server syscall.Handle, reserved uint32, version uint32, sessions **WTS_SESSION_INFO, count *uint32) (err error) = wtsapi32.WTSEnumerateSessionsW
+//sys	WTSQuerySessionInformation(server syscall.Handle, session uint32, infoClass uint32, buffer **uint16, bytesReturned *uint32) (err error) = wtsapi32.WTSQuerySessionInformationW
+//sys	WTSFreeMemory(p uintptr) = wtsapi32.WTSFreeMemory
+//sys	WTSSendMessage(server syscall.Handle, session uint32, title *uint16, titleLen uint32, message *uint16, messageLen uint32, style uint32, timeout uint32, response *uint32, wait bool) (err error) = wtsapi32.WTSSendMessageW
@@ -0,0 +1,39 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi
+
+import "syscall"
+
+const (
+	SEE_MASK_NOCLOSEPROCESS = 0x00000040
+	SEE_MASK_NOASYNC        = 0x00000100
+
+	SW_SHOWNORMAL = 1
+
+	ERROR_CANCELLED syscall.Errno = 1223
+)
+
+type SHELLEXECUTEINFO struct {
+	Size       uint32
+	Mask       uint32
+	Hwnd       syscall.Handle
+	Verb       *uint16
+	File       *uint16
+	Parameters *uint16
+	Directory  *uint16
+	Show       int32
+	InstApp    syscall.Handle
+	IDList     uintptr
+	Class      *uint16
+	KeyClass   syscall.Handle
+	HotKey     uint32
+	Icon       syscall.Handle
+	Process    syscall.Handle
+}
+
+//sys	SHLoadIndirectString(source *uint16, buf *uint16, bufLen uint32, reserved uintptr) (hr error) = shlwapi.SHLoadIndirectString
+//sys	ShellExecuteEx(info *SHELLEXECUTEINFO) (err error) = shell32.ShellExecuteExW
@@ -9,9 +9,43 @@ package winapi
 import "syscall"
 
 const (
+	DELETE                   = 0x00010000
+	READ_CONTROL             = 0x00020000
+	WRITE_DAC                = 0x00040000
+	WRITE_OWNER              = 0x00080000
 	STANDARD_RIGHTS_REQUIRED = 0xf0000
 
 	ERROR_SERVICE_SPECIFIC_ERROR syscall.Errno = 1066
 )
 
+// service control manager errors
+const (
+	ERROR_INVALID_SERVICE_CONTROL           syscall.Errno = 1052
+	ERROR_SERVICE_REQUEST_TIMEOUT           syscall.Errno = 1053
+	ERROR_SERVICE_DATABASE_LOCKED           syscall.Errno = 1055
+	ERROR_SERVICE_ALREADY_RUNNING           syscall.Errno = 1056
+	ERROR_INVALID_SERVICE_ACCOUNT           syscall.Errno = 1057
+	ERROR_SERVICE_DISABLED                  syscall.Errno = 1058
+	ERROR_CIRCULAR_DEPENDENCY               syscall.Errno = 1059
+	ERROR_SERVICE_DOES_NOT_EXIST            syscall.Errno = 1060
+	ERROR_SERVICE_CANNOT_ACCEPT_CTRL        syscall.Errno = 1061
+	ERROR_SERVICE_NOT_ACTIVE                syscall.Errno = 1062
+	ERROR_FAILED_SERVICE_CONTROLLER_CONNECT syscall.Errno = 1063
+	ERROR_SERVICE_DEPENDENCY_FAIL           syscall.Errno = 1068
+	ERROR_SERVICE_LOGON_FAILED              syscall.Errno = 1069
+	ERROR_SERVICE_START_HANG                syscall.Errno = 1070
+	ERROR_SERVICE_MARKED_FOR_DELETE         syscall.Errno = 1072
+	ERROR_SERVICE_EXISTS                    syscall.Errno = 1073
+	ERROR_SERVICE_DEPENDENCY_DELETED        syscall.Errno = 1075
+	ERROR_SERVICE_NEVER_STARTED             syscall.Errno = 1077
+	ERROR_DUPLICATE_SERVICE_NAME            syscall.Errno = 1078
+)
+
+// RPC errors returned when talking to remote service control manager
+const (
+	RPC_S_SERVER_UNAVAILABLE syscall.Errno = 1722
+	RPC_S_SERVER_TOO_BUSY    syscall.Errno = 1723
+	RPC_S_CALL_FAILED        syscall.Errno = 1726
+)
+
 //sys	GetCurrentThreadId() (id uint32)
@@ -0,0 +1,27 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi
+
+// Task Scheduler 2.0 COM classes and interfaces.
+var (
+	CLSID_TaskScheduler = GUID{0x0f87369f, 0xa4e5, 0x4cfc, [8]byte{0xbd, 0x3e, 0x73, 0xe6, 0x15, 0x45, 0x72, 0xdd}}
+	IID_ITaskService    = GUID{0x2faba4c7, 0x4da9, 0x4013, [8]byte{0x96, 0x97, 0x20, 0xcc, 0x3f, 0xd4, 0x0f, 0x85}}
+)
+
+const (
+	TASK_CREATE_OR_UPDATE = 0x6
+
+	TASK_LOGON_PASSWORD        = 1
+	TASK_LOGON_S4U             = 2
+	TASK_LOGON_SERVICE_ACCOUNT = 5
+
+	TASK_STATE_UNKNOWN  = 0
+	TASK_STATE_DISABLED = 1
+	TASK_STATE_QUEUED   = 2
+	TASK_STATE_READY    = 3
+	TASK_STATE_RUNNING  = 4
+)
@@ -0,0 +1,113 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi
+
+import (
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+)
+
+// UTF16PtrToString returns string stored at p, which must be
+// null terminated, or hold at most max characters. Unlike
+// casting p to a large array, it never touches memory past
+// the terminating 0 or past max characters. It returns empty
+// string, if p is nil.
+func UTF16PtrToString(p *uint16, max int) string {
+	if p == nil {
+		return ""
+	}
+	n := 0
+	for n < max && *(*uint16)(unsafe.Add(unsafe.Pointer(p), 2*n)) != 0 {
+		n++
+	}
+	return string(utf16.Decode(unsafe.Slice(p, n)))
+}
+
+// UTF16Slice returns n characters stored at p as a slice.
+// The slice shares memory with p.
+func UTF16Slice(p *uint16, n int) []uint16 {
+	if p == nil || n <= 0 {
+		return nil
+	}
+	return unsafe.Slice(p, n)
+}
+
+// UTF16FromStrings returns double null terminated UTF-16 list
+// (also known as REG_MULTI_SZ or string block) of strings ss.
+// Empty strings are skipped, because they would terminate the list.
+// It returns nil, if there is nothing to encode. It fails, if any
+// of ss contains a NUL character.
+func UTF16FromStrings(ss []string) ([]uint16, error) {
+	var b []uint16
+	for _, s := range ss {
+		if s == "" {
+			continue
+		}
+		for i := 0; i < len(s); i++ {
+			if s[i] == 0 {
+				return nil, syscall.EINVAL
+			}
+		}
+		b = append(b, utf16.Encode([]rune(s))...)
+		b = append(b, 0)
+	}
+	if b == nil {
+		return nil, nil
+	}
+	return append(b, 0), nil
+}
+
+// UTF16PtrFromStrings is like UTF16FromStrings, but returns pointer
+// to the list, suitable to be passed to CreateService and others.
+// It returns nil pointer, if there is nothing to encode.
+func UTF16PtrFromStrings(ss []string) (*uint16, error) {
+	b, err := UTF16FromStrings(ss)
+	if err != nil || b == nil {
+		return nil, err
+	}
+	return &b[0], nil
+}
+
+// UTF16ToStrings decodes double null terminated UTF-16 list b.
+// Unlike UTF16PtrToStrings, it never reads past the end of b,
+// even if b is not terminated properly.
+func UTF16ToStrings(b []uint16) []string {
+	var ss []string
+	from := 0
+	for i, c := range b {
+		if c != 0 {
+			continue
+		}
+		if i == from {
+			// empty string marks the end
+			break
+		}
+		ss = append(ss, string(utf16.Decode(b[from:i])))
+		from = i + 1
+	}
+	return ss
+}
+
+// UTF16PtrToStrings decodes double null terminated UTF-16 list
+// pointed to by p. It returns nil, if p is nil.
+func UTF16PtrToStrings(p *uint16) []string {
+	if p == nil {
+		return nil
+	}
+	n := 0
+	for {
+		// stop at two consecutive 0s, or at 0 in the first position
+		if *(*uint16)(unsafe.Add(unsafe.Pointer(p), 2*n)) == 0 {
+			if n == 0 || *(*uint16)(unsafe.Add(unsafe.Pointer(p), 2*(n-1))) == 0 {
+				break
+			}
+		}
+		n++
+	}
+	return UTF16ToStrings(unsafe.Slice(p, n+1))
+}
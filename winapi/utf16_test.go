@@ -0,0 +1,48 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winapi_test
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/multiplay/winsvc/winapi"
+)
+
+func FuzzUTF16Strings(f *testing.F) {
+	f.Add([]byte("a\x00b\x00\x00\x00c\x00\x00\x00\x00\x00"))
+	f.Add([]byte("\x00\x00a\x00"))
+	f.Add([]byte{0x3d, 0xd8, 0, 0, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		b := make([]uint16, len(data)/2)
+		for i := range b {
+			b[i] = binary.LittleEndian.Uint16(data[2*i:])
+		}
+		ss := winapi.UTF16ToStrings(b)
+		for _, s := range ss {
+			if s == "" {
+				t.Fatalf("list %q holds empty string", ss)
+			}
+		}
+
+		// list read through pointer must be the same,
+		// once it is terminated properly
+		term := append(append([]uint16(nil), b...), 0, 0)
+		if got, want := winapi.UTF16PtrToStrings(&term[0]), winapi.UTF16ToStrings(term); !reflect.DeepEqual(got, want) {
+			t.Fatalf("UTF16PtrToStrings returned %q, want %q", got, want)
+		}
+
+		enc, err := winapi.UTF16FromStrings(ss)
+		if err != nil {
+			t.Fatalf("UTF16FromStrings(%q) failed: %v", ss, err)
+		}
+		if got := winapi.UTF16ToStrings(enc); !reflect.DeepEqual(got, ss) {
+			t.Fatalf("list %q is decoded as %q", ss, got)
+		}
+	})
+}
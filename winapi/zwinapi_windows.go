@@ -1,4 +1,4 @@
-// go build mksyscall_windows.go && ./mksyscall_windows event.go eventlog.go registry.go security.go service.go syscall.go
+// go run mksyscall_windows.go -output zwinapi_windows.go cert.go com.go credential.go crypt.go desktop.go device.go event.go eventlog.go file.go lsa.go notify.go power.go process.go registry.go security.go service.go session.go shell.go syscall.go
 // MACHINE GENERATED BY THE COMMAND ABOVE; DO NOT EDIT
 
 package winapi
@@ -7,325 +7,1499 @@ import "unsafe"
 import "syscall"
 
 var (
-	modkernel32 = syscall.NewLazyDLL("kernel32.dll")
-	modadvapi32 = syscall.NewLazyDLL("advapi32.dll")
-
-	procCreateEventW                = modkernel32.NewProc("CreateEventW")
-	procSetEvent                    = modkernel32.NewProc("SetEvent")
-	procRegisterEventSourceW        = modadvapi32.NewProc("RegisterEventSourceW")
-	procDeregisterEventSource       = modadvapi32.NewProc("DeregisterEventSource")
-	procReportEventW                = modadvapi32.NewProc("ReportEventW")
-	procRegCreateKeyExW             = modadvapi32.NewProc("RegCreateKeyExW")
-	procRegDeleteKeyW               = modadvapi32.NewProc("RegDeleteKeyW")
-	procRegSetValueExW              = modadvapi32.NewProc("RegSetValueExW")
-	procAllocateAndInitializeSid    = modadvapi32.NewProc("AllocateAndInitializeSid")
-	procFreeSid                     = modadvapi32.NewProc("FreeSid")
-	procEqualSid                    = modadvapi32.NewProc("EqualSid")
-	procOpenSCManagerW              = modadvapi32.NewProc("OpenSCManagerW")
-	procCloseServiceHandle          = modadvapi32.NewProc("CloseServiceHandle")
-	procCreateServiceW              = modadvapi32.NewProc("CreateServiceW")
-	procOpenServiceW                = modadvapi32.NewProc("OpenServiceW")
-	procDeleteService               = modadvapi32.NewProc("DeleteService")
-	procStartServiceW               = modadvapi32.NewProc("StartServiceW")
-	procQueryServiceStatus          = modadvapi32.NewProc("QueryServiceStatus")
-	procControlService              = modadvapi32.NewProc("ControlService")
-	procStartServiceCtrlDispatcherW = modadvapi32.NewProc("StartServiceCtrlDispatcherW")
-	procSetServiceStatus            = modadvapi32.NewProc("SetServiceStatus")
-	procChangeServiceConfigW        = modadvapi32.NewProc("ChangeServiceConfigW")
-	procQueryServiceConfigW         = modadvapi32.NewProc("QueryServiceConfigW")
-	procChangeServiceConfig2W       = modadvapi32.NewProc("ChangeServiceConfig2W")
-	procQueryServiceConfig2W        = modadvapi32.NewProc("QueryServiceConfig2W")
-	procGetCurrentThreadId          = modkernel32.NewProc("GetCurrentThreadId")
+	modcrypt32  = NewSystemDLL("crypt32.dll")
+	modncrypt   = NewSystemDLL("ncrypt.dll")
+	modole32    = NewSystemDLL("ole32.dll")
+	modoleaut32 = NewSystemDLL("oleaut32.dll")
+	modadvapi32 = NewSystemDLL("advapi32.dll")
+	moduser32   = NewSystemDLL("user32.dll")
+	modkernel32 = NewSystemDLL("kernel32.dll")
+	modsecur32  = NewSystemDLL("secur32.dll")
+	modsechost  = NewSystemDLL("sechost.dll")
+	modrpcrt4   = NewSystemDLL("rpcrt4.dll")
+	modwtsapi32 = NewSystemDLL("wtsapi32.dll")
+	moduserenv  = NewSystemDLL("userenv.dll")
+	modshlwapi  = NewSystemDLL("shlwapi.dll")
+	modshell32  = NewSystemDLL("shell32.dll")
+
+	procCertFindCertificateInStore                           = modcrypt32.NewProc("CertFindCertificateInStore")
+	procCertDuplicateCertificateContext                      = modcrypt32.NewProc("CertDuplicateCertificateContext")
+	procCryptAcquireCertificatePrivateKey                    = modcrypt32.NewProc("CryptAcquireCertificatePrivateKey")
+	procNCryptSignHash                                       = modncrypt.NewProc("NCryptSignHash")
+	procNCryptGetProperty                                    = modncrypt.NewProc("NCryptGetProperty")
+	procNCryptFreeObject                                     = modncrypt.NewProc("NCryptFreeObject")
+	procCoInitializeEx                                       = modole32.NewProc("CoInitializeEx")
+	procCoUninitialize                                       = modole32.NewProc("CoUninitialize")
+	procCoCreateInstance                                     = modole32.NewProc("CoCreateInstance")
+	procSysAllocString                                       = modoleaut32.NewProc("SysAllocString")
+	procSysFreeString                                        = modoleaut32.NewProc("SysFreeString")
+	procCredReadW                                            = modadvapi32.NewProc("CredReadW")
+	procCredWriteW                                           = modadvapi32.NewProc("CredWriteW")
+	procCredDeleteW                                          = modadvapi32.NewProc("CredDeleteW")
+	procCredFree                                             = modadvapi32.NewProc("CredFree")
+	procCryptProtectData                                     = modcrypt32.NewProc("CryptProtectData")
+	procCryptUnprotectData                                   = modcrypt32.NewProc("CryptUnprotectData")
+	procGetProcessWindowStation                              = moduser32.NewProc("GetProcessWindowStation")
+	procGetThreadDesktop                                     = moduser32.NewProc("GetThreadDesktop")
+	procGetUserObjectInformationW                            = moduser32.NewProc("GetUserObjectInformationW")
+	procProcessIdToSessionId                                 = modkernel32.NewProc("ProcessIdToSessionId")
+	procRegisterDeviceNotificationW                          = moduser32.NewProc("RegisterDeviceNotificationW")
+	procUnregisterDeviceNotification                         = moduser32.NewProc("UnregisterDeviceNotification")
+	procCreateEventW                                         = modkernel32.NewProc("CreateEventW")
+	procSetEvent                                             = modkernel32.NewProc("SetEvent")
+	procRegisterEventSourceW                                 = modadvapi32.NewProc("RegisterEventSourceW")
+	procDeregisterEventSource                                = modadvapi32.NewProc("DeregisterEventSource")
+	procReportEventW                                         = modadvapi32.NewProc("ReportEventW")
+	procOpenEventLogW                                        = modadvapi32.NewProc("OpenEventLogW")
+	procCloseEventLog                                        = modadvapi32.NewProc("CloseEventLog")
+	procReadEventLogW                                        = modadvapi32.NewProc("ReadEventLogW")
+	procGetNumberOfEventLogRecords                           = modadvapi32.NewProc("GetNumberOfEventLogRecords")
+	procGetOldestEventLogRecord                              = modadvapi32.NewProc("GetOldestEventLogRecord")
+	procClearEventLogW                                       = modadvapi32.NewProc("ClearEventLogW")
+	procBackupEventLogW                                      = modadvapi32.NewProc("BackupEventLogW")
+	procMoveFileExW                                          = modkernel32.NewProc("MoveFileExW")
+	procLsaOpenPolicy                                        = modadvapi32.NewProc("LsaOpenPolicy")
+	procLsaClose                                             = modadvapi32.NewProc("LsaClose")
+	procLsaFreeMemory                                        = modadvapi32.NewProc("LsaFreeMemory")
+	procLsaStorePrivateData                                  = modadvapi32.NewProc("LsaStorePrivateData")
+	procLsaRetrievePrivateData                               = modadvapi32.NewProc("LsaRetrievePrivateData")
+	procLsaAddAccountRights                                  = modadvapi32.NewProc("LsaAddAccountRights")
+	procLsaRemoveAccountRights                               = modadvapi32.NewProc("LsaRemoveAccountRights")
+	procLsaNtStatusToWinError                                = modadvapi32.NewProc("LsaNtStatusToWinError")
+	procLsaGetLogonSessionData                               = modsecur32.NewProc("LsaGetLogonSessionData")
+	procLsaFreeReturnBuffer                                  = modsecur32.NewProc("LsaFreeReturnBuffer")
+	procNotifyServiceStatusChangeW                           = modadvapi32.NewProc("NotifyServiceStatusChangeW")
+	procSleepEx                                              = modkernel32.NewProc("SleepEx")
+	procWaitForSingleObjectEx                                = modkernel32.NewProc("WaitForSingleObjectEx")
+	procSubscribeServiceChangeNotifications                  = modsechost.NewProc("SubscribeServiceChangeNotifications")
+	procUnsubscribeServiceChangeNotifications                = modsechost.NewProc("UnsubscribeServiceChangeNotifications")
+	procRegisterPowerSettingNotification                     = moduser32.NewProc("RegisterPowerSettingNotification")
+	procUnregisterPowerSettingNotification                   = moduser32.NewProc("UnregisterPowerSettingNotification")
+	procSetProcessMitigationPolicy                           = modkernel32.NewProc("SetProcessMitigationPolicy")
+	procGetProcessMitigationPolicy                           = modkernel32.NewProc("GetProcessMitigationPolicy")
+	procRegCreateKeyExW                                      = modadvapi32.NewProc("RegCreateKeyExW")
+	procRegDeleteKeyW                                        = modadvapi32.NewProc("RegDeleteKeyW")
+	procRegDeleteTreeW                                       = modadvapi32.NewProc("RegDeleteTreeW")
+	procRegSetValueExW                                       = modadvapi32.NewProc("RegSetValueExW")
+	procRegDeleteValueW                                      = modadvapi32.NewProc("RegDeleteValueW")
+	procAllocateAndInitializeSid                             = modadvapi32.NewProc("AllocateAndInitializeSid")
+	procFreeSid                                              = modadvapi32.NewProc("FreeSid")
+	procEqualSid                                             = modadvapi32.NewProc("EqualSid")
+	procImpersonateNamedPipeClient                           = modadvapi32.NewProc("ImpersonateNamedPipeClient")
+	procImpersonateLoggedOnUser                              = modadvapi32.NewProc("ImpersonateLoggedOnUser")
+	procRevertToSelf                                         = modadvapi32.NewProc("RevertToSelf")
+	procRpcImpersonateClient                                 = modrpcrt4.NewProc("RpcImpersonateClient")
+	procRpcRevertToSelfEx                                    = modrpcrt4.NewProc("RpcRevertToSelfEx")
+	procQueryServiceObjectSecurity                           = modadvapi32.NewProc("QueryServiceObjectSecurity")
+	procSetServiceObjectSecurity                             = modadvapi32.NewProc("SetServiceObjectSecurity")
+	procConvertSecurityDescriptorToStringSecurityDescriptorW = modadvapi32.NewProc("ConvertSecurityDescriptorToStringSecurityDescriptorW")
+	procLookupPrivilegeValueW                                = modadvapi32.NewProc("LookupPrivilegeValueW")
+	procAdjustTokenPrivileges                                = modadvapi32.NewProc("AdjustTokenPrivileges")
+	procConvertStringSecurityDescriptorToSecurityDescriptorW = modadvapi32.NewProc("ConvertStringSecurityDescriptorToSecurityDescriptorW")
+	procGetSecurityDescriptorOwner                           = modadvapi32.NewProc("GetSecurityDescriptorOwner")
+	procGetSecurityDescriptorGroup                           = modadvapi32.NewProc("GetSecurityDescriptorGroup")
+	procGetSecurityDescriptorDacl                            = modadvapi32.NewProc("GetSecurityDescriptorDacl")
+	procOpenSCManagerW                                       = modadvapi32.NewProc("OpenSCManagerW")
+	procCloseServiceHandle                                   = modadvapi32.NewProc("CloseServiceHandle")
+	procCreateServiceW                                       = modadvapi32.NewProc("CreateServiceW")
+	procOpenServiceW                                         = modadvapi32.NewProc("OpenServiceW")
+	procDeleteService                                        = modadvapi32.NewProc("DeleteService")
+	procStartServiceW                                        = modadvapi32.NewProc("StartServiceW")
+	procQueryServiceStatus                                   = modadvapi32.NewProc("QueryServiceStatus")
+	procQueryServiceStatusEx                                 = modadvapi32.NewProc("QueryServiceStatusEx")
+	procControlService                                       = modadvapi32.NewProc("ControlService")
+	procControlServiceExW                                    = modadvapi32.NewProc("ControlServiceExW")
+	procStartServiceCtrlDispatcherW                          = modadvapi32.NewProc("StartServiceCtrlDispatcherW")
+	procSetServiceStatus                                     = modadvapi32.NewProc("SetServiceStatus")
+	procRegisterServiceCtrlHandlerExW                        = modadvapi32.NewProc("RegisterServiceCtrlHandlerExW")
+	procChangeServiceConfigW                                 = modadvapi32.NewProc("ChangeServiceConfigW")
+	procQueryServiceConfigW                                  = modadvapi32.NewProc("QueryServiceConfigW")
+	procChangeServiceConfig2W                                = modadvapi32.NewProc("ChangeServiceConfig2W")
+	procQueryServiceConfig2W                                 = modadvapi32.NewProc("QueryServiceConfig2W")
+	procEnumDependentServicesW                               = modadvapi32.NewProc("EnumDependentServicesW")
+	procEnumServicesStatusExW                                = modadvapi32.NewProc("EnumServicesStatusExW")
+	procGetServiceDisplayNameW                               = modadvapi32.NewProc("GetServiceDisplayNameW")
+	procGetServiceKeyNameW                                   = modadvapi32.NewProc("GetServiceKeyNameW")
+	procLockServiceDatabase                                  = modadvapi32.NewProc("LockServiceDatabase")
+	procUnlockServiceDatabase                                = modadvapi32.NewProc("UnlockServiceDatabase")
+	procQueryServiceLockStatusW                              = modadvapi32.NewProc("QueryServiceLockStatusW")
+	procNotifyBootConfigStatus                               = modadvapi32.NewProc("NotifyBootConfigStatus")
+	procQueryServiceDynamicInformation                       = modadvapi32.NewProc("QueryServiceDynamicInformation")
+	procWTSGetActiveConsoleSessionId                         = modkernel32.NewProc("WTSGetActiveConsoleSessionId")
+	procWTSQueryUserToken                                    = modwtsapi32.NewProc("WTSQueryUserToken")
+	procCreateEnvironmentBlock                               = moduserenv.NewProc("CreateEnvironmentBlock")
+	procDestroyEnvironmentBlock                              = moduserenv.NewProc("DestroyEnvironmentBlock")
+	procWTSEnumerateSessionsW                                = modwtsapi32.NewProc("WTSEnumerateSessionsW")
+	procWTSQuerySessionInformationW                          = modwtsapi32.NewProc("WTSQuerySessionInformationW")
+	procWTSFreeMemory                                        = modwtsapi32.NewProc("WTSFreeMemory")
+	procWTSSendMessageW                                      = modwtsapi32.NewProc("WTSSendMessageW")
+	procSHLoadIndirectString                                 = modshlwapi.NewProc("SHLoadIndirectString")
+	procShellExecuteExW                                      = modshell32.NewProc("ShellExecuteExW")
+	procGetCurrentThreadId                                   = modkernel32.NewProc("GetCurrentThreadId")
 )
 
+func CertFindCertificateInStore(store syscall.Handle, encoding uint32, findFlags uint32, findType uint32, para uintptr, prev *syscall.CertContext) (cert *syscall.CertContext, err error) {
+	if e := procCertFindCertificateInStore.Find(); e != nil {
+		err = unavailable("CertFindCertificateInStore", e)
+		return
+	}
+	r0, _, e1 := syscall.Syscall6(procCertFindCertificateInStore.Addr(), 6, uintptr(store), uintptr(encoding), uintptr(findFlags), uintptr(findType), uintptr(para), uintptr(unsafe.Pointer(prev)))
+	cert = (*syscall.CertContext)(*(*unsafe.Pointer)(unsafe.Pointer(&r0)))
+	if cert == nil {
+		err = errnoErr("CertFindCertificateInStore", e1)
+	}
+	return
+}
+
+func CertDuplicateCertificateContext(cert *syscall.CertContext) (dup *syscall.CertContext) {
+	if procCertDuplicateCertificateContext.Find() != nil {
+		return
+	}
+	r0, _, _ := syscall.Syscall(procCertDuplicateCertificateContext.Addr(), 1, uintptr(unsafe.Pointer(cert)), 0, 0)
+	dup = (*syscall.CertContext)(*(*unsafe.Pointer)(unsafe.Pointer(&r0)))
+	return
+}
+
+func CryptAcquireCertificatePrivateKey(cert *syscall.CertContext, flags uint32, para uintptr, key *uintptr, keySpec *uint32, callerFree *uint32) (err error) {
+	if e := procCryptAcquireCertificatePrivateKey.Find(); e != nil {
+		err = unavailable("CryptAcquireCertificatePrivateKey", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall6(procCryptAcquireCertificatePrivateKey.Addr(), 6, uintptr(unsafe.Pointer(cert)), uintptr(flags), uintptr(para), uintptr(unsafe.Pointer(key)), uintptr(unsafe.Pointer(keySpec)), uintptr(unsafe.Pointer(callerFree)))
+	if r1 == 0 {
+		err = errnoErr("CryptAcquireCertificatePrivateKey", e1)
+	}
+	return
+}
+
+func NCryptSignHash(key uintptr, padding unsafe.Pointer, hash *byte, hashLen uint32, sig *byte, sigLen uint32, result *uint32, flags uint32) (status error) {
+	if e := procNCryptSignHash.Find(); e != nil {
+		status = unavailable("NCryptSignHash", e)
+		return
+	}
+	r0, _, _ := syscall.Syscall9(procNCryptSignHash.Addr(), 8, uintptr(key), uintptr(padding), uintptr(unsafe.Pointer(hash)), uintptr(hashLen), uintptr(unsafe.Pointer(sig)), uintptr(sigLen), uintptr(unsafe.Pointer(result)), uintptr(flags), 0)
+	if r0 != 0 {
+		status = errnoErr("NCryptSignHash", syscall.Errno(r0))
+	}
+	return
+}
+
+func NCryptGetProperty(object uintptr, property *uint16, buf *byte, bufLen uint32, result *uint32, flags uint32) (status error) {
+	if e := procNCryptGetProperty.Find(); e != nil {
+		status = unavailable("NCryptGetProperty", e)
+		return
+	}
+	r0, _, _ := syscall.Syscall6(procNCryptGetProperty.Addr(), 6, uintptr(object), uintptr(unsafe.Pointer(property)), uintptr(unsafe.Pointer(buf)), uintptr(bufLen), uintptr(unsafe.Pointer(result)), uintptr(flags))
+	if r0 != 0 {
+		status = errnoErr("NCryptGetProperty", syscall.Errno(r0))
+	}
+	return
+}
+
+func NCryptFreeObject(object uintptr) (status error) {
+	if e := procNCryptFreeObject.Find(); e != nil {
+		status = unavailable("NCryptFreeObject", e)
+		return
+	}
+	r0, _, _ := syscall.Syscall(procNCryptFreeObject.Addr(), 1, uintptr(object), 0, 0)
+	if r0 != 0 {
+		status = errnoErr("NCryptFreeObject", syscall.Errno(r0))
+	}
+	return
+}
+
+func CoInitializeEx(reserved uintptr, coInit uint32) (hr error) {
+	if e := procCoInitializeEx.Find(); e != nil {
+		hr = unavailable("CoInitializeEx", e)
+		return
+	}
+	r0, _, _ := syscall.Syscall(procCoInitializeEx.Addr(), 2, uintptr(reserved), uintptr(coInit), 0)
+	if r0&0x80000000 != 0 {
+		hr = errnoErr("CoInitializeEx", syscall.Errno(r0))
+	}
+	return
+}
+
+func CoUninitialize() {
+	if procCoUninitialize.Find() != nil {
+		return
+	}
+	syscall.Syscall(procCoUninitialize.Addr(), 0, 0, 0, 0)
+	return
+}
+
+func CoCreateInstance(clsid *GUID, outer uintptr, clsContext uint32, iid *GUID, obj *unsafe.Pointer) (hr error) {
+	if e := procCoCreateInstance.Find(); e != nil {
+		hr = unavailable("CoCreateInstance", e)
+		return
+	}
+	r0, _, _ := syscall.Syscall6(procCoCreateInstance.Addr(), 5, uintptr(unsafe.Pointer(clsid)), uintptr(outer), uintptr(clsContext), uintptr(unsafe.Pointer(iid)), uintptr(unsafe.Pointer(obj)), 0)
+	if r0&0x80000000 != 0 {
+		hr = errnoErr("CoCreateInstance", syscall.Errno(r0))
+	}
+	return
+}
+
+func SysAllocString(s *uint16) (bstr *uint16) {
+	if procSysAllocString.Find() != nil {
+		return
+	}
+	r0, _, _ := syscall.Syscall(procSysAllocString.Addr(), 1, uintptr(unsafe.Pointer(s)), 0, 0)
+	bstr = (*uint16)(*(*unsafe.Pointer)(unsafe.Pointer(&r0)))
+	return
+}
+
+func SysFreeString(bstr *uint16) {
+	if procSysFreeString.Find() != nil {
+		return
+	}
+	syscall.Syscall(procSysFreeString.Addr(), 1, uintptr(unsafe.Pointer(bstr)), 0, 0)
+	return
+}
+
+func CredRead(target *uint16, ctype uint32, flags uint32, cred **CREDENTIAL) (err error) {
+	if e := procCredReadW.Find(); e != nil {
+		err = unavailable("CredReadW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall6(procCredReadW.Addr(), 4, uintptr(unsafe.Pointer(target)), uintptr(ctype), uintptr(flags), uintptr(unsafe.Pointer(cred)), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("CredReadW", e1)
+	}
+	return
+}
+
+func CredWrite(cred *CREDENTIAL, flags uint32) (err error) {
+	if e := procCredWriteW.Find(); e != nil {
+		err = unavailable("CredWriteW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procCredWriteW.Addr(), 2, uintptr(unsafe.Pointer(cred)), uintptr(flags), 0)
+	if r1 == 0 {
+		err = errnoErr("CredWriteW", e1)
+	}
+	return
+}
+
+func CredDelete(target *uint16, ctype uint32, flags uint32) (err error) {
+	if e := procCredDeleteW.Find(); e != nil {
+		err = unavailable("CredDeleteW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procCredDeleteW.Addr(), 3, uintptr(unsafe.Pointer(target)), uintptr(ctype), uintptr(flags))
+	if r1 == 0 {
+		err = errnoErr("CredDeleteW", e1)
+	}
+	return
+}
+
+func CredFree(buffer uintptr) {
+	if procCredFree.Find() != nil {
+		return
+	}
+	syscall.Syscall(procCredFree.Addr(), 1, uintptr(buffer), 0, 0)
+	return
+}
+
+func CryptProtectData(in *DATA_BLOB, description *uint16, entropy *DATA_BLOB, reserved uintptr, prompt uintptr, flags uint32, out *DATA_BLOB) (err error) {
+	if e := procCryptProtectData.Find(); e != nil {
+		err = unavailable("CryptProtectData", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall9(procCryptProtectData.Addr(), 7, uintptr(unsafe.Pointer(in)), uintptr(unsafe.Pointer(description)), uintptr(unsafe.Pointer(entropy)), uintptr(reserved), uintptr(prompt), uintptr(flags), uintptr(unsafe.Pointer(out)), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("CryptProtectData", e1)
+	}
+	return
+}
+
+func CryptUnprotectData(in *DATA_BLOB, description **uint16, entropy *DATA_BLOB, reserved uintptr, prompt uintptr, flags uint32, out *DATA_BLOB) (err error) {
+	if e := procCryptUnprotectData.Find(); e != nil {
+		err = unavailable("CryptUnprotectData", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall9(procCryptUnprotectData.Addr(), 7, uintptr(unsafe.Pointer(in)), uintptr(unsafe.Pointer(description)), uintptr(unsafe.Pointer(entropy)), uintptr(reserved), uintptr(prompt), uintptr(flags), uintptr(unsafe.Pointer(out)), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("CryptUnprotectData", e1)
+	}
+	return
+}
+
+func GetProcessWindowStation() (h syscall.Handle, err error) {
+	if e := procGetProcessWindowStation.Find(); e != nil {
+		err = unavailable("GetProcessWindowStation", e)
+		return
+	}
+	r0, _, e1 := syscall.Syscall(procGetProcessWindowStation.Addr(), 0, 0, 0, 0)
+	h = syscall.Handle(r0)
+	if h == 0 {
+		err = errnoErr("GetProcessWindowStation", e1)
+	}
+	return
+}
+
+func GetThreadDesktop(threadId uint32) (h syscall.Handle, err error) {
+	if e := procGetThreadDesktop.Find(); e != nil {
+		err = unavailable("GetThreadDesktop", e)
+		return
+	}
+	r0, _, e1 := syscall.Syscall(procGetThreadDesktop.Addr(), 1, uintptr(threadId), 0, 0)
+	h = syscall.Handle(r0)
+	if h == 0 {
+		err = errnoErr("GetThreadDesktop", e1)
+	}
+	return
+}
+
+func GetUserObjectInformation(obj syscall.Handle, index uint32, info *byte, length uint32, needed *uint32) (err error) {
+	if e := procGetUserObjectInformationW.Find(); e != nil {
+		err = unavailable("GetUserObjectInformationW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall6(procGetUserObjectInformationW.Addr(), 5, uintptr(obj), uintptr(index), uintptr(unsafe.Pointer(info)), uintptr(length), uintptr(unsafe.Pointer(needed)), 0)
+	if r1 == 0 {
+		err = errnoErr("GetUserObjectInformationW", e1)
+	}
+	return
+}
+
+func ProcessIdToSessionId(pid uint32, session *uint32) (err error) {
+	if e := procProcessIdToSessionId.Find(); e != nil {
+		err = unavailable("ProcessIdToSessionId", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procProcessIdToSessionId.Addr(), 2, uintptr(pid), uintptr(unsafe.Pointer(session)), 0)
+	if r1 == 0 {
+		err = errnoErr("ProcessIdToSessionId", e1)
+	}
+	return
+}
+
+func RegisterDeviceNotification(recipient syscall.Handle, filter *byte, flags uint32) (notify syscall.Handle, err error) {
+	if e := procRegisterDeviceNotificationW.Find(); e != nil {
+		err = unavailable("RegisterDeviceNotificationW", e)
+		return
+	}
+	r0, _, e1 := syscall.Syscall(procRegisterDeviceNotificationW.Addr(), 3, uintptr(recipient), uintptr(unsafe.Pointer(filter)), uintptr(flags))
+	notify = syscall.Handle(r0)
+	if notify == 0 {
+		err = errnoErr("RegisterDeviceNotificationW", e1)
+	}
+	return
+}
+
+func UnregisterDeviceNotification(notify syscall.Handle) (err error) {
+	if e := procUnregisterDeviceNotification.Find(); e != nil {
+		err = unavailable("UnregisterDeviceNotification", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procUnregisterDeviceNotification.Addr(), 1, uintptr(notify), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("UnregisterDeviceNotification", e1)
+	}
+	return
+}
+
 func CreateEvent(eventAttrs *syscall.SecurityAttributes, manualReset uint32, initialState uint32, name *uint16) (handle syscall.Handle, err error) {
+	if e := procCreateEventW.Find(); e != nil {
+		err = unavailable("CreateEventW", e)
+		return
+	}
 	r0, _, e1 := syscall.Syscall6(procCreateEventW.Addr(), 4, uintptr(unsafe.Pointer(eventAttrs)), uintptr(manualReset), uintptr(initialState), uintptr(unsafe.Pointer(name)), 0, 0)
 	handle = syscall.Handle(r0)
 	if handle == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("CreateEventW", e1)
 	}
 	return
 }
 
 func SetEvent(event syscall.Handle) (err error) {
+	if e := procSetEvent.Find(); e != nil {
+		err = unavailable("SetEvent", e)
+		return
+	}
 	r1, _, e1 := syscall.Syscall(procSetEvent.Addr(), 1, uintptr(event), 0, 0)
 	if r1 == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("SetEvent", e1)
 	}
 	return
 }
 
 func RegisterEventSource(uncServerName *uint16, sourceName *uint16) (handle syscall.Handle, err error) {
+	if e := procRegisterEventSourceW.Find(); e != nil {
+		err = unavailable("RegisterEventSourceW", e)
+		return
+	}
 	r0, _, e1 := syscall.Syscall(procRegisterEventSourceW.Addr(), 2, uintptr(unsafe.Pointer(uncServerName)), uintptr(unsafe.Pointer(sourceName)), 0)
 	handle = syscall.Handle(r0)
 	if handle == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("RegisterEventSourceW", e1)
+	}
+	return
+}
+
+func DeregisterEventSource(handle syscall.Handle) (err error) {
+	if e := procDeregisterEventSource.Find(); e != nil {
+		err = unavailable("DeregisterEventSource", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procDeregisterEventSource.Addr(), 1, uintptr(handle), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("DeregisterEventSource", e1)
+	}
+	return
+}
+
+func ReportEvent(log syscall.Handle, etype uint16, category uint16, eventId uint32, usrSId uintptr, numStrings uint16, dataSize uint32, strings **uint16, rawData *byte) (err error) {
+	if e := procReportEventW.Find(); e != nil {
+		err = unavailable("ReportEventW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall9(procReportEventW.Addr(), 9, uintptr(log), uintptr(etype), uintptr(category), uintptr(eventId), uintptr(usrSId), uintptr(numStrings), uintptr(dataSize), uintptr(unsafe.Pointer(strings)), uintptr(unsafe.Pointer(rawData)))
+	if r1 == 0 {
+		err = errnoErr("ReportEventW", e1)
+	}
+	return
+}
+
+func OpenEventLog(uncServerName *uint16, sourceName *uint16) (handle syscall.Handle, err error) {
+	if e := procOpenEventLogW.Find(); e != nil {
+		err = unavailable("OpenEventLogW", e)
+		return
+	}
+	r0, _, e1 := syscall.Syscall(procOpenEventLogW.Addr(), 2, uintptr(unsafe.Pointer(uncServerName)), uintptr(unsafe.Pointer(sourceName)), 0)
+	handle = syscall.Handle(r0)
+	if handle == 0 {
+		err = errnoErr("OpenEventLogW", e1)
+	}
+	return
+}
+
+func CloseEventLog(log syscall.Handle) (err error) {
+	if e := procCloseEventLog.Find(); e != nil {
+		err = unavailable("CloseEventLog", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procCloseEventLog.Addr(), 1, uintptr(log), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("CloseEventLog", e1)
+	}
+	return
+}
+
+func ReadEventLog(log syscall.Handle, flags uint32, offset uint32, buf *byte, bufSize uint32, bytesRead *uint32, minBytesNeeded *uint32) (err error) {
+	if e := procReadEventLogW.Find(); e != nil {
+		err = unavailable("ReadEventLogW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall9(procReadEventLogW.Addr(), 7, uintptr(log), uintptr(flags), uintptr(offset), uintptr(unsafe.Pointer(buf)), uintptr(bufSize), uintptr(unsafe.Pointer(bytesRead)), uintptr(unsafe.Pointer(minBytesNeeded)), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("ReadEventLogW", e1)
+	}
+	return
+}
+
+func GetNumberOfEventLogRecords(log syscall.Handle, count *uint32) (err error) {
+	if e := procGetNumberOfEventLogRecords.Find(); e != nil {
+		err = unavailable("GetNumberOfEventLogRecords", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procGetNumberOfEventLogRecords.Addr(), 2, uintptr(log), uintptr(unsafe.Pointer(count)), 0)
+	if r1 == 0 {
+		err = errnoErr("GetNumberOfEventLogRecords", e1)
+	}
+	return
+}
+
+func GetOldestEventLogRecord(log syscall.Handle, oldest *uint32) (err error) {
+	if e := procGetOldestEventLogRecord.Find(); e != nil {
+		err = unavailable("GetOldestEventLogRecord", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procGetOldestEventLogRecord.Addr(), 2, uintptr(log), uintptr(unsafe.Pointer(oldest)), 0)
+	if r1 == 0 {
+		err = errnoErr("GetOldestEventLogRecord", e1)
+	}
+	return
+}
+
+func ClearEventLog(log syscall.Handle, backupFileName *uint16) (err error) {
+	if e := procClearEventLogW.Find(); e != nil {
+		err = unavailable("ClearEventLogW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procClearEventLogW.Addr(), 2, uintptr(log), uintptr(unsafe.Pointer(backupFileName)), 0)
+	if r1 == 0 {
+		err = errnoErr("ClearEventLogW", e1)
+	}
+	return
+}
+
+func BackupEventLog(log syscall.Handle, backupFileName *uint16) (err error) {
+	if e := procBackupEventLogW.Find(); e != nil {
+		err = unavailable("BackupEventLogW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procBackupEventLogW.Addr(), 2, uintptr(log), uintptr(unsafe.Pointer(backupFileName)), 0)
+	if r1 == 0 {
+		err = errnoErr("BackupEventLogW", e1)
+	}
+	return
+}
+
+func MoveFileEx(from *uint16, to *uint16, flags uint32) (err error) {
+	if e := procMoveFileExW.Find(); e != nil {
+		err = unavailable("MoveFileExW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procMoveFileExW.Addr(), 3, uintptr(unsafe.Pointer(from)), uintptr(unsafe.Pointer(to)), uintptr(flags))
+	if r1 == 0 {
+		err = errnoErr("MoveFileExW", e1)
+	}
+	return
+}
+
+func LsaOpenPolicy(system *LSA_UNICODE_STRING, attrs *LSA_OBJECT_ATTRIBUTES, access uint32, policy *syscall.Handle) (status uint32) {
+	if procLsaOpenPolicy.Find() != nil {
+		status = STATUS_PROCEDURE_NOT_FOUND
+		return
+	}
+	r0, _, _ := syscall.Syscall6(procLsaOpenPolicy.Addr(), 4, uintptr(unsafe.Pointer(system)), uintptr(unsafe.Pointer(attrs)), uintptr(access), uintptr(unsafe.Pointer(policy)), 0, 0)
+	status = uint32(r0)
+	return
+}
+
+func LsaClose(policy syscall.Handle) (status uint32) {
+	if procLsaClose.Find() != nil {
+		status = STATUS_PROCEDURE_NOT_FOUND
+		return
+	}
+	r0, _, _ := syscall.Syscall(procLsaClose.Addr(), 1, uintptr(policy), 0, 0)
+	status = uint32(r0)
+	return
+}
+
+func LsaFreeMemory(buffer uintptr) (status uint32) {
+	if procLsaFreeMemory.Find() != nil {
+		status = STATUS_PROCEDURE_NOT_FOUND
+		return
+	}
+	r0, _, _ := syscall.Syscall(procLsaFreeMemory.Addr(), 1, uintptr(buffer), 0, 0)
+	status = uint32(r0)
+	return
+}
+
+func LsaStorePrivateData(policy syscall.Handle, key *LSA_UNICODE_STRING, data *LSA_UNICODE_STRING) (status uint32) {
+	if procLsaStorePrivateData.Find() != nil {
+		status = STATUS_PROCEDURE_NOT_FOUND
+		return
+	}
+	r0, _, _ := syscall.Syscall(procLsaStorePrivateData.Addr(), 3, uintptr(policy), uintptr(unsafe.Pointer(key)), uintptr(unsafe.Pointer(data)))
+	status = uint32(r0)
+	return
+}
+
+func LsaRetrievePrivateData(policy syscall.Handle, key *LSA_UNICODE_STRING, data **LSA_UNICODE_STRING) (status uint32) {
+	if procLsaRetrievePrivateData.Find() != nil {
+		status = STATUS_PROCEDURE_NOT_FOUND
+		return
+	}
+	r0, _, _ := syscall.Syscall(procLsaRetrievePrivateData.Addr(), 3, uintptr(policy), uintptr(unsafe.Pointer(key)), uintptr(unsafe.Pointer(data)))
+	status = uint32(r0)
+	return
+}
+
+func LsaAddAccountRights(policy syscall.Handle, sid *syscall.SID, rights *LSA_UNICODE_STRING, count uint32) (status uint32) {
+	if procLsaAddAccountRights.Find() != nil {
+		status = STATUS_PROCEDURE_NOT_FOUND
+		return
+	}
+	r0, _, _ := syscall.Syscall6(procLsaAddAccountRights.Addr(), 4, uintptr(policy), uintptr(unsafe.Pointer(sid)), uintptr(unsafe.Pointer(rights)), uintptr(count), 0, 0)
+	status = uint32(r0)
+	return
+}
+
+func LsaRemoveAccountRights(policy syscall.Handle, sid *syscall.SID, all bool, rights *LSA_UNICODE_STRING, count uint32) (status uint32) {
+	if procLsaRemoveAccountRights.Find() != nil {
+		status = STATUS_PROCEDURE_NOT_FOUND
+		return
+	}
+	var _p0 uint32
+	if all {
+		_p0 = 1
+	} else {
+		_p0 = 0
+	}
+	r0, _, _ := syscall.Syscall6(procLsaRemoveAccountRights.Addr(), 5, uintptr(policy), uintptr(unsafe.Pointer(sid)), uintptr(_p0), uintptr(unsafe.Pointer(rights)), uintptr(count), 0)
+	status = uint32(r0)
+	return
+}
+
+func LsaNtStatusToWinError(status uint32) (errno uint32) {
+	if procLsaNtStatusToWinError.Find() != nil {
+		errno = uint32(syscall.ERROR_PROC_NOT_FOUND)
+		return
+	}
+	r0, _, _ := syscall.Syscall(procLsaNtStatusToWinError.Addr(), 1, uintptr(status), 0, 0)
+	errno = uint32(r0)
+	return
+}
+
+func LsaGetLogonSessionData(logonId *LUID, data **SECURITY_LOGON_SESSION_DATA) (status uint32) {
+	if procLsaGetLogonSessionData.Find() != nil {
+		status = STATUS_PROCEDURE_NOT_FOUND
+		return
+	}
+	r0, _, _ := syscall.Syscall(procLsaGetLogonSessionData.Addr(), 2, uintptr(unsafe.Pointer(logonId)), uintptr(unsafe.Pointer(data)), 0)
+	status = uint32(r0)
+	return
+}
+
+func LsaFreeReturnBuffer(buffer uintptr) (status uint32) {
+	if procLsaFreeReturnBuffer.Find() != nil {
+		status = STATUS_PROCEDURE_NOT_FOUND
+		return
+	}
+	r0, _, _ := syscall.Syscall(procLsaFreeReturnBuffer.Addr(), 1, uintptr(buffer), 0, 0)
+	status = uint32(r0)
+	return
+}
+
+func NotifyServiceStatusChange(service syscall.Handle, mask uint32, notify *SERVICE_NOTIFY) (ret error) {
+	if e := procNotifyServiceStatusChangeW.Find(); e != nil {
+		ret = unavailable("NotifyServiceStatusChangeW", e)
+		return
+	}
+	r0, _, _ := syscall.Syscall(procNotifyServiceStatusChangeW.Addr(), 3, uintptr(service), uintptr(mask), uintptr(unsafe.Pointer(notify)))
+	if r0 != 0 {
+		ret = errnoErr("NotifyServiceStatusChangeW", syscall.Errno(r0))
+	}
+	return
+}
+
+func SleepEx(milliseconds uint32, alertable bool) (ret uint32) {
+	if procSleepEx.Find() != nil {
+		return
+	}
+	var _p0 uint32
+	if alertable {
+		_p0 = 1
+	} else {
+		_p0 = 0
+	}
+	r0, _, _ := syscall.Syscall(procSleepEx.Addr(), 2, uintptr(milliseconds), uintptr(_p0), 0)
+	ret = uint32(r0)
+	return
+}
+
+func WaitForSingleObjectEx(handle syscall.Handle, milliseconds uint32, alertable bool) (event uint32, err error) {
+	if e := procWaitForSingleObjectEx.Find(); e != nil {
+		err = unavailable("WaitForSingleObjectEx", e)
+		return
+	}
+	var _p0 uint32
+	if alertable {
+		_p0 = 1
+	} else {
+		_p0 = 0
+	}
+	r0, _, e1 := syscall.Syscall(procWaitForSingleObjectEx.Addr(), 3, uintptr(handle), uintptr(milliseconds), uintptr(_p0))
+	event = uint32(r0)
+	if event == 0xffffffff {
+		err = errnoErr("WaitForSingleObjectEx", e1)
+	}
+	return
+}
+
+func SubscribeServiceChangeNotifications(service syscall.Handle, eventType uint32, callback uintptr, context uintptr, subscription *uintptr) (ret error) {
+	if e := procSubscribeServiceChangeNotifications.Find(); e != nil {
+		ret = unavailable("SubscribeServiceChangeNotifications", e)
+		return
+	}
+	r0, _, _ := syscall.Syscall6(procSubscribeServiceChangeNotifications.Addr(), 5, uintptr(service), uintptr(eventType), uintptr(callback), uintptr(context), uintptr(unsafe.Pointer(subscription)), 0)
+	if r0 != 0 {
+		ret = errnoErr("SubscribeServiceChangeNotifications", syscall.Errno(r0))
+	}
+	return
+}
+
+func UnsubscribeServiceChangeNotifications(subscription uintptr) {
+	if procUnsubscribeServiceChangeNotifications.Find() != nil {
+		return
+	}
+	syscall.Syscall(procUnsubscribeServiceChangeNotifications.Addr(), 1, uintptr(subscription), 0, 0)
+	return
+}
+
+func RegisterPowerSettingNotification(recipient syscall.Handle, setting *GUID, flags uint32) (notify syscall.Handle, err error) {
+	if e := procRegisterPowerSettingNotification.Find(); e != nil {
+		err = unavailable("RegisterPowerSettingNotification", e)
+		return
+	}
+	r0, _, e1 := syscall.Syscall(procRegisterPowerSettingNotification.Addr(), 3, uintptr(recipient), uintptr(unsafe.Pointer(setting)), uintptr(flags))
+	notify = syscall.Handle(r0)
+	if notify == 0 {
+		err = errnoErr("RegisterPowerSettingNotification", e1)
+	}
+	return
+}
+
+func UnregisterPowerSettingNotification(notify syscall.Handle) (err error) {
+	if e := procUnregisterPowerSettingNotification.Find(); e != nil {
+		err = unavailable("UnregisterPowerSettingNotification", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procUnregisterPowerSettingNotification.Addr(), 1, uintptr(notify), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("UnregisterPowerSettingNotification", e1)
 	}
 	return
 }
 
-func DeregisterEventSource(handle syscall.Handle) (err error) {
-	r1, _, e1 := syscall.Syscall(procDeregisterEventSource.Addr(), 1, uintptr(handle), 0, 0)
+func SetProcessMitigationPolicy(policy uint32, buffer *byte, length uintptr) (err error) {
+	if e := procSetProcessMitigationPolicy.Find(); e != nil {
+		err = unavailable("SetProcessMitigationPolicy", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procSetProcessMitigationPolicy.Addr(), 3, uintptr(policy), uintptr(unsafe.Pointer(buffer)), uintptr(length))
 	if r1 == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("SetProcessMitigationPolicy", e1)
 	}
 	return
 }
 
-func ReportEvent(log syscall.Handle, etype uint16, category uint16, eventId uint32, usrSId uintptr, numStrings uint16, dataSize uint32, strings **uint16, rawData *byte) (err error) {
-	r1, _, e1 := syscall.Syscall9(procReportEventW.Addr(), 9, uintptr(log), uintptr(etype), uintptr(category), uintptr(eventId), uintptr(usrSId), uintptr(numStrings), uintptr(dataSize), uintptr(unsafe.Pointer(strings)), uintptr(unsafe.Pointer(rawData)))
+func GetProcessMitigationPolicy(process syscall.Handle, policy uint32, buffer *byte, length uintptr) (err error) {
+	if e := procGetProcessMitigationPolicy.Find(); e != nil {
+		err = unavailable("GetProcessMitigationPolicy", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall6(procGetProcessMitigationPolicy.Addr(), 4, uintptr(process), uintptr(policy), uintptr(unsafe.Pointer(buffer)), uintptr(length), 0, 0)
 	if r1 == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("GetProcessMitigationPolicy", e1)
 	}
 	return
 }
 
 func RegCreateKeyEx(key syscall.Handle, subkey *uint16, reserved uint32, class *uint16, options uint32, desired uint32, sa *syscall.SecurityAttributes, result *syscall.Handle, disposition *uint32) (regerrno error) {
+	if e := procRegCreateKeyExW.Find(); e != nil {
+		regerrno = unavailable("RegCreateKeyExW", e)
+		return
+	}
 	r0, _, _ := syscall.Syscall9(procRegCreateKeyExW.Addr(), 9, uintptr(key), uintptr(unsafe.Pointer(subkey)), uintptr(reserved), uintptr(unsafe.Pointer(class)), uintptr(options), uintptr(desired), uintptr(unsafe.Pointer(sa)), uintptr(unsafe.Pointer(result)), uintptr(unsafe.Pointer(disposition)))
 	if r0 != 0 {
-		regerrno = syscall.Errno(r0)
+		regerrno = errnoErr("RegCreateKeyExW", syscall.Errno(r0))
 	}
 	return
 }
 
 func RegDeleteKey(key syscall.Handle, subkey *uint16) (regerrno error) {
+	if e := procRegDeleteKeyW.Find(); e != nil {
+		regerrno = unavailable("RegDeleteKeyW", e)
+		return
+	}
 	r0, _, _ := syscall.Syscall(procRegDeleteKeyW.Addr(), 2, uintptr(key), uintptr(unsafe.Pointer(subkey)), 0)
 	if r0 != 0 {
-		regerrno = syscall.Errno(r0)
+		regerrno = errnoErr("RegDeleteKeyW", syscall.Errno(r0))
+	}
+	return
+}
+
+func RegDeleteTree(key syscall.Handle, subkey *uint16) (regerrno error) {
+	if e := procRegDeleteTreeW.Find(); e != nil {
+		regerrno = unavailable("RegDeleteTreeW", e)
+		return
+	}
+	r0, _, _ := syscall.Syscall(procRegDeleteTreeW.Addr(), 2, uintptr(key), uintptr(unsafe.Pointer(subkey)), 0)
+	if r0 != 0 {
+		regerrno = errnoErr("RegDeleteTreeW", syscall.Errno(r0))
 	}
 	return
 }
 
 func RegSetValueEx(key syscall.Handle, valueName *uint16, reserved uint32, vtype uint32, buf *byte, bufsize uint32) (regerrno error) {
+	if e := procRegSetValueExW.Find(); e != nil {
+		regerrno = unavailable("RegSetValueExW", e)
+		return
+	}
 	r0, _, _ := syscall.Syscall6(procRegSetValueExW.Addr(), 6, uintptr(key), uintptr(unsafe.Pointer(valueName)), uintptr(reserved), uintptr(vtype), uintptr(unsafe.Pointer(buf)), uintptr(bufsize))
 	if r0 != 0 {
-		regerrno = syscall.Errno(r0)
+		regerrno = errnoErr("RegSetValueExW", syscall.Errno(r0))
+	}
+	return
+}
+
+func RegDeleteValue(key syscall.Handle, valueName *uint16) (regerrno error) {
+	if e := procRegDeleteValueW.Find(); e != nil {
+		regerrno = unavailable("RegDeleteValueW", e)
+		return
+	}
+	r0, _, _ := syscall.Syscall(procRegDeleteValueW.Addr(), 2, uintptr(key), uintptr(unsafe.Pointer(valueName)), 0)
+	if r0 != 0 {
+		regerrno = errnoErr("RegDeleteValueW", syscall.Errno(r0))
 	}
 	return
 }
 
 func AllocateAndInitializeSid(identAuth *SidIdentifierAuthority, subAuth byte, subAuth0 uint32, subAuth1 uint32, subAuth2 uint32, subAuth3 uint32, subAuth4 uint32, subAuth5 uint32, subAuth6 uint32, subAuth7 uint32, sid **syscall.SID) (err error) {
+	if e := procAllocateAndInitializeSid.Find(); e != nil {
+		err = unavailable("AllocateAndInitializeSid", e)
+		return
+	}
 	r1, _, e1 := syscall.Syscall12(procAllocateAndInitializeSid.Addr(), 11, uintptr(unsafe.Pointer(identAuth)), uintptr(subAuth), uintptr(subAuth0), uintptr(subAuth1), uintptr(subAuth2), uintptr(subAuth3), uintptr(subAuth4), uintptr(subAuth5), uintptr(subAuth6), uintptr(subAuth7), uintptr(unsafe.Pointer(sid)), 0)
 	if r1 == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("AllocateAndInitializeSid", e1)
 	}
 	return
 }
 
 func FreeSid(sid *syscall.SID) (err error) {
+	if e := procFreeSid.Find(); e != nil {
+		err = unavailable("FreeSid", e)
+		return
+	}
 	r1, _, e1 := syscall.Syscall(procFreeSid.Addr(), 1, uintptr(unsafe.Pointer(sid)), 0, 0)
 	if r1 != 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("FreeSid", e1)
 	}
 	return
 }
 
 func EqualSid(sid1 *syscall.SID, sid2 *syscall.SID) (isEqual bool) {
+	if procEqualSid.Find() != nil {
+		return
+	}
 	r0, _, _ := syscall.Syscall(procEqualSid.Addr(), 2, uintptr(unsafe.Pointer(sid1)), uintptr(unsafe.Pointer(sid2)), 0)
 	isEqual = r0 != 0
 	return
 }
 
+func ImpersonateNamedPipeClient(pipe syscall.Handle) (err error) {
+	if e := procImpersonateNamedPipeClient.Find(); e != nil {
+		err = unavailable("ImpersonateNamedPipeClient", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procImpersonateNamedPipeClient.Addr(), 1, uintptr(pipe), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("ImpersonateNamedPipeClient", e1)
+	}
+	return
+}
+
+func ImpersonateLoggedOnUser(token syscall.Token) (err error) {
+	if e := procImpersonateLoggedOnUser.Find(); e != nil {
+		err = unavailable("ImpersonateLoggedOnUser", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procImpersonateLoggedOnUser.Addr(), 1, uintptr(token), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("ImpersonateLoggedOnUser", e1)
+	}
+	return
+}
+
+func RevertToSelf() (err error) {
+	if e := procRevertToSelf.Find(); e != nil {
+		err = unavailable("RevertToSelf", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procRevertToSelf.Addr(), 0, 0, 0, 0)
+	if r1 == 0 {
+		err = errnoErr("RevertToSelf", e1)
+	}
+	return
+}
+
+func RpcImpersonateClient(binding uintptr) (status error) {
+	if e := procRpcImpersonateClient.Find(); e != nil {
+		status = unavailable("RpcImpersonateClient", e)
+		return
+	}
+	r0, _, _ := syscall.Syscall(procRpcImpersonateClient.Addr(), 1, uintptr(binding), 0, 0)
+	if r0 != 0 {
+		status = errnoErr("RpcImpersonateClient", syscall.Errno(r0))
+	}
+	return
+}
+
+func RpcRevertToSelfEx(binding uintptr) (status error) {
+	if e := procRpcRevertToSelfEx.Find(); e != nil {
+		status = unavailable("RpcRevertToSelfEx", e)
+		return
+	}
+	r0, _, _ := syscall.Syscall(procRpcRevertToSelfEx.Addr(), 1, uintptr(binding), 0, 0)
+	if r0 != 0 {
+		status = errnoErr("RpcRevertToSelfEx", syscall.Errno(r0))
+	}
+	return
+}
+
+func QueryServiceObjectSecurity(service syscall.Handle, securityInformation uint32, sd *byte, bufSize uint32, bytesNeeded *uint32) (err error) {
+	if e := procQueryServiceObjectSecurity.Find(); e != nil {
+		err = unavailable("QueryServiceObjectSecurity", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall6(procQueryServiceObjectSecurity.Addr(), 5, uintptr(service), uintptr(securityInformation), uintptr(unsafe.Pointer(sd)), uintptr(bufSize), uintptr(unsafe.Pointer(bytesNeeded)), 0)
+	if r1 == 0 {
+		err = errnoErr("QueryServiceObjectSecurity", e1)
+	}
+	return
+}
+
+func SetServiceObjectSecurity(service syscall.Handle, securityInformation uint32, sd *byte) (err error) {
+	if e := procSetServiceObjectSecurity.Find(); e != nil {
+		err = unavailable("SetServiceObjectSecurity", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procSetServiceObjectSecurity.Addr(), 3, uintptr(service), uintptr(securityInformation), uintptr(unsafe.Pointer(sd)))
+	if r1 == 0 {
+		err = errnoErr("SetServiceObjectSecurity", e1)
+	}
+	return
+}
+
+func ConvertSecurityDescriptorToStringSecurityDescriptor(sd *byte, revision uint32, securityInformation uint32, str **uint16, strLen *uint32) (err error) {
+	if e := procConvertSecurityDescriptorToStringSecurityDescriptorW.Find(); e != nil {
+		err = unavailable("ConvertSecurityDescriptorToStringSecurityDescriptorW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall6(procConvertSecurityDescriptorToStringSecurityDescriptorW.Addr(), 5, uintptr(unsafe.Pointer(sd)), uintptr(revision), uintptr(securityInformation), uintptr(unsafe.Pointer(str)), uintptr(unsafe.Pointer(strLen)), 0)
+	if r1 == 0 {
+		err = errnoErr("ConvertSecurityDescriptorToStringSecurityDescriptorW", e1)
+	}
+	return
+}
+
+func LookupPrivilegeValue(system *uint16, name *uint16, luid *LUID) (err error) {
+	if e := procLookupPrivilegeValueW.Find(); e != nil {
+		err = unavailable("LookupPrivilegeValueW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procLookupPrivilegeValueW.Addr(), 3, uintptr(unsafe.Pointer(system)), uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(luid)))
+	if r1 == 0 {
+		err = errnoErr("LookupPrivilegeValueW", e1)
+	}
+	return
+}
+
+func AdjustTokenPrivileges(token syscall.Token, disableAll bool, newState *TOKEN_PRIVILEGES, bufLen uint32, prevState *TOKEN_PRIVILEGES, returnLen *uint32) (err error) {
+	if e := procAdjustTokenPrivileges.Find(); e != nil {
+		err = unavailable("AdjustTokenPrivileges", e)
+		return
+	}
+	var _p0 uint32
+	if disableAll {
+		_p0 = 1
+	} else {
+		_p0 = 0
+	}
+	r1, _, e1 := syscall.Syscall6(procAdjustTokenPrivileges.Addr(), 6, uintptr(token), uintptr(_p0), uintptr(unsafe.Pointer(newState)), uintptr(bufLen), uintptr(unsafe.Pointer(prevState)), uintptr(unsafe.Pointer(returnLen)))
+	if r1 == 0 || e1 == ERROR_NOT_ALL_ASSIGNED {
+		err = errnoErr("AdjustTokenPrivileges", e1)
+	}
+	return
+}
+
+func ConvertStringSecurityDescriptorToSecurityDescriptor(str *uint16, revision uint32, sd **byte, size *uint32) (err error) {
+	if e := procConvertStringSecurityDescriptorToSecurityDescriptorW.Find(); e != nil {
+		err = unavailable("ConvertStringSecurityDescriptorToSecurityDescriptorW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall6(procConvertStringSecurityDescriptorToSecurityDescriptorW.Addr(), 4, uintptr(unsafe.Pointer(str)), uintptr(revision), uintptr(unsafe.Pointer(sd)), uintptr(unsafe.Pointer(size)), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("ConvertStringSecurityDescriptorToSecurityDescriptorW", e1)
+	}
+	return
+}
+
+func GetSecurityDescriptorOwner(sd *byte, owner **syscall.SID, defaulted *int32) (err error) {
+	if e := procGetSecurityDescriptorOwner.Find(); e != nil {
+		err = unavailable("GetSecurityDescriptorOwner", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procGetSecurityDescriptorOwner.Addr(), 3, uintptr(unsafe.Pointer(sd)), uintptr(unsafe.Pointer(owner)), uintptr(unsafe.Pointer(defaulted)))
+	if r1 == 0 {
+		err = errnoErr("GetSecurityDescriptorOwner", e1)
+	}
+	return
+}
+
+func GetSecurityDescriptorGroup(sd *byte, group **syscall.SID, defaulted *int32) (err error) {
+	if e := procGetSecurityDescriptorGroup.Find(); e != nil {
+		err = unavailable("GetSecurityDescriptorGroup", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procGetSecurityDescriptorGroup.Addr(), 3, uintptr(unsafe.Pointer(sd)), uintptr(unsafe.Pointer(group)), uintptr(unsafe.Pointer(defaulted)))
+	if r1 == 0 {
+		err = errnoErr("GetSecurityDescriptorGroup", e1)
+	}
+	return
+}
+
+func GetSecurityDescriptorDacl(sd *byte, present *int32, dacl *uintptr, defaulted *int32) (err error) {
+	if e := procGetSecurityDescriptorDacl.Find(); e != nil {
+		err = unavailable("GetSecurityDescriptorDacl", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall6(procGetSecurityDescriptorDacl.Addr(), 4, uintptr(unsafe.Pointer(sd)), uintptr(unsafe.Pointer(present)), uintptr(unsafe.Pointer(dacl)), uintptr(unsafe.Pointer(defaulted)), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("GetSecurityDescriptorDacl", e1)
+	}
+	return
+}
+
 func OpenSCManager(machineName *uint16, databaseName *uint16, access uint32) (handle syscall.Handle, err error) {
+	if e := procOpenSCManagerW.Find(); e != nil {
+		err = unavailable("OpenSCManagerW", e)
+		return
+	}
 	r0, _, e1 := syscall.Syscall(procOpenSCManagerW.Addr(), 3, uintptr(unsafe.Pointer(machineName)), uintptr(unsafe.Pointer(databaseName)), uintptr(access))
 	handle = syscall.Handle(r0)
 	if handle == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("OpenSCManagerW", e1)
 	}
 	return
 }
 
 func CloseServiceHandle(handle syscall.Handle) (err error) {
+	if e := procCloseServiceHandle.Find(); e != nil {
+		err = unavailable("CloseServiceHandle", e)
+		return
+	}
 	r1, _, e1 := syscall.Syscall(procCloseServiceHandle.Addr(), 1, uintptr(handle), 0, 0)
 	if r1 == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("CloseServiceHandle", e1)
 	}
 	return
 }
 
 func CreateService(mgr syscall.Handle, serviceName *uint16, displayName *uint16, access uint32, srvType uint32, startType uint32, errCtl uint32, pathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16) (handle syscall.Handle, err error) {
+	if e := procCreateServiceW.Find(); e != nil {
+		err = unavailable("CreateServiceW", e)
+		return
+	}
 	r0, _, e1 := syscall.Syscall15(procCreateServiceW.Addr(), 13, uintptr(mgr), uintptr(unsafe.Pointer(serviceName)), uintptr(unsafe.Pointer(displayName)), uintptr(access), uintptr(srvType), uintptr(startType), uintptr(errCtl), uintptr(unsafe.Pointer(pathName)), uintptr(unsafe.Pointer(loadOrderGroup)), uintptr(unsafe.Pointer(tagId)), uintptr(unsafe.Pointer(dependencies)), uintptr(unsafe.Pointer(serviceStartName)), uintptr(unsafe.Pointer(password)), 0, 0)
 	handle = syscall.Handle(r0)
 	if handle == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("CreateServiceW", e1)
 	}
 	return
 }
 
 func OpenService(mgr syscall.Handle, serviceName *uint16, access uint32) (handle syscall.Handle, err error) {
+	if e := procOpenServiceW.Find(); e != nil {
+		err = unavailable("OpenServiceW", e)
+		return
+	}
 	r0, _, e1 := syscall.Syscall(procOpenServiceW.Addr(), 3, uintptr(mgr), uintptr(unsafe.Pointer(serviceName)), uintptr(access))
 	handle = syscall.Handle(r0)
 	if handle == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("OpenServiceW", e1)
 	}
 	return
 }
 
 func DeleteService(service syscall.Handle) (err error) {
+	if e := procDeleteService.Find(); e != nil {
+		err = unavailable("DeleteService", e)
+		return
+	}
 	r1, _, e1 := syscall.Syscall(procDeleteService.Addr(), 1, uintptr(service), 0, 0)
 	if r1 == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("DeleteService", e1)
 	}
 	return
 }
 
 func StartService(service syscall.Handle, numArgs uint32, argVectors **uint16) (err error) {
+	if e := procStartServiceW.Find(); e != nil {
+		err = unavailable("StartServiceW", e)
+		return
+	}
 	r1, _, e1 := syscall.Syscall(procStartServiceW.Addr(), 3, uintptr(service), uintptr(numArgs), uintptr(unsafe.Pointer(argVectors)))
 	if r1 == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("StartServiceW", e1)
 	}
 	return
 }
 
 func QueryServiceStatus(service syscall.Handle, status *SERVICE_STATUS) (err error) {
+	if e := procQueryServiceStatus.Find(); e != nil {
+		err = unavailable("QueryServiceStatus", e)
+		return
+	}
 	r1, _, e1 := syscall.Syscall(procQueryServiceStatus.Addr(), 2, uintptr(service), uintptr(unsafe.Pointer(status)), 0)
 	if r1 == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("QueryServiceStatus", e1)
+	}
+	return
+}
+
+func QueryServiceStatusEx(service syscall.Handle, infoLevel uint32, buff *byte, buffSize uint32, bytesNeeded *uint32) (err error) {
+	if e := procQueryServiceStatusEx.Find(); e != nil {
+		err = unavailable("QueryServiceStatusEx", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall6(procQueryServiceStatusEx.Addr(), 5, uintptr(service), uintptr(infoLevel), uintptr(unsafe.Pointer(buff)), uintptr(buffSize), uintptr(unsafe.Pointer(bytesNeeded)), 0)
+	if r1 == 0 {
+		err = errnoErr("QueryServiceStatusEx", e1)
 	}
 	return
 }
 
 func ControlService(service syscall.Handle, control uint32, status *SERVICE_STATUS) (err error) {
+	if e := procControlService.Find(); e != nil {
+		err = unavailable("ControlService", e)
+		return
+	}
 	r1, _, e1 := syscall.Syscall(procControlService.Addr(), 3, uintptr(service), uintptr(control), uintptr(unsafe.Pointer(status)))
 	if r1 == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("ControlService", e1)
+	}
+	return
+}
+
+func ControlServiceEx(service syscall.Handle, control uint32, infoLevel uint32, params *byte) (err error) {
+	if e := procControlServiceExW.Find(); e != nil {
+		err = unavailable("ControlServiceExW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall6(procControlServiceExW.Addr(), 4, uintptr(service), uintptr(control), uintptr(infoLevel), uintptr(unsafe.Pointer(params)), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("ControlServiceExW", e1)
 	}
 	return
 }
 
 func StartServiceCtrlDispatcher(serviceTable *SERVICE_TABLE_ENTRY) (err error) {
+	if e := procStartServiceCtrlDispatcherW.Find(); e != nil {
+		err = unavailable("StartServiceCtrlDispatcherW", e)
+		return
+	}
 	r1, _, e1 := syscall.Syscall(procStartServiceCtrlDispatcherW.Addr(), 1, uintptr(unsafe.Pointer(serviceTable)), 0, 0)
 	if r1 == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("StartServiceCtrlDispatcherW", e1)
 	}
 	return
 }
 
 func SetServiceStatus(service syscall.Handle, serviceStatus *SERVICE_STATUS) (err error) {
+	if e := procSetServiceStatus.Find(); e != nil {
+		err = unavailable("SetServiceStatus", e)
+		return
+	}
 	r1, _, e1 := syscall.Syscall(procSetServiceStatus.Addr(), 2, uintptr(service), uintptr(unsafe.Pointer(serviceStatus)), 0)
 	if r1 == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("SetServiceStatus", e1)
+	}
+	return
+}
+
+func RegisterServiceCtrlHandlerEx(serviceName *uint16, handlerProc uintptr, context uintptr) (handle syscall.Handle, err error) {
+	if e := procRegisterServiceCtrlHandlerExW.Find(); e != nil {
+		err = unavailable("RegisterServiceCtrlHandlerExW", e)
+		return
+	}
+	r0, _, e1 := syscall.Syscall(procRegisterServiceCtrlHandlerExW.Addr(), 3, uintptr(unsafe.Pointer(serviceName)), uintptr(handlerProc), uintptr(context))
+	handle = syscall.Handle(r0)
+	if handle == 0 {
+		err = errnoErr("RegisterServiceCtrlHandlerExW", e1)
 	}
 	return
 }
 
 func ChangeServiceConfig(service syscall.Handle, serviceType uint32, startType uint32, errorControl uint32, binaryPathName *uint16, loadOrderGroup *uint16, tagId *uint32, dependencies *uint16, serviceStartName *uint16, password *uint16, displayName *uint16) (err error) {
+	if e := procChangeServiceConfigW.Find(); e != nil {
+		err = unavailable("ChangeServiceConfigW", e)
+		return
+	}
 	r1, _, e1 := syscall.Syscall12(procChangeServiceConfigW.Addr(), 11, uintptr(service), uintptr(serviceType), uintptr(startType), uintptr(errorControl), uintptr(unsafe.Pointer(binaryPathName)), uintptr(unsafe.Pointer(loadOrderGroup)), uintptr(unsafe.Pointer(tagId)), uintptr(unsafe.Pointer(dependencies)), uintptr(unsafe.Pointer(serviceStartName)), uintptr(unsafe.Pointer(password)), uintptr(unsafe.Pointer(displayName)), 0)
 	if r1 == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("ChangeServiceConfigW", e1)
 	}
 	return
 }
 
 func QueryServiceConfig(service syscall.Handle, serviceConfig *QUERY_SERVICE_CONFIG, bufSize uint32, bytesNeeded *uint32) (err error) {
+	if e := procQueryServiceConfigW.Find(); e != nil {
+		err = unavailable("QueryServiceConfigW", e)
+		return
+	}
 	r1, _, e1 := syscall.Syscall6(procQueryServiceConfigW.Addr(), 4, uintptr(service), uintptr(unsafe.Pointer(serviceConfig)), uintptr(bufSize), uintptr(unsafe.Pointer(bytesNeeded)), 0, 0)
 	if r1 == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("QueryServiceConfigW", e1)
 	}
 	return
 }
 
 func ChangeServiceConfig2(service syscall.Handle, infoLevel uint32, info *byte) (err error) {
+	if e := procChangeServiceConfig2W.Find(); e != nil {
+		err = unavailable("ChangeServiceConfig2W", e)
+		return
+	}
 	r1, _, e1 := syscall.Syscall(procChangeServiceConfig2W.Addr(), 3, uintptr(service), uintptr(infoLevel), uintptr(unsafe.Pointer(info)))
 	if r1 == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("ChangeServiceConfig2W", e1)
 	}
 	return
 }
 
 func QueryServiceConfig2(service syscall.Handle, infoLevel uint32, buff *byte, buffSize uint32, bytesNeeded *uint32) (err error) {
+	if e := procQueryServiceConfig2W.Find(); e != nil {
+		err = unavailable("QueryServiceConfig2W", e)
+		return
+	}
 	r1, _, e1 := syscall.Syscall6(procQueryServiceConfig2W.Addr(), 5, uintptr(service), uintptr(infoLevel), uintptr(unsafe.Pointer(buff)), uintptr(buffSize), uintptr(unsafe.Pointer(bytesNeeded)), 0)
 	if r1 == 0 {
-		if e1 != 0 {
-			err = error(e1)
-		} else {
-			err = syscall.EINVAL
-		}
+		err = errnoErr("QueryServiceConfig2W", e1)
+	}
+	return
+}
+
+func EnumDependentServices(service syscall.Handle, serviceState uint32, services *ENUM_SERVICE_STATUS, bufSize uint32, bytesNeeded *uint32, servicesReturned *uint32) (err error) {
+	if e := procEnumDependentServicesW.Find(); e != nil {
+		err = unavailable("EnumDependentServicesW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall6(procEnumDependentServicesW.Addr(), 6, uintptr(service), uintptr(serviceState), uintptr(unsafe.Pointer(services)), uintptr(bufSize), uintptr(unsafe.Pointer(bytesNeeded)), uintptr(unsafe.Pointer(servicesReturned)))
+	if r1 == 0 {
+		err = errnoErr("EnumDependentServicesW", e1)
+	}
+	return
+}
+
+func EnumServicesStatusEx(mgr syscall.Handle, infoLevel uint32, serviceType uint32, serviceState uint32, services *byte, bufSize uint32, bytesNeeded *uint32, servicesReturned *uint32, resumeHandle *uint32, groupName *uint16) (err error) {
+	if e := procEnumServicesStatusExW.Find(); e != nil {
+		err = unavailable("EnumServicesStatusExW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall12(procEnumServicesStatusExW.Addr(), 10, uintptr(mgr), uintptr(infoLevel), uintptr(serviceType), uintptr(serviceState), uintptr(unsafe.Pointer(services)), uintptr(bufSize), uintptr(unsafe.Pointer(bytesNeeded)), uintptr(unsafe.Pointer(servicesReturned)), uintptr(unsafe.Pointer(resumeHandle)), uintptr(unsafe.Pointer(groupName)), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("EnumServicesStatusExW", e1)
+	}
+	return
+}
+
+func GetServiceDisplayName(mgr syscall.Handle, serviceName *uint16, displayName *uint16, bufSize *uint32) (err error) {
+	if e := procGetServiceDisplayNameW.Find(); e != nil {
+		err = unavailable("GetServiceDisplayNameW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall6(procGetServiceDisplayNameW.Addr(), 4, uintptr(mgr), uintptr(unsafe.Pointer(serviceName)), uintptr(unsafe.Pointer(displayName)), uintptr(unsafe.Pointer(bufSize)), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("GetServiceDisplayNameW", e1)
+	}
+	return
+}
+
+func GetServiceKeyName(mgr syscall.Handle, displayName *uint16, serviceName *uint16, bufSize *uint32) (err error) {
+	if e := procGetServiceKeyNameW.Find(); e != nil {
+		err = unavailable("GetServiceKeyNameW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall6(procGetServiceKeyNameW.Addr(), 4, uintptr(mgr), uintptr(unsafe.Pointer(displayName)), uintptr(unsafe.Pointer(serviceName)), uintptr(unsafe.Pointer(bufSize)), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("GetServiceKeyNameW", e1)
+	}
+	return
+}
+
+func LockServiceDatabase(mgr syscall.Handle) (lock syscall.Handle, err error) {
+	if e := procLockServiceDatabase.Find(); e != nil {
+		err = unavailable("LockServiceDatabase", e)
+		return
+	}
+	r0, _, e1 := syscall.Syscall(procLockServiceDatabase.Addr(), 1, uintptr(mgr), 0, 0)
+	lock = syscall.Handle(r0)
+	if lock == 0 {
+		err = errnoErr("LockServiceDatabase", e1)
+	}
+	return
+}
+
+func UnlockServiceDatabase(lock syscall.Handle) (err error) {
+	if e := procUnlockServiceDatabase.Find(); e != nil {
+		err = unavailable("UnlockServiceDatabase", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procUnlockServiceDatabase.Addr(), 1, uintptr(lock), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("UnlockServiceDatabase", e1)
+	}
+	return
+}
+
+func QueryServiceLockStatus(mgr syscall.Handle, lockStatus *QUERY_SERVICE_LOCK_STATUS, bufSize uint32, bytesNeeded *uint32) (err error) {
+	if e := procQueryServiceLockStatusW.Find(); e != nil {
+		err = unavailable("QueryServiceLockStatusW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall6(procQueryServiceLockStatusW.Addr(), 4, uintptr(mgr), uintptr(unsafe.Pointer(lockStatus)), uintptr(bufSize), uintptr(unsafe.Pointer(bytesNeeded)), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("QueryServiceLockStatusW", e1)
+	}
+	return
+}
+
+func NotifyBootConfigStatus(bootAcceptable bool) (err error) {
+	if e := procNotifyBootConfigStatus.Find(); e != nil {
+		err = unavailable("NotifyBootConfigStatus", e)
+		return
+	}
+	var _p0 uint32
+	if bootAcceptable {
+		_p0 = 1
+	} else {
+		_p0 = 0
+	}
+	r1, _, e1 := syscall.Syscall(procNotifyBootConfigStatus.Addr(), 1, uintptr(_p0), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("NotifyBootConfigStatus", e1)
+	}
+	return
+}
+
+func QueryServiceDynamicInformation(serviceStatus syscall.Handle, infoLevel uint32, info **byte) (err error) {
+	if e := procQueryServiceDynamicInformation.Find(); e != nil {
+		err = unavailable("QueryServiceDynamicInformation", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procQueryServiceDynamicInformation.Addr(), 3, uintptr(serviceStatus), uintptr(infoLevel), uintptr(unsafe.Pointer(info)))
+	if r1 == 0 {
+		err = errnoErr("QueryServiceDynamicInformation", e1)
+	}
+	return
+}
+
+func WTSGetActiveConsoleSessionId() (id uint32) {
+	if procWTSGetActiveConsoleSessionId.Find() != nil {
+		id = NO_ACTIVE_CONSOLE_SESSION
+		return
+	}
+	r0, _, _ := syscall.Syscall(procWTSGetActiveConsoleSessionId.Addr(), 0, 0, 0, 0)
+	id = uint32(r0)
+	return
+}
+
+func WTSQueryUserToken(session uint32, token *syscall.Token) (err error) {
+	if e := procWTSQueryUserToken.Find(); e != nil {
+		err = unavailable("WTSQueryUserToken", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procWTSQueryUserToken.Addr(), 2, uintptr(session), uintptr(unsafe.Pointer(token)), 0)
+	if r1 == 0 {
+		err = errnoErr("WTSQueryUserToken", e1)
+	}
+	return
+}
+
+func CreateEnvironmentBlock(block **uint16, token syscall.Token, inherit bool) (err error) {
+	if e := procCreateEnvironmentBlock.Find(); e != nil {
+		err = unavailable("CreateEnvironmentBlock", e)
+		return
+	}
+	var _p0 uint32
+	if inherit {
+		_p0 = 1
+	} else {
+		_p0 = 0
+	}
+	r1, _, e1 := syscall.Syscall(procCreateEnvironmentBlock.Addr(), 3, uintptr(unsafe.Pointer(block)), uintptr(token), uintptr(_p0))
+	if r1 == 0 {
+		err = errnoErr("CreateEnvironmentBlock", e1)
+	}
+	return
+}
+
+func DestroyEnvironmentBlock(block *uint16) (err error) {
+	if e := procDestroyEnvironmentBlock.Find(); e != nil {
+		err = unavailable("DestroyEnvironmentBlock", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procDestroyEnvironmentBlock.Addr(), 1, uintptr(unsafe.Pointer(block)), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("DestroyEnvironmentBlock", e1)
+	}
+	return
+}
+
+func WTSEnumerateSessions(server syscall.Handle, reserved uint32, version uint32, sessions **WTS_SESSION_INFO, count *uint32) (err error) {
+	if e := procWTSEnumerateSessionsW.Find(); e != nil {
+		err = unavailable("WTSEnumerateSessionsW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall6(procWTSEnumerateSessionsW.Addr(), 5, uintptr(server), uintptr(reserved), uintptr(version), uintptr(unsafe.Pointer(sessions)), uintptr(unsafe.Pointer(count)), 0)
+	if r1 == 0 {
+		err = errnoErr("WTSEnumerateSessionsW", e1)
+	}
+	return
+}
+
+func WTSQuerySessionInformation(server syscall.Handle, session uint32, infoClass uint32, buffer **uint16, bytesReturned *uint32) (err error) {
+	if e := procWTSQuerySessionInformationW.Find(); e != nil {
+		err = unavailable("WTSQuerySessionInformationW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall6(procWTSQuerySessionInformationW.Addr(), 5, uintptr(server), uintptr(session), uintptr(infoClass), uintptr(unsafe.Pointer(buffer)), uintptr(unsafe.Pointer(bytesReturned)), 0)
+	if r1 == 0 {
+		err = errnoErr("WTSQuerySessionInformationW", e1)
+	}
+	return
+}
+
+func WTSFreeMemory(p uintptr) {
+	if procWTSFreeMemory.Find() != nil {
+		return
+	}
+	syscall.Syscall(procWTSFreeMemory.Addr(), 1, uintptr(p), 0, 0)
+	return
+}
+
+func WTSSendMessage(server syscall.Handle, session uint32, title *uint16, titleLen uint32, message *uint16, messageLen uint32, style uint32, timeout uint32, response *uint32, wait bool) (err error) {
+	if e := procWTSSendMessageW.Find(); e != nil {
+		err = unavailable("WTSSendMessageW", e)
+		return
+	}
+	var _p0 uint32
+	if wait {
+		_p0 = 1
+	} else {
+		_p0 = 0
+	}
+	r1, _, e1 := syscall.Syscall12(procWTSSendMessageW.Addr(), 10, uintptr(server), uintptr(session), uintptr(unsafe.Pointer(title)), uintptr(titleLen), uintptr(unsafe.Pointer(message)), uintptr(messageLen), uintptr(style), uintptr(timeout), uintptr(unsafe.Pointer(response)), uintptr(_p0), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("WTSSendMessageW", e1)
+	}
+	return
+}
+
+func SHLoadIndirectString(source *uint16, buf *uint16, bufLen uint32, reserved uintptr) (hr error) {
+	if e := procSHLoadIndirectString.Find(); e != nil {
+		hr = unavailable("SHLoadIndirectString", e)
+		return
+	}
+	r0, _, _ := syscall.Syscall6(procSHLoadIndirectString.Addr(), 4, uintptr(unsafe.Pointer(source)), uintptr(unsafe.Pointer(buf)), uintptr(bufLen), uintptr(reserved), 0, 0)
+	if r0 != 0 {
+		hr = errnoErr("SHLoadIndirectString", syscall.Errno(r0))
+	}
+	return
+}
+
+func ShellExecuteEx(info *SHELLEXECUTEINFO) (err error) {
+	if e := procShellExecuteExW.Find(); e != nil {
+		err = unavailable("ShellExecuteExW", e)
+		return
+	}
+	r1, _, e1 := syscall.Syscall(procShellExecuteExW.Addr(), 1, uintptr(unsafe.Pointer(info)), 0, 0)
+	if r1 == 0 {
+		err = errnoErr("ShellExecuteExW", e1)
 	}
 	return
 }
 
 func GetCurrentThreadId() (id uint32) {
+	if procGetCurrentThreadId.Find() != nil {
+		return
+	}
 	r0, _, _ := syscall.Syscall(procGetCurrentThreadId.Addr(), 0, 0, 0, 0)
 	id = uint32(r0)
 	return
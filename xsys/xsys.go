@@ -0,0 +1,169 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+// Package xsys converts between svc package types and those of
+// golang.org/x/sys/windows/svc. A handler written for either
+// package can run under Run of the other, so projects can move
+// to this repository one service at a time.
+//
+// Command, state and accepted control values are the same Windows
+// constants in both packages, and are converted unchanged.
+// ChangeRequest event fields and Status exit codes, which svc does
+// not have, are dropped on the way to svc and zero on the way back.
+//
+package xsys
+
+import (
+	"sync"
+
+	xsvc "golang.org/x/sys/windows/svc"
+
+	"github.com/multiplay/winsvc/svc"
+)
+
+// Status converts x/sys status s into svc.Status.
+func Status(s xsvc.Status) svc.Status {
+	return svc.Status{
+		State:      svc.State(s.State),
+		Accepts:    svc.Accepted(s.Accepts),
+		CheckPoint: s.CheckPoint,
+		WaitHint:   s.WaitHint,
+		ProcessId:  s.ProcessId,
+	}
+}
+
+// XStatus converts s into x/sys status.
+func XStatus(s svc.Status) xsvc.Status {
+	return xsvc.Status{
+		State:      xsvc.State(s.State),
+		Accepts:    xsvc.Accepted(s.Accepts),
+		CheckPoint: s.CheckPoint,
+		WaitHint:   s.WaitHint,
+		ProcessId:  s.ProcessId,
+	}
+}
+
+// ChangeRequest converts x/sys change request c into svc.ChangeRequest.
+func ChangeRequest(c xsvc.ChangeRequest) svc.ChangeRequest {
+	return svc.ChangeRequest{
+		Cmd:           svc.Cmd(c.Cmd),
+		CurrentStatus: Status(c.CurrentStatus),
+	}
+}
+
+// XChangeRequest converts c into x/sys change request.
+func XChangeRequest(c svc.ChangeRequest) xsvc.ChangeRequest {
+	return xsvc.ChangeRequest{
+		Cmd:           xsvc.Cmd(c.Cmd),
+		CurrentStatus: XStatus(c.CurrentStatus),
+	}
+}
+
+// Handler returns svc.Handler that runs x/sys handler h,
+// so h can be passed to svc.Run or debug.Run.
+func Handler(h xsvc.Handler) svc.Handler {
+	return fromX{h}
+}
+
+// XHandler returns x/sys handler that runs h, so
+// h can be passed to Run of golang.org/x/sys/windows/svc.
+func XHandler(h svc.Handler) xsvc.Handler {
+	return toX{h}
+}
+
+type fromX struct {
+	h xsvc.Handler
+}
+
+func (f fromX) Execute(args []string, r <-chan svc.ChangeRequest, s chan<- svc.Status) (bool, uint32) {
+	xr := make(chan xsvc.ChangeRequest)
+	xs := make(chan xsvc.Status)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case c, ok := <-r:
+				if !ok {
+					r = nil
+					continue
+				}
+				select {
+				case xr <- XChangeRequest(c):
+				case <-done:
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case st := <-xs:
+				s <- Status(st)
+			case <-done:
+				return
+			}
+		}
+	}()
+	ssec, ec := f.h.Execute(args, xr, xs)
+	// status being copied is delivered before
+	// Execute returns, so it is not lost
+	close(done)
+	wg.Wait()
+	return ssec, ec
+}
+
+type toX struct {
+	h svc.Handler
+}
+
+func (t toX) Execute(args []string, r <-chan xsvc.ChangeRequest, s chan<- xsvc.Status) (bool, uint32) {
+	sr := make(chan svc.ChangeRequest)
+	ss := make(chan svc.Status)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case c, ok := <-r:
+				if !ok {
+					r = nil
+					continue
+				}
+				select {
+				case sr <- ChangeRequest(c):
+				case <-done:
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case st := <-ss:
+				s <- XStatus(st)
+			case <-done:
+				return
+			}
+		}
+	}()
+	ssec, ec := t.h.Execute(args, sr, ss)
+	close(done)
+	wg.Wait()
+	return ssec, ec
+}
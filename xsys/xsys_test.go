@@ -0,0 +1,86 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package xsys_test
+
+import (
+	"testing"
+
+	xsvc "golang.org/x/sys/windows/svc"
+
+	"github.com/multiplay/winsvc/svc"
+	"github.com/multiplay/winsvc/xsys"
+)
+
+func TestStatus(t *testing.T) {
+	s := svc.Status{
+		State:      svc.StopPending,
+		Accepts:    svc.AcceptStop | svc.AcceptPreShutdown,
+		CheckPoint: 3,
+		WaitHint:   1000,
+		ProcessId:  42,
+	}
+	x := xsys.XStatus(s)
+	if x.State != xsvc.StopPending || x.Accepts != xsvc.AcceptStop|xsvc.AcceptPreShutdown {
+		t.Fatalf("XStatus(%+v) = %+v", s, x)
+	}
+	if got := xsys.Status(x); got != s {
+		t.Fatalf("round trip returns %+v, want %+v", got, s)
+	}
+	c := xsys.ChangeRequest(xsvc.ChangeRequest{Cmd: xsvc.PreShutdown, CurrentStatus: x})
+	if c.Cmd != svc.PreShutdown || c.CurrentStatus != s {
+		t.Fatalf("ChangeRequest returns %+v", c)
+	}
+}
+
+// xstoppable is x/sys handler that runs until stopped.
+type xstoppable struct{}
+
+func (xstoppable) Execute(args []string, r <-chan xsvc.ChangeRequest, s chan<- xsvc.Status) (bool, uint32) {
+	s <- xsvc.Status{State: xsvc.Running, Accepts: xsvc.AcceptStop}
+	for c := range r {
+		switch c.Cmd {
+		case xsvc.Interrogate:
+			s <- c.CurrentStatus
+		case xsvc.Stop:
+			s <- xsvc.Status{State: xsvc.StopPending}
+			return true, 7
+		}
+	}
+	return false, 0
+}
+
+func TestHandler(t *testing.T) {
+	// XHandler(Handler(h)) runs h through both adapters
+	h := xsys.XHandler(xsys.Handler(xstoppable{}))
+	r := make(chan xsvc.ChangeRequest)
+	s := make(chan xsvc.Status)
+	type result struct {
+		ssec bool
+		ec   uint32
+	}
+	done := make(chan result)
+	go func() {
+		ssec, ec := h.Execute([]string{"test"}, r, s)
+		done <- result{ssec, ec}
+	}()
+	if st := <-s; st.State != xsvc.Running {
+		t.Fatalf("first status is %+v, want Running", st)
+	}
+	cur := xsvc.Status{State: xsvc.Running, Accepts: xsvc.AcceptStop}
+	r <- xsvc.ChangeRequest{Cmd: xsvc.Interrogate, CurrentStatus: cur}
+	if st := <-s; st != cur {
+		t.Fatalf("Interrogate returns %+v, want %+v", st, cur)
+	}
+	r <- xsvc.ChangeRequest{Cmd: xsvc.Stop}
+	if st := <-s; st.State != xsvc.StopPending {
+		t.Fatalf("status after Stop is %+v, want StopPending", st)
+	}
+	res := <-done
+	if !res.ssec || res.ec != 7 {
+		t.Fatalf("Execute returns %v, %d, want true, 7", res.ssec, res.ec)
+	}
+}